@@ -1,11 +1,39 @@
 import { Injectable } from '@angular/core';
 import { HttpClient, HttpErrorResponse } from '@angular/common/http';
-import { map, tap, catchError } from 'rxjs/operators';
+import { map, tap, catchError, shareReplay, finalize } from 'rxjs/operators';
 import { Observable, throwError } from 'rxjs';
 
+export type Role = 'viewer' | 'operator' | 'admin';
+
+export type UserSource = 'local' | 'oidc';
+
 export interface IUser {
   id: number;
   username: string;
+  displayName: string;
+  email: string;
+  role: Role;
+  source: UserSource;
+  mfaEnabled: boolean;
+  isDisabled: boolean;
+  allProjects: boolean;
+  lockedUntil?: Date;
+}
+
+export interface IAuthOptions {
+  oidc: boolean;
+}
+
+export interface IUserCreateParams {
+  username: string;
+  displayName: string;
+  email: string;
+  role: Role;
+}
+
+export interface IUserPasswordResponse {
+  user: IUser;
+  password: string;
 }
 
 export type BackupType = 'last' | 'archive';
@@ -16,6 +44,34 @@ export interface IBackup {
   time: Date;
   type: BackupType;
   length: number;
+  keepReason?: string;
+  pin?: IBackupPin;
+  lock?: IBackupLock;
+  storage?: string;
+  deletedAt?: string;
+}
+
+export interface ITrashedBackup extends IBackup {
+  projectId: string;
+  purgeAt: string;
+}
+
+export interface IBackupPin {
+  reason: string;
+  pinnedBy: string;
+  pinnedAt: string;
+  until?: string;
+}
+
+export interface IBackupLock {
+  mode: string;
+  until: string;
+}
+
+export interface ILegalHold {
+  reason: string;
+  placedBy: string;
+  placedAt: string;
 }
 export type BackupStatus = 'ok' | 'outdated' | 'none';
 
@@ -26,15 +82,93 @@ export interface INotificationParams {
   webhook: string[];
 }
 
+export interface IRetentionRules {
+  daily: number;
+  weekly: number;
+  monthly: number;
+  yearly: number;
+  keepWithin?: string;
+  maxSize?: number;
+  minCount?: number;
+}
+
+export type QuotaPolicy = 'reject' | 'prune';
+
+export interface IStorageQuota {
+  limit: number;
+  policy: QuotaPolicy;
+}
+
+export interface IStorageUsage {
+  projectId?: string;
+  used: number;
+  quota?: IStorageQuota;
+  percent: number;
+}
+
+export interface IDiskSpace {
+  free: number;
+  total: number;
+  percent: number;
+}
+
+export interface IStorageBackend {
+  name: string;
+  type: 'fs' | 's3';
+  isDefault: boolean;
+  lockMode?: string;
+  disk?: IDiskSpace;
+}
+
+export interface IStorageStatus {
+  usage: IStorageUsage;
+  backends: IStorageBackend[];
+}
+
+export interface IRetentionParams {
+  backupRetention?: number;
+  retention?: IRetentionRules;
+}
+
+export interface IRetentionDecision {
+  backup: IBackup;
+  keep: boolean;
+  reason: string;
+}
+
+export interface IRetentionPreview {
+  deleted: IRetentionDecision[];
+  kept: number;
+  freedBytes: number;
+  keptBytes: number;
+}
+
 export interface IProject {
   id: string;
   name: string;
   isActive: boolean;
   backupFrequency: number;
   backupRetention: number;
+  retention?: IRetentionRules;
   notifications: INotificationParams;
   lastBackup?: IBackup;
   backupStatus: BackupStatus;
+  lastCheckIn?: string;
+  legalHold?: ILegalHold;
+  quota?: IStorageQuota;
+  storage: string;
+  deletedAt?: string;
+  tags: string[];
+  labels: { [key: string]: string };
+}
+
+export interface ITrashedProject extends IProject {
+  purgeAt: string;
+}
+
+export interface ITrash {
+  projects: ITrashedProject[];
+  backups: ITrashedBackup[];
 }
 
 export interface IProjectCreateParams {
@@ -43,6 +177,7 @@ export interface IProjectCreateParams {
   isActive: boolean;
   backupFrequency: number;
   backupRetention: number;
+  storage?: string;
   notifications: INotificationParams;
 }
 
@@ -51,22 +186,110 @@ export interface IProjectUpdateParams {
   isActive?: boolean;
   backupFrequency: number;
   backupRetention: number;
+  retention?: IRetentionRules;
+  quota?: IStorageQuota;
+  storage?: string;
   notifications: INotificationParams;
+  confirmDeletion?: boolean;
+  tags?: string[];
+  labels?: { [key: string]: string };
 }
 
+export type AccessKeyScope = 'upload' | 'download' | 'checkin' | 'list';
+
 export interface IAccessKey {
   id: number;
   label: string;
-  key: string;
+  key?: string;
+  prefix: string;
+  scopes: AccessKeyScope[];
+  createdAt?: string;
+  expiresAt?: string;
+  lastUsedAt?: string;
+  lastUsedIp?: string;
+  replacedBy?: number;
+  allowedNetworks: string[];
+}
+
+export interface IDownloadLink {
+  url: string;
+  expiresAt: Date;
+}
+
+export type DeliveryStatus = 'pending' | 'sent' | 'dead' | 'skipped';
+
+export interface IDelivery {
+  id: number;
+  projectId: string;
+  event: string;
+  channel: string;
+  target: string;
+  status: DeliveryStatus;
+  attempts: number;
+  lastError: string;
+  createdAt: Date;
+  deliveredAt?: Date;
 }
 
 interface IAuthResponse {
   token: string;
+  expiresAt: Date;
+  refreshToken: string;
   user: IUser;
+  mfa?: IMFAChallenge;
+  recoveryCodes?: string[];
+}
+
+export interface IMFAEnrollment {
+  secret: string;
+  uri: string;
+  qrCode: string;
+}
+
+export interface IMFAChallenge {
+  token: string;
+  expiresAt: Date;
+  enrollment?: IMFAEnrollment;
+  enrollmentTokenRequired?: boolean;
+}
+
+export interface IMFAEnrollmentToken {
+  token: string;
+  expiresAt: Date;
+}
+
+export interface ISecuritySettings {
+  requireMfa: boolean;
+  requireMfaEnrollmentToken: boolean;
+}
+
+export interface ISession {
+  id: string;
+  createdAt: Date;
+  lastUsedAt: Date;
+  expiresAt: Date;
+  ip: string;
+  userAgent: string;
+  isCurrent: boolean;
+}
+
+export interface IAPIToken {
+  id: number;
+  userId: number;
+  name: string;
+  token?: string;
+  prefix: string;
+  role: Role;
+  projects: string[];
+  createdAt: Date;
+  expiresAt?: Date;
+  lastUsedAt?: Date;
+  lastUsedIp?: string;
 }
 
 const localStorageKeys = {
   token: 'api_token',
+  refreshToken: 'api_refresh_token',
   user: 'api_user'
 };
 
@@ -75,10 +298,13 @@ const localStorageKeys = {
 })
 export class ApiService {
   private token: string | null;
+  private refreshToken: string | null;
   private user: IUser | null;
+  private refreshing?: Observable<string>;
 
   constructor(private http: HttpClient) {
     this.token = localStorage.getItem(localStorageKeys.token);
+    this.refreshToken = localStorage.getItem(localStorageKeys.refreshToken);
 
     const j = localStorage.getItem(localStorageKeys.user);
     this.user = j && JSON.parse(j) || null;
@@ -92,35 +318,231 @@ export class ApiService {
     return this.user as IUser;
   }
 
-  public authorize(username: string, password: string): Observable<void> {
+  // Signs in with a password. Returns an MFA challenge if user has to enter a one-time code
+  public authorize(username: string, password: string): Observable<IMFAChallenge | undefined> {
     return this.http.post<IAuthResponse>('/api/authorize', { username, password })
       .pipe(
         catchError(ApiService.handleError)
       )
       .pipe(
         tap(r => {
-          this.token = r.token;
-          this.user = r.user;
-
-          localStorage.setItem(localStorageKeys.token, this.token);
-          localStorage.setItem(localStorageKeys.user, JSON.stringify(this.user));
+          if (!r.mfa) {
+            this.storeAuthResponse(r);
+          }
         })
       )
+      .pipe(
+        map(r => r.mfa)
+      );
+  }
+
+  // Completes an MFA challenge. Returns recovery codes if user has just enrolled
+  public completeMFA(token: string, code: string): Observable<string[] | undefined> {
+    return this.http.post<IAuthResponse>('/api/authorize/mfa', { token, code })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        tap(r => this.storeAuthResponse(r))
+      )
+      .pipe(
+        map(r => r.recoveryCodes)
+      );
+  }
+
+  // Exchanges an enrollment token issued by admin to a pending enrollment of an MFA challenge
+  public startMFAEnrollment(token: string, enrollmentToken: string): Observable<IMFAEnrollment> {
+    return this.http.post<IMFAEnrollment>('/api/authorize/mfa/enroll', { token, enrollmentToken })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public enrollMFA(): Observable<IMFAEnrollment> {
+    return this.http.post<IMFAEnrollment>('/api/me/mfa', {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public activateMFA(code: string): Observable<string[]> {
+    return this.http.post<{ codes: string[] }>('/api/me/mfa/activate', { code }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        tap(() => this.setMFAEnabled(true))
+      )
+      .pipe(
+        map(r => r.codes)
+      );
+  }
+
+  public disableMFA(code: string): Observable<void> {
+    return this.http.post<void>('/api/me/mfa/disable', { code }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        tap(() => this.setMFAEnabled(false))
+      );
+  }
+
+  public regenerateRecoveryCodes(code: string): Observable<string[]> {
+    return this.http.post<{ codes: string[] }>('/api/me/mfa/recovery-codes', { code }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        map(r => r.codes)
+      );
+  }
+
+  private setMFAEnabled(enabled: boolean) {
+    if (this.user) {
+      this.user.mfaEnabled = enabled;
+      localStorage.setItem(localStorageKeys.user, JSON.stringify(this.user));
+    }
+  }
+
+  public getAuthOptions(): Observable<IAuthOptions> {
+    return this.http.get<IAuthOptions>('/api/authorize/options')
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  // Exchanges a one-time ticket issued after OpenID Connect login to a session
+  public authorizeWithTicket(ticket: string): Observable<void> {
+    return this.http.post<IAuthResponse>('/api/authorize/oidc/token', { ticket })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        tap(r => this.storeAuthResponse(r))
+      )
       .pipe(
         map(_ => { })
       );
   }
 
+  // Renews access token. Concurrent callers share a single request, since refresh tokens are rotated
+  public refresh(): Observable<string> {
+    if (!this.refreshToken) {
+      this.unauthorize();
+      return throwError('session has expired');
+    }
+
+    if (!this.refreshing) {
+      this.refreshing = this.http.post<IAuthResponse>('/api/authorize/refresh', { refreshToken: this.refreshToken })
+        .pipe(
+          catchError((e) => {
+            this.unauthorize();
+            window.location.href = '/login';
+            return ApiService.handleError(e);
+          }),
+          tap(r => this.storeAuthResponse(r)),
+          map(r => r.token),
+          finalize(() => this.refreshing = undefined),
+          shareReplay(1)
+        );
+    }
+
+    return this.refreshing;
+  }
+
+  public logout(): Observable<void> {
+    return this.http.post<void>('/api/logout', {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        finalize(() => this.unauthorize())
+      );
+  }
+
   public unauthorize() {
     this.token = null;
+    this.refreshToken = null;
     this.user = null;
 
     localStorage.removeItem(localStorageKeys.token);
+    localStorage.removeItem(localStorageKeys.refreshToken);
     localStorage.removeItem(localStorageKeys.user);
   }
 
   public changePassword(oldPassword: string, newPassword: string): Observable<{}> {
-    return this.http.post<{}>(`/api/me/password`, { oldPassword, newPassword }, {
+    return this.http.post<IAuthResponse>(`/api/me/password`, { oldPassword, newPassword }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        tap(r => this.storeAuthResponse(r))
+      );
+  }
+
+  public getSessions(): Observable<ISession[]> {
+    return this.http.get<ISession[]>('/api/me/sessions', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public revokeAllSessions(): Observable<void> {
+    return this.http.delete<void>('/api/me/sessions', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        finalize(() => this.unauthorize())
+      );
+  }
+
+  public getAPITokens(): Observable<IAPIToken[]> {
+    return this.http.get<IAPIToken[]>('/api/me/tokens', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public createAPIToken(name: string, role: Role, projects: string[], expiresAt?: string): Observable<IAPIToken> {
+    return this.http.post<IAPIToken>('/api/me/tokens', { name, role, projects, expiresAt }, {
       headers: {
         Authorization: `Bearer ${this.token}`
       }
@@ -130,6 +552,27 @@ export class ApiService {
       );
   }
 
+  public revokeAPIToken(id: number): Observable<void> {
+    return this.http.delete<void>(`/api/me/tokens/${id}`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  private storeAuthResponse(r: IAuthResponse) {
+    this.token = r.token;
+    this.refreshToken = r.refreshToken;
+    this.user = r.user;
+
+    localStorage.setItem(localStorageKeys.token, this.token);
+    localStorage.setItem(localStorageKeys.refreshToken, this.refreshToken);
+    localStorage.setItem(localStorageKeys.user, JSON.stringify(this.user));
+  }
+
   public getProjects(): Observable<IProject[]> {
     return this.http.get<IProject[]>('/api/projects', {
       headers: {
@@ -177,6 +620,39 @@ export class ApiService {
       );
   }
 
+  public placeLegalHold(id: string, reason: string): Observable<IProject> {
+    return this.http.post<IProject>(`/api/projects/${id}/hold`, { reason }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public releaseLegalHold(id: string): Observable<IProject> {
+    return this.http.delete<IProject>(`/api/projects/${id}/hold`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public previewRetention(id: string, params: IRetentionParams): Observable<IRetentionPreview> {
+    return this.http.post<IRetentionPreview>(`/api/projects/${id}/retention/preview`, params, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
   public updateProject(id: string, project: IProjectUpdateParams): Observable<IProject> {
     return this.http.put<IProject>(`/api/projects/${id}`, project, {
       headers: {
@@ -199,6 +675,72 @@ export class ApiService {
       );
   }
 
+  public restoreProject(projectId: string): Observable<IProject> {
+    return this.http.post<IProject>(`/api/projects/${projectId}/restore`, {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getTrash(): Observable<ITrash> {
+    return this.http.get<ITrash>('/api/trash', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getProjectUsage(projectId: string): Observable<IStorageUsage> {
+    return this.http.get<IStorageUsage>(`/api/projects/${projectId}/usage`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getStorageStatus(): Observable<IStorageStatus> {
+    return this.http.get<IStorageStatus>('/api/storage', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getStorageBackends(): Observable<IStorageBackend[]> {
+    return this.http.get<IStorageBackend[]>('/api/storage/backends', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getProjectTrash(projectId: string): Observable<ITrashedBackup[]> {
+    return this.http.get<ITrashedBackup[]>(`/api/projects/${projectId}/trash`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
   public getProjectBackups(id: string): Observable<IBackup[]> {
     return this.http.get<IBackup[]>(`/api/projects/${id}/backup`, {
       headers: {
@@ -229,6 +771,50 @@ export class ApiService {
       );
   }
 
+  public restoreBackup(backupId: string): Observable<IBackup> {
+    return this.http.post<IBackup>(`/api/backup/${backupId}/restore`, {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public pinBackup(backupId: string, reason: string, until?: string): Observable<IBackup> {
+    return this.http.post<IBackup>(`/api/backup/${backupId}/pin`, { reason, until }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public unpinBackup(backupId: string): Observable<IBackup> {
+    return this.http.delete<IBackup>(`/api/backup/${backupId}/pin`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public createBackupDownloadLink(backupId: string): Observable<IDownloadLink> {
+    return this.http.post<IDownloadLink>(`/api/backup/${backupId}/link`, {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
   public getProjectAccessKeys(id: string): Observable<IAccessKey[]> {
     return this.http.get<IAccessKey[]>(`/api/projects/${id}/keys`, {
       headers: {
@@ -240,8 +826,24 @@ export class ApiService {
       );
   }
 
-  public createProjectAccessKey(projectId: string, label: string): Observable<IAccessKey> {
-    return this.http.post<IAccessKey>(`/api/projects/${projectId}/keys`, { label }, {
+  public createProjectAccessKey(
+    projectId: string,
+    label: string,
+    scopes: AccessKeyScope[],
+    allowedNetworks: string[],
+    expiresAt?: string): Observable<IAccessKey> {
+    return this.http.post<IAccessKey>(`/api/projects/${projectId}/keys`, { label, scopes, allowedNetworks, expiresAt }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public rotateProjectAccessKey(projectId: string, accessKeyId: number, overlap: string): Observable<IAccessKey> {
+    return this.http.post<IAccessKey>(`/api/projects/${projectId}/keys/${accessKeyId}/rotate`, { overlap }, {
       headers: {
         Authorization: `Bearer ${this.token}`
       }
@@ -295,6 +897,168 @@ export class ApiService {
       );
   }
 
+  public getDeliveries(): Observable<IDelivery[]> {
+    return this.http.get<IDelivery[]>('/api/notify/deliveries', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      )
+      .pipe(
+        map((xs) => {
+          for (const i in xs) {
+            xs[i] = ApiService.mapDelivery(xs[i]);
+          }
+          return xs;
+        })
+      );
+  }
+
+  public redeliver(deliveryId: number): Observable<IDelivery> {
+    return this.http.post<IDelivery>(`/api/notify/deliveries/${deliveryId}/redeliver`, {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getUsers(): Observable<IUser[]> {
+    return this.http.get<IUser[]>('/api/users', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public createUser(params: IUserCreateParams): Observable<IUserPasswordResponse> {
+    return this.http.post<IUserPasswordResponse>('/api/users', params, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public setUserDisabled(userId: number, isDisabled: boolean): Observable<IUser> {
+    return this.http.put<IUser>(`/api/users/${userId}`, { isDisabled }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public setUserRole(userId: number, role: Role): Observable<IUser> {
+    return this.http.put<IUser>(`/api/users/${userId}`, { role }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public setUserAllProjects(userId: number, allProjects: boolean): Observable<IUser> {
+    return this.http.put<IUser>(`/api/users/${userId}`, { allProjects }, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public deleteUser(userId: number): Observable<void> {
+    return this.http.delete<void>(`/api/users/${userId}`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public resetUserPassword(userId: number): Observable<IUserPasswordResponse> {
+    return this.http.post<IUserPasswordResponse>(`/api/users/${userId}/password/reset`, {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public resetUserMFA(userId: number): Observable<IUser> {
+    return this.http.delete<IUser>(`/api/users/${userId}/mfa`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public issueUserMFAEnrollmentToken(userId: number): Observable<IMFAEnrollmentToken> {
+    return this.http.post<IMFAEnrollmentToken>(`/api/users/${userId}/mfa/enrollment-token`, {}, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public unlockUser(userId: number): Observable<IUser> {
+    return this.http.delete<IUser>(`/api/users/${userId}/lockout`, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public getSecuritySettings(): Observable<ISecuritySettings> {
+    return this.http.get<ISecuritySettings>('/api/settings/security', {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
+  public updateSecuritySettings(settings: Partial<ISecuritySettings>): Observable<ISecuritySettings> {
+    return this.http.put<ISecuritySettings>('/api/settings/security', settings, {
+      headers: {
+        Authorization: `Bearer ${this.token}`
+      }
+    })
+      .pipe(
+        catchError(ApiService.handleError)
+      );
+  }
+
   private static handleError(error: HttpErrorResponse) {
     if (error.error?.message) {
       return throwError(error.error?.message);
@@ -329,5 +1093,20 @@ export class ApiService {
 
     return obj as IBackup;
   }
-}
 
+  private static mapDelivery(obj: any): IDelivery {
+    if (!obj) {
+      return obj;
+    }
+
+    if (obj.createdAt) {
+      obj.createdAt = new Date(Date.parse(obj.createdAt as string));
+    }
+
+    if (obj.deliveredAt) {
+      obj.deliveredAt = new Date(Date.parse(obj.deliveredAt as string));
+    }
+
+    return obj as IDelivery;
+  }
+}
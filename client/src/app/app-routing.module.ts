@@ -8,6 +8,8 @@ import { ProjectsPageComponent } from './projects-page/projects-page.component';
 import { PageNotFoundComponent } from './page-not-found/page-not-found.component';
 import { CreateProjectPageComponent } from './create-project-page/create-project-page.component';
 import { EditProjectPageComponent } from './edit-project-page/edit-project-page.component';
+import { DeliveriesPageComponent } from './deliveries-page/deliveries-page.component';
+import { UsersPageComponent } from './users-page/users-page.component';
 
 const routes: Routes = [
   { path: 'login', component: LoginPageComponent },
@@ -16,6 +18,8 @@ const routes: Routes = [
   { path: 'new-project', component: CreateProjectPageComponent, canActivate: [AuthGuard] },
   { path: 'projects/:id/edit', component: EditProjectPageComponent, canActivate: [AuthGuard] },
   { path: 'projects/:id', component: ProjectPageComponent, canActivate: [AuthGuard] },
+  { path: 'deliveries', component: DeliveriesPageComponent, canActivate: [AuthGuard] },
+  { path: 'users', component: UsersPageComponent, canActivate: [AuthGuard] },
   { path: '', redirectTo: '/projects', canActivate: [AuthGuard], pathMatch: 'full' },
   { path: '**', component: PageNotFoundComponent }
 ];
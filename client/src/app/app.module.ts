@@ -1,7 +1,7 @@
 import { BrowserModule } from '@angular/platform-browser';
 import { NgModule } from '@angular/core';
 import { FormsModule, ReactiveFormsModule } from '@angular/forms';
-import { HttpClientModule } from '@angular/common/http';
+import { HttpClientModule, HTTP_INTERCEPTORS } from '@angular/common/http';
 import { FontAwesomeModule } from '@fortawesome/angular-fontawesome';
 import { HighlightModule, HIGHLIGHT_OPTIONS } from 'ngx-highlightjs';
 import { NgbModalModule, NgbDropdownModule, NgbCollapseModule } from '@ng-bootstrap/ng-bootstrap';
@@ -22,6 +22,7 @@ import { ProjectAccessKeysComponent } from './project-page/project-access-keys/p
 import { ViewAccessKeyModalComponent } from './modals/view-access-key-modal/view-access-key-modal.component';
 import { DeleteAccessKeyModalComponent } from './modals/delete-access-key-modal/delete-access-key-modal.component';
 import { CreateAccessKeyModalComponent } from './modals/create-access-key-modal/create-access-key-modal.component';
+import { RotateAccessKeyModalComponent } from './modals/rotate-access-key-modal/rotate-access-key-modal.component';
 import { DeleteBackupModalComponent } from './modals/delete-backup-modal/delete-backup-modal.component';
 import { DeleteProjectModalComponent } from './modals/delete-project-modal/delete-project-modal.component';
 import { EditProjectPageComponent } from './edit-project-page/edit-project-page.component';
@@ -30,6 +31,11 @@ import { NotificationTargetsEditorComponent } from './controls/notification-targ
 import { AddNotificationTargetModalComponent } from './modals/add-notification-target-modal/add-notification-target-modal.component';
 import { ProjectListItemComponent } from './projects-page/project-list-item/project-list-item.component';
 import { ChangePasswordModalComponent } from './modals/change-password-modal/change-password-modal.component';
+import { TwoFactorModalComponent } from './modals/two-factor-modal/two-factor-modal.component';
+import { APITokensModalComponent } from './modals/api-tokens-modal/api-tokens-modal.component';
+import { DeliveriesPageComponent } from './deliveries-page/deliveries-page.component';
+import { UsersPageComponent } from './users-page/users-page.component';
+import { AuthInterceptor } from './auth.interceptor';
 
 export function getHighlightLanguages() {
   return {
@@ -54,6 +60,7 @@ export function getHighlightLanguages() {
     ViewAccessKeyModalComponent,
     DeleteAccessKeyModalComponent,
     CreateAccessKeyModalComponent,
+    RotateAccessKeyModalComponent,
     DeleteBackupModalComponent,
     DeleteProjectModalComponent,
     EditProjectPageComponent,
@@ -61,7 +68,11 @@ export function getHighlightLanguages() {
     NotificationTargetsEditorComponent,
     AddNotificationTargetModalComponent,
     ProjectListItemComponent,
-    ChangePasswordModalComponent
+    ChangePasswordModalComponent,
+    TwoFactorModalComponent,
+    APITokensModalComponent,
+    DeliveriesPageComponent,
+    UsersPageComponent
   ],
   imports: [
     BrowserModule,
@@ -78,6 +89,11 @@ export function getHighlightLanguages() {
     ClipboardModule,
   ],
   providers: [
+    {
+      provide: HTTP_INTERCEPTORS,
+      useClass: AuthInterceptor,
+      multi: true
+    },
     {
       provide: HIGHLIGHT_OPTIONS,
       useValue: {
@@ -0,0 +1,35 @@
+import { Injectable, Injector } from '@angular/core';
+import { HttpInterceptor, HttpRequest, HttpHandler, HttpEvent, HttpErrorResponse } from '@angular/common/http';
+import { Observable, throwError } from 'rxjs';
+import { catchError, switchMap } from 'rxjs/operators';
+import { ApiService } from './api.service';
+
+// Renews expired access tokens with refresh token and retries failed requests
+@Injectable()
+export class AuthInterceptor implements HttpInterceptor {
+  constructor(private injector: Injector) { }
+
+  intercept(req: HttpRequest<any>, next: HttpHandler): Observable<HttpEvent<any>> {
+    if (req.url.startsWith('/api/authorize') || !req.headers.has('Authorization')) {
+      return next.handle(req);
+    }
+
+    return next.handle(req).pipe(
+      catchError((e: HttpErrorResponse) => {
+        if (e.status !== 401) {
+          return throwError(e);
+        }
+
+        // ApiService depends on HttpClient, so it can't be injected directly
+        const api = this.injector.get(ApiService);
+        return api.refresh().pipe(
+          switchMap(token => next.handle(req.clone({
+            setHeaders: {
+              Authorization: `Bearer ${token}`
+            }
+          })))
+        );
+      })
+    );
+  }
+}
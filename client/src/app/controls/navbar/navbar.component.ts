@@ -1,7 +1,10 @@
 import { Component } from '@angular/core';
 import { ApiService } from '../../api.service';
 import { NgbModal } from '@ng-bootstrap/ng-bootstrap';
+import { Router } from '@angular/router';
 import { ChangePasswordModalComponent } from 'src/app/modals/change-password-modal/change-password-modal.component';
+import { TwoFactorModalComponent } from 'src/app/modals/two-factor-modal/two-factor-modal.component';
+import { APITokensModalComponent } from 'src/app/modals/api-tokens-modal/api-tokens-modal.component';
 
 @Component({
   selector: 'app-navbar',
@@ -9,10 +12,12 @@ import { ChangePasswordModalComponent } from 'src/app/modals/change-password-mod
 })
 export class NavBarComponent {
   username: string;
+  isAdmin: boolean;
   isMenuCollapsed: boolean = true;
 
-  constructor(api: ApiService, private modal: NgbModal) {
+  constructor(private api: ApiService, private modal: NgbModal, private router: Router) {
     this.username = api.getUser()?.username;
+    this.isAdmin = api.getUser()?.role === 'admin';
   }
 
   toggleNavBar() {
@@ -25,6 +30,30 @@ export class NavBarComponent {
     return false;
   }
 
+  twoFactor() {
+    this.onItemClicked();
+    this.modal.open(TwoFactorModalComponent);
+    return false;
+  }
+
+  apiTokens() {
+    this.onItemClicked();
+    this.modal.open(APITokensModalComponent, { size: 'lg' });
+    return false;
+  }
+
+  signOutEverywhere() {
+    this.onItemClicked();
+    if (!confirm('Sign out of all sessions, including this one?')) {
+      return false;
+    }
+
+    this.api.revokeAllSessions().subscribe(
+      () => this.router.navigate(['/login']),
+      () => this.router.navigate(['/login']));
+    return false;
+  }
+
   onItemClicked() {
     this.isMenuCollapsed = true;
   }
@@ -1,6 +1,6 @@
 import { Component, OnInit } from '@angular/core';
 import { FormGroup, FormControl, Validators } from '@angular/forms';
-import { IProjectCreateParams, ApiService } from '../api.service';
+import { IProjectCreateParams, ApiService, IStorageBackend } from '../api.service';
 import { Router } from '@angular/router';
 
 @Component({
@@ -28,6 +28,7 @@ export class CreateProjectPageComponent implements OnInit {
 
   project: IProjectCreateParams;
   form: FormGroup;
+  backends: IStorageBackend[] = [];
 
   isBusy: boolean;
   error?: string;
@@ -59,10 +60,15 @@ export class CreateProjectPageComponent implements OnInit {
         Validators.required,
         Validators.min(1),
       ]),
+      'storage': new FormControl(''),
       'isActive': new FormControl(this.project.isActive),
       'notifications': new FormControl(this.project.notifications),
     });
 
+    this.api.getStorageBackends().subscribe(
+      (backends) => this.backends = backends,
+      () => this.backends = []);
+
   }
 
   onSubmit() {
@@ -100,6 +106,10 @@ export class CreateProjectPageComponent implements OnInit {
     this.router.navigate(['/projects']);
   }
 
+  getDefaultBackend(): string {
+    return this.backends.find((b) => b.isDefault)?.name || '';
+  }
+
   validate(model: IProjectCreateParams): string | null {
     if (!model.id) {
       return 'ID is not set';
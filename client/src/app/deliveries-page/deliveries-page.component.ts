@@ -0,0 +1,68 @@
+import { Component, OnInit } from '@angular/core';
+import { ApiService, IDelivery } from '../api.service';
+import { PrettyTimeService } from '../pretty-time.service';
+
+@Component({
+  selector: 'app-deliveries-page',
+  templateUrl: './deliveries-page.component.html',
+  styleUrls: ['./deliveries-page.component.scss']
+})
+export class DeliveriesPageComponent implements OnInit {
+  constructor(private api: ApiService, private time: PrettyTimeService) {
+    this.deliveries = [];
+  }
+
+  isBusy: boolean;
+  deliveries: IDelivery[];
+  error?: string;
+
+  ngOnInit(): void {
+    this.refresh()
+  }
+
+  refresh() {
+    this.isBusy = true;
+    this.error = undefined;
+
+    this.api.getDeliveries().subscribe(
+      (deliveries) => {
+        this.deliveries = deliveries;
+        this.isBusy = false;
+      },
+      (e) => {
+        this.isBusy = false;
+        this.error = e;
+      });
+  }
+
+  redeliver(delivery: IDelivery) {
+    this.api.redeliver(delivery.id).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  getDeliveryAge(delivery: IDelivery): string {
+    return this.time.formatRelative(delivery.createdAt);
+  }
+
+  getStatusClass(delivery: IDelivery): string {
+    switch (delivery.status) {
+      case 'sent':
+        return 'badge-success';
+      case 'dead':
+        return 'badge-danger';
+      case 'skipped':
+        return 'badge-secondary';
+      default:
+        return 'badge-warning';
+    }
+  }
+
+  dismissError() {
+    this.error = undefined;
+  }
+}
@@ -1,7 +1,8 @@
 import { Component, OnInit } from '@angular/core';
-import { ApiService, IProject, IProjectUpdateParams, IProjectCreateParams } from '../api.service';
+import { ApiService, IProject, IProjectUpdateParams, IProjectCreateParams, IStorageBackend } from '../api.service';
 import { ActivatedRoute, Router } from '@angular/router';
 import { FormGroup, FormControl, Validators } from '@angular/forms';
+import * as pretty from 'pretty-bytes';
 
 @Component({
   selector: 'app-edit-project-page',
@@ -15,6 +16,7 @@ export class EditProjectPageComponent implements OnInit {
   id: string;
   project: IProject;
   form: FormGroup;
+  backends: IStorageBackend[] = [];
 
   isBusy: boolean;
   error?: string;
@@ -22,8 +24,12 @@ export class EditProjectPageComponent implements OnInit {
   get name() { return this.form.get('name'); }
   get backupFrequency() { return this.form.get('backupFrequency'); }
   get backupRetention() { return this.form.get('backupRetention'); }
+  get retention() { return this.form.get('retention'); }
+  get quota() { return this.form.get('quota'); }
   get isActive() { return this.form.get('isActive'); }
   get notifications() { return this.form.get('notifications'); }
+  get tags() { return this.form.get('tags'); }
+  get labels() { return this.form.get('labels'); }
 
   ngOnInit(): void {
     this.isBusy = true;
@@ -65,14 +71,56 @@ export class EditProjectPageComponent implements OnInit {
             Validators.required,
             Validators.min(1),
           ]),
+          'retention': new FormGroup({
+            'daily': new FormControl(this.project.retention?.daily || 0, [Validators.min(0)]),
+            'weekly': new FormControl(this.project.retention?.weekly || 0, [Validators.min(0)]),
+            'monthly': new FormControl(this.project.retention?.monthly || 0, [Validators.min(0)]),
+            'yearly': new FormControl(this.project.retention?.yearly || 0, [Validators.min(0)]),
+            'keepWithin': new FormControl(this.project.retention?.keepWithin || ''),
+            'maxSizeGb': new FormControl(
+              this.project.retention?.maxSize ? this.project.retention.maxSize / EditProjectPageComponent.GB : '',
+              [Validators.min(0)]
+            ),
+            'minCount': new FormControl(this.project.retention?.minCount || 0, [Validators.min(0)]),
+          }),
+          'quota': new FormGroup({
+            'limitGb': new FormControl(
+              this.project.quota?.limit ? this.project.quota.limit / EditProjectPageComponent.GB : '',
+              [Validators.min(0)]
+            ),
+            'policy': new FormControl(this.project.quota?.policy || 'reject'),
+          }),
+          'storage': new FormControl({
+            value: this.project.storage || '',
+            disabled: this.api.getUser()?.role !== 'admin',
+          }),
           'isActive': new FormControl(this.project.isActive),
           'notifications': new FormControl(this.project.notifications),
+          'tags': new FormControl((this.project.tags || []).join(', ')),
+          'labels': new FormControl(
+            Object.keys(this.project.labels || {}).map((k) => `${k}=${this.project.labels[k]}`).join(', ')
+          ),
         });
+        if (this.api.getUser()?.role !== 'admin') {
+          this.quota.disable();
+          this.tags.disable();
+          this.labels.disable();
+        }
       },
       (e) => {
         this.isBusy = false;
         this.error = e;
       });
+
+    // Only admins are able to list (and choose) storage backends
+    if (this.api.getUser()?.role !== 'admin') {
+      this.backends = [];
+      return;
+    }
+
+    this.api.getStorageBackends().subscribe(
+      (backends) => this.backends = backends,
+      () => this.backends = []);
   }
 
   onSubmit() {
@@ -83,9 +131,36 @@ export class EditProjectPageComponent implements OnInit {
     this.isBusy = true;
     this.error = undefined;
 
-    const model: IProjectUpdateParams = this.form.value;
+    const model: IProjectUpdateParams = { ...this.form.value };
     model.backupFrequency = parseInt(model.backupFrequency as any);
     model.backupRetention = parseInt(model.backupRetention as any);
+    model.retention = {
+      daily: parseInt(this.form.value.retention.daily) || 0,
+      weekly: parseInt(this.form.value.retention.weekly) || 0,
+      monthly: parseInt(this.form.value.retention.monthly) || 0,
+      yearly: parseInt(this.form.value.retention.yearly) || 0,
+      keepWithin: (this.form.value.retention.keepWithin || '').trim(),
+      maxSize: Math.round((parseFloat(this.form.value.retention.maxSizeGb) || 0) * EditProjectPageComponent.GB),
+      minCount: parseInt(this.form.value.retention.minCount) || 0,
+    };
+    if (this.form.value.quota) {
+      model.quota = {
+        limit: Math.round((parseFloat(this.form.value.quota.limitGb) || 0) * EditProjectPageComponent.GB),
+        policy: this.form.value.quota.policy,
+      };
+    }
+    if (this.tags.enabled) {
+      model.tags = EditProjectPageComponent.parseList(this.form.value.tags);
+    }
+    if (this.labels.enabled) {
+      model.labels = {};
+      for (const pair of EditProjectPageComponent.parseList(this.form.value.labels)) {
+        const i = pair.indexOf('=');
+        if (i > 0) {
+          model.labels[pair.substring(0, i).trim()] = pair.substring(i + 1).trim();
+        }
+      }
+    }
 
     const e = this.validate(model);
     if (!!e) {
@@ -94,6 +169,28 @@ export class EditProjectPageComponent implements OnInit {
       return;
     }
 
+    // Ask for a confirmation if new retention policy deletes any backups
+    this.api.previewRetention(this.id, { backupRetention: model.backupRetention, retention: model.retention })
+      .subscribe(
+        (preview) => {
+          if (preview.deleted.length > 0) {
+            const freed = pretty(preview.freedBytes);
+            if (!confirm(`New retention policy will delete ${preview.deleted.length} backup(s) (${freed}). Continue?`)) {
+              this.isBusy = false;
+              return;
+            }
+            model.confirmDeletion = true;
+          }
+
+          this.update(model);
+        },
+        (e) => {
+          this.isBusy = false;
+          this.error = e;
+        });
+  }
+
+  private update(model: IProjectUpdateParams) {
     this.api.updateProject(this.id, model)
       .subscribe(
         (p) => {
@@ -110,6 +207,16 @@ export class EditProjectPageComponent implements OnInit {
     this.router.navigate(['/projects', this.id]);
   }
 
+  getDefaultBackend(): string {
+    return this.backends.find((b) => b.isDefault)?.name || '';
+  }
+
+  private static readonly GB = 1000 * 1000 * 1000;
+
+  private static parseList(str: string): string[] {
+    return (str || '').split(',').map((x) => x.trim()).filter((x) => !!x);
+  }
+
   validate(model: IProjectUpdateParams): string | null {
     if (!model.name) {
       return 'Name is not set';
@@ -1,5 +1,5 @@
 import { Component, OnInit } from '@angular/core';
-import { ApiService } from '../api.service';
+import { ApiService, IMFAChallenge } from '../api.service';
 import { FaConfig } from '@fortawesome/angular-fontawesome';
 import { Router } from '@angular/router';
 
@@ -24,8 +24,38 @@ export class LoginPageComponent implements OnInit {
 
   isBusy: boolean;
   error?: string;
+  isOIDCEnabled: boolean;
+
+  challenge?: IMFAChallenge;
+  code: string;
+  enrollmentToken: string;
+  recoveryCodes?: string[];
 
   ngOnInit(): void {
+    const u = this.router.parseUrl(this.router.url);
+
+    // Redirected back from OpenID Connect provider
+    const ticket = u.queryParams['ticket'];
+    if (ticket) {
+      this.isBusy = true;
+      this.api.authorizeWithTicket(ticket)
+        .subscribe(
+          () => {
+            this.isBusy = false;
+            this.router.navigate(['/']);
+          },
+          (e) => {
+            this.isBusy = false;
+            this.error = e;
+          });
+    }
+
+    if (u.queryParams['error']) {
+      this.error = u.queryParams['error'];
+    }
+
+    this.api.getAuthOptions()
+      .subscribe(options => this.isOIDCEnabled = options.oidc, () => { });
   }
 
   onSubmit() {
@@ -53,19 +83,87 @@ export class LoginPageComponent implements OnInit {
 
     this.api.authorize(this.username, this.password)
       .subscribe(
-        () => {
+        (challenge) => {
+          this.isBusy = false;
+
+          if (challenge) {
+            this.challenge = challenge;
+            this.code = '';
+            this.enrollmentToken = '';
+            return;
+          }
+
+          this.continue();
+        },
+        (e) => {
+          this.isBusy = false;
+          this.error = e;
+        });
+  }
+
+  onSubmitEnrollmentToken() {
+    if (this.isBusy || !this.challenge || !this.enrollmentToken) {
+      return;
+    }
+
+    this.isBusy = true;
+    this.error = undefined;
+
+    const challenge = this.challenge;
+    this.api.startMFAEnrollment(challenge.token, this.enrollmentToken)
+      .subscribe(
+        (enrollment) => {
+          this.isBusy = false;
+          challenge.enrollment = enrollment;
+          challenge.enrollmentTokenRequired = false;
+        },
+        (e) => {
+          this.isBusy = false;
+          this.error = e;
+          this.enrollmentToken = '';
+        });
+  }
+
+  onSubmitCode() {
+    if (this.isBusy || !this.challenge || !this.code) {
+      return;
+    }
+
+    this.isBusy = true;
+    this.error = undefined;
+
+    this.api.completeMFA(this.challenge.token, this.code)
+      .subscribe(
+        (recoveryCodes) => {
           this.isBusy = false;
 
-          const u = this.router.parseUrl(this.router.url);
-          const url = u.queryParams['returnUrl'] || '/';
-          this.router.navigate([url])
+          // Recovery codes of a just enrolled user are shown only once
+          if (recoveryCodes) {
+            this.recoveryCodes = recoveryCodes;
+            return;
+          }
+
+          this.continue();
         },
         (e) => {
           this.isBusy = false;
           this.error = e;
+          this.code = '';
         });
   }
 
+  cancelChallenge() {
+    this.challenge = undefined;
+    this.password = '';
+    this.error = undefined;
+  }
+
+  continue() {
+    const u = this.router.parseUrl(this.router.url);
+    const url = u.queryParams['returnUrl'] || '/';
+    this.router.navigate([url]);
+  }
+
   dismissError() {
     this.error = undefined;
   }
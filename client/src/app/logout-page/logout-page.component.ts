@@ -11,8 +11,9 @@ export class LogoutPageComponent implements OnInit {
   constructor(private api: ApiService, private router: Router) { }
 
   ngOnInit(): void {
-    this.api.unauthorize();
-    this.router.navigate(['/']);
+    this.api.logout().subscribe(
+      () => this.router.navigate(['/']),
+      () => this.router.navigate(['/']));
   }
 
 }
@@ -0,0 +1,88 @@
+import { Component, OnInit } from '@angular/core';
+import { NgbActiveModal } from '@ng-bootstrap/ng-bootstrap';
+import { Observable } from 'rxjs';
+import { ApiService, IAPIToken, Role } from 'src/app/api.service';
+
+@Component({
+  selector: 'app-api-tokens-modal',
+  templateUrl: './api-tokens-modal.component.html',
+  styleUrls: ['./api-tokens-modal.component.scss']
+})
+export class APITokensModalComponent implements OnInit {
+  constructor(private modal: NgbActiveModal, private api: ApiService) {
+  }
+
+  tokens: IAPIToken[] = [];
+  createdToken?: IAPIToken;
+
+  name: string;
+  role: Role;
+  projects: string = '';
+  expiresAt?: string;
+
+  roles: Role[] = [];
+
+  isBusy: boolean;
+  error?: string;
+
+  ngOnInit(): void {
+    const allRoles: Role[] = ['viewer', 'operator', 'admin'];
+    const userRole = this.api.getUser()?.role;
+    this.roles = allRoles.slice(0, allRoles.indexOf(userRole) + 1);
+    this.role = userRole;
+
+    this.load();
+  }
+
+  load() {
+    this.run(this.api.getAPITokens(), (tokens) => this.tokens = tokens);
+  }
+
+  create() {
+    if (!this.name) {
+      return;
+    }
+
+    const projects = this.projects.split(/[\s,]+/).filter(x => !!x);
+    const expiresAt = this.expiresAt ? new Date(this.expiresAt).toISOString() : undefined;
+
+    this.run(this.api.createAPIToken(this.name, this.role, projects, expiresAt), (token) => {
+      this.createdToken = token;
+      this.name = '';
+      this.projects = '';
+      this.expiresAt = undefined;
+      this.load();
+    });
+  }
+
+  revoke(token: IAPIToken) {
+    if (!confirm(`Revoke API token "${token.name}"?`)) {
+      return;
+    }
+
+    this.run(this.api.revokeAPIToken(token.id), () => this.load());
+  }
+
+  private run<T>(request: Observable<T>, next: (r: T) => void) {
+    if (this.isBusy) {
+      return;
+    }
+
+    this.isBusy = true;
+    this.error = undefined;
+
+    request.subscribe(
+      (r) => {
+        this.isBusy = false;
+        next(r);
+      },
+      (e) => {
+        this.isBusy = false;
+        this.error = e;
+      });
+  }
+
+  dismiss() {
+    this.modal.dismiss();
+  }
+}
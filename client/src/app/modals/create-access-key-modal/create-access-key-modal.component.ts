@@ -1,6 +1,6 @@
 import { Component, Input } from '@angular/core';
 import { NgbActiveModal } from '@ng-bootstrap/ng-bootstrap';
-import { ApiService, IProject } from 'src/app/api.service';
+import { ApiService, IProject, AccessKeyScope } from 'src/app/api.service';
 
 @Component({
   selector: 'app-create-access-key-modal',
@@ -15,6 +15,15 @@ export class CreateAccessKeyModalComponent {
   label: string;
   isLabelValid: boolean;
 
+  canUpload: boolean = true;
+  canDownload: boolean = false;
+  canCheckIn: boolean = false;
+  canList: boolean = false;
+
+  allowedNetworks: string = '';
+
+  expiresAt?: string;
+
   isBusy: boolean;
   error?: string;
 
@@ -30,13 +39,31 @@ export class CreateAccessKeyModalComponent {
       return;
     }
 
+    const scopes: AccessKeyScope[] = [];
+    if (this.canUpload) {
+      scopes.push('upload');
+    }
+    if (this.canDownload) {
+      scopes.push('download');
+    }
+    if (this.canCheckIn) {
+      scopes.push('checkin');
+    }
+    if (this.canList) {
+      scopes.push('list');
+    }
+
+    const allowedNetworks = this.allowedNetworks.split(/[\s,]+/).filter(x => !!x);
+
     this.isBusy = true;
     this.error = undefined;
 
-    this.api.createProjectAccessKey(this.project.id, this.label)
+    const expiresAt = this.expiresAt ? new Date(this.expiresAt).toISOString() : undefined;
+
+    this.api.createProjectAccessKey(this.project.id, this.label, scopes, allowedNetworks, expiresAt)
       .subscribe(
-        () => {
-          this.modal.close(true);
+        (accessKey) => {
+          this.modal.close(accessKey);
           this.isBusy = false;
         },
         (e) => {
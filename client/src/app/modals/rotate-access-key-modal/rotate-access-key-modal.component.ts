@@ -0,0 +1,44 @@
+import { Component, Input } from '@angular/core';
+import { IAccessKey, ApiService, IProject } from 'src/app/api.service';
+import { NgbActiveModal } from '@ng-bootstrap/ng-bootstrap';
+
+@Component({
+  selector: 'app-rotate-access-key-modal',
+  templateUrl: './rotate-access-key-modal.component.html',
+  styleUrls: ['./rotate-access-key-modal.component.scss']
+})
+export class RotateAccessKeyModalComponent {
+  constructor(private modal: NgbActiveModal, private api: ApiService) { }
+
+  @Input() public project: IProject;
+  @Input() public accessKey: IAccessKey;
+
+  overlap: string = '24h';
+
+  isBusy: boolean;
+  error?: string;
+
+  ok() {
+    if (this.isBusy) {
+      return;
+    }
+
+    this.isBusy = true;
+    this.error = undefined;
+
+    this.api.rotateProjectAccessKey(this.project.id, this.accessKey.id, this.overlap)
+      .subscribe(
+        (accessKey) => {
+          this.modal.close(accessKey);
+          this.isBusy = false;
+        },
+        (e) => {
+          this.isBusy = false;
+          this.error = e;
+        });
+  }
+
+  dismiss() {
+    this.modal.dismiss();
+  }
+}
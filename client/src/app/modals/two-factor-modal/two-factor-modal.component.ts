@@ -0,0 +1,74 @@
+import { Component, OnInit } from '@angular/core';
+import { NgbActiveModal } from '@ng-bootstrap/ng-bootstrap';
+import { Observable } from 'rxjs';
+import { ApiService, IMFAEnrollment } from 'src/app/api.service';
+
+@Component({
+  selector: 'app-two-factor-modal',
+  templateUrl: './two-factor-modal.component.html',
+  styleUrls: ['./two-factor-modal.component.scss']
+})
+export class TwoFactorModalComponent implements OnInit {
+  constructor(private modal: NgbActiveModal, private api: ApiService) {
+  }
+
+  isEnabled: boolean;
+  enrollment?: IMFAEnrollment;
+  recoveryCodes?: string[];
+
+  code: string;
+
+  isBusy: boolean;
+  error?: string;
+
+  ngOnInit(): void {
+    this.isEnabled = !!this.api.getUser()?.mfaEnabled;
+  }
+
+  enroll() {
+    this.run(this.api.enrollMFA(), (enrollment) => this.enrollment = enrollment);
+  }
+
+  activate() {
+    this.run(this.api.activateMFA(this.code), (codes) => {
+      this.isEnabled = true;
+      this.enrollment = undefined;
+      this.recoveryCodes = codes;
+    });
+  }
+
+  regenerateRecoveryCodes() {
+    this.run(this.api.regenerateRecoveryCodes(this.code), (codes) => this.recoveryCodes = codes);
+  }
+
+  disable() {
+    this.run(this.api.disableMFA(this.code), () => {
+      this.isEnabled = false;
+      this.recoveryCodes = undefined;
+    });
+  }
+
+  private run<T>(request: Observable<T>, next: (r: T) => void) {
+    if (this.isBusy) {
+      return;
+    }
+
+    this.isBusy = true;
+    this.error = undefined;
+
+    request.subscribe(
+      (r) => {
+        this.isBusy = false;
+        this.code = '';
+        next(r);
+      },
+      (e) => {
+        this.isBusy = false;
+        this.error = e;
+      });
+  }
+
+  dismiss() {
+    this.modal.dismiss();
+  }
+}
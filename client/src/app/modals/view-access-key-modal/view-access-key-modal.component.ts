@@ -15,7 +15,7 @@ export class ViewAccessKeyModalComponent {
   copyStatus?: string;
 
   copy() {
-    this.clipboard.copy(this.accessKey.key);
+    this.clipboard.copy(this.accessKey.key!);
     this.copyStatus = 'Copied to clipboard';
     window.setTimeout(() => { this.copyStatus = undefined }, 1000);
   }
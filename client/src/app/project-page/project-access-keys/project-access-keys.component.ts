@@ -4,6 +4,8 @@ import { NgbModal } from '@ng-bootstrap/ng-bootstrap';
 import { ViewAccessKeyModalComponent } from 'src/app/modals/view-access-key-modal/view-access-key-modal.component';
 import { DeleteAccessKeyModalComponent } from 'src/app/modals/delete-access-key-modal/delete-access-key-modal.component';
 import { CreateAccessKeyModalComponent } from 'src/app/modals/create-access-key-modal/create-access-key-modal.component';
+import { RotateAccessKeyModalComponent } from 'src/app/modals/rotate-access-key-modal/rotate-access-key-modal.component';
+import { PrettyTimeService } from 'src/app/pretty-time.service';
 
 @Component({
   selector: 'app-project-access-keys',
@@ -11,7 +13,7 @@ import { CreateAccessKeyModalComponent } from 'src/app/modals/create-access-key-
   styleUrls: ['./project-access-keys.component.scss']
 })
 export class ProjectAccessKeysComponent {
-  constructor(private modalService: NgbModal) { }
+  constructor(private modalService: NgbModal, private time: PrettyTimeService) { }
 
   @Input() project?: IProject;
   @Input() accessKeys: IAccessKey[];
@@ -28,15 +30,39 @@ export class ProjectAccessKeysComponent {
     instance.accessKey = accessKey;
   }
 
+  isExpired(accessKey: IAccessKey): boolean {
+    return !!accessKey.expiresAt && new Date(accessKey.expiresAt).getTime() <= Date.now();
+  }
+
+  getLastUsed(accessKey: IAccessKey): string {
+    return accessKey.lastUsedAt ? this.time.formatRelative(new Date(accessKey.lastUsedAt)) : 'Never';
+  }
+
+  getExpiration(accessKey: IAccessKey): string {
+    return accessKey.expiresAt ? new Date(accessKey.expiresAt).toLocaleString() : 'Never';
+  }
+
   createKey() {
     const modalRef = this.modalService.open(CreateAccessKeyModalComponent);
     const instance = modalRef.componentInstance as CreateAccessKeyModalComponent;
     instance.project = this.project!;
 
-    modalRef.result.then((created: boolean) => {
-      if (created) {
-        this.refreshRequested.emit();
-      }
+    modalRef.result.then((accessKey: IAccessKey) => {
+      this.refreshRequested.emit();
+      this.showKey(accessKey);
+    })
+      .catch(() => { });
+  }
+
+  rotateKey(accessKey: IAccessKey) {
+    const modalRef = this.modalService.open(RotateAccessKeyModalComponent);
+    const instance = modalRef.componentInstance as RotateAccessKeyModalComponent;
+    instance.project = this.project!;
+    instance.accessKey = accessKey;
+
+    modalRef.result.then((replacement: IAccessKey) => {
+      this.refreshRequested.emit();
+      this.showKey(replacement);
     })
       .catch(() => { });
   }
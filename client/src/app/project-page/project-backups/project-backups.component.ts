@@ -1,5 +1,5 @@
 import { Component, Input, Output, EventEmitter } from '@angular/core';
-import { IProject, IBackup } from 'src/app/api.service';
+import { ApiService, IProject, IBackup, ITrashedBackup } from 'src/app/api.service';
 import { IconDefinition } from '@fortawesome/fontawesome-svg-core';
 import { faStar as fasStar } from '@fortawesome/free-solid-svg-icons';
 import { faStar as farStar } from '@fortawesome/free-regular-svg-icons';
@@ -14,7 +14,7 @@ import * as pretty from 'pretty-bytes';
   styleUrls: ['./project-backups.component.scss']
 })
 export class ProjectBackupsComponent {
-  constructor(private modalService: NgbModal, private time: PrettyTimeService) {
+  constructor(private modalService: NgbModal, private time: PrettyTimeService, private api: ApiService) {
   }
 
   @Input() project?: IProject;
@@ -22,8 +22,43 @@ export class ProjectBackupsComponent {
 
   @Output() refreshRequested = new EventEmitter<void>();
 
+  trash?: ITrashedBackup[];
+
   refresh() {
     this.refreshRequested.emit();
+    if (this.trash) {
+      this.loadTrash();
+    }
+  }
+
+  toggleTrash() {
+    if (this.trash) {
+      this.trash = undefined;
+      return;
+    }
+
+    this.loadTrash();
+  }
+
+  loadTrash() {
+    this.api.getProjectTrash(this.project!.id).subscribe(
+      (trash) => this.trash = trash,
+      (e) => alert(e));
+  }
+
+  getPurgeTime(backup: ITrashedBackup): string {
+    const left = new Date(backup.purgeAt).getTime() - Date.now();
+    if (left <= 0) {
+      return 'soon';
+    }
+
+    return `in ${this.time.formatDuration(left)}`;
+  }
+
+  restoreBackup(backup: ITrashedBackup) {
+    this.api.restoreBackup(backup.id).subscribe(
+      () => this.refresh(),
+      (e) => alert(e));
   }
 
   getBackupIcon(b: IBackup): IconDefinition {
@@ -38,8 +73,14 @@ export class ProjectBackupsComponent {
     return this.time.formatRelative(backup.time);
   }
 
-  getBackupDownloadUrl(backup: IBackup): string {
-    return `${window.location.protocol}//${window.location.host}/api/backup/${backup.id}`;
+  downloadBackup(backup: IBackup) {
+    this.api.createBackupDownloadLink(backup.id).subscribe(
+      (link) => {
+        window.open(link.url, '_blank');
+      },
+      (e) => {
+        alert(e);
+      });
   }
 
   getBackupSize(backup: IBackup): string {
@@ -51,6 +92,61 @@ export class ProjectBackupsComponent {
     return size;
   }
 
+  isPinned(backup: IBackup): boolean {
+    return !!backup.pin && (!backup.pin.until || new Date(backup.pin.until) > new Date());
+  }
+
+  getPinText(backup: IBackup): string {
+    if (!backup.pin) {
+      return '';
+    }
+
+    let str = `Pinned by ${backup.pin.pinnedBy}: ${backup.pin.reason}`;
+    if (backup.pin.until) {
+      str += ` (until ${new Date(backup.pin.until).toLocaleString()})`;
+    }
+    return str;
+  }
+
+  isLocked(backup: IBackup): boolean {
+    return !!backup.lock && new Date(backup.lock.until) > new Date();
+  }
+
+  getLockText(backup: IBackup): string {
+    if (!backup.lock) {
+      return '';
+    }
+
+    return `Locked in ${backup.lock.mode} mode until ${new Date(backup.lock.until).toLocaleString()}`;
+  }
+
+  pinBackup(backup: IBackup) {
+    const reason = prompt(`Why should backup "${backup.id}" be kept?`);
+    if (!reason) {
+      return;
+    }
+
+    const days = prompt('Keep it for how many days? Leave empty to keep it until it\'s unpinned');
+    let until: string | undefined;
+    if (days && parseInt(days) > 0) {
+      until = new Date(Date.now() + parseInt(days) * 24 * 3600 * 1000).toISOString();
+    }
+
+    this.api.pinBackup(backup.id, reason, until).subscribe(
+      () => this.refresh(),
+      (e) => alert(e));
+  }
+
+  unpinBackup(backup: IBackup) {
+    if (!confirm(`Unpin backup "${backup.id}"? It may be deleted by retention policy.`)) {
+      return;
+    }
+
+    this.api.unpinBackup(backup.id).subscribe(
+      () => this.refresh(),
+      (e) => alert(e));
+  }
+
   deleteBackup(backup: IBackup) {
     const modalRef = this.modalService.open(DeleteBackupModalComponent);
     const instance = modalRef.componentInstance as DeleteBackupModalComponent;
@@ -1,5 +1,5 @@
-import { Component, Input } from '@angular/core';
-import { IProject } from 'src/app/api.service';
+import { Component, Input, Output, EventEmitter, OnChanges } from '@angular/core';
+import { ApiService, IProject, IStorageUsage } from 'src/app/api.service';
 import { IconDefinition } from '@fortawesome/fontawesome-svg-core';
 import {
   faExclamationTriangle,
@@ -13,6 +13,7 @@ import { PrettyTimeService } from 'src/app/pretty-time.service';
 import { NgbModal } from '@ng-bootstrap/ng-bootstrap';
 import { DeleteProjectModalComponent } from 'src/app/modals/delete-project-modal/delete-project-modal.component';
 import { Router } from '@angular/router';
+import * as pretty from 'pretty-bytes';
 
 
 interface INotificationTarget {
@@ -26,12 +27,57 @@ interface INotificationTarget {
   templateUrl: './project-summary.component.html',
   styleUrls: ['./project-summary.component.scss']
 })
-export class ProjectSummaryComponent {
-  constructor(private time: PrettyTimeService, private router: Router, private modalService: NgbModal) {
+export class ProjectSummaryComponent implements OnChanges {
+  constructor(
+    private time: PrettyTimeService,
+    private router: Router,
+    private modalService: NgbModal,
+    private api: ApiService) {
   }
 
   @Input() project?: IProject;
 
+  @Output() refreshRequested = new EventEmitter<void>();
+
+  usage?: IStorageUsage;
+
+  ngOnChanges() {
+    if (!this.project) {
+      this.usage = undefined;
+      return;
+    }
+
+    this.api.getProjectUsage(this.project.id).subscribe(
+      (usage) => this.usage = usage,
+      () => this.usage = undefined);
+  }
+
+  getStorageUsageText(): string {
+    if (!this.usage) {
+      return '';
+    }
+
+    const quota = this.usage.quota;
+    if (!quota?.limit) {
+      return `Backups take ${pretty(this.usage.used)}`;
+    }
+
+    const policy = quota.policy === 'prune' ? 'oldest backups are pruned' : 'uploads are rejected';
+    return `Backups take ${pretty(this.usage.used)} of ${pretty(quota.limit)} (${this.usage.percent.toFixed(0)}%), then ${policy}`;
+  }
+
+  getStorageUsageClass(): string {
+    if (!this.usage?.quota?.limit) {
+      return '';
+    }
+
+    if (this.usage.percent >= 95) {
+      return 'text-danger';
+    }
+
+    return this.usage.percent >= 80 ? 'text-warning' : '';
+  }
+
   getBackupStatusClass(): string {
     switch (this.project?.backupStatus) {
       case 'ok':
@@ -73,6 +119,14 @@ export class ProjectSummaryComponent {
     return str;
   }
 
+  getLastCheckInText(): string {
+    if (!this.project?.lastCheckIn) {
+      return 'Backup host has never checked in';
+    }
+
+    return `Backup host has checked in ${this.time.formatRelative(new Date(this.project.lastCheckIn))}`;
+  }
+
   getBackupPeriodText(): string {
     if (!this.project) {
       return '';
@@ -87,7 +141,29 @@ export class ProjectSummaryComponent {
       return '';
     }
 
-    const str = `Will keep at least ${this.project.backupRetention} last backups`;
+    const r = this.project.retention;
+    const parts: string[] = [];
+    if (r && (r.daily > 0 || r.weekly > 0 || r.monthly > 0 || r.yearly > 0)) {
+      const buckets: [number, string][] = [[r.daily, 'daily'], [r.weekly, 'weekly'], [r.monthly, 'monthly'], [r.yearly, 'yearly']];
+      for (const [n, name] of buckets) {
+        if (n > 0) {
+          parts.push(`${n} ${name}`);
+        }
+      }
+    } else {
+      parts.push(`${this.project.backupRetention} last`);
+    }
+
+    let str = `Will keep ${parts.join(', ')} backups`;
+    if (r?.keepWithin) {
+      str += ` and everything within ${r.keepWithin}`;
+    }
+    if (r?.maxSize) {
+      str += `, up to ${(r.maxSize / 1000 / 1000 / 1000).toFixed(1)} GB`;
+    }
+    if (r?.minCount) {
+      str += `, but never less than ${r.minCount}`;
+    }
     return str;
   }
 
@@ -169,4 +245,34 @@ export class ProjectSummaryComponent {
     })
       .catch(() => { });;
   }
+
+  getLegalHoldText(): string {
+    const hold = this.project?.legalHold;
+    if (!hold) {
+      return '';
+    }
+
+    return `${hold.reason} (placed by ${hold.placedBy} ${this.time.formatRelative(new Date(hold.placedAt))})`;
+  }
+
+  placeLegalHold() {
+    const reason = prompt(`Why should all backups of project "${this.project!.id}" be kept?`);
+    if (!reason) {
+      return;
+    }
+
+    this.api.placeLegalHold(this.project!.id, reason).subscribe(
+      () => this.refreshRequested.emit(),
+      (e) => alert(e));
+  }
+
+  releaseLegalHold() {
+    if (!confirm(`Release legal hold of project "${this.project!.id}"? Its backups may be deleted by retention policy.`)) {
+      return;
+    }
+
+    this.api.releaseLegalHold(this.project!.id).subscribe(
+      () => this.refreshRequested.emit(),
+      (e) => alert(e));
+  }
 }
@@ -1,5 +1,5 @@
 import { Component, OnInit } from '@angular/core';
-import { ApiService, IProject } from '../api.service';
+import { ApiService, IProject, ITrashedProject } from '../api.service';
 
 @Component({
   selector: 'app-main-page',
@@ -14,6 +14,7 @@ export class ProjectsPageComponent implements OnInit {
   isBusy: boolean;
   projects: IProject[];
   error?: string;
+  trash?: ITrashedProject[];
 
   ngOnInit(): void {
     this.refresh()
@@ -34,6 +35,30 @@ export class ProjectsPageComponent implements OnInit {
       });
   }
 
+  toggleTrash() {
+    if (this.trash) {
+      this.trash = undefined;
+      return;
+    }
+
+    this.loadTrash();
+  }
+
+  loadTrash() {
+    this.api.getTrash().subscribe(
+      (trash) => this.trash = trash.projects,
+      (e) => alert(e));
+  }
+
+  restoreProject(project: ITrashedProject) {
+    this.api.restoreProject(project.id).subscribe(
+      () => {
+        this.loadTrash();
+        this.refresh();
+      },
+      (e) => alert(e));
+  }
+
   dismissError() {
     this.error = undefined;
   }
@@ -0,0 +1,199 @@
+import { Component, OnInit } from '@angular/core';
+import {
+  ApiService, IMFAEnrollmentToken, ISecuritySettings, IUser, IUserCreateParams, IUserPasswordResponse, Role
+} from '../api.service';
+
+@Component({
+  selector: 'app-users-page',
+  templateUrl: './users-page.component.html',
+  styleUrls: ['./users-page.component.scss']
+})
+export class UsersPageComponent implements OnInit {
+  constructor(private api: ApiService) {
+    this.users = [];
+    this.newUser = { username: '', displayName: '', email: '', role: 'viewer' };
+  }
+
+  readonly roles: Role[] = ['viewer', 'operator', 'admin'];
+
+  isBusy: boolean;
+  users: IUser[];
+  error?: string;
+  newUser: IUserCreateParams;
+  generatedPassword?: IUserPasswordResponse;
+  enrollmentToken?: { user: IUser, token: IMFAEnrollmentToken };
+  security?: ISecuritySettings;
+
+  get currentUserId(): number {
+    return this.api.getUser().id;
+  }
+
+  ngOnInit(): void {
+    this.refresh()
+  }
+
+  refresh() {
+    this.isBusy = true;
+    this.error = undefined;
+
+    this.api.getUsers().subscribe(
+      (users) => {
+        this.users = users;
+        this.isBusy = false;
+      },
+      (e) => {
+        this.isBusy = false;
+        this.error = e;
+      });
+
+    this.api.getSecuritySettings().subscribe(
+      (settings) => {
+        this.security = settings;
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  toggleRequireMFA() {
+    const requireMfa = !this.security?.requireMfa;
+    if (requireMfa && !confirm('Require two-factor authentication for all users that sign in with a password?')) {
+      return;
+    }
+
+    this.api.updateSecuritySettings({ requireMfa }).subscribe(
+      (settings) => {
+        this.security = settings;
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  toggleRequireMFAEnrollmentToken() {
+    const requireMfaEnrollmentToken = !this.security?.requireMfaEnrollmentToken;
+    this.api.updateSecuritySettings({ requireMfaEnrollmentToken }).subscribe(
+      (settings) => {
+        this.security = settings;
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  create() {
+    this.api.createUser(this.newUser).subscribe(
+      (r) => {
+        this.generatedPassword = r;
+        this.newUser = { username: '', displayName: '', email: '', role: 'viewer' };
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  toggleDisabled(user: IUser) {
+    this.api.setUserDisabled(user.id, !user.isDisabled).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  setRole(user: IUser, role: Role) {
+    this.api.setUserRole(user.id, role).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  toggleAllProjects(user: IUser) {
+    this.api.setUserAllProjects(user.id, !user.allProjects).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  resetPassword(user: IUser) {
+    if (!confirm(`Reset password of "${user.username}"?`)) {
+      return;
+    }
+
+    this.api.resetUserPassword(user.id).subscribe(
+      (r) => {
+        this.generatedPassword = r;
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  resetMFA(user: IUser) {
+    if (!confirm(`Reset two-factor authentication of "${user.username}"?`)) {
+      return;
+    }
+
+    this.api.resetUserMFA(user.id).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  issueEnrollmentToken(user: IUser) {
+    this.api.issueUserMFAEnrollmentToken(user.id).subscribe(
+      (token) => {
+        this.enrollmentToken = { user, token };
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  dismissEnrollmentToken() {
+    this.enrollmentToken = undefined;
+  }
+
+  unlock(user: IUser) {
+    this.api.unlockUser(user.id).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  delete(user: IUser) {
+    if (!confirm(`Delete user "${user.username}"?`)) {
+      return;
+    }
+
+    this.api.deleteUser(user.id).subscribe(
+      () => {
+        this.refresh();
+      },
+      (e) => {
+        this.error = e;
+      });
+  }
+
+  dismissPassword() {
+    this.generatedPassword = undefined;
+  }
+
+  dismissError() {
+    this.error = undefined;
+  }
+}
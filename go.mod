@@ -4,14 +4,15 @@ go 1.16
 
 require (
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
+	github.com/coreos/go-oidc v2.2.1+incompatible
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/dustin/go-humanize v1.0.0
 	github.com/gin-gonic/gin v1.7.1
 	github.com/go-ini/ini v1.62.0 // indirect
+	github.com/go-ldap/ldap/v3 v3.3.0
 	github.com/go-openapi/spec v0.19.8 // indirect
 	github.com/go-openapi/swag v0.19.9 // indirect
 	github.com/go-telegram-bot-api/telegram-bot-api v4.6.4+incompatible
-	github.com/google/go-cmp v0.3.0 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20181103185306-d547d1d9531e // indirect
 	github.com/hackebrot/go-repr v0.1.0 // indirect
 	github.com/hackebrot/turtle v0.1.0
@@ -19,6 +20,9 @@ require (
 	github.com/m1/go-generate-password v0.0.0-20191114193340-84682ecbc3fd
 	github.com/mailru/easyjson v0.7.1 // indirect
 	github.com/minio/minio-go v6.0.14+incompatible
+	github.com/pquerna/cachecontrol v0.2.0 // indirect
+	github.com/pquerna/otp v1.3.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/sarulabs/di v2.0.0+incompatible
 	github.com/slack-go/slack v0.9.0
 	github.com/smartystreets/assertions v0.0.0-20190116191733-b6c0e53d7304 // indirect
@@ -28,8 +32,9 @@ require (
 	github.com/technoweenie/multipartstreamer v1.0.1 // indirect
 	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b
 	golang.org/x/mod v0.3.0 // indirect
+	golang.org/x/oauth2 v0.0.0-20210413134643-5e61552d6c78
 	golang.org/x/text v0.3.3 // indirect
-	golang.org/x/tools v0.0.0-20200701151220-7cb253f4c4f8 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+	gopkg.in/square/go-jose.v2 v2.6.0 // indirect
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
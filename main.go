@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/itglobal/backupmonitor/pkg/api"
+	"github.com/itglobal/backupmonitor/pkg/bot"
 	"github.com/itglobal/backupmonitor/pkg/component"
 	"github.com/itglobal/backupmonitor/pkg/database"
 	"github.com/itglobal/backupmonitor/pkg/notify"
@@ -20,8 +21,32 @@ import (
 func configure() error {
 	cwd, _ := os.Getwd()
 	viper.SetDefault("VAR", path.Join(cwd, "var"))
-	viper.SetDefault("JWT_KEY", "test")
+	viper.SetDefault("JWT_KEY", service.DefaultJWTKey)
 	viper.SetDefault("LISTEN_ADDR", "0.0.0.0:8000")
+	viper.SetDefault("ACCESS_TOKEN_TTL", "15m")
+	viper.SetDefault("REFRESH_TOKEN_TTL", "720h")
+	viper.SetDefault("NOTIFY_MAX_ATTEMPTS", 10)
+	viper.SetDefault("SLACK_SIGNATURE_MAX_AGE", "5m")
+	viper.SetDefault("DOWNLOAD_LINK_MAX_TTL", "24h")
+	viper.SetDefault("TRASH_PURGE_DELAY", "168h")
+	viper.SetDefault("STORAGE_QUOTA_POLICY", "reject")
+	viper.SetDefault("QUOTA_WARNING_THRESHOLDS", "80,95")
+	viper.SetDefault("AUTH_BACKENDS", "local")
+	viper.SetDefault("MFA_ISSUER", "BackupMonitor")
+	viper.SetDefault("LOCKOUT_MAX_FAILURES", 5)
+	viper.SetDefault("LOCKOUT_MAX_IP_FAILURES", 20)
+	viper.SetDefault("LOCKOUT_MAX_KEY_FAILURES", 20)
+	viper.SetDefault("LOCKOUT_WINDOW", "15m")
+	viper.SetDefault("LOCKOUT_DURATION", "15m")
+	viper.SetDefault("LOCKOUT_DELAY", "1s")
+	viper.SetDefault("LDAP_USER_FILTER", "(uid=%s)")
+	viper.SetDefault("LDAP_USERNAME_ATTRIBUTE", "uid")
+	viper.SetDefault("LDAP_GROUP_ATTRIBUTE", "memberOf")
+	viper.SetDefault("LDAP_DEFAULT_ROLE", "viewer")
+	viper.SetDefault("OIDC_SCOPES", "openid profile email")
+	viper.SetDefault("OIDC_USERNAME_CLAIM", "preferred_username")
+	viper.SetDefault("OIDC_GROUPS_CLAIM", "groups")
+	viper.SetDefault("OIDC_DEFAULT_ROLE", "viewer")
 
 	viper.AutomaticEnv()
 
@@ -46,6 +71,7 @@ func setup() (component.Registry, error) {
 	service.Setup(builder)
 	notify.Setup(builder)
 	policy.Setup(builder)
+	bot.Setup(builder)
 	api.Setup(builder)
 
 	registry, err := builder.Build()
@@ -78,7 +104,7 @@ func main() {
 		component.Start(group, stop)
 	}
 
-	interrupt := make(chan os.Signal)
+	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 	go func() {
 		<-interrupt
@@ -13,16 +13,21 @@ import (
 func (s *server) ConfigureAccessAPI() {
 	controller := &accessController{
 		repository: service.GetAccessKeyRepository(s.services),
+		auditLog:   service.GetAuditLog(s.services),
 	}
 
-	s.authorized.GET("/api/projects/:id/keys", controller.List)
-	s.authorized.GET("/api/projects/:id/keys/:key", controller.Get)
-	s.authorized.POST("/api/projects/:id/keys", controller.Post)
-	s.authorized.DELETE("/api/projects/:id/keys/:key", controller.Delete)
+	operator := requireProjectRole(s.services, model.RoleOperator)
+
+	s.authorized.GET("/api/projects/:id/keys", operator, controller.List)
+	s.authorized.GET("/api/projects/:id/keys/:key", operator, controller.Get)
+	s.authorized.POST("/api/projects/:id/keys", operator, controller.Post)
+	s.authorized.POST("/api/projects/:id/keys/:key/rotate", operator, controller.Rotate)
+	s.authorized.DELETE("/api/projects/:id/keys/:key", operator, controller.Delete)
 }
 
 type accessController struct {
 	repository service.AccessKeyRepository
+	auditLog   service.AuditLog
 }
 
 // @Summary List project's access keys
@@ -98,6 +103,50 @@ func (controller *accessController) Post(c *gin.Context) {
 		return
 	}
 
+	controller.audit(c, model.AuditActionAccessKeyCreate, nil, p, "")
+
+	c.Header("Location", fmt.Sprintf("/api/projects/%s/keys/%d", url.QueryEscape(p.ProjectID), p.ID))
+	c.JSON(201, p)
+}
+
+// @Summary Rotate a project's access key
+// @Description Issues a replacement key, old key keeps working during overlap period (24h by default)
+// @Router /api/projects/:id/keys/:key/rotate [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param key path string true "Access Key ID"
+// @Param body body model.AccessKeyRotateParams false "Body"
+// @Success 201 {object} model.AccessKey
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+func (controller *accessController) Rotate(c *gin.Context) {
+	projectID := c.Param("id")
+	accessKeyID, err := strconv.Atoi(c.Param("key"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	var req model.AccessKeyRotateParams
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+			return
+		}
+	}
+
+	p, err := controller.repository.Rotate(projectID, accessKeyID, &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.audit(c, model.AuditActionAccessKeyRotate, nil, p, fmt.Sprintf("replaces access key #%d", accessKeyID))
+
 	c.Header("Location", fmt.Sprintf("/api/projects/%s/keys/%d", url.QueryEscape(p.ProjectID), p.ID))
 	c.JSON(201, p)
 }
@@ -119,11 +168,46 @@ func (controller *accessController) Delete(c *gin.Context) {
 		return
 	}
 
+	p, err := controller.repository.GetByID(projectID, accessKeyID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
 	err = controller.repository.Delete(projectID, accessKeyID)
 	if err != nil {
 		processError(c, err)
 		return
 	}
 
+	controller.audit(c, model.AuditActionAccessKeyDelete, p, nil, "")
+
 	c.Status(204)
 }
+
+// audit records an action on an access key to audit log. Key values are never recorded
+func (controller *accessController) audit(c *gin.Context, action model.AuditAction, before, after *model.AccessKey, details string) {
+	key := after
+	if key == nil {
+		key = before
+	}
+
+	redact := func(p *model.AccessKey) *model.AccessKey {
+		if p == nil {
+			return nil
+		}
+
+		copy := *p
+		copy.Key = ""
+		return &copy
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetAccessKey,
+		TargetID:   strconv.Itoa(key.ID),
+		ProjectID:  key.ProjectID,
+		Changes:    model.NewAuditChanges(redact(before), redact(after)),
+		Details:    details,
+	})
+}
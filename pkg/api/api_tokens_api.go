@@ -0,0 +1,171 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureTokensAPI() {
+	controller := &tokenController{
+		repository: service.GetAPITokenRepository(s.services),
+		users:      service.GetUserRepository(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+
+	// Tokens can't be used to manage tokens, otherwise a leaked token could outlive its revocation
+	s.authorized.GET("/api/me/tokens", requireSession, controller.List)
+	s.authorized.POST("/api/me/tokens", requireSession, controller.Post)
+	s.authorized.DELETE("/api/me/tokens/:token", requireSession, controller.Delete)
+
+	s.authorized.GET("/api/users/:id/tokens", admin, controller.ListUserTokens)
+	s.authorized.DELETE("/api/users/:id/tokens/:token", admin, controller.DeleteUserToken)
+}
+
+type tokenController struct {
+	repository service.APITokenRepository
+	users      service.UserRepository
+}
+
+// @Summary List current user's API tokens
+// @Router /api/me/tokens [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.APITokens
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *tokenController) List(c *gin.Context) {
+	list, err := controller.repository.List(currentUser(c).ID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Create new API token for current user
+// @Description Token value is returned only once. Token can't have more permissions than its user
+// @Router /api/me/tokens [post]
+// @Accept json
+// @Produce json
+// @Param body body model.APITokenCreateParams true "Body"
+// @Success 201 {object} model.APIToken
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *tokenController) Post(c *gin.Context) {
+	var req model.APITokenCreateParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	token, err := controller.repository.Create(currentUser(c), &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(201, token)
+}
+
+// @Summary Revoke an API token of current user
+// @Router /api/me/tokens/:token [delete]
+// @Accept json
+// @Produce json
+// @Param token path int true "Token ID"
+// @Success 200 {object} model.EmptyResponse
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *tokenController) Delete(c *gin.Context) {
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	err := controller.repository.Revoke(currentUser(c).ID, tokenID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.EmptyResponse{})
+}
+
+// @Summary List API tokens of a user
+// @Router /api/users/:id/tokens [get]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.APITokens
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *tokenController) ListUserTokens(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	_, err := controller.users.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	list, err := controller.repository.List(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Revoke an API token of a user
+// @Router /api/users/:id/tokens/:token [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param token path int true "Token ID"
+// @Success 200 {object} model.EmptyResponse
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *tokenController) DeleteUserToken(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	err := controller.repository.Revoke(id, tokenID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.EmptyResponse{})
+}
+
+func parseTokenID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("token"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid token id"))
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureAuditAPI() {
+	controller := &auditController{
+		auditLog: service.GetAuditLog(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+
+	s.authorized.GET("/api/audit", admin, controller.List)
+	s.authorized.GET("/api/audit/export", admin, controller.Export)
+}
+
+type auditController struct {
+	auditLog service.AuditLog
+}
+
+// @Summary List audit log entries
+// @Description Entries are ordered from newest to oldest
+// @Router /api/audit [get]
+// @Accept json
+// @Produce json
+// @Param actor query string false "Actor's name or ID"
+// @Param actorType query string false "Actor type (user, access_key, link, policy, anonymous)"
+// @Param action query string false "Action (e.g. project.delete) or action group (e.g. project.*)"
+// @Param targetType query string false "Target type (user, project, access_key, backup)"
+// @Param target query string false "Target ID"
+// @Param project query string false "Project ID"
+// @Param ip query string false "Source IP address"
+// @Param from query string false "Start of time range (RFC 3339)"
+// @Param to query string false "End of time range (RFC 3339)"
+// @Param offset query int false "Number of entries to skip"
+// @Param limit query int false "Max number of entries"
+// @Success 200 {object} model.AuditPage
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *auditController) List(c *gin.Context) {
+	var req model.AuditListParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	page, err := controller.auditLog.List(&req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, page)
+}
+
+// @Summary Export audit log entries as JSON Lines
+// @Description Accepts the same filters as /api/audit. Entries are ordered from oldest to newest, all matching entries are exported
+// @Router /api/audit/export [get]
+// @Accept json
+// @Produce application/x-ndjson
+// @Success 200
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *auditController) Export(c *gin.Context) {
+	var req model.AuditListParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.jsonl", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(200)
+
+	encoder := json.NewEncoder(c.Writer)
+	err := controller.auditLog.Export(&req, func(entry *model.AuditEntry) error {
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		// Response is already started, so export is just cut short
+		c.Error(err)
+	}
+}
+
+const accessKeyContextKey = "accessKey"
+
+// auditActor returns an actor of current request for audit log
+func auditActor(c *gin.Context) model.AuditActor {
+	if u, ok := c.Get(gin.AuthUserKey); ok {
+		actor := userActor(u.(*model.User))
+		if token := currentAPIToken(c); token != nil {
+			actor.Token = token.Prefix
+		}
+		return actor
+	}
+
+	if k, ok := c.Get(accessKeyContextKey); ok {
+		accessKey := k.(*model.AccessKey)
+		return model.AuditActor{
+			Type: model.AuditActorAccessKey,
+			ID:   strconv.Itoa(accessKey.ID),
+			Name: accessKey.Label,
+		}
+	}
+
+	return model.AuditActor{Type: model.AuditActorAnonymous}
+}
+
+// userActor returns an audit log actor for a user
+func userActor(user *model.User) model.AuditActor {
+	return model.AuditActor{
+		Type: model.AuditActorUser,
+		ID:   strconv.Itoa(user.ID),
+		Name: user.UserName,
+	}
+}
+
+// audit records an action performed within current request to audit log
+func audit(c *gin.Context, auditLog service.AuditLog, entry *model.AuditEntry) {
+	if entry.Actor.Type == "" {
+		entry.Actor = auditActor(c)
+	}
+	entry.IP = clientIP(c)
+
+	auditLog.Record(entry)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/itglobal/backupmonitor/pkg/model"
 	"github.com/itglobal/backupmonitor/pkg/service"
@@ -9,26 +11,57 @@ import (
 func (s *server) ConfigureAuthAPI() {
 	repository := service.GetUserRepository(s.services)
 	jwt := service.GetJwt(s.services)
+	sessions := service.GetSessionRepository(s.services)
+	oidc := service.GetOIDCService(s.services)
+	authenticator := service.GetAuthenticator(s.services)
+	mfa := service.GetMFAService(s.services)
+	auditLog := service.GetAuditLog(s.services)
+	lockouts := service.GetLockoutService(s.services)
 
-	controller := &authController{repository, jwt}
+	controller := &authController{repository, jwt, sessions, oidc, authenticator, mfa, auditLog, lockouts}
 
 	s.router.POST("/api/authorize", controller.Authorize)
+	s.router.GET("/api/authorize/options", controller.GetOptions)
+	s.router.POST("/api/authorize/refresh", controller.Refresh)
+	s.router.POST("/api/authorize/mfa", controller.CompleteMFA)
+	s.router.POST("/api/authorize/mfa/enroll", controller.StartMFAEnrollment)
+	s.router.GET("/api/authorize/oidc", controller.StartOIDC)
+	s.router.GET("/api/authorize/oidc/callback", controller.FinishOIDC)
+	s.router.POST("/api/authorize/oidc/token", controller.RedeemOIDCTicket)
+	s.authorized.POST("/api/logout", requireSession, controller.Logout)
 	s.authorized.GET("/api/me", controller.GetMe)
-	s.authorized.POST("/api/me/password", controller.ChangePassword)
+	s.authorized.POST("/api/me/password", requireSession, controller.ChangePassword)
+	s.authorized.GET("/api/me/sessions", requireSession, controller.ListSessions)
+	s.authorized.DELETE("/api/me/sessions", requireSession, controller.RevokeAllSessions)
+	s.authorized.DELETE("/api/me/sessions/:id", requireSession, controller.RevokeSession)
+	s.authorized.POST("/api/me/mfa", requireSession, controller.EnrollMFA)
+	s.authorized.POST("/api/me/mfa/activate", requireSession, controller.ActivateMFA)
+	s.authorized.POST("/api/me/mfa/disable", requireSession, controller.DisableMFA)
+	s.authorized.POST("/api/me/mfa/recovery-codes", requireSession, controller.RegenerateRecoveryCodes)
 }
 
 type authController struct {
-	repository service.UserRepository
-	jwt        service.Jwt
+	repository    service.UserRepository
+	jwt           service.Jwt
+	sessions      service.SessionRepository
+	oidc          service.OIDCService
+	authenticator service.Authenticator
+	mfa           service.MFAService
+	auditLog      service.AuditLog
+	lockouts      service.LockoutService
 }
 
 // @Summary Get an access token
+// @Description Starts a new session. Access token is short-lived and should be renewed with refresh token.
+// @Description If user has to pass two-factor authentication, response contains an MFA challenge instead of tokens
 // @Router /api/authorize [post]
 // @Accept json
 // @Produce json
 // @Param account body model.AuthRequest true "Request"
 // @Success 200 {object} model.AuthResponse
 // @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 429 {object} model.Error
 func (t *authController) Authorize(c *gin.Context) {
 	var request model.AuthRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -36,23 +69,93 @@ func (t *authController) Authorize(c *gin.Context) {
 		return
 	}
 
-	user, err := t.repository.Get(request.Username)
-	if err == nil {
-		if user.CheckPassword(request.Password) {
-			token, err := t.jwt.GenerateToken(user)
-			if err != nil {
-				processError(c, err)
-				return
-			}
+	// Passwords are not checked at all while attempts are delayed or locked
+	keys := []model.LockoutKey{
+		model.NewLockoutKey(model.LockoutScopeUser, request.Username),
+		model.NewLockoutKey(model.LockoutScopeIP, clientIP(c)),
+	}
+	err := t.lockouts.Check(keys...)
+	if err != nil {
+		t.auditLoginFailure(c, request.Username, err)
+		processError(c, err)
+		return
+	}
 
-			c.JSON(200, &model.AuthResponse{
-				Token: token,
-				User:  user})
-			return
+	user, err := t.authenticator.Authenticate(request.Username, request.Password)
+	if err != nil {
+		if isCredentialsError(err) {
+			t.lockouts.Fail(clientIP(c), keys...)
 		}
+
+		t.auditLoginFailure(c, request.Username, err)
+		processError(c, err)
+		return
+	}
+
+	// Failures from client's address are kept, otherwise they could be reset with attacker's own account
+	t.lockouts.Reset(keys[0])
+
+	challenge, err := t.mfa.Challenge(user)
+	if err != nil {
+		processError(c, err)
+		return
 	}
 
-	c.JSON(400, model.NewError(model.EBadRequest, "invalid credentials"))
+	if challenge != nil {
+		c.JSON(200, &model.AuthResponse{MFA: challenge})
+		return
+	}
+
+	t.auditLogin(c, user, "password")
+	t.startSession(c, user)
+}
+
+// @Summary Renew an access token
+// @Description Refresh token is rotated, so the one from response should be used next time
+// @Router /api/authorize/refresh [post]
+// @Accept json
+// @Produce json
+// @Param body body model.RefreshRequest true "Request"
+// @Success 200 {object} model.AuthResponse
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+func (t *authController) Refresh(c *gin.Context) {
+	var request model.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	session, refreshToken, err := t.sessions.Refresh(request.RefreshToken, clientIP(c))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	user, err := t.repository.GetByID(session.UserID)
+	if err != nil || user.IsDisabled {
+		processError(c, model.NewError(model.EAccessDenied, "invalid refresh token"))
+		return
+	}
+
+	t.issueToken(c, user, session, refreshToken)
+}
+
+// @Summary Sign out (revoke current session)
+// @Router /api/logout [post]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.EmptyResponse
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (t *authController) Logout(c *gin.Context) {
+	err := t.sessions.Revoke(currentUser(c).ID, currentSession(c).ID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.EmptyResponse{})
 }
 
 // @Summary Get current user
@@ -70,11 +173,12 @@ func (t *authController) GetMe(c *gin.Context) {
 }
 
 // @Summary Change current user's password
+// @Description All user's sessions are revoked, and a new one is started
 // @Router /api/me/password [post]
 // @Accept json
 // @Produce json
 // @Param account body model.UserChangePasswordRequest true "Request"
-// @Success 200 {object} model.EmptyResponse
+// @Success 200 {object} model.AuthResponse
 // @Failure 400 {object} model.Error
 // @Failure 401 {object} model.Error
 // @Failure 403 {object} model.Error
@@ -94,15 +198,165 @@ func (t *authController) ChangePassword(c *gin.Context) {
 		panic(err)
 	}
 
+	if user.Source != model.UserSourceLocal {
+		c.JSON(400, model.NewError(model.EBadRequest, "password is managed by %s", user.Source))
+		return
+	}
+
 	if !user.CheckPassword(request.OldPassword) {
 		c.JSON(400, model.NewError(model.EBadRequest, "invalid old password"))
 		return
 	}
 
+	err = model.ValidatePassword(request.NewPassword)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	// Changing password revokes all sessions, including current one
 	err = t.repository.SetPassword(user.ID, request.NewPassword)
 	if err != nil {
 		panic(err)
 	}
 
+	t.startSession(c, user)
+}
+
+// @Summary List current user's active sessions
+// @Router /api/me/sessions [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Sessions
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (t *authController) ListSessions(c *gin.Context) {
+	list, err := t.sessions.List(currentUser(c).ID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	current := currentSession(c)
+	for _, session := range list {
+		session.IsCurrent = session.ID == current.ID
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Revoke all sessions of current user (sign out everywhere)
+// @Router /api/me/sessions [delete]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.EmptyResponse
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (t *authController) RevokeAllSessions(c *gin.Context) {
+	err := t.sessions.RevokeAll(currentUser(c).ID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.EmptyResponse{})
+}
+
+// @Summary Revoke a session of current user
+// @Router /api/me/sessions/:id [delete]
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} model.EmptyResponse
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (t *authController) RevokeSession(c *gin.Context) {
+	err := t.sessions.Revoke(currentUser(c).ID, c.Param("id"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
 	c.JSON(200, &model.EmptyResponse{})
 }
+
+// isCredentialsError returns true if an error is caused by invalid credentials (rather than e.g. an unavailable backend)
+func isCredentialsError(err error) bool {
+	e, ok := err.(*model.Error)
+	return ok && e.Code == model.EBadRequest
+}
+
+// auditLogin records a successful sign in to audit log
+func (t *authController) auditLogin(c *gin.Context, user *model.User, method string) {
+	audit(c, t.auditLog, &model.AuditEntry{
+		Actor:      userActor(user),
+		Action:     model.AuditActionLogin,
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Details:    method,
+	})
+}
+
+// auditLoginFailure records a failed sign in attempt to audit log
+func (t *authController) auditLoginFailure(c *gin.Context, username string, err error) {
+	entry := &model.AuditEntry{
+		Action:  model.AuditActionLoginFailed,
+		Details: err.Error(),
+	}
+
+	if username != "" {
+		entry.Actor = model.AuditActor{Type: model.AuditActorUser, Name: username}
+	} else {
+		entry.Actor = model.AuditActor{Type: model.AuditActorAnonymous}
+	}
+
+	if e, ok := err.(*model.Error); ok {
+		entry.Details = e.Message
+	}
+
+	audit(c, t.auditLog, entry)
+}
+
+// startSession starts a new session for a user and writes its tokens into response
+func (t *authController) startSession(c *gin.Context, user *model.User) {
+	response := t.createSession(c, user)
+	if response != nil {
+		c.JSON(200, response)
+	}
+}
+
+// createSession starts a new session for a user and returns its tokens (or writes an error response and returns nil)
+func (t *authController) createSession(c *gin.Context, user *model.User) *model.AuthResponse {
+	session, refreshToken, err := t.sessions.Create(user, clientIP(c), c.GetHeader("User-Agent"))
+	if err != nil {
+		processError(c, err)
+		return nil
+	}
+
+	return t.createToken(c, user, session, refreshToken)
+}
+
+// issueToken generates an access token within a session and writes it into response
+func (t *authController) issueToken(c *gin.Context, user *model.User, session *model.Session, refreshToken string) {
+	response := t.createToken(c, user, session, refreshToken)
+	if response != nil {
+		c.JSON(200, response)
+	}
+}
+
+// createToken generates an access token within a session (or writes an error response and returns nil)
+func (t *authController) createToken(c *gin.Context, user *model.User, session *model.Session, refreshToken string) *model.AuthResponse {
+	token, expiresAt, err := t.jwt.GenerateToken(user, session)
+	if err != nil {
+		processError(c, err)
+		return nil
+	}
+
+	return &model.AuthResponse{
+		Token:        token,
+		ExpiresAt:    &expiresAt,
+		RefreshToken: refreshToken,
+		User:         user,
+	}
+}
@@ -3,8 +3,11 @@ package api
 import (
 	"fmt"
 	"io"
+	"log"
 	"net/url"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/itglobal/backupmonitor/pkg/model"
@@ -13,31 +16,62 @@ import (
 
 func (s *server) ConfigureBackupAPI() {
 	controller := &backupController{
-		accessRepo: service.GetAccessKeyRepository(s.services),
-		backupRepo: service.GetBackupRepository(s.services),
+		accessRepo:  service.GetAccessKeyRepository(s.services),
+		backupRepo:  service.GetBackupRepository(s.services),
+		projectRepo: service.GetProjectRepository(s.services),
+		grantRepo:   service.GetProjectGrantRepository(s.services),
+		auth:        newBearerAuthenticator(s.services),
+		links:       service.GetDownloadLinkService(s.services),
+		auditLog:    service.GetAuditLog(s.services),
+		lockouts:    service.GetLockoutService(s.services),
 	}
 
+	// Downloads accept either a JWT, an access key or a signed link, so they are authorized by controller itself
 	s.router.GET("/api/backup/:id", controller.Download)
+	s.router.GET("/api/backup", controller.ListByKey)
 	s.router.POST("/api/backup", controller.Upload)
+	s.router.POST("/api/checkin", controller.CheckIn)
 
-	s.authorized.GET("/api/projects/:id/backup", controller.List)
+	s.authorized.POST("/api/backup/:id/link", controller.CreateLink)
+
+	s.authorized.GET("/api/projects/:id/backup", requireProjectRole(s.services, model.RoleViewer), controller.List)
 	s.authorized.DELETE("/api/backup/:id", controller.Delete)
+	s.authorized.POST("/api/backup/:id/pin", controller.Pin)
+	s.authorized.DELETE("/api/backup/:id/pin", controller.Unpin)
 }
 
 type backupController struct {
-	accessRepo service.AccessKeyRepository
-	backupRepo service.BackupRepository
+	accessRepo  service.AccessKeyRepository
+	backupRepo  service.BackupRepository
+	projectRepo service.ProjectRepository
+	grantRepo   service.ProjectGrantRepository
+	auth        *bearerAuthenticator
+	links       service.DownloadLinkService
+	auditLog    service.AuditLog
+	lockouts    service.LockoutService
 }
 
 // @Summary Download backup file
+// @Description Requires either a JWT (or a personal API token), a project access key with "download" scope or a signed download link
 // @Router /api/backup/:id [get]
 // @Accept json
 // @Produce application/octet-stream
+// @Param id path string true "ID"
+// @Param key query string false "Access key"
+// @Param expires query int false "Signed link expiration time"
+// @Param signature query string false "Signed link signature"
 // @Success 200
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
 // @Failure 404 {object} model.Error
+// @Failure 429 {object} model.Error
 func (controller *backupController) Download(c *gin.Context) {
 	id := c.Param("id")
 
+	if !controller.authorizeDownload(c, id) {
+		return
+	}
+
 	result, err := controller.backupRepo.Download(id)
 	if err != nil {
 		processError(c, err)
@@ -46,6 +80,18 @@ func (controller *backupController) Download(c *gin.Context) {
 
 	defer result.File.Close()
 
+	entry := &model.AuditEntry{
+		Action:     model.AuditActionBackupDownload,
+		TargetType: model.AuditTargetBackup,
+		TargetID:   result.Backup.ID,
+		ProjectID:  result.Backup.ProjectID,
+		Details:    result.Backup.FileName,
+	}
+	if c.Query("signature") != "" {
+		entry.Actor = model.AuditActor{Type: model.AuditActorLink}
+	}
+	audit(c, controller.auditLog, entry)
+
 	if result.Backup.Length >= 0 {
 		c.Header("Content-Length", fmt.Sprintf("%d", result.Backup.Length))
 	}
@@ -56,6 +102,121 @@ func (controller *backupController) Download(c *gin.Context) {
 	io.Copy(c.Writer, result.File)
 }
 
+// authorizeDownload checks download credentials and writes an error response if they are not valid
+func (controller *backupController) authorizeDownload(c *gin.Context, id string) bool {
+	// Signed download link
+	signature := c.Query("signature")
+	if signature != "" {
+		err := controller.links.Verify(id, c.Query("expires"), signature)
+		if err != nil {
+			processError(c, err)
+			return false
+		}
+
+		return true
+	}
+
+	key := c.Query("key")
+	header := c.GetHeader("Authorization")
+	if key == "" {
+		// JWT (or API token) of a user that can view backup's project
+		splitted := strings.Split(header, " ")
+		if len(splitted) == 2 && strings.ToLower(splitted[0]) == "bearer" {
+			e := controller.auth.authenticate(c, splitted[1])
+			if e != nil {
+				c.JSON(e.StatusCode, model.NewError(model.EAccessDenied, e.Message))
+				return false
+			}
+
+			access, err := controller.grantRepo.GetAccess(currentUser(c))
+			if err != nil {
+				processError(c, err)
+				return false
+			}
+
+			access.Token = currentAPIToken(c)
+			c.Set(accessContextKey, access)
+
+			_, project, ok := controller.getBackup(c, id)
+			if !ok {
+				return false
+			}
+
+			return checkProjectRole(c, project, model.RoleViewer)
+		}
+
+		key = header
+	}
+
+	// Project's access key with download scope
+	accessKey, ok := controller.authorizeAccessKey(c, key, model.AccessKeyScopeDownload)
+	if !ok {
+		return false
+	}
+
+	backup, err := controller.backupRepo.Get(id)
+	if err != nil {
+		processError(c, err)
+		return false
+	}
+
+	// Backups of other projects are reported as missing
+	if backup.ProjectID != accessKey.ProjectID {
+		processError(c, model.NewError(model.ENotFound, "backup \"%s\" doesn't exist", id))
+		return false
+	}
+
+	return true
+}
+
+// @Summary Create a short-lived download link for a backup
+// @Description Link can be used to download backup without any other credentials until it expires
+// @Router /api/backup/:id/link [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Param body body model.DownloadLinkParams false "Body"
+// @Success 200 {object} model.DownloadLink
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *backupController) CreateLink(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.DownloadLinkParams
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+			return
+		}
+	}
+
+	req.Normalize()
+	ttl, err := req.Lifetime(controller.links.MaxTTL())
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	backup, project, ok := controller.getBackup(c, id)
+	if !ok {
+		return
+	}
+
+	if !checkProjectRole(c, project, model.RoleViewer) {
+		return
+	}
+
+	link, err := controller.links.Create(backup, ttl)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, link)
+}
+
 // @Summary Upload backup file
 // @Router /api/backup [post]
 // @Accept json
@@ -65,16 +226,11 @@ func (controller *backupController) Download(c *gin.Context) {
 // @Failure 401 {object} model.Error
 // @Failure 403 {object} model.Error
 // @Failure 404 {object} model.Error
+// @Failure 429 {object} model.Error
+// @Failure 507 {object} model.Error
 func (controller *backupController) Upload(c *gin.Context) {
-	key := c.Query("key")
-
-	if key == "" {
-		key = c.GetHeader("Authorization")
-	}
-
-	accessKey, err := controller.accessRepo.Get(key)
-	if accessKey == nil {
-		c.JSON(403, model.NewError(model.EAccessDenied, "access denied"))
+	accessKey, ok := controller.authorizeAccessKey(c, accessKeyFromRequest(c), model.AccessKeyScopeUpload)
+	if !ok {
 		return
 	}
 
@@ -111,6 +267,120 @@ func (controller *backupController) Upload(c *gin.Context) {
 	c.JSON(400, model.NewError(model.EBadRequest, "no files uploaded"))
 }
 
+// @Summary List backups of access key's project
+// @Router /api/backup [get]
+// @Accept json
+// @Produce json
+// @Param key query string true "Access key with \"list\" scope"
+// @Success 200 {object} model.Backups
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 429 {object} model.Error
+func (controller *backupController) ListByKey(c *gin.Context) {
+	accessKey, ok := controller.authorizeAccessKey(c, accessKeyFromRequest(c), model.AccessKeyScopeList)
+	if !ok {
+		return
+	}
+
+	list, err := controller.backupRepo.List(accessKey.ProjectID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Check in from a backup host
+// @Description Records a check-in time and returns project's backup status
+// @Router /api/checkin [post]
+// @Accept json
+// @Produce json
+// @Param key query string true "Access key with \"checkin\" scope"
+// @Success 200 {object} model.CheckIn
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 429 {object} model.Error
+func (controller *backupController) CheckIn(c *gin.Context) {
+	accessKey, ok := controller.authorizeAccessKey(c, accessKeyFromRequest(c), model.AccessKeyScopeCheckIn)
+	if !ok {
+		return
+	}
+
+	project, err := controller.projectRepo.CheckIn(accessKey.ProjectID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	checkIn := &model.CheckIn{
+		ProjectID:    project.ID,
+		BackupStatus: project.BackupStatus,
+		CheckedInAt:  *project.LastCheckIn,
+	}
+	if project.LastBackup != nil {
+		checkIn.LastBackupTime = &project.LastBackup.Time
+	}
+
+	c.JSON(200, checkIn)
+}
+
+// accessKeyFromRequest returns an access key from "key" query parameter or from "Authorization" header
+func accessKeyFromRequest(c *gin.Context) string {
+	key := c.Query("key")
+	if key == "" {
+		key = c.GetHeader("Authorization")
+	}
+
+	return key
+}
+
+// authorizeAccessKey checks that an access key has a scope and can be used from client's address,
+// writes an error response if it can't
+func (controller *backupController) authorizeAccessKey(c *gin.Context, key string, scope model.AccessKeyScope) (*model.AccessKey, bool) {
+	if key == "" {
+		c.JSON(401, model.NewError(model.EAccessDenied, "missing credentials"))
+		return nil, false
+	}
+
+	// Keys are not checked at all while client's address is locked after too many invalid keys
+	ip := clientIP(c)
+	lockoutKey := model.NewLockoutKey(model.LockoutScopeAccessKey, ip)
+	err := controller.lockouts.Check(lockoutKey)
+	if err != nil {
+		processError(c, err)
+		return nil, false
+	}
+
+	accessKey, _ := controller.accessRepo.Get(key)
+	if accessKey == nil {
+		controller.lockouts.Fail(ip, lockoutKey)
+		c.JSON(403, model.NewError(model.EAccessDenied, "access denied"))
+		return nil, false
+	}
+
+	if !accessKey.AllowsIP(ip) {
+		log.Printf("access key #%d (project \"%s\") has been used from disallowed address %s", accessKey.ID, accessKey.ProjectID, ip)
+		c.JSON(403, model.NewError(model.EAccessDenied, "access key can't be used from %s", ip))
+		return nil, false
+	}
+
+	if !accessKey.HasScope(scope) {
+		c.JSON(403, model.NewError(model.EAccessDenied, "access key has no \"%s\" scope", scope))
+		return nil, false
+	}
+
+	c.Set(accessKeyContextKey, accessKey)
+
+	// Failures to record last use shouldn't break uploads and downloads
+	err = controller.accessRepo.MarkUsed(accessKey.ID, ip)
+	if err != nil {
+		log.Printf("unable to record use of access key #%d: %v", accessKey.ID, err)
+	}
+
+	return accessKey, true
+}
+
 // @Summary List project's backups
 // @Router /api/projects/:id/backup [get]
 // @Accept json
@@ -140,14 +410,170 @@ func (controller *backupController) List(c *gin.Context) {
 // @Failure 401 {object} model.Error
 // @Failure 403 {object} model.Error
 // @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
 func (controller *backupController) Delete(c *gin.Context) {
 	id := c.Param("id")
 
+	backup, project, ok := controller.getBackup(c, id)
+	if !ok {
+		return
+	}
+
+	if !checkProjectRole(c, project, model.RoleOperator) {
+		return
+	}
+
 	err := controller.backupRepo.Delete(id, "manually")
 	if err != nil {
 		processError(c, err)
 		return
 	}
 
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionBackupDelete,
+		TargetType: model.AuditTargetBackup,
+		TargetID:   backup.ID,
+		ProjectID:  backup.ProjectID,
+		Changes:    model.NewAuditChanges(backup, nil),
+		Details:    "manually",
+	})
+
 	c.Status(204)
 }
+
+// @Summary Pin a backup, so it's not deleted until pin is removed or expires
+// @Router /api/backup/:id/pin [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Param body body model.BackupPinParams true "Body"
+// @Success 200 {object} model.Backup
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *backupController) Pin(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.BackupPinParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	now := time.Now().UTC()
+	req.Normalize()
+	if err := req.Validate(now); err != nil {
+		processError(c, err)
+		return
+	}
+
+	before, project, ok := controller.getBackup(c, id)
+	if !ok {
+		return
+	}
+
+	if !checkProjectRole(c, project, model.RoleOperator) || !checkPinOwner(c, before, now) {
+		return
+	}
+
+	pin := &model.BackupPin{
+		Reason:   req.Reason,
+		PinnedBy: currentUser(c).UserName,
+		PinnedAt: now,
+		Until:    req.Until,
+	}
+	backup, err := controller.backupRepo.Pin(id, pin)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionBackupPin,
+		TargetType: model.AuditTargetBackup,
+		TargetID:   backup.ID,
+		ProjectID:  backup.ProjectID,
+		Changes:    model.NewAuditChanges(before, backup),
+		Details:    req.Reason,
+	})
+
+	c.JSON(200, backup)
+}
+
+// @Summary Unpin a backup
+// @Router /api/backup/:id/pin [delete]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.Backup
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *backupController) Unpin(c *gin.Context) {
+	id := c.Param("id")
+
+	before, project, ok := controller.getBackup(c, id)
+	if !ok {
+		return
+	}
+
+	if !checkProjectRole(c, project, model.RoleOperator) || !checkPinOwner(c, before, time.Now().UTC()) {
+		return
+	}
+
+	details := ""
+	if before.Pin != nil {
+		details = fmt.Sprintf("pinned by %s: %s", before.Pin.PinnedBy, before.Pin.Reason)
+	}
+
+	backup, err := controller.backupRepo.Pin(id, nil)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionBackupUnpin,
+		TargetType: model.AuditTargetBackup,
+		TargetID:   backup.ID,
+		ProjectID:  backup.ProjectID,
+		Changes:    model.NewAuditChanges(before, backup),
+		Details:    details,
+	})
+
+	c.JSON(200, backup)
+}
+
+// checkPinOwner writes an error response if backup has an active pin of another user,
+// since only admins are able to remove (or replace) someone else's pin, just like a legal hold
+func checkPinOwner(c *gin.Context, backup *model.Backup, now time.Time) bool {
+	if !backup.Pin.IsActive(now) || backup.Pin.PinnedBy == currentUser(c).UserName || currentAccess(c).Has(model.RoleAdmin) {
+		return true
+	}
+
+	processError(c, model.NewError(
+		model.EAccessDenied,
+		"backup \"%s\" is pinned by %s, %s role is required to change someone else's pin",
+		backup.ID,
+		backup.Pin.PinnedBy,
+		model.RoleAdmin))
+	return false
+}
+
+// getBackup returns a backup with its project and writes an error response if it's missing
+func (controller *backupController) getBackup(c *gin.Context, id string) (*model.Backup, *model.Project, bool) {
+	backup, err := controller.backupRepo.Get(id)
+	if err != nil {
+		processError(c, err)
+		return nil, nil, false
+	}
+
+	project, err := controller.projectRepo.Get(backup.ProjectID)
+	if err != nil {
+		processError(c, err)
+		return nil, nil, false
+	}
+
+	return backup, project, true
+}
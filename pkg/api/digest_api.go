@@ -0,0 +1,75 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureDigestAPI() {
+	controller := &digestController{
+		service: service.GetDigestService(s.services),
+	}
+
+	// Digest covers all projects, so it's not available to users restricted by project grants
+	s.authorized.GET("/api/digest", requireRole(model.RoleViewer), controller.Get)
+}
+
+type digestController struct {
+	service service.DigestService
+}
+
+// @Summary Generate a digest report across all projects
+// @Router /api/digest [get]
+// @Produce json
+// @Produce html
+// @Produce text/markdown
+// @Param period query string false "Report period (daily, weekly or a duration like 12h)"
+// @Param format query string false "Report format (markdown, html, json or slack)"
+// @Success 200 {object} model.Digest
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *digestController) Get(c *gin.Context) {
+	var req model.DigestParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	req.Normalize()
+	err := req.Validate()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	period, _ := model.ParseDigestPeriod(req.Period)
+	now := time.Now().UTC()
+
+	digest, err := controller.service.Build(now.Add(-period), now)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	report, err := controller.service.Render(digest, req.Format)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	contentType := "text/markdown; charset=utf-8"
+	switch req.Format {
+	case model.DigestFormatHTML:
+		contentType = "text/html; charset=utf-8"
+	case model.DigestFormatJSON:
+		contentType = "application/json; charset=utf-8"
+	case model.DigestFormatSlack:
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	c.Data(200, contentType, report)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureLockoutsAPI() {
+	controller := &lockoutController{
+		lockouts: service.GetLockoutService(s.services),
+		auditLog: service.GetAuditLog(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+
+	s.authorized.GET("/api/lockouts", admin, controller.List)
+	s.authorized.DELETE("/api/lockouts/:scope/:key", admin, controller.Delete)
+}
+
+type lockoutController struct {
+	lockouts service.LockoutService
+	auditLog service.AuditLog
+}
+
+// @Summary List usernames and addresses with recent failed attempts
+// @Router /api/lockouts [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Lockouts
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *lockoutController) List(c *gin.Context) {
+	c.JSON(200, controller.lockouts.List())
+}
+
+// @Summary Release a lockout and forget failed attempts
+// @Router /api/lockouts/:scope/:key [delete]
+// @Accept json
+// @Param scope path string true "Scope (user, ip or access_key)"
+// @Param key path string true "Username or IP address"
+// @Success 204
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *lockoutController) Delete(c *gin.Context) {
+	scope := model.LockoutScope(c.Param("scope"))
+	if !scope.IsValid() {
+		c.JSON(400, model.NewError(model.EBadRequest, "\"%s\" is not a valid lockout scope", scope))
+		return
+	}
+
+	key := model.NewLockoutKey(scope, c.Param("key"))
+	if !controller.lockouts.Unlock(key) {
+		processError(c, model.NewError(model.ENotFound, "%s has no recent failed attempts", key))
+		return
+	}
+
+	targetType := model.AuditTargetIP
+	if scope == model.LockoutScopeUser {
+		targetType = model.AuditTargetUser
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionUnlock,
+		TargetType: targetType,
+		TargetID:   key.Key,
+		Details:    string(scope),
+	})
+
+	c.Status(204)
+}
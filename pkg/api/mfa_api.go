@@ -0,0 +1,189 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+)
+
+// @Summary Complete two-factor authentication and get an access token
+// @Description Accepts either a one-time code from authenticator app or a recovery code.
+// @Description If user has enrolled while signing in, response contains recovery codes too
+// @Router /api/authorize/mfa [post]
+// @Accept json
+// @Produce json
+// @Param body body model.MFAVerifyRequest true "Request"
+// @Success 200 {object} model.AuthResponse
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 429 {object} model.Error
+func (t *authController) CompleteMFA(c *gin.Context) {
+	var request model.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	// One-time code guesses are counted against client's address
+	key := model.NewLockoutKey(model.LockoutScopeIP, clientIP(c))
+	err := t.lockouts.Check(key)
+	if err != nil {
+		t.auditLoginFailure(c, "", err)
+		processError(c, err)
+		return
+	}
+
+	user, codes, err := t.mfa.Complete(request.Token, request.Code)
+	if err != nil {
+		if isCredentialsError(err) {
+			t.lockouts.Fail(clientIP(c), key)
+		}
+
+		t.auditLoginFailure(c, "", err)
+		processError(c, err)
+		return
+	}
+
+	t.auditLogin(c, user, "password and two-factor code")
+	response := t.createSession(c, user)
+	if response == nil {
+		return
+	}
+
+	response.RecoveryCodes = codes
+	c.JSON(200, response)
+}
+
+// @Summary Get a pending enrollment of a two-factor authentication challenge
+// @Description Required if admin requires enrollment tokens and user hasn't enrolled yet.
+// @Description Enrollment token is issued by admin and is valid until user has enrolled
+// @Router /api/authorize/mfa/enroll [post]
+// @Accept json
+// @Produce json
+// @Param body body model.MFAEnrollRequest true "Request"
+// @Success 200 {object} model.MFAEnrollment
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 429 {object} model.Error
+func (t *authController) StartMFAEnrollment(c *gin.Context) {
+	var request model.MFAEnrollRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	// Enrollment token guesses are counted against client's address
+	key := model.NewLockoutKey(model.LockoutScopeIP, clientIP(c))
+	err := t.lockouts.Check(key)
+	if err != nil {
+		t.auditLoginFailure(c, "", err)
+		processError(c, err)
+		return
+	}
+
+	enrollment, err := t.mfa.StartEnrollment(request.Token, request.EnrollmentToken)
+	if err != nil {
+		if isCredentialsError(err) {
+			t.lockouts.Fail(clientIP(c), key)
+		}
+
+		t.auditLoginFailure(c, "", err)
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, enrollment)
+}
+
+// @Summary Start two-factor authentication enrollment
+// @Description Returns a new TOTP secret. It should be activated with a one-time code
+// @Router /api/me/mfa [post]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.MFAEnrollment
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 409 {object} model.Error
+func (t *authController) EnrollMFA(c *gin.Context) {
+	enrollment, err := t.mfa.Enroll(currentUser(c).ID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, enrollment)
+}
+
+// @Summary Activate two-factor authentication
+// @Description Recovery codes are returned only once
+// @Router /api/me/mfa/activate [post]
+// @Accept json
+// @Produce json
+// @Param body body model.MFACodeRequest true "One-time code"
+// @Success 200 {object} model.MFARecoveryCodes
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 409 {object} model.Error
+func (t *authController) ActivateMFA(c *gin.Context) {
+	var request model.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	codes, err := t.mfa.Activate(currentUser(c).ID, request.Code)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.MFARecoveryCodes{Codes: codes})
+}
+
+// @Summary Disable two-factor authentication
+// @Router /api/me/mfa/disable [post]
+// @Accept json
+// @Produce json
+// @Param body body model.MFACodeRequest true "One-time code or recovery code"
+// @Success 200 {object} model.EmptyResponse
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+func (t *authController) DisableMFA(c *gin.Context) {
+	var request model.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	err := t.mfa.Disable(currentUser(c).ID, request.Code)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.EmptyResponse{})
+}
+
+// @Summary Replace recovery codes with new ones
+// @Router /api/me/mfa/recovery-codes [post]
+// @Accept json
+// @Produce json
+// @Param body body model.MFACodeRequest true "One-time code"
+// @Success 200 {object} model.MFARecoveryCodes
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+func (t *authController) RegenerateRecoveryCodes(c *gin.Context) {
+	var request model.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	codes, err := t.mfa.RegenerateRecoveryCodes(currentUser(c).ID, request.Code)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, &model.MFARecoveryCodes{Codes: codes})
+}
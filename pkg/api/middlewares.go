@@ -2,11 +2,13 @@ package api
 
 import (
 	"fmt"
-	"github.com/itglobal/backupmonitor/pkg/model"
 	"log"
+	"net"
 	"strings"
 	"time"
 
+	"github.com/itglobal/backupmonitor/pkg/model"
+
 	"github.com/gin-gonic/gin"
 	"github.com/itglobal/backupmonitor/pkg/service"
 	"github.com/sarulabs/di"
@@ -45,7 +47,7 @@ func createLoggerMiddleware(log *log.Logger) gin.HandlerFunc {
 }
 
 func createJwtMiddleware(c di.Container) gin.HandlerFunc {
-	jwt := service.GetJwt(c)
+	auth := newBearerAuthenticator(c)
 
 	var processJwtError = func(c *gin.Context, e *service.JwtError) {
 		if e.StatusCode == 403 {
@@ -73,15 +75,227 @@ func createJwtMiddleware(c di.Container) gin.HandlerFunc {
 			return
 		}
 
-		user, e := jwt.ValidateToken(splitted[1])
+		e := auth.authenticate(c, splitted[1])
 		if e != nil {
 			processJwtError(c, e)
 			return
 		}
 
-		c.Set(gin.AuthUserKey, user)
 		c.Next()
 	}
 
 	return middleware
 }
+
+// bearerAuthenticator accepts both JWTs of login sessions and personal API tokens
+type bearerAuthenticator struct {
+	jwt            service.Jwt
+	apiTokens      service.APITokenRepository
+	userRepository service.UserRepository
+}
+
+func newBearerAuthenticator(c di.Container) *bearerAuthenticator {
+	return &bearerAuthenticator{
+		jwt:            service.GetJwt(c),
+		apiTokens:      service.GetAPITokenRepository(c),
+		userRepository: service.GetUserRepository(c),
+	}
+}
+
+// authenticate validates a bearer token and puts its user and session (or API token) into request context
+func (a *bearerAuthenticator) authenticate(c *gin.Context, token string) *service.JwtError {
+	if !strings.HasPrefix(token, model.APITokenPrefix) {
+		user, session, e := a.jwt.ValidateToken(token)
+		if e != nil {
+			return e
+		}
+
+		c.Set(gin.AuthUserKey, user)
+		c.Set(sessionContextKey, session)
+		return nil
+	}
+
+	apiToken, err := a.apiTokens.Authenticate(token, clientIP(c))
+	if err != nil {
+		if e, ok := err.(*model.Error); ok {
+			return service.NewJwtError(401, e.Message)
+		}
+
+		return service.NewJwtError(401, "bad api token")
+	}
+
+	user, err := a.userRepository.GetByID(apiToken.UserID)
+	if err != nil {
+		return service.NewJwtError(401, "bad api token")
+	}
+
+	if user.IsDisabled {
+		return service.NewJwtError(401, "user is disabled")
+	}
+
+	c.Set(gin.AuthUserKey, user)
+	c.Set(apiTokenContextKey, apiToken)
+	return nil
+}
+
+const clientIPContextKey = "clientIP"
+
+// createClientIPMiddleware resolves client's IP address.
+// Forwarding headers are taken into account only if a request comes from a trusted proxy.
+// Gin's own TrustedProxies setting is applied by Engine.Run only, and server isn't started with it
+func createClientIPMiddleware(trustedProxies []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(clientIPContextKey, resolveClientIP(c, trustedProxies))
+		c.Next()
+	}
+}
+
+// resolveClientIP walks X-Forwarded-For from right to left skipping trusted proxies,
+// so addresses that were added by client itself are ignored
+func resolveClientIP(c *gin.Context, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(c.Request.RemoteAddr)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !model.NetworksContain(trustedProxies, ip) {
+		return host
+	}
+
+	forwarded := strings.Split(c.GetHeader("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+		if !model.NetworksContain(trustedProxies, hop) {
+			break
+		}
+	}
+
+	if ip.Equal(net.ParseIP(host)) {
+		realIP := net.ParseIP(strings.TrimSpace(c.GetHeader("X-Real-IP")))
+		if realIP != nil {
+			ip = realIP
+		}
+	}
+
+	return ip.String()
+}
+
+// clientIP returns client's IP address
+func clientIP(c *gin.Context) string {
+	ip, ok := c.Get(clientIPContextKey)
+	if !ok {
+		return c.ClientIP()
+	}
+
+	return ip.(string)
+}
+
+const accessContextKey = "access"
+
+const sessionContextKey = "session"
+
+const apiTokenContextKey = "apiToken"
+
+// currentSession returns a session of current user (or nil if request is authorized with an API token)
+func currentSession(c *gin.Context) *model.Session {
+	s, _ := c.Get(sessionContextKey)
+	session, _ := s.(*model.Session)
+	return session
+}
+
+// currentAPIToken returns an API token request is authorized with (or nil for login sessions)
+func currentAPIToken(c *gin.Context) *model.APIToken {
+	t, _ := c.Get(apiTokenContextKey)
+	token, _ := t.(*model.APIToken)
+	return token
+}
+
+// requireSession allows requests within interactive login sessions only, API tokens are rejected
+func requireSession(c *gin.Context) {
+	if currentSession(c) == nil {
+		c.JSON(403, model.NewError(model.EAccessDenied, "this operation is not available with an api token"))
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+func createAccessMiddleware(c di.Container) gin.HandlerFunc {
+	grantRepository := service.GetProjectGrantRepository(c)
+
+	return func(c *gin.Context) {
+		access, err := grantRepository.GetAccess(currentUser(c))
+		if err != nil {
+			processError(c, err)
+			return
+		}
+
+		access.Token = currentAPIToken(c)
+		c.Set(accessContextKey, access)
+		c.Next()
+	}
+}
+
+// currentAccess returns access rights of current user
+func currentAccess(c *gin.Context) *model.Access {
+	a, _ := c.Get(accessContextKey)
+	return a.(*model.Access)
+}
+
+// requireRole allows requests from users that have a global role
+func requireRole(role model.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !currentAccess(c).Has(role) {
+			processError(c, model.NewError(model.EAccessDenied, "%s role is required", role))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireProjectRole allows requests from users that have a role on a project from ":id" route parameter.
+// Projects that are not visible to user are reported as missing
+func requireProjectRole(services di.Container, role model.Role) gin.HandlerFunc {
+	projectRepository := service.GetProjectRepository(services)
+
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		project, err := projectRepository.Get(id)
+		if err != nil {
+			processError(c, err)
+			return
+		}
+
+		if !checkProjectRole(c, project, role) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// checkProjectRole checks that current user has a role on a project and writes an error response otherwise
+func checkProjectRole(c *gin.Context, project *model.Project, role model.Role) bool {
+	access := currentAccess(c)
+
+	if !access.CanView(project) {
+		processError(c, model.NewError(model.ENotFound, "project \"%s\" doesn't exist", project.ID))
+		return false
+	}
+
+	if !access.Can(project, role) {
+		processError(c, model.NewError(model.EAccessDenied, "%s role is required on project \"%s\"", role, project.ID))
+		return false
+	}
+
+	return true
+}
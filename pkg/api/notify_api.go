@@ -1,22 +1,34 @@
 package api
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/itglobal/backupmonitor/pkg/model"
 	"github.com/itglobal/backupmonitor/pkg/notify"
+	"github.com/itglobal/backupmonitor/pkg/service"
 )
 
 func (s *server) ConfigureNotifyAPI() {
 	svc := notify.GetService(s.services)
-	controller := &notifyController{svc}
+	deliveryRepository := service.GetDeliveryRepository(s.services)
+	controller := &notifyController{svc, deliveryRepository}
+
+	admin := requireRole(model.RoleAdmin)
 
-	s.authorized.POST("/api/notify/slack", controller.NotifySlack)
-	s.authorized.POST("/api/notify/telegram", controller.NotifyTelegram)
-	s.authorized.POST("/api/notify/webhook", controller.NotifyWebhook)
+	s.authorized.POST("/api/notify/slack", admin, controller.NotifySlack)
+	s.authorized.POST("/api/notify/telegram", admin, controller.NotifyTelegram)
+	s.authorized.POST("/api/notify/webhook", admin, controller.NotifyWebhook)
+
+	s.authorized.GET("/api/notify/deliveries", admin, controller.ListDeliveries)
+	s.authorized.GET("/api/notify/deliveries/:id", admin, controller.GetDelivery)
+	s.authorized.POST("/api/notify/deliveries/:id/redeliver", admin, controller.Redeliver)
 }
 
 type notifyController struct {
-	service notify.Service
+	service            notify.Service
+	deliveryRepository service.DeliveryRepository
 }
 
 // @Summary Send a test Slack notification
@@ -40,7 +52,7 @@ func (controller *notifyController) NotifySlack(c *gin.Context) {
 	msg := req.ToMessage()
 
 	err := controller.service.NotifySlack(msg)
-	if err != nil {
+	if err != nil && !errors.Is(err, notify.ErrDisabled) {
 		processError(c, err)
 		return
 	}
@@ -69,7 +81,7 @@ func (controller *notifyController) NotifyTelegram(c *gin.Context) {
 	msg := req.ToMessage()
 
 	err := controller.service.NotifyTelegram(msg)
-	if err != nil {
+	if err != nil && !errors.Is(err, notify.ErrDisabled) {
 		processError(c, err)
 		return
 	}
@@ -105,3 +117,80 @@ func (controller *notifyController) NotifyWebhook(c *gin.Context) {
 
 	c.JSON(200, model.Empty{})
 }
+
+// @Summary List recent notification deliveries
+// @Router /api/notify/deliveries [get]
+// @Accept json
+// @Produce json
+// @Param project query string false "Project ID"
+// @Param status query string false "Delivery status (pending, sent, dead, skipped)"
+// @Param limit query int false "Max number of deliveries"
+// @Success 200 {object} model.Deliveries
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *notifyController) ListDeliveries(c *gin.Context) {
+	var req model.DeliveryListParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	list, err := controller.deliveryRepository.List(&req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Get a notification delivery
+// @Router /api/notify/deliveries/:id [get]
+// @Accept json
+// @Produce json
+// @Param id path int true "Delivery ID"
+// @Success 200 {object} model.Delivery
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *notifyController) GetDelivery(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid delivery id"))
+		return
+	}
+
+	delivery, err := controller.deliveryRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, delivery)
+}
+
+// @Summary Schedule a notification delivery to be sent again
+// @Router /api/notify/deliveries/:id/redeliver [post]
+// @Accept json
+// @Produce json
+// @Param id path int true "Delivery ID"
+// @Success 200 {object} model.Delivery
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *notifyController) Redeliver(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid delivery id"))
+		return
+	}
+
+	delivery, err := controller.deliveryRepository.Redeliver(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, delivery)
+}
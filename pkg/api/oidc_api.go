@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+)
+
+// @Summary Get available sign in methods
+// @Router /api/authorize/options [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.AuthOptions
+func (t *authController) GetOptions(c *gin.Context) {
+	c.JSON(200, &model.AuthOptions{OIDC: t.oidc.Enabled()})
+}
+
+// @Summary Sign in with OpenID Connect provider
+// @Description Redirects to provider's login page
+// @Router /api/authorize/oidc [get]
+// @Success 302
+// @Failure 404 {object} model.Error
+func (t *authController) StartOIDC(c *gin.Context) {
+	location, binding, err := t.oidc.Start()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	setOIDCCookie(c, binding, int(oidcCookieTTL.Seconds()))
+	c.Redirect(302, location)
+}
+
+// @Summary OpenID Connect redirect URI
+// @Description Redirects back to login page with either a one-time ticket or an error message
+// @Router /api/authorize/oidc/callback [get]
+// @Param state query string true "State"
+// @Param code query string false "Authorization code"
+// @Param error query string false "Error code"
+// @Success 302
+func (t *authController) FinishOIDC(c *gin.Context) {
+	query := make(url.Values)
+
+	if e := c.Query("error"); e != "" {
+		description := c.Query("error_description")
+		if description == "" {
+			description = e
+		}
+		query.Set("error", description)
+	} else {
+		binding, _ := c.Cookie(oidcCookieName)
+		user, err := t.oidc.Finish(c.Request.Context(), c.Query("state"), binding, c.Query("code"))
+		if err != nil {
+			t.auditLoginFailure(c, "", err)
+			if e, ok := err.(*model.Error); ok {
+				query.Set("error", e.Message)
+			} else {
+				query.Set("error", "unable to sign in")
+			}
+		} else {
+			query.Set("ticket", t.oidc.IssueTicket(user))
+		}
+	}
+
+	setOIDCCookie(c, "", -1)
+	c.Redirect(302, "/login?"+query.Encode())
+}
+
+const (
+	oidcCookieName = "oidc_login"
+	oidcCookiePath = "/api/authorize/oidc"
+	oidcCookieTTL  = 10 * time.Minute
+)
+
+// setOIDCCookie sets (or deletes if maxAge is negative) a cookie which binds a login to user's browser.
+// It has to be sent along with provider's redirect back to application, so it's "lax" rather than "strict"
+func setOIDCCookie(c *gin.Context, binding string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookieName, binding, maxAge, oidcCookiePath, "", secure, true)
+}
+
+// @Summary Exchange a one-time OpenID Connect login ticket to an access token
+// @Router /api/authorize/oidc/token [post]
+// @Accept json
+// @Produce json
+// @Param body body model.OIDCTicketRequest true "Request"
+// @Success 200 {object} model.AuthResponse
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+func (t *authController) RedeemOIDCTicket(c *gin.Context) {
+	var request model.OIDCTicketRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	user, err := t.oidc.RedeemTicket(request.Ticket)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	t.auditLogin(c, user, "single sign-on")
+	t.startSession(c, user)
+}
@@ -3,34 +3,48 @@ package api
 import (
 	"fmt"
 	"net/url"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/gin-gonic/gin"
 	"github.com/itglobal/backupmonitor/pkg/model"
 	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/itglobal/backupmonitor/pkg/storage"
 )
 
 func (s *server) ConfigureProjectsAPI() {
 	projectRepository := service.GetProjectRepository(s.services)
-	accessKeyRepository := service.GetAccessKeyRepository(s.services)
 	backupRepository := service.GetBackupRepository(s.services)
 
 	controller := &projectController{
-		projectRepository:   projectRepository,
-		accessKeyRepository: accessKeyRepository,
-		backupRepository:    backupRepository,
+		projectRepository: projectRepository,
+		backupRepository:  backupRepository,
+		auditLog:          service.GetAuditLog(s.services),
+		storage:           storage.GetRegistry(s.services),
 	}
 
+	admin := requireRole(model.RoleAdmin)
+	viewer := requireProjectRole(s.services, model.RoleViewer)
+	operator := requireProjectRole(s.services, model.RoleOperator)
+
 	s.authorized.GET("/api/projects", controller.List)
-	s.authorized.GET("/api/projects/:id", controller.Get)
-	s.authorized.POST("/api/projects", controller.Post)
-	s.authorized.PUT("/api/projects/:id", controller.Put)
-	s.authorized.DELETE("/api/projects/:id", controller.Delete)
+	s.authorized.GET("/api/projects/:id", viewer, controller.Get)
+	s.authorized.POST("/api/projects", admin, controller.Post)
+	s.authorized.PUT("/api/projects/:id", operator, controller.Put)
+	s.authorized.POST("/api/projects/:id/retention/preview", viewer, controller.PreviewRetention)
+	s.authorized.DELETE("/api/projects/:id", admin, controller.Delete)
+	s.authorized.POST("/api/projects/:id/silence", operator, controller.Silence)
+	s.authorized.DELETE("/api/projects/:id/silence", operator, controller.Unsilence)
+	s.authorized.POST("/api/projects/:id/ack", operator, controller.Acknowledge)
+	s.authorized.POST("/api/projects/:id/hold", admin, controller.Hold)
+	s.authorized.DELETE("/api/projects/:id/hold", admin, controller.Release)
 }
 
 type projectController struct {
-	projectRepository   service.ProjectRepository
-	accessKeyRepository service.AccessKeyRepository
-	backupRepository    service.BackupRepository
+	projectRepository service.ProjectRepository
+	backupRepository  service.BackupRepository
+	auditLog          service.AuditLog
+	storage           storage.Registry
 }
 
 // @Summary List projects
@@ -41,7 +55,7 @@ type projectController struct {
 // @Failure 401 {object} model.Error
 // @Failure 403 {object} model.Error
 func (controller *projectController) List(c *gin.Context) {
-	list, err := controller.projectRepository.List()
+	list, err := controller.projectRepository.List(currentAccess(c))
 	if err != nil {
 		processError(c, err)
 		return
@@ -89,12 +103,20 @@ func (controller *projectController) Post(c *gin.Context) {
 		return
 	}
 
+	req.Normalize()
+	if err := controller.checkStorage(req.Storage); err != nil {
+		processError(c, err)
+		return
+	}
+
 	p, err := controller.projectRepository.Create(&req)
 	if err != nil {
 		processError(c, err)
 		return
 	}
 
+	controller.audit(c, model.AuditActionProjectCreate, nil, p, "")
+
 	c.Header("Location", fmt.Sprintf("/api/projects/%s", url.QueryEscape(p.ID)))
 	c.JSON(201, p)
 }
@@ -110,6 +132,7 @@ func (controller *projectController) Post(c *gin.Context) {
 // @Failure 401 {object} model.Error
 // @Failure 403 {object} model.Error
 // @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
 func (controller *projectController) Put(c *gin.Context) {
 	id := c.Param("id")
 
@@ -119,15 +142,130 @@ func (controller *projectController) Put(c *gin.Context) {
 		return
 	}
 
+	before, err := controller.projectRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	// Only admins choose where project's data lives
+	req.Normalize()
+	if req.Storage != nil && *req.Storage != before.Storage {
+		if !currentAccess(c).Has(model.RoleAdmin) {
+			processError(c, model.NewError(model.EAccessDenied, "%s role is required to change project's storage", model.RoleAdmin))
+			return
+		}
+
+		if err := controller.checkStorage(*req.Storage); err != nil {
+			processError(c, err)
+			return
+		}
+	}
+
+	// Neither can operators lift quotas they are limited by
+	if req.Quota != nil && !req.Quota.Equals(before.Quota) && !currentAccess(c).Has(model.RoleAdmin) {
+		processError(c, model.NewError(model.EAccessDenied, "%s role is required to change project's quota", model.RoleAdmin))
+		return
+	}
+
+	// Project grants and routing rules select projects by tags and labels, so they decide who sees a project
+	if (req.ChangesTags(before) || req.ChangesLabels(before)) && !currentAccess(c).Has(model.RoleAdmin) {
+		processError(c, model.NewError(model.EAccessDenied, "%s role is required to change project's tags or labels", model.RoleAdmin))
+		return
+	}
+
+	// Retention policy changes which delete any backups should be confirmed explicitly
+	if req.RetentionParams().IsSet() && !req.ConfirmDeletion {
+		preview, err := controller.previewRetention(before, req.RetentionParams())
+		if err != nil {
+			processError(c, err)
+			return
+		}
+
+		if len(preview.Deleted) > 0 {
+			processError(c, model.NewError(
+				model.EConflict,
+				"this change would delete %d backup(s) (%s), set \"confirmDeletion\" to apply it",
+				len(preview.Deleted),
+				humanize.Bytes(uint64(preview.FreedBytes))))
+			return
+		}
+	}
+
 	p, err := controller.projectRepository.Update(id, &req)
 	if err != nil {
 		processError(c, err)
 		return
 	}
 
+	controller.audit(c, model.AuditActionProjectUpdate, before, p, "")
+
 	c.JSON(200, p)
 }
 
+// @Summary Preview which backups a retention policy would delete
+// @Router /api/projects/:id/retention/preview [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Param body body model.RetentionParams true "Proposed retention policy (omitted fields are left as is)"
+// @Success 200 {object} model.RetentionPreview
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *projectController) PreviewRetention(c *gin.Context) {
+	var req model.RetentionParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	project, err := controller.projectRepository.Get(c.Param("id"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	preview, err := controller.previewRetention(project, &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, preview)
+}
+
+// checkStorage checks that a storage backend exists (empty name means default backend)
+func (controller *projectController) checkStorage(name string) error {
+	if name != "" && !controller.storage.Has(name) {
+		return model.NewError(model.EBadRequest, "storage backend \"%s\" is not configured", name)
+	}
+
+	return nil
+}
+
+// previewRetention evaluates what a proposed retention policy would do to project's backups
+func (controller *projectController) previewRetention(project *model.Project, req *model.RetentionParams) (*model.RetentionPreview, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Normalize()
+
+	backups, err := controller.backupRepository.List(project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	proposed := *project
+	req.ApplyTo(&proposed)
+
+	plan := proposed.PlanRetention(backups, time.Now().UTC())
+	return model.NewRetentionPreview(plan), nil
+}
+
 // @Summary Delete existing project
 // @Router /api/projects/:id [delete]
 // @Accept json
@@ -136,54 +274,244 @@ func (controller *projectController) Put(c *gin.Context) {
 // @Failure 401 {object} model.Error
 // @Failure 403 {object} model.Error
 // @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
 func (controller *projectController) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	// Mark projects as disabled
-	isActive := false
-	req := &model.ProjectUpdateParams{
-		IsActive: &isActive,
-	}
-	_, err := controller.projectRepository.Update(id, req)
+	before, err := controller.projectRepository.Get(id)
 	if err != nil {
 		processError(c, err)
 		return
 	}
 
-	// Drop all access keys
-	accessKeys, err := controller.accessKeyRepository.List(id)
-	if err != nil {
-		processError(c, err)
+	// Projects with pinned or locked backups or on legal hold can't be deleted
+	if before.LegalHold != nil {
+		processError(c, model.NewError(model.EConflict, "project \"%s\" is on legal hold (%s)", id, before.LegalHold.Reason))
 		return
 	}
-	for _, accessKey := range accessKeys {
-		err = controller.accessKeyRepository.Delete(id, accessKey.ID)
-		if err != nil {
-			processError(c, err)
-			return
-		}
-	}
 
-	// Drop all backups
 	backups, err := controller.backupRepository.List(id)
 	if err != nil {
 		processError(c, err)
 		return
 	}
+	now := time.Now().UTC()
 	for _, backup := range backups {
-		err = controller.backupRepository.Delete(backup.ID, "project deletion")
+		err = before.CheckBackupDeletion(backup, now)
 		if err != nil {
 			processError(c, err)
 			return
 		}
 	}
 
-	// Drop project
+	// Project is moved to trash along with its backups and access keys, they are purged later
 	err = controller.projectRepository.Delete(id)
 	if err != nil {
 		processError(c, err)
 		return
 	}
 
+	details := fmt.Sprintf("moved to trash with %d backup(s)", len(backups))
+	controller.audit(c, model.AuditActionProjectDelete, before, nil, details)
+
 	c.Status(204)
 }
+
+// @Summary Silence project notifications for a while
+// @Router /api/projects/:id/silence [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Param body body model.ProjectSilenceParams true "Body"
+// @Success 200 {object} model.Project
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *projectController) Silence(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.ProjectSilenceParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	req.Normalize()
+	until, err := req.Until(time.Now().UTC())
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	before, err := controller.projectRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	p, err := controller.projectRepository.Silence(id, &until)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.audit(c, model.AuditActionProjectUpdate, before, p, "silenced")
+
+	c.JSON(200, p)
+}
+
+// @Summary Unsilence project notifications
+// @Router /api/projects/:id/silence [delete]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.Project
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *projectController) Unsilence(c *gin.Context) {
+	id := c.Param("id")
+
+	before, err := controller.projectRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	p, err := controller.projectRepository.Silence(id, nil)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.audit(c, model.AuditActionProjectUpdate, before, p, "unsilenced")
+
+	c.JSON(200, p)
+}
+
+// @Summary Acknowledge project's backup problem
+// @Router /api/projects/:id/ack [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.Project
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *projectController) Acknowledge(c *gin.Context) {
+	id := c.Param("id")
+
+	u, _ := c.Get(gin.AuthUserKey)
+	user := u.(*model.User)
+
+	before, err := controller.projectRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	p, err := controller.projectRepository.Acknowledge(id, user.UserName)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.audit(c, model.AuditActionProjectUpdate, before, p, "acknowledged")
+
+	c.JSON(200, p)
+}
+
+// @Summary Put a project on legal hold, so none of its backups can be deleted
+// @Router /api/projects/:id/hold [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Param body body model.LegalHoldParams true "Body"
+// @Success 200 {object} model.Project
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *projectController) Hold(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.LegalHoldParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		processError(c, err)
+		return
+	}
+
+	before, err := controller.projectRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	hold := &model.LegalHold{
+		Reason:   req.Reason,
+		PlacedBy: currentUser(c).UserName,
+		PlacedAt: time.Now().UTC(),
+	}
+	p, err := controller.projectRepository.SetLegalHold(id, hold)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.audit(c, model.AuditActionProjectHold, before, p, req.Reason)
+
+	c.JSON(200, p)
+}
+
+// @Summary Release project's legal hold
+// @Router /api/projects/:id/hold [delete]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.Project
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *projectController) Release(c *gin.Context) {
+	id := c.Param("id")
+
+	before, err := controller.projectRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	p, err := controller.projectRepository.SetLegalHold(id, nil)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.audit(c, model.AuditActionProjectRelease, before, p, "")
+
+	c.JSON(200, p)
+}
+
+// audit records an action on a project to audit log
+func (controller *projectController) audit(c *gin.Context, action model.AuditAction, before, after *model.Project, details string) {
+	project := after
+	if project == nil {
+		project = before
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetProject,
+		TargetID:   project.ID,
+		ProjectID:  project.ID,
+		Changes:    model.NewAuditChanges(before, after),
+		Details:    details,
+	})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureQuotaAPI() {
+	controller := &quotaController{
+		projectRepository: service.GetProjectRepository(s.services),
+		quotaService:      service.GetQuotaService(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+	viewer := requireProjectRole(s.services, model.RoleViewer)
+
+	s.authorized.GET("/api/storage", admin, controller.Status)
+	s.authorized.GET("/api/storage/backends", admin, controller.Backends)
+	s.authorized.GET("/api/projects/:id/usage", viewer, controller.ProjectUsage)
+}
+
+type quotaController struct {
+	projectRepository service.ProjectRepository
+	quotaService      service.QuotaService
+}
+
+// @Summary Get global storage usage and storage backends with their free disk space
+// @Router /api/storage [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.StorageStatus
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *quotaController) Status(c *gin.Context) {
+	status, err := controller.quotaService.Status()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+// @Summary List storage backends
+// @Router /api/storage/backends [get]
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.StorageBackend
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *quotaController) Backends(c *gin.Context) {
+	c.JSON(200, controller.quotaService.Backends())
+}
+
+// @Summary Get project's storage usage
+// @Router /api/projects/:id/usage [get]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.StorageUsage
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *quotaController) ProjectUsage(c *gin.Context) {
+	project, err := controller.projectRepository.Get(c.Param("id"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	usage, err := controller.quotaService.ProjectUsage(project)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, usage)
+}
@@ -0,0 +1,367 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureRoutingAPI() {
+	controller := &routingController{
+		notificationTargetRepository: service.GetNotificationTargetRepository(s.services),
+		routingRuleRepository:        service.GetRoutingRuleRepository(s.services),
+		router:                       service.GetNotificationRouter(s.services),
+		auditLog:                     service.GetAuditLog(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+
+	s.authorized.GET("/api/notify/targets", admin, controller.ListTargets)
+	s.authorized.GET("/api/notify/targets/:name", admin, controller.GetTarget)
+	s.authorized.POST("/api/notify/targets", admin, controller.CreateTarget)
+	s.authorized.PUT("/api/notify/targets/:name", admin, controller.UpdateTarget)
+	s.authorized.DELETE("/api/notify/targets/:name", admin, controller.DeleteTarget)
+
+	s.authorized.GET("/api/notify/rules", admin, controller.ListRules)
+	s.authorized.GET("/api/notify/rules/:id", admin, controller.GetRule)
+	s.authorized.POST("/api/notify/rules", admin, controller.CreateRule)
+	s.authorized.PUT("/api/notify/rules/:id", admin, controller.UpdateRule)
+	s.authorized.DELETE("/api/notify/rules/:id", admin, controller.DeleteRule)
+
+	s.authorized.POST("/api/notify/route/test", admin, controller.TestRoute)
+}
+
+type routingController struct {
+	notificationTargetRepository service.NotificationTargetRepository
+	routingRuleRepository        service.RoutingRuleRepository
+	router                       service.NotificationRouter
+	auditLog                     service.AuditLog
+}
+
+// @Summary List notification targets
+// @Router /api/notify/targets [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.NotificationTargets
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *routingController) ListTargets(c *gin.Context) {
+	list, err := controller.notificationTargetRepository.List()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Get a notification target
+// @Router /api/notify/targets/:name [get]
+// @Accept json
+// @Produce json
+// @Param name path string true "Target name"
+// @Success 200 {object} model.NotificationTarget
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *routingController) GetTarget(c *gin.Context) {
+	target, err := controller.notificationTargetRepository.Get(c.Param("name"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, target)
+}
+
+// @Summary Create a notification target
+// @Router /api/notify/targets [post]
+// @Accept json
+// @Produce json
+// @Param body body model.NotificationTargetCreateParams true "Body"
+// @Success 200 {object} model.NotificationTarget
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 409 {object} model.Error
+func (controller *routingController) CreateTarget(c *gin.Context) {
+	var req model.NotificationTargetCreateParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	target, err := controller.notificationTargetRepository.Create(&req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditTarget(c, model.AuditActionNotificationTargetCreate, target.Name, nil, target)
+
+	c.JSON(200, target)
+}
+
+// @Summary Update a notification target
+// @Router /api/notify/targets/:name [put]
+// @Accept json
+// @Produce json
+// @Param name path string true "Target name"
+// @Param body body model.NotificationTargetUpdateParams true "Body"
+// @Success 200 {object} model.NotificationTarget
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *routingController) UpdateTarget(c *gin.Context) {
+	var req model.NotificationTargetUpdateParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	before, err := controller.notificationTargetRepository.Get(c.Param("name"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	target, err := controller.notificationTargetRepository.Update(before.Name, &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditTarget(c, model.AuditActionNotificationTargetUpdate, target.Name, before, target)
+
+	c.JSON(200, target)
+}
+
+// @Summary Delete a notification target
+// @Router /api/notify/targets/:name [delete]
+// @Accept json
+// @Produce json
+// @Param name path string true "Target name"
+// @Success 200 {object} model.Empty
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+func (controller *routingController) DeleteTarget(c *gin.Context) {
+	target, err := controller.notificationTargetRepository.Get(c.Param("name"))
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	err = controller.notificationTargetRepository.Delete(target.Name)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditTarget(c, model.AuditActionNotificationTargetDelete, target.Name, target, nil)
+
+	c.JSON(200, model.Empty{})
+}
+
+// auditTarget records a change of notification target into audit log
+func (controller *routingController) auditTarget(c *gin.Context, action model.AuditAction, name string, before, after *model.NotificationTarget) {
+	changes := model.NewAuditChanges(before, after)
+	if before != nil && after != nil && len(changes) == 0 {
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetNotificationTarget,
+		TargetID:   name,
+		Changes:    changes,
+	})
+}
+
+// @Summary List notification routing rules
+// @Router /api/notify/rules [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.RoutingRules
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *routingController) ListRules(c *gin.Context) {
+	list, err := controller.routingRuleRepository.List()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Get a notification routing rule
+// @Router /api/notify/rules/:id [get]
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} model.RoutingRule
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *routingController) GetRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid rule id"))
+		return
+	}
+
+	rule, err := controller.routingRuleRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, rule)
+}
+
+// @Summary Create a notification routing rule
+// @Router /api/notify/rules [post]
+// @Accept json
+// @Produce json
+// @Param body body model.RoutingRuleParams true "Body"
+// @Success 200 {object} model.RoutingRule
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *routingController) CreateRule(c *gin.Context) {
+	var req model.RoutingRuleParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	rule, err := controller.routingRuleRepository.Create(&req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditRule(c, model.AuditActionRoutingRuleCreate, rule.ID, nil, rule)
+
+	c.JSON(200, rule)
+}
+
+// @Summary Update a notification routing rule
+// @Router /api/notify/rules/:id [put]
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param body body model.RoutingRuleParams true "Body"
+// @Success 200 {object} model.RoutingRule
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *routingController) UpdateRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid rule id"))
+		return
+	}
+
+	var req model.RoutingRuleParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	before, err := controller.routingRuleRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	rule, err := controller.routingRuleRepository.Update(id, &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditRule(c, model.AuditActionRoutingRuleUpdate, id, before, rule)
+
+	c.JSON(200, rule)
+}
+
+// @Summary Delete a notification routing rule
+// @Router /api/notify/rules/:id [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} model.Empty
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *routingController) DeleteRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid rule id"))
+		return
+	}
+
+	rule, err := controller.routingRuleRepository.Get(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	err = controller.routingRuleRepository.Delete(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditRule(c, model.AuditActionRoutingRuleDelete, id, rule, nil)
+
+	c.JSON(200, model.Empty{})
+}
+
+// auditRule records a change of routing rule into audit log
+func (controller *routingController) auditRule(c *gin.Context, action model.AuditAction, id int, before, after *model.RoutingRule) {
+	changes := model.NewAuditChanges(before, after)
+	if before != nil && after != nil && len(changes) == 0 {
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetRoutingRule,
+		TargetID:   strconv.Itoa(id),
+		Changes:    changes,
+	})
+}
+
+// @Summary Show which targets a notification event would reach
+// @Router /api/notify/route/test [post]
+// @Accept json
+// @Produce json
+// @Param body body model.RouteTestParams true "Body"
+// @Success 200 {object} model.Route
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *routingController) TestRoute(c *gin.Context) {
+	var req model.RouteTestParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	route, err := controller.router.Test(&req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, route)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureSettingsAPI() {
+	controller := &settingsController{
+		repository: service.GetSettingsRepository(s.services),
+		auditLog:   service.GetAuditLog(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+
+	s.authorized.GET("/api/settings/security", admin, controller.GetSecurity)
+	s.authorized.PUT("/api/settings/security", admin, controller.PutSecurity)
+}
+
+type settingsController struct {
+	repository service.SettingsRepository
+	auditLog   service.AuditLog
+}
+
+// @Summary Get security settings
+// @Router /api/settings/security [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.SecuritySettings
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *settingsController) GetSecurity(c *gin.Context) {
+	settings, err := controller.repository.GetSecurity()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, settings)
+}
+
+// @Summary Update security settings
+// @Router /api/settings/security [put]
+// @Accept json
+// @Produce json
+// @Param body body model.SecuritySettingsUpdateParams true "Settings"
+// @Success 200 {object} model.SecuritySettings
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *settingsController) PutSecurity(c *gin.Context) {
+	var request model.SecuritySettingsUpdateParams
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	before, err := controller.repository.GetSecurity()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	settings, err := controller.repository.UpdateSecurity(&request)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	changes := model.NewAuditChanges(before, settings)
+	if len(changes) > 0 {
+		audit(c, controller.auditLog, &model.AuditEntry{
+			Action:     model.AuditActionSettingsUpdate,
+			TargetType: model.AuditTargetSettings,
+			TargetID:   "security",
+			Changes:    changes,
+		})
+	}
+
+	c.JSON(200, settings)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"log"
 	"mime"
+	"net"
 	"net/http"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,27 +34,46 @@ func Setup(builder component.Builder) {
 	builder.AddService(di.Def{
 		Name: serverKey,
 		Build: func(c di.Container) (interface{}, error) {
-			return newServer(c), nil
+			return newServer(c)
 		},
 	})
 
 	builder.AddComponent(createComponent)
 }
 
-func newServer(c di.Container) *server {
+func newServer(c di.Container) (*server, error) {
 	logger := log.New(log.Writer(), "[api] ", log.Flags())
 
+	trustedProxies, err := parseTrustedProxies(viper.GetString("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 	router.Use(createLoggerMiddleware(logger))
 	router.Use(gin.Recovery())
+	router.Use(createClientIPMiddleware(trustedProxies))
 
 	authorized := router.Group("/")
-	authorized.Use(createJwtMiddleware(c))
+	authorized.Use(createJwtMiddleware(c), createAccessMiddleware(c))
 
 	s := &server{c, logger, router, authorized}
-	return s
+	return s, nil
+}
+
+// parseTrustedProxies parses a comma-separated list of proxy addresses and networks
+func parseTrustedProxies(str string) ([]*net.IPNet, error) {
+	values := make([]string, 0)
+	for _, value := range strings.Split(str, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return model.ParseNetworks(values)
 }
 
 func createComponent(c di.Container) (component.T, error) {
@@ -60,10 +81,20 @@ func createComponent(c di.Container) (component.T, error) {
 
 	server.ConfigureSwagger()
 	server.ConfigureAuthAPI()
+	server.ConfigureUsersAPI()
+	server.ConfigureTokensAPI()
+	server.ConfigureSettingsAPI()
 	server.ConfigureProjectsAPI()
 	server.ConfigureBackupAPI()
+	server.ConfigureTrashAPI()
+	server.ConfigureQuotaAPI()
 	server.ConfigureAccessAPI()
 	server.ConfigureNotifyAPI()
+	server.ConfigureRoutingAPI()
+	server.ConfigureDigestAPI()
+	server.ConfigureAuditAPI()
+	server.ConfigureLockoutsAPI()
+	server.ConfigureSlackAPI()
 	server.ConfigureStaticFiles()
 
 	http.Handle("/", server.router)
@@ -135,6 +166,12 @@ func processError(c *gin.Context, err error) {
 		case model.EConflict:
 			status = 409
 			break
+		case model.ETooManyRequests:
+			status = 429
+			break
+		case model.EQuotaExceeded:
+			status = 507
+			break
 		}
 
 		c.JSON(status, e)
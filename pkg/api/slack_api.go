@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+)
+
+func (s *server) ConfigureSlackAPI() {
+	controller := &slackController{
+		logger:            s.logger,
+		projectRepository: service.GetProjectRepository(s.services),
+		signingSecret:     viper.GetString("SLACK_SIGNING_SECRET"),
+		maxAge:            viper.GetDuration("SLACK_SIGNATURE_MAX_AGE"),
+		now:               time.Now,
+		client:            &http.Client{Timeout: 30 * time.Second},
+	}
+
+	s.router.POST("/api/slack/interactions", controller.Interact)
+}
+
+type slackController struct {
+	logger            *log.Logger
+	projectRepository service.ProjectRepository
+	signingSecret     string
+	maxAge            time.Duration
+	now               func() time.Time
+	client            *http.Client
+}
+
+const maxSlackRequestLength = 1 << 20
+
+// @Summary Handle Slack interactive message actions
+// @Router /api/slack/interactions [post]
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param payload formData string true "Slack interaction payload"
+// @Success 200
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *slackController) Interact(c *gin.Context) {
+	if controller.signingSecret == "" {
+		c.JSON(404, model.NewError(model.ENotFound, "slack interactions are disabled"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxSlackRequestLength))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	err = verifySlackSignature(c.Request.Header, body, controller.signingSecret, controller.now(), controller.maxAge)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	var callback slack.InteractionCallback
+	err = json.Unmarshal([]byte(values.Get("payload")), &callback)
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	text, err := controller.handleInteraction(&callback)
+	if err != nil {
+		if _, ok := err.(*model.Error); !ok {
+			processError(c, err)
+			return
+		}
+
+		// Slack shows non-200 responses as a generic failure,
+		// so a reply message is the only way to explain what went wrong
+		text = fmt.Sprintf(":warning: %s", err)
+	}
+
+	if text != "" && callback.ResponseURL != "" {
+		go controller.reply(callback.ResponseURL, text)
+	}
+
+	c.Status(200)
+}
+
+// Executes actions from an interaction callback and returns a reply text
+func (controller *slackController) handleInteraction(callback *slack.InteractionCallback) (string, error) {
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return "", nil
+	}
+
+	user := callback.User.Name
+	if user == "" {
+		user = callback.User.ID
+	}
+
+	replies := make([]string, 0)
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case model.ActionSilence24h:
+			until := controller.now().UTC().Add(24 * time.Hour)
+			p, err := controller.projectRepository.Silence(action.Value, &until)
+			if err != nil {
+				return "", err
+			}
+
+			controller.logger.Printf("project \"%s\" has been silenced by slack user \"%s\"", p.ID, user)
+			replies = append(replies, fmt.Sprintf(
+				"Notifications for *%s* are silenced until %s by %s",
+				p.Name, until.Format("2006-01-02 15:04 MST"), user,
+			))
+
+		case model.ActionAcknowledge:
+			p, err := controller.projectRepository.Acknowledge(action.Value, "slack:"+user)
+			if err != nil {
+				return "", err
+			}
+
+			controller.logger.Printf("project \"%s\" has been acknowledged by slack user \"%s\"", p.ID, user)
+			replies = append(replies, fmt.Sprintf("Backup problem of *%s* is acknowledged by %s", p.Name, user))
+		}
+	}
+
+	return strings.Join(replies, "\n"), nil
+}
+
+// Posts a reply message into a conversation where an action has been triggered
+func (controller *slackController) reply(responseURL, text string) {
+	body, err := json.Marshal(map[string]interface{}{
+		"response_type":    "in_channel",
+		"replace_original": false,
+		"text":             text,
+	})
+	if err != nil {
+		controller.logger.Printf("unable to reply to slack action: %v", err)
+		return
+	}
+
+	resp, err := controller.client.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		controller.logger.Printf("unable to reply to slack action: %v", err)
+		return
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		controller.logger.Printf("unable to reply to slack action: got HTTP %d", resp.StatusCode)
+	}
+}
+
+// Verifies Slack request signature (see https://api.slack.com/authentication/verifying-requests-from-slack).
+// A zero maxAge disables timestamp check, which allows recorded requests to be replayed.
+func verifySlackSignature(header http.Header, body []byte, secret string, now time.Time, maxAge time.Duration) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return model.NewError(model.EAccessDenied, "missing slack request signature")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return model.NewError(model.EAccessDenied, "invalid slack request timestamp")
+	}
+
+	if maxAge > 0 {
+		age := now.Sub(time.Unix(seconds, 0))
+		if age > maxAge || age < -maxAge {
+			return model.NewError(model.EAccessDenied, "slack request is too old")
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return model.NewError(model.EAccessDenied, "invalid slack request signature")
+	}
+
+	return nil
+}
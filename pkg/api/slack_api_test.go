@@ -0,0 +1,282 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/slack-go/slack"
+)
+
+// Recorded interaction requests are signed with this secret at slackTestTimestamp
+const (
+	slackTestSecret    = "8f742231b10e8888abcd99yyyzzz85a5"
+	slackTestTimestamp = "1531420618"
+)
+
+var slackTestRequests = map[string]struct {
+	file      string
+	signature string
+}{
+	model.ActionSilence24h: {
+		file:      "testdata/slack_silence_24h.txt",
+		signature: "v0=8519fd189dfd2ca91dea66b7f7133e008b2dc7a2898b09f695e5398825ae2723",
+	},
+	model.ActionAcknowledge: {
+		file:      "testdata/slack_acknowledge.txt",
+		signature: "v0=40e171ebe155bee3215a63495b087fddce4231f2711fb042f327862e5a810b73",
+	},
+}
+
+func loadSlackRequest(t *testing.T, action string) ([]byte, http.Header) {
+	t.Helper()
+
+	r := slackTestRequests[action]
+	body, err := ioutil.ReadFile(r.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	header.Set("X-Slack-Request-Timestamp", slackTestTimestamp)
+	header.Set("X-Slack-Signature", r.signature)
+	return body, header
+}
+
+func slackTestTime(offset time.Duration) time.Time {
+	return time.Unix(1531420618, 0).Add(offset)
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	body, header := loadSlackRequest(t, model.ActionSilence24h)
+	tampered := bytes.Replace(body, []byte("my-project"), []byte("other-project"), 1)
+
+	noSignature := header.Clone()
+	noSignature.Del("X-Slack-Signature")
+
+	cases := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		secret string
+		now    time.Time
+		maxAge time.Duration
+		valid  bool
+	}{
+		{"valid", header, body, slackTestSecret, slackTestTime(time.Second), 5 * time.Minute, true},
+		{"tampered body", header, tampered, slackTestSecret, slackTestTime(time.Second), 5 * time.Minute, false},
+		{"wrong secret", header, body, "another-secret", slackTestTime(time.Second), 5 * time.Minute, false},
+		{"stale timestamp", header, body, slackTestSecret, slackTestTime(10 * time.Minute), 5 * time.Minute, false},
+		{"future timestamp", header, body, slackTestSecret, slackTestTime(-10 * time.Minute), 5 * time.Minute, false},
+		{"stale timestamp without max age", header, body, slackTestSecret, slackTestTime(10 * time.Minute), 0, true},
+		{"missing signature", noSignature, body, slackTestSecret, slackTestTime(time.Second), 5 * time.Minute, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := verifySlackSignature(c.header, c.body, c.secret, c.now, c.maxAge)
+			if c.valid && err != nil {
+				t.Fatalf("expected signature to be valid, got %v", err)
+			}
+
+			if !c.valid {
+				e, ok := err.(*model.Error)
+				if !ok || e.Code != model.EAccessDenied {
+					t.Fatalf("expected access denied error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestSlackInteract(t *testing.T) {
+	cases := []struct {
+		name    string
+		action  string
+		tamper  bool
+		offset  time.Duration
+		status  int
+		calls   []string
+		replies []string
+	}{
+		{
+			name:    "silence",
+			action:  model.ActionSilence24h,
+			offset:  time.Second,
+			status:  200,
+			calls:   []string{"silence my-project until 2018-07-13 18:36"},
+			replies: []string{"Notifications for *My Project* are silenced until 2018-07-13 18:36 UTC by roadrunner"},
+		},
+		{
+			name:    "acknowledge",
+			action:  model.ActionAcknowledge,
+			offset:  time.Second,
+			status:  200,
+			calls:   []string{"acknowledge my-project by slack:roadrunner"},
+			replies: []string{"Backup problem of *My Project* is acknowledged by roadrunner"},
+		},
+		{
+			name:   "tampered body",
+			action: model.ActionSilence24h,
+			tamper: true,
+			offset: time.Second,
+			status: 403,
+		},
+		{
+			name:   "stale timestamp",
+			action: model.ActionAcknowledge,
+			offset: 10 * time.Minute,
+			status: 403,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &fakeSlackProjectRepository{}
+			replies := make(chan string, 1)
+
+			controller := &slackController{
+				logger:            log.New(ioutil.Discard, "", 0),
+				projectRepository: repo,
+				signingSecret:     slackTestSecret,
+				maxAge:            5 * time.Minute,
+				now:               func() time.Time { return slackTestTime(c.offset) },
+				client:            &http.Client{Transport: slackReplyRecorder(replies)},
+			}
+
+			router := gin.New()
+			router.POST("/api/slack/interactions", controller.Interact)
+
+			body, header := loadSlackRequest(t, c.action)
+			if c.tamper {
+				body = bytes.Replace(body, []byte("my-project"), []byte("other-project"), 1)
+			}
+
+			req := httptest.NewRequest("POST", "/api/slack/interactions", bytes.NewReader(body))
+			req.Header = header
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != c.status {
+				t.Fatalf("expected HTTP %d, got %d: %s", c.status, w.Code, w.Body.String())
+			}
+
+			if strings.Join(repo.calls, "\n") != strings.Join(c.calls, "\n") {
+				t.Fatalf("expected calls %q, got %q", c.calls, repo.calls)
+			}
+
+			for _, expected := range c.replies {
+				select {
+				case reply := <-replies:
+					if reply != expected {
+						t.Fatalf("expected reply %q, got %q", expected, reply)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("expected reply %q, got nothing", expected)
+				}
+			}
+		})
+	}
+}
+
+func TestSlackHandleInteractionUnknownProject(t *testing.T) {
+	body, _ := loadSlackRequest(t, model.ActionAcknowledge)
+	body = bytes.Replace(body, []byte("my-project"), []byte("other-project"), 1)
+
+	controller := &slackController{
+		logger:            log.New(ioutil.Discard, "", 0),
+		projectRepository: &fakeSlackProjectRepository{},
+		now:               func() time.Time { return slackTestTime(time.Second) },
+	}
+
+	callback := parseSlackTestCallback(t, body)
+	_, err := controller.handleInteraction(callback)
+	if e, ok := err.(*model.Error); !ok || e.Code != model.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func parseSlackTestCallback(t *testing.T, body []byte) *slack.InteractionCallback {
+	t.Helper()
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var callback slack.InteractionCallback
+	err = json.Unmarshal([]byte(values.Get("payload")), &callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &callback
+}
+
+// fakeSlackProjectRepository records silence and acknowledge calls.
+// Other methods of ProjectRepository are not used by slack controller
+type fakeSlackProjectRepository struct {
+	service.ProjectRepository
+	calls []string
+}
+
+func (r *fakeSlackProjectRepository) get(id string) (*model.Project, error) {
+	if id != "my-project" {
+		return nil, model.NewError(model.ENotFound, "project \"%s\" doesn't exist", id)
+	}
+
+	return &model.Project{ID: id, Name: "My Project"}, nil
+}
+
+func (r *fakeSlackProjectRepository) Silence(id string, until *time.Time) (*model.Project, error) {
+	p, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.calls = append(r.calls, "silence "+id+" until "+until.Format("2006-01-02 15:04"))
+	return p, nil
+}
+
+func (r *fakeSlackProjectRepository) Acknowledge(id, by string) (*model.Project, error) {
+	p, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.calls = append(r.calls, "acknowledge "+id+" by "+by)
+	return p, nil
+}
+
+// slackReplyRecorder is an HTTP transport that captures texts of replies posted to response URLs
+type slackReplyRecorder chan string
+
+func (r slackReplyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reply struct {
+		Text string `json:"text"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&reply)
+	if err != nil {
+		return nil, err
+	}
+
+	r <- reply.Text
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader("ok")),
+		Request:    req,
+	}, nil
+}
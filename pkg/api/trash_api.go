@@ -0,0 +1,152 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/spf13/viper"
+)
+
+func (s *server) ConfigureTrashAPI() {
+	controller := &trashController{
+		projectRepository: service.GetProjectRepository(s.services),
+		backupRepository:  service.GetBackupRepository(s.services),
+		auditLog:          service.GetAuditLog(s.services),
+		purgeDelay:        viper.GetDuration("TRASH_PURGE_DELAY"),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+	viewer := requireProjectRole(s.services, model.RoleViewer)
+
+	s.authorized.GET("/api/trash", admin, controller.List)
+	s.authorized.GET("/api/projects/:id/trash", viewer, controller.ListBackups)
+	s.authorized.POST("/api/projects/:id/restore", admin, controller.RestoreProject)
+	s.authorized.POST("/api/backup/:id/restore", controller.RestoreBackup)
+}
+
+type trashController struct {
+	projectRepository service.ProjectRepository
+	backupRepository  service.BackupRepository
+	auditLog          service.AuditLog
+	purgeDelay        time.Duration
+}
+
+// @Summary List trashed projects and backups
+// @Router /api/trash [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Trash
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *trashController) List(c *gin.Context) {
+	projects, err := controller.projectRepository.ListTrash()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	backups, err := controller.backupRepository.ListTrash("")
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, model.NewTrash(projects, backups, controller.purgeDelay))
+}
+
+// @Summary List project's trashed backups
+// @Router /api/projects/:id/trash [get]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {array} model.TrashedBackup
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *trashController) ListBackups(c *gin.Context) {
+	projectID := c.Param("id")
+
+	backups, err := controller.backupRepository.ListTrash(projectID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, model.NewTrash(nil, backups, controller.purgeDelay).Backups)
+}
+
+// @Summary Restore a project from trash
+// @Router /api/projects/:id/restore [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.Project
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *trashController) RestoreProject(c *gin.Context) {
+	id := c.Param("id")
+
+	project, err := controller.projectRepository.Restore(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionProjectRestore,
+		TargetType: model.AuditTargetProject,
+		TargetID:   project.ID,
+		ProjectID:  project.ID,
+		Changes:    model.NewAuditChanges(nil, project),
+	})
+
+	c.JSON(200, project)
+}
+
+// @Summary Restore a backup from trash
+// @Router /api/backup/:id/restore [post]
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.Backup
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *trashController) RestoreBackup(c *gin.Context) {
+	id := c.Param("id")
+
+	trashed, err := controller.backupRepository.GetTrashed(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	project, err := controller.projectRepository.Get(trashed.ProjectID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	if !checkProjectRole(c, project, model.RoleOperator) {
+		return
+	}
+
+	backup, err := controller.backupRepository.Restore(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionBackupRestore,
+		TargetType: model.AuditTargetBackup,
+		TargetID:   backup.ID,
+		ProjectID:  backup.ProjectID,
+		Changes:    model.NewAuditChanges(trashed, backup),
+	})
+
+	c.JSON(200, backup)
+}
@@ -0,0 +1,554 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+)
+
+func (s *server) ConfigureUsersAPI() {
+	controller := &userController{
+		repository:      service.GetUserRepository(s.services),
+		grantRepository: service.GetProjectGrantRepository(s.services),
+		sessions:        service.GetSessionRepository(s.services),
+		mfa:             service.GetMFAService(s.services),
+		lockouts:        service.GetLockoutService(s.services),
+		auditLog:        service.GetAuditLog(s.services),
+	}
+
+	admin := requireRole(model.RoleAdmin)
+
+	s.authorized.GET("/api/users", admin, controller.List)
+	s.authorized.GET("/api/users/:id", admin, controller.Get)
+	s.authorized.POST("/api/users", admin, controller.Post)
+	s.authorized.PUT("/api/users/:id", admin, controller.Put)
+	s.authorized.DELETE("/api/users/:id", admin, controller.Delete)
+	s.authorized.POST("/api/users/:id/password/reset", admin, controller.ResetPassword)
+
+	s.authorized.GET("/api/users/:id/grants", admin, controller.ListGrants)
+	s.authorized.POST("/api/users/:id/grants", admin, controller.CreateGrant)
+	s.authorized.DELETE("/api/users/:id/grants/:grant", admin, controller.DeleteGrant)
+
+	s.authorized.DELETE("/api/users/:id/sessions", admin, controller.RevokeSessions)
+	s.authorized.DELETE("/api/users/:id/mfa", admin, controller.ResetMFA)
+	s.authorized.POST("/api/users/:id/mfa/enrollment-token", admin, controller.IssueMFAEnrollmentToken)
+	s.authorized.DELETE("/api/users/:id/lockout", admin, controller.Unlock)
+}
+
+type userController struct {
+	repository      service.UserRepository
+	grantRepository service.ProjectGrantRepository
+	sessions        service.SessionRepository
+	mfa             service.MFAService
+	lockouts        service.LockoutService
+	auditLog        service.AuditLog
+}
+
+// @Summary List users
+// @Router /api/users [get]
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Users
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *userController) List(c *gin.Context) {
+	list, err := controller.repository.List()
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	for _, user := range list {
+		controller.setLockout(user)
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Get a user
+// @Router /api/users/:id [get]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) Get(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.setLockout(user)
+	c.JSON(200, user)
+}
+
+// setLockout fills user's lockout state
+func (controller *userController) setLockout(user *model.User) {
+	lockout := controller.lockouts.Get(model.NewLockoutKey(model.LockoutScopeUser, user.UserName))
+	if lockout != nil && lockout.IsLocked(time.Now()) {
+		user.LockedUntil = lockout.LockedUntil
+	}
+}
+
+// @Summary Create new user
+// @Description If password is not specified, a random one is generated and returned (only once)
+// @Router /api/users [post]
+// @Accept json
+// @Produce json
+// @Param body body model.UserCreateParams true "Body"
+// @Success 200 {object} model.UserPasswordResponse
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 409 {object} model.Error
+func (controller *userController) Post(c *gin.Context) {
+	var req model.UserCreateParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	user, password, err := controller.repository.Create(&req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionUserCreate,
+		TargetType: model.AuditTargetUser,
+		TargetID:   user.UserName,
+		Changes:    model.NewAuditChanges(nil, user),
+	})
+
+	c.JSON(200, &model.UserPasswordResponse{User: user, Password: password})
+}
+
+// @Summary Update an existing user (e.g. disable it)
+// @Router /api/users/:id [put]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param body body model.UserUpdateParams true "Body"
+// @Success 200 {object} model.User
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) Put(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UserUpdateParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	if req.IsDisabled != nil && *req.IsDisabled && id == currentUser(c).ID {
+		c.JSON(400, model.NewError(model.EBadRequest, "you can't disable yourself"))
+		return
+	}
+
+	if req.Role != nil && *req.Role != model.RoleAdmin && id == currentUser(c).ID {
+		c.JSON(400, model.NewError(model.EBadRequest, "you can't demote yourself"))
+		return
+	}
+
+	before, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	user, err := controller.repository.Update(id, &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	changes := model.NewAuditChanges(before, user)
+	if len(changes) > 0 {
+		audit(c, controller.auditLog, &model.AuditEntry{
+			Action:     model.AuditActionUserUpdate,
+			TargetType: model.AuditTargetUser,
+			TargetID:   user.UserName,
+			Changes:    changes,
+		})
+	}
+
+	c.JSON(200, user)
+}
+
+// @Summary Delete an existing user
+// @Router /api/users/:id [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.Empty
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) Delete(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if id == currentUser(c).ID {
+		c.JSON(400, model.NewError(model.EBadRequest, "you can't delete yourself"))
+		return
+	}
+
+	user, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	err = controller.repository.Delete(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionUserDelete,
+		TargetType: model.AuditTargetUser,
+		TargetID:   user.UserName,
+		Changes:    model.NewAuditChanges(user, nil),
+	})
+
+	c.JSON(200, model.Empty{})
+}
+
+// @Summary Reset user's password to a random one
+// @Description Generated password is returned only once
+// @Router /api/users/:id/password/reset [post]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.UserPasswordResponse
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) ResetPassword(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	password, err := controller.repository.ResetPassword(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	user, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	// Password itself is never recorded
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionUserPasswordReset,
+		TargetType: model.AuditTargetUser,
+		TargetID:   user.UserName,
+	})
+
+	c.JSON(200, &model.UserPasswordResponse{User: user, Password: password})
+}
+
+// @Summary List user's project grants
+// @Description Users without grants have their role on all projects
+// @Router /api/users/:id/grants [get]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.ProjectGrants
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+func (controller *userController) ListGrants(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	list, err := controller.grantRepository.List(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// @Summary Grant a user access to projects by tag or ID pattern
+// @Router /api/users/:id/grants [post]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param body body model.ProjectGrantCreateParams true "Body"
+// @Success 200 {object} model.ProjectGrant
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) CreateGrant(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ProjectGrantCreateParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid request parameters"))
+		return
+	}
+
+	grant, err := controller.grantRepository.Create(id, &req)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	controller.auditGrant(c, model.AuditActionGrantCreate, nil, grant)
+
+	c.JSON(200, grant)
+}
+
+// @Summary Revoke user's project grant
+// @Router /api/users/:id/grants/:grant [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param grant path int true "Grant ID"
+// @Success 200 {object} model.Empty
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) DeleteGrant(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	grantID, err := strconv.Atoi(c.Param("grant"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid grant id"))
+		return
+	}
+
+	grants, err := controller.grantRepository.List(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	err = controller.grantRepository.Delete(id, grantID)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	for _, grant := range grants {
+		if grant.ID == grantID {
+			controller.auditGrant(c, model.AuditActionGrantDelete, grant, nil)
+		}
+	}
+
+	c.JSON(200, model.Empty{})
+}
+
+// auditGrant records a change of user's project grant into audit log
+func (controller *userController) auditGrant(c *gin.Context, action model.AuditAction, before, after *model.ProjectGrant) {
+	grant := after
+	if grant == nil {
+		grant = before
+	}
+
+	details := ""
+	user, err := controller.repository.GetByID(grant.UserID)
+	if err == nil {
+		details = fmt.Sprintf("user %s", user.UserName)
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetGrant,
+		TargetID:   strconv.Itoa(grant.ID),
+		ProjectID:  grant.Project,
+		Changes:    model.NewAuditChanges(before, after),
+		Details:    details,
+	})
+}
+
+// @Summary Revoke all sessions of a user (sign it out everywhere)
+// @Router /api/users/:id/sessions [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.Empty
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) RevokeSessions(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	_, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	err = controller.sessions.RevokeAll(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, model.Empty{})
+}
+
+func parseUserID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, model.NewError(model.EBadRequest, "invalid user id"))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func currentUser(c *gin.Context) *model.User {
+	u, _ := c.Get(gin.AuthUserKey)
+	return u.(*model.User)
+}
+
+// @Summary Reset two-factor authentication of a user
+// @Description User will have to enroll again if MFA is required
+// @Router /api/users/:id/mfa [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) ResetMFA(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	before, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	err = controller.mfa.Reset(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	user, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	audit(c, controller.auditLog, &model.AuditEntry{
+		Action:     model.AuditActionUserMFAReset,
+		TargetType: model.AuditTargetUser,
+		TargetID:   user.UserName,
+		Changes:    model.NewAuditChanges(before, user),
+	})
+
+	c.JSON(200, user)
+}
+
+// @Summary Issue a two-factor enrollment token for a user
+// @Description The token should be handed to user out-of-band, user enters it to enroll while signing in.
+// @Description It's required only if admin requires enrollment tokens. Token is returned only once
+// @Router /api/users/:id/mfa/enrollment-token [post]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.MFAEnrollmentToken
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+func (controller *userController) IssueMFAEnrollmentToken(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	token, err := controller.mfa.IssueEnrollmentToken(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	c.JSON(200, token)
+}
+
+// @Summary Unlock sign in of a user locked after too many failed attempts
+// @Router /api/users/:id/lockout [delete]
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+func (controller *userController) Unlock(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := controller.repository.GetByID(id)
+	if err != nil {
+		processError(c, err)
+		return
+	}
+
+	key := model.NewLockoutKey(model.LockoutScopeUser, user.UserName)
+	if controller.lockouts.Unlock(key) {
+		audit(c, controller.auditLog, &model.AuditEntry{
+			Action:     model.AuditActionUnlock,
+			TargetType: model.AuditTargetUser,
+			TargetID:   key.Key,
+		})
+	}
+
+	c.JSON(200, user)
+}
@@ -0,0 +1,10 @@
+package bot
+
+import (
+	"github.com/itglobal/backupmonitor/pkg/component"
+)
+
+// Setup configures package services
+func Setup(builder component.Builder) {
+	builder.AddComponent(createTelegramBot)
+}
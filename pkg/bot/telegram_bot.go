@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	telegram "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/itglobal/backupmonitor/pkg/component"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/sarulabs/di"
+	"github.com/spf13/viper"
+)
+
+// Long polling timeout, in seconds.
+// Kept short enough not to delay graceful shutdown too much
+const telegramPollTimeout = 10
+
+type telegramBot struct {
+	logger            *log.Logger
+	projectRepository service.ProjectRepository
+	token             string
+	chats             map[int64]bool
+	stopped           chan interface{}
+}
+
+func createTelegramBot(c di.Container) (component.T, error) {
+	logger := log.New(log.Writer(), "[bot] ", log.Flags())
+
+	chats := make(map[int64]bool)
+	for _, str := range strings.Split(viper.GetString("TELEGRAM_BOT_CHATS"), ";") {
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" is not a valid telegram chat id", str)
+		}
+		chats[id] = true
+	}
+
+	s := &telegramBot{
+		logger:            logger,
+		projectRepository: service.GetProjectRepository(c),
+		token:             viper.GetString("TELEGRAM_TOKEN"),
+		chats:             chats,
+		stopped:           make(chan interface{}),
+	}
+	return s, nil
+}
+
+func (s *telegramBot) Start(group *sync.WaitGroup, stop chan interface{}) {
+	if s.token == "" || len(s.chats) == 0 {
+		s.logger.Printf("telegram bot is disabled")
+		return
+	}
+
+	group.Add(1)
+	go func() {
+		api, err := s.connect()
+		if err != nil {
+			s.logger.Printf("telegram bot is disabled: unable to connect to telegram: %v", err)
+			return
+		}
+
+		s.logger.Printf("telegram bot \"%s\" is listening for commands in %d chat(s)", api.Self.UserName, len(s.chats))
+		s.poll(api)
+	}()
+
+	go func() {
+		for range stop {
+		}
+
+		close(s.stopped)
+		group.Done()
+	}()
+}
+
+func (s *telegramBot) connect() (*telegram.BotAPI, error) {
+	for {
+		api, err := telegram.NewBotAPI(s.token)
+		if err == nil {
+			return api, nil
+		}
+
+		if strings.Contains(err.Error(), "Unauthorized") {
+			return nil, err
+		}
+
+		s.logger.Printf("unable to connect to telegram, retrying in 30 seconds: %v", err)
+		select {
+		case <-s.stopped:
+			return nil, fmt.Errorf("shutting down")
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+// Receives updates via long polling until the bot is stopped
+func (s *telegramBot) poll(api *telegram.BotAPI) {
+	config := telegram.NewUpdate(0)
+	config.Timeout = telegramPollTimeout
+
+	for {
+		select {
+		case <-s.stopped:
+			return
+		default:
+		}
+
+		updates, err := api.GetUpdates(config)
+		if err != nil {
+			s.logger.Printf("unable to receive telegram updates, retrying in 3 seconds: %v", err)
+			select {
+			case <-s.stopped:
+				return
+			case <-time.After(3 * time.Second):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			if update.UpdateID >= config.Offset {
+				config.Offset = update.UpdateID + 1
+			}
+
+			if update.Message == nil || !update.Message.IsCommand() {
+				continue
+			}
+
+			reply := s.Execute(update.Message)
+			if reply == "" {
+				continue
+			}
+
+			m := telegram.NewMessage(update.Message.Chat.ID, reply)
+			m.ReplyToMessageID = update.Message.MessageID
+			_, err = api.Send(m)
+			if err != nil {
+				s.logger.Printf("unable to reply to telegram chat %d: %v", update.Message.Chat.ID, err)
+			}
+		}
+	}
+}
+
+// Execute runs a bot command and returns a reply text
+func (s *telegramBot) Execute(msg *telegram.Message) string {
+	if !s.chats[msg.Chat.ID] {
+		s.logger.Printf("ignored command \"/%s\" from unauthorized chat %d", msg.Command(), msg.Chat.ID)
+		return fmt.Sprintf("This chat (%d) is not authorized to use this bot", msg.Chat.ID)
+	}
+
+	args := strings.Fields(msg.CommandArguments())
+
+	var reply string
+	var err error
+	switch msg.Command() {
+	case "status":
+		reply, err = s.status()
+	case "project":
+		reply, err = s.project(args)
+	case "silence":
+		reply, err = s.silence(args)
+	case "ack":
+		reply, err = s.acknowledge(args, userName(msg.From))
+	case "start", "help":
+		reply = telegramBotHelp
+	default:
+		return ""
+	}
+
+	if err != nil {
+		if e, ok := err.(*model.Error); ok {
+			return e.Message
+		}
+
+		s.logger.Printf("unable to execute command \"/%s\": %v", msg.Command(), err)
+		return "Internal error, see server logs for details"
+	}
+
+	return reply
+}
+
+const telegramBotHelp = `Available commands:
+/status - show backup status of all projects
+/project <id> - show project details
+/silence <id> <duration> - silence project notifications, e.g. /silence my-project 12h
+/ack [id] - acknowledge backup problem of a project (or all projects if no id is given)`
+
+func (s *telegramBot) status() (string, error) {
+	projects, err := s.projectRepository.List(nil)
+	if err != nil {
+		return "", err
+	}
+
+	if len(projects) == 0 {
+		return "There are no projects yet", nil
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+
+	now := time.Now().UTC()
+	lines := make([]string, len(projects))
+	for i, project := range projects {
+		lines[i] = fmt.Sprintf("%s %s: %s%s", statusIcon(project), project.ID, project.BackupStatus, projectMarks(project, now))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *telegramBot) project(args []string) (string, error) {
+	if len(args) != 1 {
+		return "Usage: /project <id>", nil
+	}
+
+	project, err := s.projectRepository.Get(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	lastBackup := "never"
+	if project.LastBackup != nil {
+		lastBackup = fmt.Sprintf("%s (%s)", humanize.Time(project.LastBackup.Time), humanize.Bytes(uint64(nonNegative(project.LastBackup.Length))))
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s %s (%s)", statusIcon(project), project.ID, project.Name),
+		fmt.Sprintf("Status: %s%s", project.BackupStatus, projectMarks(project, time.Now().UTC())),
+		fmt.Sprintf("Active: %v", project.IsActive),
+		fmt.Sprintf("Last backup: %s", lastBackup),
+		fmt.Sprintf("Expected every: %s", time.Duration(project.BackupFrequency)*time.Second),
+		fmt.Sprintf("Retention: %s", project.RetentionDescription()),
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *telegramBot) silence(args []string) (string, error) {
+	if len(args) != 2 {
+		return "Usage: /silence <id> <duration>", nil
+	}
+
+	params := &model.ProjectSilenceParams{Duration: args[1]}
+	params.Normalize()
+	until, err := params.Until(time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	project, err := s.projectRepository.Silence(args[0], &until)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Notifications for %s are silenced until %s", project.ID, until.Format("2006-01-02 15:04 MST")), nil
+}
+
+func (s *telegramBot) acknowledge(args []string, by string) (string, error) {
+	if len(args) > 1 {
+		return "Usage: /ack [id]", nil
+	}
+
+	if len(args) == 1 {
+		project, err := s.projectRepository.Acknowledge(args[0], by)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("Backup problem of %s is acknowledged", project.ID), nil
+	}
+
+	projects, err := s.projectRepository.List(nil)
+	if err != nil {
+		return "", err
+	}
+
+	ids := make([]string, 0)
+	for _, project := range projects {
+		if !project.IsActive || project.BackupStatus == model.BackupStatusOk || project.IsAcknowledged() {
+			continue
+		}
+
+		_, err = s.projectRepository.Acknowledge(project.ID, by)
+		if err != nil {
+			return "", err
+		}
+		ids = append(ids, project.ID)
+	}
+
+	if len(ids) == 0 {
+		return "There are no unacknowledged backup problems", nil
+	}
+
+	sort.Strings(ids)
+	return fmt.Sprintf("Backup problems are acknowledged: %s", strings.Join(ids, ", ")), nil
+}
+
+func statusIcon(project *model.Project) string {
+	if !project.IsActive {
+		return "⏸"
+	}
+
+	switch project.BackupStatus {
+	case model.BackupStatusOk:
+		return "✅"
+	case model.BackupStatusOutdated:
+		return "⚠️"
+	default:
+		return "❌"
+	}
+}
+
+func projectMarks(project *model.Project, now time.Time) string {
+	marks := ""
+	if project.IsSilenced(now) {
+		marks += fmt.Sprintf(", silenced until %s", project.SilencedUntil.Format("2006-01-02 15:04 MST"))
+	}
+	if project.IsAcknowledged() {
+		marks += fmt.Sprintf(", acknowledged by %s", project.AcknowledgedBy)
+	}
+	return marks
+}
+
+func userName(user *telegram.User) string {
+	if user == nil {
+		return "telegram"
+	}
+
+	if user.UserName != "" {
+		return "telegram:" + user.UserName
+	}
+
+	return fmt.Sprintf("telegram:%d", user.ID)
+}
+
+func nonNegative(length int64) int64 {
+	if length < 0 {
+		return 0
+	}
+
+	return length
+}
@@ -25,22 +25,27 @@ func Setup(builder component.Builder) {
 	builder.AddService(di.Def{
 		Name: providerKey,
 		Build: func(c di.Container) (interface{}, error) {
-			logger := log.New(log.Writer(), "[db] ", log.Flags())
 			filepath := path.Join(viper.GetString("VAR"), "db/sqlite.db")
-			filepath = path.Clean(filepath)
-
-			dbLogger := &dbLogger{logger}
-			p := &provider{logger, dbLogger, filepath}
-			err := p.Initialize()
-			if err != nil {
-				return nil, err
-			}
-
-			return p, nil
+			return NewProvider(filepath)
 		},
 	})
 }
 
+// NewProvider creates a provider for SQLite database file and migrates its schema
+func NewProvider(filepath string) (Provider, error) {
+	logger := log.New(log.Writer(), "[db] ", log.Flags())
+	filepath = path.Clean(filepath)
+
+	dbLogger := &dbLogger{logger}
+	p := &provider{logger, dbLogger, filepath}
+	err := p.Initialize()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
 // GetProvider returns an implementation of Provider from DI container
 func GetProvider(c di.Container) Provider {
 	return c.Get(providerKey).(Provider)
@@ -71,7 +76,7 @@ func (p *provider) Initialize() error {
 
 	defer db.Close()
 
-	err = db.AutoMigrate(&User{}, &Project{}, &Backup{}, &AccessKey{}).Error
+	err = db.AutoMigrate(&User{}, &Project{}, &Backup{}, &AccessKey{}, &Delivery{}, &BackupDeletion{}, &NotificationTarget{}, &RoutingRule{}, &ProjectGrant{}, &Session{}, &Setting{}, &APIToken{}, &AuditEntry{}).Error
 	if err != nil {
 		p.logger.Printf("unable to migrate database \"%s\": %v", p.filepath, err)
 		return err
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -9,9 +10,25 @@ import (
 
 // User contains information about application user
 type User struct {
-	ID           int    `gorm:"column:id;auto_increment;primary_key"`
-	UserName     string `gorm:"column:username;unique_index"`
-	PasswordHash string `gorm:"column:password"`
+	ID           int       `gorm:"column:id;auto_increment;primary_key"`
+	UserName     string    `gorm:"column:username;unique_index"`
+	PasswordHash string    `gorm:"column:password"`
+	DisplayName  string    `gorm:"column:display_name;type:varchar(256)"`
+	Email        string    `gorm:"column:email;type:varchar(256)"`
+	Role         string    `gorm:"column:role;type:varchar(16)"`
+	Source       string    `gorm:"column:source;type:varchar(16)"`
+	IsDisabled   bool      `gorm:"column:is_disabled"`
+	AllProjects  bool      `gorm:"column:all_projects"`
+	ExternalID   string    `gorm:"column:external_id;type:varchar(512);index"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+
+	// MFA state is managed by MFA service only, so it's not copied into model (except for a flag)
+	MFASecret                   string     `gorm:"column:mfa_secret;type:varchar(64)"`
+	MFAEnabled                  bool       `gorm:"column:mfa_enabled"`
+	MFARecoveryCodes            string     `gorm:"column:mfa_recovery_codes;type:varchar(1024)"`
+	MFALastStep                 int64      `gorm:"column:mfa_last_step"`
+	MFAEnrollmentToken          string     `gorm:"column:mfa_enrollment_token;type:varchar(64)"`
+	MFAEnrollmentTokenExpiresAt *time.Time `gorm:"column:mfa_enrollment_token_expires_at"`
 }
 
 // TableName returns database table name
@@ -19,11 +36,30 @@ func (User) TableName() string {
 	return "users"
 }
 
+// ToModel creates new model and copies entity data to it
+func (p *User) ToModel() *model.User {
+	m := &model.User{}
+	p.CopyToModel(m)
+	return m
+}
+
 // CopyToModel copies entity data to model
 func (p *User) CopyToModel(m *model.User) {
 	m.ID = p.ID
 	m.UserName = p.UserName
 	m.PasswordHash = p.PasswordHash
+	m.DisplayName = p.DisplayName
+	m.Email = p.Email
+	m.Role = model.Role(p.Role)
+	m.Source = model.UserSource(p.Source)
+	if m.Source == "" {
+		m.Source = model.UserSourceLocal
+	}
+	m.MFAEnabled = p.MFAEnabled
+	m.IsDisabled = p.IsDisabled
+	m.AllProjects = p.AllProjects
+	m.ExternalID = p.ExternalID
+	m.CreatedAt = p.CreatedAt
 }
 
 // CopyFromModel copies model data to entity
@@ -31,6 +67,14 @@ func (p *User) CopyFromModel(m *model.User) {
 	p.ID = m.ID
 	p.UserName = m.UserName
 	p.PasswordHash = m.PasswordHash
+	p.DisplayName = m.DisplayName
+	p.Email = m.Email
+	p.Role = string(m.Role)
+	p.Source = string(m.Source)
+	p.IsDisabled = m.IsDisabled
+	p.AllProjects = m.AllProjects
+	p.ExternalID = m.ExternalID
+	p.CreatedAt = m.CreatedAt
 }
 
 // Project contains information about project
@@ -38,6 +82,10 @@ type Project struct {
 	ID                  string             `gorm:"column:id;type:varchar(64);primary_key"`
 	Name                string             `gorm:"column:name;type:varchar(256)"`
 	BackupRetention     int                `gorm:"column:backup_retention"`
+	Retention           string             `gorm:"column:retention;type:text"`
+	QuotaLimit          int64              `gorm:"column:quota_limit"`
+	QuotaPolicy         string             `gorm:"column:quota_policy;type:varchar(32)"`
+	Storage             string             `gorm:"column:storage;type:varchar(64)"`
 	BackupFrequency     int                `gorm:"column:backup_frequency"`
 	IsActive            bool               `gorm:"column:is_active"`
 	EnableNotifications bool               `gorm:"column:enable_notifications"`
@@ -46,6 +94,16 @@ type Project struct {
 	TelegramUsers       string             `gorm:"column:notify_telegram;type:varchar(256)"`
 	Webhooks            string             `gorm:"column:notify_webhook;type:varchar(1024)"`
 	BackupStatus        model.BackupStatus `gorm:"column:backup_status"`
+	SilencedUntil       *time.Time         `gorm:"column:silenced_until"`
+	AcknowledgedAt      *time.Time         `gorm:"column:acknowledged_at"`
+	AcknowledgedBy      string             `gorm:"column:acknowledged_by;type:varchar(256)"`
+	LastCheckIn         *time.Time         `gorm:"column:last_checkin"`
+	LegalHoldReason     string             `gorm:"column:legal_hold_reason;type:varchar(1024)"`
+	LegalHoldBy         string             `gorm:"column:legal_hold_by;type:varchar(256)"`
+	LegalHoldAt         *time.Time         `gorm:"column:legal_hold_at"`
+	DeletedAt           *time.Time         `gorm:"column:deleted_at;index"`
+	Tags                string             `gorm:"column:tags;type:varchar(1024)"`
+	Labels              string             `gorm:"column:labels;type:text"`
 	Backups             []*Backup          `gorm:"foreignkey:project_id"`
 	AccessKeys          []*AccessKey       `gorm:"foreignkey:project_id"`
 }
@@ -67,10 +125,41 @@ func (p *Project) CopyToModel(m *model.Project) {
 	m.ID = p.ID
 	m.Name = p.Name
 	m.BackupRetention = p.BackupRetention
+	m.Retention = nil
+	if p.Retention != "" {
+		rules := &model.RetentionRules{}
+		if json.Unmarshal([]byte(p.Retention), rules) == nil && !rules.IsEmpty() {
+			m.Retention = rules
+		}
+	}
+	m.Quota = nil
+	if p.QuotaLimit > 0 {
+		m.Quota = &model.StorageQuota{Limit: p.QuotaLimit, Policy: model.QuotaPolicy(p.QuotaPolicy)}
+	}
+	m.Storage = p.Storage
 	m.BackupFrequency = p.BackupFrequency
 	m.IsActive = p.IsActive
 	m.BackupStatus = p.BackupStatus
 	m.LastNotification = p.LastNotification
+	m.SilencedUntil = p.SilencedUntil
+	m.AcknowledgedAt = p.AcknowledgedAt
+	m.AcknowledgedBy = p.AcknowledgedBy
+	m.LastCheckIn = p.LastCheckIn
+	m.DeletedAt = p.DeletedAt
+	m.LegalHold = nil
+	if p.LegalHoldAt != nil {
+		m.LegalHold = &model.LegalHold{
+			Reason:   p.LegalHoldReason,
+			PlacedBy: p.LegalHoldBy,
+			PlacedAt: *p.LegalHoldAt,
+		}
+	}
+	m.Tags = commaSeparatedToStringArray(p.Tags)
+
+	m.Labels = make(map[string]string)
+	if p.Labels != "" {
+		_ = json.Unmarshal([]byte(p.Labels), &m.Labels)
+	}
 
 	if m.Notifications == nil {
 		m.Notifications = &model.NotificationParams{}
@@ -87,10 +176,46 @@ func (p *Project) CopyFromModel(m *model.Project) {
 	p.ID = m.ID
 	p.Name = m.Name
 	p.BackupRetention = m.BackupRetention
+	p.Retention = ""
+	if !m.Retention.IsEmpty() {
+		buff, err := json.Marshal(m.Retention)
+		if err == nil {
+			p.Retention = string(buff)
+		}
+	}
+	p.QuotaLimit = 0
+	p.QuotaPolicy = ""
+	if !m.Quota.IsEmpty() {
+		p.QuotaLimit = m.Quota.Limit
+		p.QuotaPolicy = string(m.Quota.Policy)
+	}
+	p.Storage = m.Storage
 	p.BackupFrequency = m.BackupFrequency
 	p.IsActive = m.IsActive
 	p.BackupStatus = m.BackupStatus
 	p.LastNotification = m.LastNotification
+	p.SilencedUntil = m.SilencedUntil
+	p.AcknowledgedAt = m.AcknowledgedAt
+	p.AcknowledgedBy = m.AcknowledgedBy
+	p.LastCheckIn = m.LastCheckIn
+	if m.LegalHold != nil {
+		p.LegalHoldReason = m.LegalHold.Reason
+		p.LegalHoldBy = m.LegalHold.PlacedBy
+		p.LegalHoldAt = &m.LegalHold.PlacedAt
+	} else {
+		p.LegalHoldReason = ""
+		p.LegalHoldBy = ""
+		p.LegalHoldAt = nil
+	}
+	p.Tags = stringArrayToCommaSeparated(m.Tags)
+
+	p.Labels = ""
+	if len(m.Labels) > 0 {
+		buff, err := json.Marshal(m.Labels)
+		if err == nil {
+			p.Labels = string(buff)
+		}
+	}
 
 	if m.Notifications != nil {
 		p.EnableNotifications = m.Notifications.Enabled
@@ -126,9 +251,18 @@ type Backup struct {
 	ProjectID       string           `gorm:"column:project_id;type:varchar(128);foreignkey"`
 	FileName        string           `gorm:"column:filename;type:varchar(256)"`
 	StorageFilePath string           `gorm:"column:storage_path;type:varchar(256);unique_index"`
+	Storage         string           `gorm:"column:storage;type:varchar(64)"`
 	Time            time.Time        `gorm:"column:time"`
 	Type            model.BackupType `gorm:"column:type"`
-	Length          int64            `gorm:"column:length";default:-1`
+	Length          int64            `gorm:"column:length"`
+	KeepReason      string           `gorm:"column:keep_reason;type:varchar(256)"`
+	PinReason       string           `gorm:"column:pin_reason;type:varchar(1024)"`
+	PinnedBy        string           `gorm:"column:pinned_by;type:varchar(256)"`
+	PinnedAt        *time.Time       `gorm:"column:pinned_at"`
+	PinnedUntil     *time.Time       `gorm:"column:pinned_until"`
+	LockMode        string           `gorm:"column:lock_mode;type:varchar(32)"`
+	LockedUntil     *time.Time       `gorm:"column:locked_until"`
+	DeletedAt       *time.Time       `gorm:"column:deleted_at;index"`
 }
 
 // TableName returns database table name
@@ -149,9 +283,28 @@ func (p *Backup) CopyToModel(m *model.Backup) {
 	m.ProjectID = p.ProjectID
 	m.FileName = p.FileName
 	m.StorageFilePath = p.StorageFilePath
+	m.Storage = p.Storage
 	m.Time = p.Time
 	m.Type = p.Type
 	m.Length = p.Length
+	m.KeepReason = p.KeepReason
+	m.DeletedAt = p.DeletedAt
+	m.Pin = nil
+	if p.PinnedAt != nil {
+		m.Pin = &model.BackupPin{
+			Reason:   p.PinReason,
+			PinnedBy: p.PinnedBy,
+			PinnedAt: *p.PinnedAt,
+			Until:    p.PinnedUntil,
+		}
+	}
+	m.Lock = nil
+	if p.LockedUntil != nil {
+		m.Lock = &model.BackupLock{
+			Mode:  p.LockMode,
+			Until: *p.LockedUntil,
+		}
+	}
 }
 
 // CopyFromModel copies model data to entity
@@ -160,17 +313,94 @@ func (p *Backup) CopyFromModel(m *model.Backup) {
 	p.ProjectID = m.ProjectID
 	p.FileName = m.FileName
 	p.StorageFilePath = m.StorageFilePath
+	p.Storage = m.Storage
 	p.Time = m.Time
 	p.Type = m.Type
 	p.Length = m.Length
+	p.KeepReason = m.KeepReason
+	if m.Pin != nil {
+		p.PinReason = m.Pin.Reason
+		p.PinnedBy = m.Pin.PinnedBy
+		p.PinnedAt = &m.Pin.PinnedAt
+		p.PinnedUntil = m.Pin.Until
+	} else {
+		p.PinReason = ""
+		p.PinnedBy = ""
+		p.PinnedAt = nil
+		p.PinnedUntil = nil
+	}
+	if m.Lock != nil {
+		p.LockMode = m.Lock.Mode
+		p.LockedUntil = &m.Lock.Until
+	} else {
+		p.LockMode = ""
+		p.LockedUntil = nil
+	}
 }
 
-// AccessKey contains information about project's access key
+// BackupDeletion contains information about a deleted backup
+type BackupDeletion struct {
+	ID           int       `gorm:"column:id;auto_increment;primary_key"`
+	BackupID     string    `gorm:"column:backup_id;type:varchar(128)"`
+	ProjectID    string    `gorm:"column:project_id;type:varchar(128);index"`
+	FileName     string    `gorm:"column:filename;type:varchar(256)"`
+	BackupTime   time.Time `gorm:"column:backup_time"`
+	Length       int64     `gorm:"column:length"`
+	DeletionTime time.Time `gorm:"column:deletion_time;index"`
+	Reason       string    `gorm:"column:reason;type:varchar(256)"`
+}
+
+// TableName returns database table name
+func (BackupDeletion) TableName() string {
+	return "backup_deletions"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *BackupDeletion) ToModel() *model.BackupDeletion {
+	m := &model.BackupDeletion{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model
+func (p *BackupDeletion) CopyToModel(m *model.BackupDeletion) {
+	m.ID = p.ID
+	m.BackupID = p.BackupID
+	m.ProjectID = p.ProjectID
+	m.FileName = p.FileName
+	m.Time = p.BackupTime
+	m.Length = p.Length
+	m.DeletedAt = p.DeletionTime
+	m.Reason = p.Reason
+}
+
+// CopyFromModel copies model data to entity
+func (p *BackupDeletion) CopyFromModel(m *model.BackupDeletion) {
+	p.ID = m.ID
+	p.BackupID = m.BackupID
+	p.ProjectID = m.ProjectID
+	p.FileName = m.FileName
+	p.BackupTime = m.Time
+	p.Length = m.Length
+	p.DeletionTime = m.DeletedAt
+	p.Reason = m.Reason
+}
+
+// AccessKey contains information about project's access key.
+// Only a hash of the key is stored, its prefix is kept to tell keys apart
 type AccessKey struct {
-	ID        int    `gorm:"column:id;auto_increment;primary_key"`
-	Label     string `gorm:"column:label;type:varchar(256)"`
-	ProjectID string `gorm:"column:project_id;type:varchar(128);foreignkey"`
-	Key       string `gorm:"column:key;type:varchar(1024);unique_index"`
+	ID              int        `gorm:"column:id;auto_increment;primary_key"`
+	Label           string     `gorm:"column:label;type:varchar(256)"`
+	ProjectID       string     `gorm:"column:project_id;type:varchar(128);foreignkey"`
+	KeyHash         string     `gorm:"column:key_hash;type:varchar(128);unique_index"`
+	KeyPrefix       string     `gorm:"column:key_prefix;type:varchar(16)"`
+	Scopes          string     `gorm:"column:scopes;type:varchar(256)"`
+	CreatedAt       *time.Time `gorm:"column:created_at"`
+	ExpiresAt       *time.Time `gorm:"column:expires_at"`
+	LastUsedAt      *time.Time `gorm:"column:last_used_at"`
+	LastUsedIP      string     `gorm:"column:last_used_ip;type:varchar(64)"`
+	ReplacedByID    *int       `gorm:"column:replaced_by_id"`
+	AllowedNetworks string     `gorm:"column:allowed_networks;type:varchar(1024)"`
 }
 
 // TableName returns database table name
@@ -178,18 +408,460 @@ func (AccessKey) TableName() string {
 	return "access_keys"
 }
 
+// ToModel creates new model and copies entity data to it
+func (p *AccessKey) ToModel() *model.AccessKey {
+	m := &model.AccessKey{}
+	p.CopyToModel(m)
+	return m
+}
+
 // CopyToModel copies entity data to model
 func (p *AccessKey) CopyToModel(m *model.AccessKey) {
 	m.ID = p.ID
 	m.Label = p.Label
 	m.ProjectID = p.ProjectID
-	m.Key = p.Key
+	m.Prefix = p.KeyPrefix
+	m.CreatedAt = p.CreatedAt
+	m.ExpiresAt = p.ExpiresAt
+	m.LastUsedAt = p.LastUsedAt
+	m.LastUsedIP = p.LastUsedIP
+	m.ReplacedBy = p.ReplacedByID
+	m.AllowedNetworks = commaSeparatedToStringArray(p.AllowedNetworks)
+
+	scopes := commaSeparatedToStringArray(p.Scopes)
+	m.Scopes = make([]model.AccessKeyScope, len(scopes))
+	for i, scope := range scopes {
+		m.Scopes[i] = model.AccessKeyScope(scope)
+	}
+	if len(m.Scopes) == 0 {
+		m.Scopes = append(m.Scopes, model.DefaultAccessKeyScopes...)
+	}
 }
 
-// CopyFromModel copies model data to entity
+// CopyFromModel copies model data to entity.
+// Key hash isn't copied, it's set by repository when a key is generated
 func (p *AccessKey) CopyFromModel(m *model.AccessKey) {
 	p.ID = m.ID
 	p.Label = m.Label
 	p.ProjectID = m.ProjectID
-	p.Key = m.Key
+	p.KeyPrefix = m.Prefix
+	p.CreatedAt = m.CreatedAt
+	p.ExpiresAt = m.ExpiresAt
+	p.LastUsedAt = m.LastUsedAt
+	p.LastUsedIP = m.LastUsedIP
+	p.ReplacedByID = m.ReplacedBy
+	p.AllowedNetworks = stringArrayToCommaSeparated(m.AllowedNetworks)
+
+	scopes := make([]string, len(m.Scopes))
+	for i, scope := range m.Scopes {
+		scopes[i] = string(scope)
+	}
+	p.Scopes = stringArrayToCommaSeparated(scopes)
+}
+
+// Delivery contains information about a notification delivery
+type Delivery struct {
+	ID            int                       `gorm:"column:id;auto_increment;primary_key"`
+	ProjectID     string                    `gorm:"column:project_id;type:varchar(128);index"`
+	Event         model.EventType           `gorm:"column:event;type:varchar(64)"`
+	Channel       model.NotificationChannel `gorm:"column:channel;type:varchar(32)"`
+	Target        string                    `gorm:"column:target;type:varchar(1024)"`
+	Message       string                    `gorm:"column:message;type:text"`
+	Status        model.DeliveryStatus      `gorm:"column:status;type:varchar(32);index"`
+	Attempts      int                       `gorm:"column:attempts"`
+	LastError     string                    `gorm:"column:last_error;type:varchar(1024)"`
+	CreatedAt     time.Time                 `gorm:"column:created_at"`
+	NextAttemptAt *time.Time                `gorm:"column:next_attempt_at;index"`
+	DeliveredAt   *time.Time                `gorm:"column:delivered_at"`
+}
+
+// TableName returns database table name
+func (Delivery) TableName() string {
+	return "notification_deliveries"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *Delivery) ToModel() *model.Delivery {
+	m := &model.Delivery{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model
+func (p *Delivery) CopyToModel(m *model.Delivery) {
+	m.ID = p.ID
+	m.ProjectID = p.ProjectID
+	m.Event = p.Event
+	m.Channel = p.Channel
+	m.Target = p.Target
+	m.Status = p.Status
+	m.Attempts = p.Attempts
+	m.LastError = p.LastError
+	m.CreatedAt = p.CreatedAt
+	m.NextAttemptAt = p.NextAttemptAt
+	m.DeliveredAt = p.DeliveredAt
+
+	m.Message = &model.NotificationMessage{}
+	if p.Message != "" {
+		_ = json.Unmarshal([]byte(p.Message), m.Message)
+	}
+}
+
+// CopyFromModel copies model data to entity
+func (p *Delivery) CopyFromModel(m *model.Delivery) {
+	p.ID = m.ID
+	p.ProjectID = m.ProjectID
+	p.Event = m.Event
+	p.Channel = m.Channel
+	p.Target = m.Target
+	p.Status = m.Status
+	p.Attempts = m.Attempts
+	p.LastError = m.LastError
+	p.CreatedAt = m.CreatedAt
+	p.NextAttemptAt = m.NextAttemptAt
+	p.DeliveredAt = m.DeliveredAt
+
+	p.Message = ""
+	if m.Message != nil {
+		buff, err := json.Marshal(m.Message)
+		if err == nil {
+			p.Message = string(buff)
+		}
+	}
+}
+
+// NotificationTarget is a named, reusable notification target
+type NotificationTarget struct {
+	Name        string                    `gorm:"column:name;type:varchar(64);primary_key"`
+	Channel     model.NotificationChannel `gorm:"column:channel;type:varchar(32)"`
+	Address     string                    `gorm:"column:address;type:varchar(1024)"`
+	Description string                    `gorm:"column:description;type:varchar(1024)"`
+}
+
+// TableName returns database table name
+func (NotificationTarget) TableName() string {
+	return "notification_targets"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *NotificationTarget) ToModel() *model.NotificationTarget {
+	m := &model.NotificationTarget{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model
+func (p *NotificationTarget) CopyToModel(m *model.NotificationTarget) {
+	m.Name = p.Name
+	m.Channel = p.Channel
+	m.Address = p.Address
+	m.Description = p.Description
+}
+
+// CopyFromModel copies model data to entity
+func (p *NotificationTarget) CopyFromModel(m *model.NotificationTarget) {
+	p.Name = m.Name
+	p.Channel = m.Channel
+	p.Address = m.Address
+	p.Description = m.Description
+}
+
+// RoutingRule is a global notification routing rule
+type RoutingRule struct {
+	ID          int            `gorm:"column:id;auto_increment;primary_key"`
+	Name        string         `gorm:"column:name;type:varchar(256)"`
+	Position    int            `gorm:"column:position"`
+	IsActive    bool           `gorm:"column:is_active"`
+	Projects    string         `gorm:"column:projects;type:varchar(1024)"`
+	Tags        string         `gorm:"column:tags;type:varchar(1024)"`
+	Labels      string         `gorm:"column:labels;type:text"`
+	Events      string         `gorm:"column:events;type:varchar(256)"`
+	MinSeverity model.Severity `gorm:"column:min_severity;type:varchar(32)"`
+	Targets     string         `gorm:"column:targets;type:varchar(1024)"`
+	Stop        bool           `gorm:"column:stop"`
+}
+
+// TableName returns database table name
+func (RoutingRule) TableName() string {
+	return "notification_rules"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *RoutingRule) ToModel() *model.RoutingRule {
+	m := &model.RoutingRule{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model
+func (p *RoutingRule) CopyToModel(m *model.RoutingRule) {
+	m.ID = p.ID
+	m.Name = p.Name
+	m.Position = p.Position
+	m.IsActive = p.IsActive
+	m.Projects = commaSeparatedToStringArray(p.Projects)
+	m.Tags = commaSeparatedToStringArray(p.Tags)
+	m.MinSeverity = p.MinSeverity
+	m.Targets = commaSeparatedToStringArray(p.Targets)
+	m.Stop = p.Stop
+
+	events := commaSeparatedToStringArray(p.Events)
+	m.Events = make([]model.EventType, len(events))
+	for i, event := range events {
+		m.Events[i] = model.EventType(event)
+	}
+
+	m.Labels = make(map[string]string)
+	if p.Labels != "" {
+		_ = json.Unmarshal([]byte(p.Labels), &m.Labels)
+	}
+}
+
+// CopyFromModel copies model data to entity
+func (p *RoutingRule) CopyFromModel(m *model.RoutingRule) {
+	p.ID = m.ID
+	p.Name = m.Name
+	p.Position = m.Position
+	p.IsActive = m.IsActive
+	p.Projects = stringArrayToCommaSeparated(m.Projects)
+	p.Tags = stringArrayToCommaSeparated(m.Tags)
+	p.MinSeverity = m.MinSeverity
+	p.Targets = stringArrayToCommaSeparated(m.Targets)
+	p.Stop = m.Stop
+
+	events := make([]string, len(m.Events))
+	for i, event := range m.Events {
+		events[i] = string(event)
+	}
+	p.Events = stringArrayToCommaSeparated(events)
+
+	p.Labels = ""
+	if len(m.Labels) > 0 {
+		buff, err := json.Marshal(m.Labels)
+		if err == nil {
+			p.Labels = string(buff)
+		}
+	}
+}
+
+// ProjectGrant is a database entity for model.ProjectGrant
+type ProjectGrant struct {
+	ID      int    `gorm:"column:id;auto_increment;primary_key"`
+	UserID  int    `gorm:"column:user_id;index"`
+	Tag     string `gorm:"column:tag;type:varchar(256)"`
+	Project string `gorm:"column:project;type:varchar(256)"`
+	Role    string `gorm:"column:role;type:varchar(16)"`
+}
+
+// TableName returns database table name
+func (ProjectGrant) TableName() string {
+	return "project_grants"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *ProjectGrant) ToModel() *model.ProjectGrant {
+	m := &model.ProjectGrant{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model
+func (p *ProjectGrant) CopyToModel(m *model.ProjectGrant) {
+	m.ID = p.ID
+	m.UserID = p.UserID
+	m.Tag = p.Tag
+	m.Project = p.Project
+	m.Role = model.Role(p.Role)
+}
+
+// CopyFromModel copies model data to entity
+func (p *ProjectGrant) CopyFromModel(m *model.ProjectGrant) {
+	p.ID = m.ID
+	p.UserID = m.UserID
+	p.Tag = m.Tag
+	p.Project = m.Project
+	p.Role = string(m.Role)
+}
+
+// Session is a database entity for model.Session
+type Session struct {
+	ID                string    `gorm:"column:id;type:varchar(64);primary_key"`
+	UserID            int       `gorm:"column:user_id;index"`
+	TokenHash         string    `gorm:"column:token_hash;type:varchar(128)"`
+	PreviousTokenHash string    `gorm:"column:previous_token_hash;type:varchar(128)"`
+	CreatedAt         time.Time `gorm:"column:created_at"`
+	LastUsedAt        time.Time `gorm:"column:last_used_at"`
+	ExpiresAt         time.Time `gorm:"column:expires_at;index"`
+	IP                string    `gorm:"column:ip;type:varchar(64)"`
+	UserAgent         string    `gorm:"column:user_agent;type:varchar(512)"`
+}
+
+// TableName returns database table name
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *Session) ToModel() *model.Session {
+	m := &model.Session{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model.
+// Refresh token hashes are never exposed
+func (p *Session) CopyToModel(m *model.Session) {
+	m.ID = p.ID
+	m.UserID = p.UserID
+	m.CreatedAt = p.CreatedAt
+	m.LastUsedAt = p.LastUsedAt
+	m.ExpiresAt = p.ExpiresAt
+	m.IP = p.IP
+	m.UserAgent = p.UserAgent
+}
+
+// Setting is a database entity for global settings.
+// Each group of settings is stored as a JSON value
+type Setting struct {
+	Name  string `gorm:"column:name;type:varchar(64);primary_key"`
+	Value string `gorm:"column:value;type:text"`
+}
+
+// TableName returns database table name
+func (Setting) TableName() string {
+	return "settings"
+}
+
+// APIToken is a database entity for personal API tokens.
+// Only a hash of the token is stored, its prefix is kept to tell tokens apart
+type APIToken struct {
+	ID         int        `gorm:"column:id;auto_increment;primary_key"`
+	UserID     int        `gorm:"column:user_id;index"`
+	Name       string     `gorm:"column:name;type:varchar(256)"`
+	TokenHash  string     `gorm:"column:token_hash;type:varchar(128);unique_index"`
+	Prefix     string     `gorm:"column:prefix;type:varchar(16)"`
+	Role       string     `gorm:"column:role;type:varchar(16)"`
+	Projects   string     `gorm:"column:projects;type:varchar(1024)"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	LastUsedIP string     `gorm:"column:last_used_ip;type:varchar(64)"`
+}
+
+// TableName returns database table name
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *APIToken) ToModel() *model.APIToken {
+	m := &model.APIToken{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model.
+// Token hashes are never exposed
+func (p *APIToken) CopyToModel(m *model.APIToken) {
+	m.ID = p.ID
+	m.UserID = p.UserID
+	m.Name = p.Name
+	m.Prefix = p.Prefix
+	m.Role = model.Role(p.Role)
+	m.Projects = commaSeparatedToStringArray(p.Projects)
+	m.CreatedAt = p.CreatedAt
+	m.ExpiresAt = p.ExpiresAt
+	m.LastUsedAt = p.LastUsedAt
+	m.LastUsedIP = p.LastUsedIP
+}
+
+// CopyFromModel copies model data to entity.
+// Token hash isn't copied, it's set by repository when a token is generated
+func (p *APIToken) CopyFromModel(m *model.APIToken) {
+	p.ID = m.ID
+	p.UserID = m.UserID
+	p.Name = m.Name
+	p.Prefix = m.Prefix
+	p.Role = string(m.Role)
+	p.Projects = stringArrayToCommaSeparated(m.Projects)
+	p.CreatedAt = m.CreatedAt
+	p.ExpiresAt = m.ExpiresAt
+	p.LastUsedAt = m.LastUsedAt
+	p.LastUsedIP = m.LastUsedIP
+}
+
+// AuditEntry is a record of audit log. Audit log is append-only
+type AuditEntry struct {
+	ID         int       `gorm:"column:id;auto_increment;primary_key"`
+	Time       time.Time `gorm:"column:time;index"`
+	ActorType  string    `gorm:"column:actor_type;type:varchar(32);index"`
+	ActorID    string    `gorm:"column:actor_id;type:varchar(128)"`
+	ActorName  string    `gorm:"column:actor_name;type:varchar(256);index"`
+	ActorToken string    `gorm:"column:actor_token;type:varchar(16)"`
+	Action     string    `gorm:"column:action;type:varchar(64);index"`
+	TargetType string    `gorm:"column:target_type;type:varchar(32)"`
+	TargetID   string    `gorm:"column:target_id;type:varchar(128);index"`
+	ProjectID  string    `gorm:"column:project_id;type:varchar(128);index"`
+	Changes    string    `gorm:"column:changes;type:text"`
+	IP         string    `gorm:"column:ip;type:varchar(64)"`
+	Details    string    `gorm:"column:details;type:varchar(1024)"`
+}
+
+// TableName returns database table name
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// ToModel creates new model and copies entity data to it
+func (p *AuditEntry) ToModel() *model.AuditEntry {
+	m := &model.AuditEntry{}
+	p.CopyToModel(m)
+	return m
+}
+
+// CopyToModel copies entity data to model
+func (p *AuditEntry) CopyToModel(m *model.AuditEntry) {
+	m.ID = p.ID
+	m.Time = p.Time
+	m.Actor = model.AuditActor{
+		Type:  model.AuditActorType(p.ActorType),
+		ID:    p.ActorID,
+		Name:  p.ActorName,
+		Token: p.ActorToken,
+	}
+	m.Action = model.AuditAction(p.Action)
+	m.TargetType = model.AuditTargetType(p.TargetType)
+	m.TargetID = p.TargetID
+	m.ProjectID = p.ProjectID
+	m.IP = p.IP
+	m.Details = p.Details
+
+	m.Changes = nil
+	if p.Changes != "" {
+		_ = json.Unmarshal([]byte(p.Changes), &m.Changes)
+	}
+}
+
+// CopyFromModel copies model data to entity
+func (p *AuditEntry) CopyFromModel(m *model.AuditEntry) {
+	p.ID = m.ID
+	p.Time = m.Time
+	p.ActorType = string(m.Actor.Type)
+	p.ActorID = m.Actor.ID
+	p.ActorName = m.Actor.Name
+	p.ActorToken = m.Actor.Token
+	p.Action = string(m.Action)
+	p.TargetType = string(m.TargetType)
+	p.TargetID = m.TargetID
+	p.ProjectID = m.ProjectID
+	p.IP = m.IP
+	p.Details = m.Details
+
+	p.Changes = ""
+	if len(m.Changes) > 0 {
+		buff, err := json.Marshal(m.Changes)
+		if err == nil {
+			p.Changes = string(buff)
+		}
+	}
 }
@@ -1,23 +1,105 @@
 package model
 
-import "strings"
+import (
+	"net"
+	"strings"
+	"time"
+)
 
-// AccessKey contains information about project's access key
+// AccessKeyScope is a permission of an access key
+type AccessKeyScope string
+
+const (
+	// AccessKeyScopeUpload allows to upload backups
+	AccessKeyScopeUpload AccessKeyScope = "upload"
+
+	// AccessKeyScopeDownload allows to download project's backups
+	AccessKeyScopeDownload AccessKeyScope = "download"
+
+	// AccessKeyScopeCheckIn allows to check in and read project's backup status
+	AccessKeyScopeCheckIn AccessKeyScope = "checkin"
+
+	// AccessKeyScopeList allows to list project's backups
+	AccessKeyScopeList AccessKeyScope = "list"
+)
+
+// IsValid returns true if scope is known
+func (s AccessKeyScope) IsValid() bool {
+	switch s {
+	case AccessKeyScopeUpload, AccessKeyScopeDownload, AccessKeyScopeCheckIn, AccessKeyScopeList:
+		return true
+	}
+
+	return false
+}
+
+// DefaultAccessKeyScopes are scopes of access keys created without explicit scopes
+// (and of keys that were created before scopes were introduced)
+var DefaultAccessKeyScopes = []AccessKeyScope{AccessKeyScopeUpload}
+
+// AccessKeyPrefixLength is a length of access key's visible prefix
+const AccessKeyPrefixLength = 8
+
+// AccessKey contains information about project's access key.
+// Keys are stored hashed, so full key value is available only right after creation
 type AccessKey struct {
-	ID        int    `json:"id"`
-	Label     string `json:"label"`
-	Key       string `json:"key"`
-	ProjectID string `json:"-"`
+	ID         int              `json:"id"`
+	Label      string           `json:"label"`
+	Key        string           `json:"key,omitempty"`
+	Prefix     string           `json:"prefix"`
+	ProjectID  string           `json:"-"`
+	Scopes     []AccessKeyScope `json:"scopes"`
+	CreatedAt  *time.Time       `json:"createdAt,omitempty"`
+	ExpiresAt  *time.Time       `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time       `json:"lastUsedAt,omitempty"`
+	LastUsedIP string           `json:"lastUsedIp,omitempty"`
+	ReplacedBy *int             `json:"replacedBy,omitempty"`
+	// Networks (CIDRs) the key can be used from, any address is allowed if empty
+	AllowedNetworks []string `json:"allowedNetworks"`
 }
 
 // String converts an object to string
 func (p AccessKey) String() string {
+	p.Key = ""
 	return toJSON(&p)
 }
 
+// IsExpired returns true if access key can't be used anymore
+func (p *AccessKey) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// HasScope returns true if access key has a scope
+func (p *AccessKey) HasScope(scope AccessKeyScope) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsIP returns true if access key can be used from an IP address
+func (p *AccessKey) AllowsIP(ip string) bool {
+	if len(p.AllowedNetworks) == 0 {
+		return true
+	}
+
+	networks, err := ParseNetworks(p.AllowedNetworks)
+	if err != nil {
+		return false
+	}
+
+	return NetworksContain(networks, net.ParseIP(ip))
+}
+
 // AccessKeyCreateParams contains parameters for access key creation
 type AccessKeyCreateParams struct {
-	Label string `json:"label"`
+	Label           string           `json:"label"`
+	Scopes          []AccessKeyScope `json:"scopes"`
+	ExpiresAt       *time.Time       `json:"expiresAt"`
+	AllowedNetworks []string         `json:"allowedNetworks"`
 }
 
 // String converts an object to string
@@ -28,6 +110,145 @@ func (p AccessKeyCreateParams) String() string {
 // Normalize normalizes request's fields
 func (p *AccessKeyCreateParams) Normalize() {
 	p.Label = strings.TrimSpace(p.Label)
+
+	scopes := make([]AccessKeyScope, 0, len(p.Scopes))
+	for _, scope := range p.Scopes {
+		scope = AccessKeyScope(strings.ToLower(strings.TrimSpace(string(scope))))
+		if scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		scopes = append(scopes, DefaultAccessKeyScopes...)
+	}
+	p.Scopes = scopes
+
+	networks := make([]string, 0, len(p.AllowedNetworks))
+	for _, network := range p.AllowedNetworks {
+		network = strings.TrimSpace(network)
+		if network != "" {
+			networks = append(networks, network)
+		}
+	}
+	p.AllowedNetworks = networks
+}
+
+// Validate validates request's fields
+func (p *AccessKeyCreateParams) Validate() error {
+	for _, scope := range p.Scopes {
+		if !scope.IsValid() {
+			return NewError(EBadRequest, "\"%s\" is not a valid access key scope", scope)
+		}
+	}
+
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now()) {
+		return NewError(EBadRequest, "access key expiration date should be in future")
+	}
+
+	networks, err := ParseNetworks(p.AllowedNetworks)
+	if err != nil {
+		return err
+	}
+
+	// Networks are stored in canonical form, e.g. "10.0.0.1" becomes "10.0.0.1/32"
+	for i, network := range networks {
+		p.AllowedNetworks[i] = network.String()
+	}
+
+	return nil
+}
+
+// ParseNetworks parses a list of CIDRs. Plain IP addresses are treated as single-address networks
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, len(values))
+	for i, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, NewError(EBadRequest, "\"%s\" is not a valid ip address or network", value)
+			}
+
+			if ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, NewError(EBadRequest, "\"%s\" is not a valid ip address or network", value)
+		}
+
+		networks[i] = network
+	}
+
+	return networks, nil
+}
+
+// NetworksContain returns true if an IP address belongs to any of networks
+func NetworksContain(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AccessKeyRotateParams contains parameters for access key rotation
+type AccessKeyRotateParams struct {
+	// How long the old key keeps working, e.g. "24h" ("0s" revokes it immediately)
+	Overlap string `json:"overlap"`
+	// Expiration date of the new key (by default it expires after the same lifetime as the old one)
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// String converts an object to string
+func (p AccessKeyRotateParams) String() string {
+	return toJSON(&p)
+}
+
+// DefaultAccessKeyOverlap is a default period when both old and new keys are valid after rotation
+const DefaultAccessKeyOverlap = 24 * time.Hour
+
+// Normalize normalizes request's fields
+func (p *AccessKeyRotateParams) Normalize() {
+	p.Overlap = strings.TrimSpace(p.Overlap)
+}
+
+// OverlapPeriod parses overlap period
+func (p *AccessKeyRotateParams) OverlapPeriod() (time.Duration, error) {
+	if p.Overlap == "" {
+		return DefaultAccessKeyOverlap, nil
+	}
+
+	overlap, err := time.ParseDuration(p.Overlap)
+	if err != nil || overlap < 0 {
+		return 0, NewError(EBadRequest, "\"%s\" is not a valid overlap period", p.Overlap)
+	}
+
+	return overlap, nil
+}
+
+// Validate validates request's fields
+func (p *AccessKeyRotateParams) Validate() error {
+	_, err := p.OverlapPeriod()
+	if err != nil {
+		return err
+	}
+
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now()) {
+		return NewError(EBadRequest, "access key expiration date should be in future")
+	}
+
+	return nil
 }
 
 // AccessKeys is a list of AccessKey
@@ -0,0 +1,247 @@
+package model
+
+import (
+	"path"
+	"strings"
+)
+
+// Role is a user's role
+type Role string
+
+const (
+	// RoleViewer can view projects and their backups
+	RoleViewer Role = "viewer"
+
+	// RoleOperator can also manage projects, their access keys and backups
+	RoleOperator Role = "operator"
+
+	// RoleAdmin can do anything, including user management and global settings
+	RoleAdmin Role = "admin"
+)
+
+// Rank returns a comparable rank of role (or -1 if role is not valid)
+func (r Role) Rank() int {
+	switch r {
+	case RoleViewer:
+		return 0
+	case RoleOperator:
+		return 1
+	case RoleAdmin:
+		return 2
+	}
+
+	return -1
+}
+
+// IsValid returns true if role is known
+func (r Role) IsValid() bool {
+	return r.Rank() >= 0
+}
+
+// Includes returns true if role grants all permissions of another role
+func (r Role) Includes(other Role) bool {
+	return r.Rank() >= 0 && r.Rank() >= other.Rank()
+}
+
+// ProjectGrant gives a user access to a subset of projects.
+// Projects are selected by tag or by ID glob pattern
+type ProjectGrant struct {
+	ID      int    `json:"id"`
+	UserID  int    `json:"userId"`
+	Tag     string `json:"tag,omitempty"`
+	Project string `json:"project,omitempty"`
+	Role    Role   `json:"role"`
+}
+
+// String converts an object to string
+func (p *ProjectGrant) String() string {
+	return toJSON(&p)
+}
+
+// Match returns true if grant covers a project
+func (p *ProjectGrant) Match(project *Project) bool {
+	if p.Tag != "" && !project.HasAnyTag([]string{p.Tag}) {
+		return false
+	}
+
+	if p.Project != "" {
+		ok, _ := path.Match(p.Project, project.ID)
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ProjectGrants is a list of ProjectGrant
+type ProjectGrants []*ProjectGrant
+
+// ProjectGrantCreateParams contains parameters for project grant creation
+type ProjectGrantCreateParams struct {
+	Tag     string `json:"tag"`
+	Project string `json:"project"`
+	Role    Role   `json:"role" binding:"required"`
+}
+
+// String converts an object to string
+func (p *ProjectGrantCreateParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *ProjectGrantCreateParams) Normalize() {
+	p.Tag = strings.ToLower(strings.TrimSpace(p.Tag))
+	p.Project = strings.TrimSpace(p.Project)
+	p.Role = Role(strings.ToLower(strings.TrimSpace(string(p.Role))))
+}
+
+// Validate validates request's fields
+func (p *ProjectGrantCreateParams) Validate() error {
+	if p.Tag == "" && p.Project == "" {
+		return NewError(EBadRequest, "either tag or project pattern is required")
+	}
+
+	if p.Project != "" {
+		_, err := path.Match(p.Project, "")
+		if err != nil {
+			return NewError(EBadRequest, "\"%s\" is not a valid project pattern", p.Project)
+		}
+	}
+
+	if p.Role != RoleViewer && p.Role != RoleOperator {
+		return NewError(EBadRequest, "\"%s\" is not a valid project role", p.Role)
+	}
+
+	return nil
+}
+
+// Access describes what a user is allowed to do.
+// Users with "all projects" flag have their role on every project,
+// other users have access only to projects covered by their grants (if any).
+// Requests authorized with an API token are limited further by token's role and projects
+type Access struct {
+	User   *User
+	Grants []*ProjectGrant
+	Token  *APIToken
+}
+
+// String converts an object to string
+func (p *Access) String() string {
+	return toJSON(&p)
+}
+
+// IsAdmin returns true if user is an administrator
+func (p *Access) IsAdmin() bool {
+	return p.User.Role == RoleAdmin
+}
+
+// IsRestricted returns true if user has access to a subset of projects only
+func (p *Access) IsRestricted() bool {
+	return !p.IsAdmin() && !p.User.AllProjects
+}
+
+// Has returns true if user has a global role (regardless of project grants)
+func (p *Access) Has(role Role) bool {
+	if p.IsRestricted() {
+		return false
+	}
+
+	if p.Token != nil && (len(p.Token.Projects) > 0 || !p.Token.Role.Includes(role)) {
+		return false
+	}
+
+	return p.User.Role.Includes(role)
+}
+
+// ProjectRole returns user's role on a project (or empty string if project is not visible)
+func (p *Access) ProjectRole(project *Project) Role {
+	role := p.userProjectRole(project)
+
+	if p.Token != nil && role != "" {
+		if !p.Token.Covers(project) {
+			return ""
+		}
+
+		if !p.Token.Role.Includes(role) {
+			role = p.Token.Role
+		}
+	}
+
+	return role
+}
+
+func (p *Access) userProjectRole(project *Project) Role {
+	if !p.IsRestricted() {
+		return p.User.Role
+	}
+
+	var role Role
+	for _, grant := range p.Grants {
+		if grant.Match(project) && grant.Role.Rank() > role.Rank() {
+			role = grant.Role
+		}
+	}
+
+	// Project grants can't exceed user's own role
+	if role != "" && !p.User.Role.Includes(role) {
+		role = p.User.Role
+	}
+
+	return role
+}
+
+// CanView returns true if user can see a project
+func (p *Access) CanView(project *Project) bool {
+	return p.ProjectRole(project) != ""
+}
+
+// Can returns true if user has a role on a project
+func (p *Access) Can(project *Project, role Role) bool {
+	return p.ProjectRole(project).Includes(role)
+}
+
+// RoleMapping maps external groups (e.g. from an identity provider) to roles
+type RoleMapping map[string]Role
+
+// ParseRoleMapping parses a role mapping from "group=role;group=role" string
+func ParseRoleMapping(str string) (RoleMapping, error) {
+	mapping := make(RoleMapping)
+	for _, pair := range strings.Split(str, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, NewError(EBadRequest, "\"%s\" is not a valid role mapping", pair)
+		}
+
+		group := strings.TrimSpace(pair[:i])
+		role := Role(strings.ToLower(strings.TrimSpace(pair[i+1:])))
+		if !role.IsValid() {
+			return nil, NewError(EBadRequest, "\"%s\" is not a valid role", role)
+		}
+
+		mapping[group] = role
+	}
+
+	return mapping, nil
+}
+
+// Resolve returns the highest role granted by any of groups (or fallback role if none matches)
+func (m RoleMapping) Resolve(groups []string, fallback Role) Role {
+	var role Role
+	for _, group := range groups {
+		r, ok := m[group]
+		if ok && r.Rank() > role.Rank() {
+			role = r
+		}
+	}
+
+	if role == "" {
+		return fallback
+	}
+
+	return role
+}
@@ -0,0 +1,181 @@
+package model
+
+import "testing"
+
+var (
+	accessTestTeamA = &Project{ID: "team-a-db", Tags: []string{"team-a"}}
+	accessTestTeamB = &Project{ID: "team-b-web", Tags: []string{"team-b"}}
+)
+
+func accessTestUser(role Role, allProjects bool) *User {
+	return &User{ID: 1, UserName: "alice", Role: role, AllProjects: allProjects}
+}
+
+func TestAccessProjectRole(t *testing.T) {
+	cases := []struct {
+		name   string
+		access *Access
+		teamA  Role
+		teamB  Role
+	}{
+		{
+			name:   "admin has admin role on all projects",
+			access: &Access{User: accessTestUser(RoleAdmin, false)},
+			teamA:  RoleAdmin,
+			teamB:  RoleAdmin,
+		},
+		{
+			name: "admin ignores grants",
+			access: &Access{
+				User:   accessTestUser(RoleAdmin, false),
+				Grants: []*ProjectGrant{{Tag: "team-a", Role: RoleViewer}},
+			},
+			teamA: RoleAdmin,
+			teamB: RoleAdmin,
+		},
+		{
+			name:   "all projects",
+			access: &Access{User: accessTestUser(RoleOperator, true)},
+			teamA:  RoleOperator,
+			teamB:  RoleOperator,
+		},
+		{
+			name: "all projects ignores grants",
+			access: &Access{
+				User:   accessTestUser(RoleOperator, true),
+				Grants: []*ProjectGrant{{Tag: "team-a", Role: RoleViewer}},
+			},
+			teamA: RoleOperator,
+			teamB: RoleOperator,
+		},
+		{
+			name:   "restricted without grants",
+			access: &Access{User: accessTestUser(RoleOperator, false)},
+			teamA:  "",
+			teamB:  "",
+		},
+		{
+			name: "tag grant",
+			access: &Access{
+				User:   accessTestUser(RoleOperator, false),
+				Grants: []*ProjectGrant{{Tag: "team-a", Role: RoleViewer}},
+			},
+			teamA: RoleViewer,
+			teamB: "",
+		},
+		{
+			name: "highest of matching grants",
+			access: &Access{
+				User: accessTestUser(RoleOperator, false),
+				Grants: []*ProjectGrant{
+					{Project: "team-*", Role: RoleViewer},
+					{Tag: "team-a", Role: RoleOperator},
+				},
+			},
+			teamA: RoleOperator,
+			teamB: RoleViewer,
+		},
+		{
+			name: "grant with both tag and pattern",
+			access: &Access{
+				User:   accessTestUser(RoleOperator, false),
+				Grants: []*ProjectGrant{{Tag: "team-a", Project: "team-b-*", Role: RoleViewer}},
+			},
+			teamA: "",
+			teamB: "",
+		},
+		{
+			name: "grant can't exceed user's role",
+			access: &Access{
+				User:   accessTestUser(RoleViewer, false),
+				Grants: []*ProjectGrant{{Tag: "team-a", Role: RoleOperator}},
+			},
+			teamA: RoleViewer,
+			teamB: "",
+		},
+		{
+			name:   "token lowers role",
+			access: &Access{User: accessTestUser(RoleAdmin, false), Token: &APIToken{Role: RoleViewer}},
+			teamA:  RoleViewer,
+			teamB:  RoleViewer,
+		},
+		{
+			name:   "token can't raise role",
+			access: &Access{User: accessTestUser(RoleOperator, true), Token: &APIToken{Role: RoleAdmin}},
+			teamA:  RoleOperator,
+			teamB:  RoleOperator,
+		},
+		{
+			name: "token limits projects",
+			access: &Access{
+				User:  accessTestUser(RoleOperator, true),
+				Token: &APIToken{Role: RoleOperator, Projects: []string{"team-a-*"}},
+			},
+			teamA: RoleOperator,
+			teamB: "",
+		},
+		{
+			name: "token can't widen grants",
+			access: &Access{
+				User:   accessTestUser(RoleOperator, false),
+				Grants: []*ProjectGrant{{Tag: "team-a", Role: RoleOperator}},
+				Token:  &APIToken{Role: RoleOperator, Projects: []string{"team-*"}},
+			},
+			teamA: RoleOperator,
+			teamB: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for project, expected := range map[*Project]Role{accessTestTeamA: c.teamA, accessTestTeamB: c.teamB} {
+				role := c.access.ProjectRole(project)
+				if role != expected {
+					t.Errorf("expected role %q on \"%s\", got %q", expected, project.ID, role)
+				}
+
+				if c.access.CanView(project) != (expected != "") {
+					t.Errorf("expected visibility of \"%s\" to be %v", project.ID, expected != "")
+				}
+			}
+		})
+	}
+}
+
+func TestAccessHas(t *testing.T) {
+	cases := []struct {
+		name     string
+		access   *Access
+		role     Role
+		expected bool
+	}{
+		{"admin", &Access{User: accessTestUser(RoleAdmin, false)}, RoleAdmin, true},
+		{"operator has operator role", &Access{User: accessTestUser(RoleOperator, true)}, RoleOperator, true},
+		{"operator has viewer role", &Access{User: accessTestUser(RoleOperator, true)}, RoleViewer, true},
+		{"operator has no admin role", &Access{User: accessTestUser(RoleOperator, true)}, RoleAdmin, false},
+		{"restricted user has no global role", &Access{User: accessTestUser(RoleOperator, false)}, RoleViewer, false},
+		{
+			"restricted user with grants has no global role",
+			&Access{User: accessTestUser(RoleOperator, false), Grants: []*ProjectGrant{{Project: "*", Role: RoleOperator}}},
+			RoleViewer,
+			false,
+		},
+		{"token role", &Access{User: accessTestUser(RoleAdmin, false), Token: &APIToken{Role: RoleViewer}}, RoleViewer, true},
+		{"token lowers role", &Access{User: accessTestUser(RoleAdmin, false), Token: &APIToken{Role: RoleViewer}}, RoleOperator, false},
+		{"token can't raise role", &Access{User: accessTestUser(RoleViewer, true), Token: &APIToken{Role: RoleAdmin}}, RoleOperator, false},
+		{
+			"project-scoped token has no global role",
+			&Access{User: accessTestUser(RoleAdmin, false), Token: &APIToken{Role: RoleAdmin, Projects: []string{"*"}}},
+			RoleViewer,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.access.Has(c.role) != c.expected {
+				t.Errorf("expected Has(%q) to be %v", c.role, c.expected)
+			}
+		})
+	}
+}
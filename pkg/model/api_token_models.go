@@ -0,0 +1,121 @@
+package model
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix marks personal API tokens, so they can be told apart from JWTs
+const APITokenPrefix = "bmt_"
+
+// APITokenPrefixLength is a length of API token's visible prefix (including APITokenPrefix)
+const APITokenPrefixLength = len(APITokenPrefix) + 8
+
+// APIToken is a personal long-lived token of a user for automation.
+// A token carries a subset of its user's permissions: its role and projects limit user's own access.
+// Tokens are stored hashed, so full token value is available only right after creation
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"userId"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Role       Role       `json:"role"`
+	Projects   []string   `json:"projects"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `json:"lastUsedIp,omitempty"`
+}
+
+// String converts an object to string
+func (p APIToken) String() string {
+	p.Token = ""
+	return toJSON(&p)
+}
+
+// IsExpired returns true if token can't be used anymore
+func (p *APIToken) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// Covers returns true if token can be used to access a project
+func (p *APIToken) Covers(project *Project) bool {
+	if len(p.Projects) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Projects {
+		ok, _ := path.Match(pattern, project.ID)
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APITokens is a list of APIToken
+type APITokens []*APIToken
+
+// APITokenCreateParams contains parameters for API token creation
+type APITokenCreateParams struct {
+	Name      string     `json:"name" binding:"required"`
+	Role      Role       `json:"role"`
+	Projects  []string   `json:"projects"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// String converts an object to string
+func (p APITokenCreateParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *APITokenCreateParams) Normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Role = Role(strings.ToLower(strings.TrimSpace(string(p.Role))))
+
+	projects := make([]string, 0, len(p.Projects))
+	for _, project := range p.Projects {
+		project = strings.TrimSpace(project)
+		if project != "" {
+			projects = append(projects, project)
+		}
+	}
+	p.Projects = projects
+}
+
+// Validate validates request's fields against permissions of token's user
+func (p *APITokenCreateParams) Validate(user *User) error {
+	if p.Name == "" {
+		return NewError(EBadRequest, "token name is required")
+	}
+
+	// Tokens get user's own role by default
+	if p.Role == "" {
+		p.Role = user.Role
+	}
+
+	if !p.Role.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid role", p.Role)
+	}
+
+	if !user.Role.Includes(p.Role) {
+		return NewError(EBadRequest, "token can't have more permissions than its user")
+	}
+
+	for _, project := range p.Projects {
+		_, err := path.Match(project, "")
+		if err != nil {
+			return NewError(EBadRequest, "\"%s\" is not a valid project pattern", project)
+		}
+	}
+
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now()) {
+		return NewError(EBadRequest, "token expiration date should be in future")
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuditActorType is a kind of actor that has performed an audited action
+type AuditActorType string
+
+const (
+	// AuditActorUser is a signed in user (or a user's API token)
+	AuditActorUser AuditActorType = "user"
+
+	// AuditActorAccessKey is a project's access key
+	AuditActorAccessKey AuditActorType = "access_key"
+
+	// AuditActorLink is a signed download link
+	AuditActorLink AuditActorType = "link"
+
+	// AuditActorPolicy is a background policy (e.g. retention policy)
+	AuditActorPolicy AuditActorType = "policy"
+
+	// AuditActorAnonymous is an unauthenticated client
+	AuditActorAnonymous AuditActorType = "anonymous"
+)
+
+// AuditActor describes who has performed an audited action
+type AuditActor struct {
+	Type AuditActorType `json:"type"`
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name,omitempty"`
+	// Prefix of API token the user has acted with
+	Token string `json:"token,omitempty"`
+}
+
+// AuditAction is a kind of audited action
+type AuditAction string
+
+const (
+	// AuditActionLogin is a successful sign in
+	AuditActionLogin AuditAction = "auth.login"
+
+	// AuditActionLoginFailed is a failed sign in attempt
+	AuditActionLoginFailed AuditAction = "auth.login_failed"
+
+	// AuditActionLockout is a temporary lockout after too many failed sign in attempts (or access key guesses)
+	AuditActionLockout AuditAction = "auth.lockout"
+
+	// AuditActionUnlock is a manual release of a lockout
+	AuditActionUnlock AuditAction = "auth.unlock"
+
+	// AuditActionUserCreate is a user creation
+	AuditActionUserCreate AuditAction = "user.create"
+
+	// AuditActionUserUpdate is a user modification (including role changes and disabling)
+	AuditActionUserUpdate AuditAction = "user.update"
+
+	// AuditActionUserDelete is a user deletion
+	AuditActionUserDelete AuditAction = "user.delete"
+
+	// AuditActionUserPasswordReset is a reset of user's password by admin
+	AuditActionUserPasswordReset AuditAction = "user.password_reset"
+
+	// AuditActionUserMFAReset is a reset of user's two-factor authentication by admin
+	AuditActionUserMFAReset AuditAction = "user.mfa_reset"
+
+	// AuditActionGrantCreate is a project grant creation
+	AuditActionGrantCreate AuditAction = "grant.create"
+
+	// AuditActionGrantDelete is a project grant revocation
+	AuditActionGrantDelete AuditAction = "grant.delete"
+
+	// AuditActionSettingsUpdate is a modification of global settings
+	AuditActionSettingsUpdate AuditAction = "settings.update"
+
+	// AuditActionNotificationTargetCreate is a notification target creation
+	AuditActionNotificationTargetCreate AuditAction = "notify_target.create"
+
+	// AuditActionNotificationTargetUpdate is a notification target modification
+	AuditActionNotificationTargetUpdate AuditAction = "notify_target.update"
+
+	// AuditActionNotificationTargetDelete is a notification target deletion
+	AuditActionNotificationTargetDelete AuditAction = "notify_target.delete"
+
+	// AuditActionRoutingRuleCreate is a notification routing rule creation
+	AuditActionRoutingRuleCreate AuditAction = "routing_rule.create"
+
+	// AuditActionRoutingRuleUpdate is a notification routing rule modification
+	AuditActionRoutingRuleUpdate AuditAction = "routing_rule.update"
+
+	// AuditActionRoutingRuleDelete is a notification routing rule deletion
+	AuditActionRoutingRuleDelete AuditAction = "routing_rule.delete"
+
+	// AuditActionProjectCreate is a project creation
+	AuditActionProjectCreate AuditAction = "project.create"
+
+	// AuditActionProjectUpdate is a project modification
+	AuditActionProjectUpdate AuditAction = "project.update"
+
+	// AuditActionProjectDelete is a project deletion, project is moved to trash
+	AuditActionProjectDelete AuditAction = "project.delete"
+
+	// AuditActionProjectRestore is a project restoration from trash
+	AuditActionProjectRestore AuditAction = "project.restore"
+
+	// AuditActionProjectPurge is a permanent removal of a trashed project
+	AuditActionProjectPurge AuditAction = "project.purge"
+
+	// AuditActionProjectHold is a project's legal hold placement
+	AuditActionProjectHold AuditAction = "project.hold"
+
+	// AuditActionProjectRelease is a project's legal hold release
+	AuditActionProjectRelease AuditAction = "project.release"
+
+	// AuditActionAccessKeyCreate is an access key creation
+	AuditActionAccessKeyCreate AuditAction = "access_key.create"
+
+	// AuditActionAccessKeyRotate is an access key rotation
+	AuditActionAccessKeyRotate AuditAction = "access_key.rotate"
+
+	// AuditActionAccessKeyDelete is an access key deletion
+	AuditActionAccessKeyDelete AuditAction = "access_key.delete"
+
+	// AuditActionBackupDownload is a backup download
+	AuditActionBackupDownload AuditAction = "backup.download"
+
+	// AuditActionBackupDelete is a backup deletion (either manual or by retention policy), backup is moved to trash
+	AuditActionBackupDelete AuditAction = "backup.delete"
+
+	// AuditActionBackupRestore is a backup restoration from trash
+	AuditActionBackupRestore AuditAction = "backup.restore"
+
+	// AuditActionBackupPurge is a permanent removal of a trashed backup
+	AuditActionBackupPurge AuditAction = "backup.purge"
+
+	// AuditActionBackupPin is a backup pinning
+	AuditActionBackupPin AuditAction = "backup.pin"
+
+	// AuditActionBackupUnpin is a backup unpinning
+	AuditActionBackupUnpin AuditAction = "backup.unpin"
+)
+
+// AuditTargetType is a kind of object an audited action has been performed on
+type AuditTargetType string
+
+const (
+	// AuditTargetUser is a user
+	AuditTargetUser AuditTargetType = "user"
+
+	// AuditTargetProject is a project
+	AuditTargetProject AuditTargetType = "project"
+
+	// AuditTargetAccessKey is a project's access key
+	AuditTargetAccessKey AuditTargetType = "access_key"
+
+	// AuditTargetBackup is a backup
+	AuditTargetBackup AuditTargetType = "backup"
+
+	// AuditTargetIP is a client's IP address
+	AuditTargetIP AuditTargetType = "ip"
+
+	// AuditTargetGrant is a user's project grant
+	AuditTargetGrant AuditTargetType = "grant"
+
+	// AuditTargetSettings is a group of global settings
+	AuditTargetSettings AuditTargetType = "settings"
+
+	// AuditTargetNotificationTarget is a notification target
+	AuditTargetNotificationTarget AuditTargetType = "notify_target"
+
+	// AuditTargetRoutingRule is a notification routing rule
+	AuditTargetRoutingRule AuditTargetType = "routing_rule"
+)
+
+// AuditChange contains values of a field before and after an audited action
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges maps changed fields to their values
+type AuditChanges map[string]*AuditChange
+
+// NewAuditChanges computes a diff between two states of an object.
+// Either of states can be nil (e.g. "before" state of a created object).
+// Only changed top-level fields (as they are serialized to JSON) are included
+func NewAuditChanges(before, after interface{}) AuditChanges {
+	b := auditFields(before)
+	a := auditFields(after)
+
+	keys := make([]string, 0, len(b)+len(a))
+	for key := range b {
+		keys = append(keys, key)
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make(AuditChanges)
+	for _, key := range keys {
+		if !reflect.DeepEqual(b[key], a[key]) {
+			changes[key] = &AuditChange{Before: b[key], After: a[key]}
+		}
+	}
+
+	return changes
+}
+
+func auditFields(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return fields
+	}
+
+	buff, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+
+	_ = json.Unmarshal(buff, &fields)
+	return fields
+}
+
+// AuditEntry is a record of audit log
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	Time       time.Time       `json:"time"`
+	Actor      AuditActor      `json:"actor"`
+	Action     AuditAction     `json:"action"`
+	TargetType AuditTargetType `json:"targetType,omitempty"`
+	TargetID   string          `json:"targetId,omitempty"`
+	ProjectID  string          `json:"projectId,omitempty"`
+	Changes    AuditChanges    `json:"changes,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	Details    string          `json:"details,omitempty"`
+}
+
+// String converts an object to string
+func (p *AuditEntry) String() string {
+	return toJSON(&p)
+}
+
+// AuditEntries is a list of AuditEntry
+type AuditEntries []*AuditEntry
+
+// AuditListParams contains parameters to filter audit log
+type AuditListParams struct {
+	// Actor's name or ID
+	Actor     string         `form:"actor"`
+	ActorType AuditActorType `form:"actorType"`
+	// Action, or action group with trailing ".*" (e.g. "project.*")
+	Action     string          `form:"action"`
+	TargetType AuditTargetType `form:"targetType"`
+	TargetID   string          `form:"target"`
+	ProjectID  string          `form:"project"`
+	IP         string          `form:"ip"`
+	From       time.Time       `form:"from"`
+	To         time.Time       `form:"to"`
+	Offset     int             `form:"offset"`
+	Limit      int             `form:"limit"`
+}
+
+const (
+	// DefaultAuditListLimit is a default value for AuditListParams.Limit
+	DefaultAuditListLimit = 100
+	// MaxAuditListLimit is a max value for AuditListParams.Limit
+	MaxAuditListLimit = 1000
+)
+
+// Normalize normalizes request's fields
+func (p *AuditListParams) Normalize() {
+	p.Actor = strings.TrimSpace(p.Actor)
+	p.Action = strings.TrimSpace(p.Action)
+	p.TargetID = strings.TrimSpace(p.TargetID)
+	p.ProjectID = strings.TrimSpace(p.ProjectID)
+	p.IP = strings.TrimSpace(p.IP)
+
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+
+	if p.Limit <= 0 {
+		p.Limit = DefaultAuditListLimit
+	}
+
+	if p.Limit > MaxAuditListLimit {
+		p.Limit = MaxAuditListLimit
+	}
+}
+
+// String converts an object to string
+func (p *AuditListParams) String() string {
+	return toJSON(&p)
+}
+
+// AuditPage is a page of audit log
+type AuditPage struct {
+	Entries AuditEntries `json:"entries"`
+	Total   int          `json:"total"`
+	Offset  int          `json:"offset"`
+	Limit   int          `json:"limit"`
+}
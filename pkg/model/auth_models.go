@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // AuthRequest contains parameters for authentication
 type AuthRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -11,13 +13,38 @@ func (p AuthRequest) String() string {
 	return toJSON(&p)
 }
 
-// AuthResponse contains a generated access token
+// AuthResponse contains a generated access token and a refresh token.
+// If user has to pass MFA, response contains a challenge instead
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	Token         string        `json:"token,omitempty"`
+	ExpiresAt     *time.Time    `json:"expiresAt,omitempty"`
+	RefreshToken  string        `json:"refreshToken,omitempty"`
+	User          *User         `json:"user,omitempty"`
+	MFA           *MFAChallenge `json:"mfa,omitempty"`
+	RecoveryCodes []string      `json:"recoveryCodes,omitempty"`
 }
 
 // String converts an object to string
 func (p AuthResponse) String() string {
+	return toJSON(&AuthResponse{ExpiresAt: p.ExpiresAt, User: p.User})
+}
+
+// AuthOptions describes available sign in methods
+type AuthOptions struct {
+	OIDC bool `json:"oidc"`
+}
+
+// String converts an object to string
+func (p AuthOptions) String() string {
 	return toJSON(&p)
 }
+
+// OIDCTicketRequest contains a one-time ticket issued after OpenID Connect login
+type OIDCTicketRequest struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+// String converts an object to string
+func (p OIDCTicketRequest) String() string {
+	return toJSON(&OIDCTicketRequest{})
+}
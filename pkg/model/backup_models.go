@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
@@ -24,6 +25,16 @@ type Backup struct {
 	StorageFilePath string     `json:"-"`
 	ProjectID       string     `json:"-"`
 	Length          int64      `json:"length"`
+	// Storage backend which holds backup file (empty means default backend)
+	Storage string `json:"storage,omitempty"`
+	// Why retention policy keeps the backup (e.g. "daily 2021-05-01, monthly 2021-05")
+	KeepReason string `json:"keepReason,omitempty"`
+	// Pinned backups are never deleted until pin is removed or expires
+	Pin *BackupPin `json:"pin,omitempty"`
+	// Locked backups can't be deleted until lock expires (WORM storage mode)
+	Lock *BackupLock `json:"lock,omitempty"`
+	// Time when backup has been moved to trash
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
 
 // String converts an object to string
@@ -33,3 +44,73 @@ func (p Backup) String() string {
 
 // Backups is a list of Backup
 type Backups []*Backup
+
+// BackupDeletion contains information about a deleted backup
+type BackupDeletion struct {
+	ID        int       `json:"id"`
+	BackupID  string    `json:"backupId"`
+	ProjectID string    `json:"projectId"`
+	FileName  string    `json:"filename"`
+	Time      time.Time `json:"time"`
+	Length    int64     `json:"length"`
+	DeletedAt time.Time `json:"deletedAt"`
+	Reason    string    `json:"reason"`
+}
+
+// String converts an object to string
+func (p BackupDeletion) String() string {
+	return toJSON(&p)
+}
+
+// BackupDeletions is a list of BackupDeletion
+type BackupDeletions []*BackupDeletion
+
+// DownloadLinkParams contains parameters for download link creation
+type DownloadLinkParams struct {
+	TTL string `json:"ttl"`
+}
+
+// String converts an object to string
+func (p *DownloadLinkParams) String() string {
+	return toJSON(&p)
+}
+
+// DefaultDownloadLinkTTL is a default lifetime of a download link
+const DefaultDownloadLinkTTL = time.Hour
+
+// Normalize normalizes request's fields
+func (p *DownloadLinkParams) Normalize() {
+	p.TTL = strings.TrimSpace(p.TTL)
+}
+
+// Lifetime parses download link lifetime (which can't exceed maxTTL)
+func (p *DownloadLinkParams) Lifetime(maxTTL time.Duration) (time.Duration, error) {
+	if p.TTL == "" {
+		if DefaultDownloadLinkTTL > maxTTL {
+			return maxTTL, nil
+		}
+		return DefaultDownloadLinkTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(p.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, NewError(EBadRequest, "\"%s\" is not a valid link lifetime", p.TTL)
+	}
+
+	if ttl > maxTTL {
+		return 0, NewError(EBadRequest, "link lifetime can't exceed %s", maxTTL)
+	}
+
+	return ttl, nil
+}
+
+// DownloadLink is a short-lived link to download a backup without any other credentials
+type DownloadLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// String converts an object to string
+func (p *DownloadLink) String() string {
+	return toJSON(&p)
+}
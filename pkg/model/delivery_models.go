@@ -0,0 +1,232 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/notify"
+)
+
+// NotificationChannel is a channel to deliver notifications through
+type NotificationChannel string
+
+const (
+	// NotificationChannelSlack means Slack notifications
+	NotificationChannelSlack NotificationChannel = "slack"
+
+	// NotificationChannelTelegram means Telegram notifications
+	NotificationChannelTelegram NotificationChannel = "telegram"
+
+	// NotificationChannelWebhook means webhook notifications
+	NotificationChannelWebhook NotificationChannel = "webhook"
+)
+
+// EventType is a type of notification event
+type EventType string
+
+const (
+	// EventBackupOutdated is raised when project backups are out of date
+	EventBackupOutdated EventType = "backup_outdated"
+
+	// EventDigest is raised when a scheduled digest report is generated
+	EventDigest EventType = "digest"
+
+	// EventLockout is raised when sign in (or access key use) is locked after too many failed attempts
+	EventLockout EventType = "lockout"
+
+	// EventQuotaWarning is raised when storage usage crosses a warning threshold of project's (or global) quota
+	EventQuotaWarning EventType = "quota_warning"
+
+	// EventDiskSpaceLow is raised when free disk space of file system storage drops below alert level
+	EventDiskSpaceLow EventType = "disk_space_low"
+)
+
+// DeliveryStatus is a status of notification delivery
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending means that delivery is waiting to be sent (or retried)
+	DeliveryStatusPending DeliveryStatus = "pending"
+
+	// DeliveryStatusSent means that delivery has been sent successfully
+	DeliveryStatusSent DeliveryStatus = "sent"
+
+	// DeliveryStatusDead means that all delivery attempts have failed
+	DeliveryStatusDead DeliveryStatus = "dead"
+
+	// DeliveryStatusSkipped means that delivery has not been sent because its channel is not configured
+	DeliveryStatusSkipped DeliveryStatus = "skipped"
+)
+
+// Notification actions
+const (
+	// ActionSilence24h silences project notifications for 24 hours
+	ActionSilence24h = "silence_24h"
+
+	// ActionAcknowledge acknowledges project backup problem
+	ActionAcknowledge = "acknowledge"
+
+	// ActionOpenProject opens project page in web UI
+	ActionOpenProject = "open_project"
+)
+
+// NotificationField is a titled value attached to notification
+type NotificationField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// NotificationAction is an interactive action attached to notification
+type NotificationAction struct {
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+	Value string `json:"value,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Style string `json:"style,omitempty"`
+}
+
+// NotificationMessage is a channel-independent notification content
+type NotificationMessage struct {
+	Title   string                `json:"title"`
+	Text    string                `json:"text"`
+	Emoji   string                `json:"emoji"`
+	Fields  []*NotificationField  `json:"fields,omitempty"`
+	Actions []*NotificationAction `json:"actions,omitempty"`
+	Payload json.RawMessage       `json:"payload,omitempty"`
+}
+
+// String converts an object to string
+func (p *NotificationMessage) String() string {
+	return toJSON(&p)
+}
+
+// ToSlackMessage converts message to a SlackMessage
+func (p *NotificationMessage) ToSlackMessage(to string) *notify.SlackMessage {
+	msg := &notify.SlackMessage{
+		To:      []string{to},
+		Title:   p.Title,
+		Text:    p.Text,
+		Emoji:   p.Emoji,
+		Fields:  make([]*notify.SlackField, len(p.Fields)),
+		Actions: make([]*notify.SlackAction, len(p.Actions)),
+	}
+
+	for i, field := range p.Fields {
+		msg.Fields[i] = &notify.SlackField{
+			Title: field.Title,
+			Value: field.Value,
+		}
+	}
+
+	for i, action := range p.Actions {
+		msg.Actions[i] = &notify.SlackAction{
+			ID:    action.ID,
+			Text:  action.Text,
+			Value: action.Value,
+			URL:   action.URL,
+			Style: action.Style,
+		}
+	}
+
+	return msg
+}
+
+// ToTelegramMessage converts message to a TelegramMessage
+func (p *NotificationMessage) ToTelegramMessage(to string) *notify.TelegramMessage {
+	text := p.Text
+	for _, field := range p.Fields {
+		text = fmt.Sprintf("%s\n%s: %s", text, field.Title, field.Value)
+	}
+
+	return &notify.TelegramMessage{
+		To:    []string{to},
+		Title: p.Title,
+		Text:  strings.TrimSpace(text),
+		Emoji: p.Emoji,
+	}
+}
+
+// ToWebhookMessage converts message to a WebhookMessage
+func (p *NotificationMessage) ToWebhookMessage(to string) *notify.WebhookMessage {
+	return &notify.WebhookMessage{
+		To:          []string{to},
+		PayloadJSON: p.Payload,
+	}
+}
+
+// Delivery contains information about a notification delivery to a single target
+type Delivery struct {
+	ID            int                  `json:"id"`
+	ProjectID     string               `json:"projectId"`
+	Event         EventType            `json:"event"`
+	Channel       NotificationChannel  `json:"channel"`
+	Target        string               `json:"target"`
+	Message       *NotificationMessage `json:"message"`
+	Status        DeliveryStatus       `json:"status"`
+	Attempts      int                  `json:"attempts"`
+	LastError     string               `json:"lastError"`
+	CreatedAt     time.Time            `json:"createdAt"`
+	NextAttemptAt *time.Time           `json:"nextAttemptAt"`
+	DeliveredAt   *time.Time           `json:"deliveredAt"`
+}
+
+// String converts an object to string
+func (p *Delivery) String() string {
+	return toJSON(&p)
+}
+
+// Deliveries is a list of Delivery
+type Deliveries []*Delivery
+
+// DeliveryCreateParams contains parameters for delivery creation
+type DeliveryCreateParams struct {
+	ProjectID string
+	Event     EventType
+	Channel   NotificationChannel
+	Target    string
+	Message   *NotificationMessage
+}
+
+// String converts an object to string
+func (p *DeliveryCreateParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *DeliveryCreateParams) Normalize() {
+	p.Target = strings.TrimSpace(p.Target)
+}
+
+// DeliveryListParams contains parameters to filter deliveries
+type DeliveryListParams struct {
+	ProjectID string         `form:"project"`
+	Status    DeliveryStatus `form:"status"`
+	Limit     int            `form:"limit"`
+}
+
+const (
+	// DefaultDeliveryListLimit is a default value for DeliveryListParams.Limit
+	DefaultDeliveryListLimit = 100
+	// MaxDeliveryListLimit is a max value for DeliveryListParams.Limit
+	MaxDeliveryListLimit = 1000
+)
+
+// Normalize normalizes request's fields
+func (p *DeliveryListParams) Normalize() {
+	p.ProjectID = strings.TrimSpace(p.ProjectID)
+
+	if p.Limit <= 0 {
+		p.Limit = DefaultDeliveryListLimit
+	}
+
+	if p.Limit > MaxDeliveryListLimit {
+		p.Limit = MaxDeliveryListLimit
+	}
+}
+
+// String converts an object to string
+func (p *DeliveryListParams) String() string {
+	return toJSON(&p)
+}
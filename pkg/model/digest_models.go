@@ -0,0 +1,124 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// DigestFormat is an output format of digest report
+type DigestFormat string
+
+const (
+	// DigestFormatMarkdown means Markdown digest report
+	DigestFormatMarkdown DigestFormat = "markdown"
+
+	// DigestFormatHTML means HTML digest report
+	DigestFormatHTML DigestFormat = "html"
+
+	// DigestFormatJSON means JSON digest report
+	DigestFormatJSON DigestFormat = "json"
+
+	// DigestFormatSlack means digest report in Slack's mrkdwn markup
+	DigestFormatSlack DigestFormat = "slack"
+)
+
+// DigestProject contains summary of a single project for a digest period
+type DigestProject struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	IsActive         bool         `json:"isActive"`
+	BackupStatus     BackupStatus `json:"backupStatus"`
+	LastBackupTime   *time.Time   `json:"lastBackupTime"`
+	LastBackupLength int64        `json:"lastBackupLength"`
+	StorageUsed      int64        `json:"storageUsed"`
+	BackupsReceived  int          `json:"backupsReceived"`
+	BytesReceived    int64        `json:"bytesReceived"`
+	BackupsPruned    int          `json:"backupsPruned"`
+	BytesPruned      int64        `json:"bytesPruned"`
+}
+
+// DigestTotals contains summary of all projects for a digest period
+type DigestTotals struct {
+	Projects        int   `json:"projects"`
+	Outdated        int   `json:"outdated"`
+	WithoutBackups  int   `json:"withoutBackups"`
+	StorageUsed     int64 `json:"storageUsed"`
+	BackupsReceived int   `json:"backupsReceived"`
+	BytesReceived   int64 `json:"bytesReceived"`
+	BackupsPruned   int   `json:"backupsPruned"`
+	BytesPruned     int64 `json:"bytesPruned"`
+}
+
+// Digest is a summary report across all projects
+type Digest struct {
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Projects []*DigestProject `json:"projects"`
+	Totals   *DigestTotals    `json:"totals"`
+}
+
+// String converts an object to string
+func (p *Digest) String() string {
+	return toJSON(&p)
+}
+
+// DigestParams contains parameters for digest report generation
+type DigestParams struct {
+	Period string       `form:"period"`
+	Format DigestFormat `form:"format"`
+}
+
+const (
+	// DefaultDigestPeriod is a default value for DigestParams.Period
+	DefaultDigestPeriod = 24 * time.Hour
+)
+
+// Normalize normalizes request's fields
+func (p *DigestParams) Normalize() {
+	p.Period = strings.ToLower(strings.TrimSpace(p.Period))
+	p.Format = DigestFormat(strings.ToLower(strings.TrimSpace(string(p.Format))))
+
+	if p.Format == "" || p.Format == "md" {
+		p.Format = DigestFormatMarkdown
+	}
+}
+
+// Validate validates request's fields
+func (p *DigestParams) Validate() error {
+	_, err := ParseDigestPeriod(p.Period)
+	if err != nil {
+		return err
+	}
+
+	switch p.Format {
+	case DigestFormatMarkdown, DigestFormatHTML, DigestFormatJSON, DigestFormatSlack:
+		return nil
+	}
+
+	return NewError(EBadRequest, "\"%s\" is not a valid digest format", p.Format)
+}
+
+// String converts an object to string
+func (p *DigestParams) String() string {
+	return toJSON(&p)
+}
+
+// ParseDigestPeriod parses digest period.
+// Accepts "daily", "weekly" or a duration string (e.g. "12h")
+func ParseDigestPeriod(str string) (time.Duration, error) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "":
+		return DefaultDigestPeriod, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	period, err := time.ParseDuration(str)
+	if err != nil || period <= 0 {
+		return 0, NewError(EBadRequest, "\"%s\" is not a valid digest period", str)
+	}
+
+	return period, nil
+}
@@ -23,6 +23,12 @@ const (
 
 	// EAccessDenied is an error code for an access error
 	EAccessDenied ECode = "access_denied"
+
+	// EQuotaExceeded is an error code for uploads rejected by a storage quota
+	EQuotaExceeded ECode = "quota_exceeded"
+
+	// ETooManyRequests is an error code for requests rejected after too many failed attempts
+	ETooManyRequests ECode = "too_many_requests"
 )
 
 // Error is a service error object
@@ -48,3 +54,9 @@ func NewError(code ECode, format string, a ...interface{}) error {
 		Message: fmt.Sprintf(format, a...),
 	}
 }
+
+// IsNotFound returns true if err is a "not found" service error
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.Code == ENotFound
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// BackupPin keeps a backup from being deleted (either by retention policy or manually)
+type BackupPin struct {
+	Reason   string    `json:"reason"`
+	PinnedBy string    `json:"pinnedBy"`
+	PinnedAt time.Time `json:"pinnedAt"`
+	// Pin expires at this time (or never if nil)
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// String converts an object to string
+func (p *BackupPin) String() string {
+	return toJSON(&p)
+}
+
+// IsActive returns true if pin hasn't expired yet
+func (p *BackupPin) IsActive(now time.Time) bool {
+	return p != nil && (p.Until == nil || now.Before(*p.Until))
+}
+
+// BackupPinParams contains parameters to pin a backup
+type BackupPinParams struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"`
+}
+
+// String converts an object to string
+func (p *BackupPinParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *BackupPinParams) Normalize() {
+	p.Reason = strings.TrimSpace(p.Reason)
+}
+
+// Validate validates request's fields
+func (p *BackupPinParams) Validate(now time.Time) error {
+	if p.Reason == "" {
+		return NewError(EBadRequest, "pin reason is required")
+	}
+
+	if p.Until != nil && !now.Before(*p.Until) {
+		return NewError(EBadRequest, "pin expiration time should be in the future")
+	}
+
+	return nil
+}
+
+// BackupLock is a WORM lock of backup's file, neither the file nor the backup can be deleted until lock expires
+type BackupLock struct {
+	// Storage lock mode ("governance" or "compliance")
+	Mode  string    `json:"mode"`
+	Until time.Time `json:"until"`
+}
+
+// String converts an object to string
+func (p *BackupLock) String() string {
+	return toJSON(&p)
+}
+
+// IsActive returns true if lock hasn't expired yet
+func (p *BackupLock) IsActive(now time.Time) bool {
+	return p != nil && now.Before(p.Until)
+}
+
+// LegalHold keeps all backups of a project from being deleted until it's released
+type LegalHold struct {
+	Reason   string    `json:"reason"`
+	PlacedBy string    `json:"placedBy"`
+	PlacedAt time.Time `json:"placedAt"`
+}
+
+// String converts an object to string
+func (p *LegalHold) String() string {
+	return toJSON(&p)
+}
+
+// LegalHoldParams contains parameters to put a project on legal hold
+type LegalHoldParams struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// String converts an object to string
+func (p *LegalHoldParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *LegalHoldParams) Normalize() {
+	p.Reason = strings.TrimSpace(p.Reason)
+}
+
+// Validate validates request's fields
+func (p *LegalHoldParams) Validate() error {
+	if p.Reason == "" {
+		return NewError(EBadRequest, "legal hold reason is required")
+	}
+
+	return nil
+}
+
+// CheckBackupDeletion returns an error if project's backup can't be deleted now
+// because it's pinned, locked or project is on legal hold
+func (p *Project) CheckBackupDeletion(backup *Backup, now time.Time) error {
+	if p.LegalHold != nil {
+		return NewError(EConflict, "project \"%s\" is on legal hold (%s)", p.ID, p.LegalHold.Reason)
+	}
+
+	if backup.Pin.IsActive(now) {
+		return NewError(EConflict, "backup \"%s\" is pinned (%s)", backup.ID, backup.Pin.Reason)
+	}
+
+	if backup.Lock.IsActive(now) {
+		return NewError(EConflict, "backup \"%s\" is locked until %s", backup.ID, backup.Lock.Until.Format(time.RFC3339))
+	}
+
+	return nil
+}
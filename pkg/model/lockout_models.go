@@ -0,0 +1,106 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// LockoutScope is a kind of failed attempts counter
+type LockoutScope string
+
+const (
+	// LockoutScopeUser counts failed sign in attempts for a username
+	LockoutScopeUser LockoutScope = "user"
+
+	// LockoutScopeIP counts failed sign in attempts from an IP address
+	LockoutScopeIP LockoutScope = "ip"
+
+	// LockoutScopeAccessKey counts invalid access keys sent from an IP address
+	LockoutScopeAccessKey LockoutScope = "access_key"
+)
+
+// IsValid returns true if scope is known
+func (s LockoutScope) IsValid() bool {
+	switch s {
+	case LockoutScopeUser, LockoutScopeIP, LockoutScopeAccessKey:
+		return true
+	}
+
+	return false
+}
+
+// LockoutKey identifies a failed attempts counter
+type LockoutKey struct {
+	Scope LockoutScope `json:"scope"`
+	// Username (in lower case) or IP address
+	Key string `json:"key"`
+}
+
+// NewLockoutKey creates a lockout key, usernames are case-insensitive
+func NewLockoutKey(scope LockoutScope, key string) LockoutKey {
+	key = strings.TrimSpace(key)
+	if scope == LockoutScopeUser {
+		key = strings.ToLower(key)
+	}
+
+	return LockoutKey{scope, key}
+}
+
+// String converts an object to string
+func (k LockoutKey) String() string {
+	return string(k.Scope) + ":" + k.Key
+}
+
+// Lockout contains state of a failed attempts counter
+type Lockout struct {
+	LockoutKey
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+}
+
+// String converts an object to string
+func (p *Lockout) String() string {
+	return toJSON(&p)
+}
+
+// IsLocked returns true if attempts are locked
+func (p *Lockout) IsLocked(now time.Time) bool {
+	return p.LockedUntil != nil && now.Before(*p.LockedUntil)
+}
+
+// Lockouts is a list of Lockout
+type Lockouts []*Lockout
+
+// LockoutPolicy defines how failed attempts are throttled
+type LockoutPolicy struct {
+	// Max number of failed attempts before a lockout, per scope (zero disables lockouts of a scope)
+	MaxFailures map[LockoutScope]int
+	// Failed attempts are forgotten after this period without new failures
+	Window time.Duration
+	// Duration of a lockout
+	Duration time.Duration
+	// Delay after first failed sign in attempt for a username, it doubles after each next failure (zero disables delays)
+	Delay time.Duration
+	// Max delay between sign in attempts
+	MaxDelay time.Duration
+}
+
+// DelayAfter returns how long next attempt is rejected after a number of failures
+func (p *LockoutPolicy) DelayAfter(scope LockoutScope, failures int) time.Duration {
+	// Delays apply to usernames only, so clients behind a shared address are not slowed down by each other
+	if scope != LockoutScopeUser || failures <= 0 || p.Delay <= 0 {
+		return 0
+	}
+
+	delay := p.Delay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// MFAEnrollment contains a new TOTP secret that should be added to an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	// otpauth:// provisioning URI
+	URI string `json:"uri"`
+	// Provisioning URI encoded as QR code (PNG data URL)
+	QRCode string `json:"qrCode"`
+}
+
+// String converts an object to string
+func (p *MFAEnrollment) String() string {
+	return toJSON(&MFAEnrollment{})
+}
+
+// MFAChallenge is returned instead of an access token if user has to enter a one-time code.
+// If user is required to use MFA but hasn't enrolled yet, challenge contains a pending enrollment,
+// unless an enrollment token is required to get one
+type MFAChallenge struct {
+	Token                   string         `json:"token"`
+	ExpiresAt               time.Time      `json:"expiresAt"`
+	Enrollment              *MFAEnrollment `json:"enrollment,omitempty"`
+	EnrollmentTokenRequired bool           `json:"enrollmentTokenRequired,omitempty"`
+}
+
+// String converts an object to string
+func (p *MFAChallenge) String() string {
+	return toJSON(&MFAChallenge{ExpiresAt: p.ExpiresAt})
+}
+
+// MFAVerifyRequest contains a one-time code (or a recovery code) to complete an MFA challenge
+type MFAVerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// String converts an object to string
+func (p MFAVerifyRequest) String() string {
+	return toJSON(&MFAVerifyRequest{})
+}
+
+// MFAEnrollRequest contains an enrollment token to get a pending enrollment of an MFA challenge
+type MFAEnrollRequest struct {
+	Token           string `json:"token" binding:"required"`
+	EnrollmentToken string `json:"enrollmentToken" binding:"required"`
+}
+
+// String converts an object to string
+func (p MFAEnrollRequest) String() string {
+	return toJSON(&MFAEnrollRequest{})
+}
+
+// MFAEnrollmentToken is a one-time token issued by admin that allows user to enroll while signing in.
+// Token is returned only once and is never shown again
+type MFAEnrollmentToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// String converts an object to string
+func (p *MFAEnrollmentToken) String() string {
+	return toJSON(&MFAEnrollmentToken{ExpiresAt: p.ExpiresAt})
+}
+
+// MFACodeRequest contains a one-time code (or a recovery code)
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// String converts an object to string
+func (p MFACodeRequest) String() string {
+	return toJSON(&MFACodeRequest{})
+}
+
+// MFARecoveryCodes contains one-time recovery codes.
+// Codes are returned only once and are never shown again
+type MFARecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+// String converts an object to string
+func (p *MFARecoveryCodes) String() string {
+	return toJSON(&MFARecoveryCodes{})
+}
+
+// NormalizeMFACode strips spaces and dashes that users tend to type in one-time codes
+func NormalizeMFACode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, " ", "")
+	code = strings.ReplaceAll(code, "-", "")
+	return code
+}
+
+// SecuritySettings contains global security settings
+type SecuritySettings struct {
+	// All users that sign in with a password have to use MFA
+	RequireMFA bool `json:"requireMfa"`
+	// Users that haven't enrolled yet need an enrollment token issued by admin to enroll while signing in
+	RequireMFAEnrollmentToken bool `json:"requireMfaEnrollmentToken"`
+}
+
+// String converts an object to string
+func (p *SecuritySettings) String() string {
+	return toJSON(&p)
+}
+
+// SecuritySettingsUpdateParams contains parameters to modify global security settings
+type SecuritySettingsUpdateParams struct {
+	RequireMFA                *bool `json:"requireMfa"`
+	RequireMFAEnrollmentToken *bool `json:"requireMfaEnrollmentToken"`
+}
+
+// String converts an object to string
+func (p *SecuritySettingsUpdateParams) String() string {
+	return toJSON(&p)
+}
+
+// ApplyTo applies request values to settings
+func (p *SecuritySettingsUpdateParams) ApplyTo(settings *SecuritySettings) {
+	if p.RequireMFA != nil {
+		settings.RequireMFA = *p.RequireMFA
+	}
+
+	if p.RequireMFAEnrollmentToken != nil {
+		settings.RequireMFAEnrollmentToken = *p.RequireMFAEnrollmentToken
+	}
+}
@@ -57,11 +57,22 @@ type Project struct {
 	Name             string              `json:"name"`
 	IsActive         bool                `json:"isActive"`
 	BackupRetention  int                 `json:"backupRetention"`
+	Retention        *RetentionRules     `json:"retention"`
+	Quota            *StorageQuota       `json:"quota"`
+	Storage          string              `json:"storage"`
 	BackupFrequency  int                 `json:"backupFrequency"`
 	Notifications    *NotificationParams `json:"notifications"`
 	BackupStatus     BackupStatus        `json:"backupStatus"`
 	LastBackup       *Backup             `json:"lastBackup"`
 	LastNotification *time.Time          `json:"-"`
+	SilencedUntil    *time.Time          `json:"silencedUntil"`
+	AcknowledgedAt   *time.Time          `json:"acknowledgedAt"`
+	AcknowledgedBy   string              `json:"acknowledgedBy"`
+	LastCheckIn      *time.Time          `json:"lastCheckIn"`
+	LegalHold        *LegalHold          `json:"legalHold"`
+	DeletedAt        *time.Time          `json:"deletedAt,omitempty"`
+	Tags             []string            `json:"tags"`
+	Labels           map[string]string   `json:"labels"`
 }
 
 const (
@@ -90,18 +101,84 @@ func (p *Project) CalcBackupStatus(lastBackup *Backup) BackupStatus {
 	return BackupStatusOk
 }
 
+// IsSilenced returns true if project notifications are silenced
+func (p *Project) IsSilenced(now time.Time) bool {
+	return p.SilencedUntil != nil && now.Before(*p.SilencedUntil)
+}
+
+// IsAcknowledged returns true if current project backup problem has been acknowledged
+func (p *Project) IsAcknowledged() bool {
+	return p.AcknowledgedAt != nil
+}
+
+// HasAnyTag returns true if project has any of specified tags
+func (p *Project) HasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		for _, t := range p.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Projects is a list of Project
 type Projects []*Project
 
+// CheckIn is a response to backup host's check-in
+type CheckIn struct {
+	ProjectID      string       `json:"projectId"`
+	BackupStatus   BackupStatus `json:"backupStatus"`
+	LastBackupTime *time.Time   `json:"lastBackupTime"`
+	CheckedInAt    time.Time    `json:"checkedInAt"`
+}
+
+// String converts an object to string
+func (p *CheckIn) String() string {
+	return toJSON(&p)
+}
+
+// ProjectSilenceParams contains parameters to silence project notifications
+type ProjectSilenceParams struct {
+	Duration string `json:"duration" binding:"required"`
+}
+
+// String converts an object to string
+func (p *ProjectSilenceParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *ProjectSilenceParams) Normalize() {
+	p.Duration = strings.TrimSpace(p.Duration)
+}
+
+// Until evaluates time until notifications are silenced
+func (p *ProjectSilenceParams) Until(now time.Time) (time.Time, error) {
+	duration, err := time.ParseDuration(p.Duration)
+	if err != nil || duration <= 0 {
+		return now, NewError(EBadRequest, "\"%s\" is not a valid silence duration", p.Duration)
+	}
+
+	return now.Add(duration), nil
+}
+
 // ProjectCreateParams contains parameters for project creation
 type ProjectCreateParams struct {
 	ID              string              `json:"id" binding:"required"`
 	Name            string              `json:"name" binding:"required"`
 	BackupRetention *int                `json:"backupRetention"`
+	Retention       *RetentionRules     `json:"retention"`
+	Quota           *StorageQuota       `json:"quota"`
+	Storage         string              `json:"storage"`
 	BackupFrequency *int                `json:"backupFrequency"`
 	Enable          *bool               `json:"isActive"`
 	Notifications   *NotificationParams `json:"notifications"`
 	Webhooks        *[]string           `json:"webhook"`
+	Tags            []string            `json:"tags"`
+	Labels          map[string]string   `json:"labels"`
 }
 
 // Normalize normalizes request's fields
@@ -113,6 +190,11 @@ func (p *ProjectCreateParams) Normalize() {
 	p.ID = r.ReplaceAllLiteralString(p.ID, "")
 
 	p.Name = strings.TrimSpace(p.Name)
+	p.Storage = strings.ToLower(strings.TrimSpace(p.Storage))
+	p.Tags = normalizeList(p.Tags, true)
+	p.Labels = normalizeLabels(p.Labels)
+	p.Retention.Normalize()
+	p.Quota.Normalize()
 }
 
 // Validate validates request's fields
@@ -126,6 +208,14 @@ func (p *ProjectCreateParams) Validate() error {
 		return NewError(EBadRequest, fmt.Sprintf("\"%d\" is not a valid backup retention", *p.BackupRetention))
 	}
 
+	if err := p.Retention.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.Quota.Validate(); err != nil {
+		return err
+	}
+
 	if p.BackupFrequency != nil && *p.BackupFrequency < 0 {
 		return NewError(EBadRequest, fmt.Sprintf("\"%d\" is not a valid backup check period", *p.BackupFrequency))
 	}
@@ -137,6 +227,8 @@ func (p *ProjectCreateParams) Validate() error {
 func (p *ProjectCreateParams) ApplyTo(proj *Project) {
 	proj.ID = p.ID
 	proj.Name = p.Name
+	proj.Tags = append([]string{}, p.Tags...)
+	proj.Labels = normalizeLabels(p.Labels)
 
 	if p.BackupRetention != nil {
 		proj.BackupRetention = *p.BackupRetention
@@ -144,6 +236,18 @@ func (p *ProjectCreateParams) ApplyTo(proj *Project) {
 		proj.BackupRetention = DefaultRetain
 	}
 
+	if !p.Retention.IsEmpty() {
+		rules := *p.Retention
+		proj.Retention = &rules
+	}
+
+	if !p.Quota.IsEmpty() {
+		quota := *p.Quota
+		proj.Quota = &quota
+	}
+
+	proj.Storage = p.Storage
+
 	if p.BackupFrequency != nil {
 		proj.BackupFrequency = *p.BackupFrequency
 	} else {
@@ -183,10 +287,57 @@ func (p *ProjectCreateParams) String() string {
 type ProjectUpdateParams struct {
 	Name             *string             `json:"name"`
 	BackupRetention  *int                `json:"backupRetention"`
+	Retention        *RetentionRules     `json:"retention"`
+	Quota            *StorageQuota       `json:"quota"`
+	Storage          *string             `json:"storage"`
 	BackupFrequency  *int                `json:"backupFrequency"`
 	IsActive         *bool               `json:"isActive"`
 	Notifications    *NotificationParams `json:"notifications"`
+	Tags             []string            `json:"tags"`
+	Labels           map[string]string   `json:"labels"`
 	LastNotification *time.Time          `json:"-"`
+	// Should be set to apply a retention policy change which deletes any backups
+	ConfirmDeletion bool `json:"confirmDeletion"`
+}
+
+// ChangesTags returns true if request changes project's tags (ignoring their order)
+func (p *ProjectUpdateParams) ChangesTags(project *Project) bool {
+	if p.Tags == nil {
+		return false
+	}
+
+	tags := make(map[string]bool)
+	for _, tag := range project.Tags {
+		tags[tag] = true
+	}
+
+	for _, tag := range p.Tags {
+		if !tags[tag] {
+			return true
+		}
+		delete(tags, tag)
+	}
+
+	return len(tags) > 0
+}
+
+// ChangesLabels returns true if request changes project's labels
+func (p *ProjectUpdateParams) ChangesLabels(project *Project) bool {
+	if p.Labels == nil {
+		return false
+	}
+
+	if len(p.Labels) != len(project.Labels) {
+		return true
+	}
+
+	for key, value := range p.Labels {
+		if v, ok := project.Labels[key]; !ok || v != value {
+			return true
+		}
+	}
+
+	return false
 }
 
 // Normalize normalizes request's fields
@@ -194,12 +345,31 @@ func (p *ProjectUpdateParams) Normalize() {
 	if p.Name != nil {
 		*p.Name = strings.TrimSpace(*p.Name)
 	}
+
+	if p.Storage != nil {
+		*p.Storage = strings.ToLower(strings.TrimSpace(*p.Storage))
+	}
+
+	if p.Tags != nil {
+		p.Tags = normalizeList(p.Tags, true)
+	}
+
+	if p.Labels != nil {
+		p.Labels = normalizeLabels(p.Labels)
+	}
+
+	p.Retention.Normalize()
+	p.Quota.Normalize()
 }
 
 // Validate validates request's fields
 func (p *ProjectUpdateParams) Validate() error {
-	if p.BackupRetention != nil && *p.BackupRetention < 0 {
-		return NewError(EBadRequest, fmt.Sprintf("\"%d\" is not a valid backup retention", *p.BackupRetention))
+	if err := p.RetentionParams().Validate(); err != nil {
+		return err
+	}
+
+	if err := p.Quota.Validate(); err != nil {
+		return err
 	}
 
 	if p.BackupFrequency != nil && *p.BackupFrequency < 0 {
@@ -209,6 +379,11 @@ func (p *ProjectUpdateParams) Validate() error {
 	return nil
 }
 
+// RetentionParams returns retention policy fields of request
+func (p *ProjectUpdateParams) RetentionParams() *RetentionParams {
+	return &RetentionParams{BackupRetention: p.BackupRetention, Retention: p.Retention}
+}
+
 // String converts an object to string
 func (p *ProjectUpdateParams) String() string {
 	return toJSON(&p)
@@ -220,8 +395,21 @@ func (p *ProjectUpdateParams) ApplyTo(proj *Project) {
 		proj.Name = *p.Name
 	}
 
-	if p.BackupRetention != nil {
-		proj.BackupRetention = *p.BackupRetention
+	p.RetentionParams().ApplyTo(proj)
+
+	if p.Quota != nil {
+		// A zero limit removes project's quota
+		if p.Quota.IsEmpty() {
+			proj.Quota = nil
+		} else {
+			quota := *p.Quota
+			proj.Quota = &quota
+		}
+	}
+
+	// Existing backups stay in their backends, only new ones go to the new backend
+	if p.Storage != nil {
+		proj.Storage = *p.Storage
 	}
 
 	if p.BackupFrequency != nil {
@@ -232,6 +420,18 @@ func (p *ProjectUpdateParams) ApplyTo(proj *Project) {
 		proj.IsActive = *p.IsActive
 	}
 
+	if p.Tags != nil {
+		proj.Tags = append([]string{}, p.Tags...)
+	}
+
+	if p.Labels != nil {
+		proj.Labels = normalizeLabels(p.Labels)
+	}
+
+	if p.LastNotification != nil {
+		proj.LastNotification = p.LastNotification
+	}
+
 	if p.Notifications != nil {
 		if proj.Notifications != nil {
 			p.Notifications.ApplyTo(proj.Notifications)
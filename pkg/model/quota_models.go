@@ -0,0 +1,292 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// QuotaPolicy defines what happens to an upload which exceeds a storage quota
+type QuotaPolicy string
+
+const (
+	// QuotaPolicyReject rejects uploads which exceed quota
+	QuotaPolicyReject QuotaPolicy = "reject"
+
+	// QuotaPolicyPrune deletes project's oldest backups early to make room for an upload
+	QuotaPolicyPrune QuotaPolicy = "prune"
+)
+
+// IsValid returns true if quota policy is known
+func (p QuotaPolicy) IsValid() bool {
+	return p == QuotaPolicyReject || p == QuotaPolicyPrune
+}
+
+// StorageQuota limits total length of backups (either of a project or of all projects)
+type StorageQuota struct {
+	// Max total length of backups in bytes
+	Limit  int64       `json:"limit"`
+	Policy QuotaPolicy `json:"policy"`
+}
+
+// String converts an object to string
+func (p *StorageQuota) String() string {
+	return toJSON(&p)
+}
+
+// IsEmpty returns true if quota doesn't limit anything
+func (p *StorageQuota) IsEmpty() bool {
+	return p == nil || p.Limit <= 0
+}
+
+// Equals returns true if both quotas limit the same (empty quotas are equal)
+func (p *StorageQuota) Equals(other *StorageQuota) bool {
+	if p.IsEmpty() || other.IsEmpty() {
+		return p.IsEmpty() && other.IsEmpty()
+	}
+
+	return p.Limit == other.Limit && p.Policy == other.Policy
+}
+
+// Normalize normalizes quota's fields
+func (p *StorageQuota) Normalize() {
+	if p != nil {
+		p.Policy = QuotaPolicy(strings.ToLower(strings.TrimSpace(string(p.Policy))))
+		if p.Policy == "" {
+			p.Policy = QuotaPolicyReject
+		}
+	}
+}
+
+// Validate validates quota's fields
+func (p *StorageQuota) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.Limit < 0 {
+		return NewError(EBadRequest, "\"%d\" is not a valid storage quota", p.Limit)
+	}
+
+	if !p.Policy.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid quota policy", p.Policy)
+	}
+
+	return nil
+}
+
+// Description returns a human-readable description of a quota
+func (p *StorageQuota) Description() string {
+	if p.IsEmpty() {
+		return "unlimited"
+	}
+
+	return fmt.Sprintf("%s (%s)", humanize.Bytes(uint64(p.Limit)), p.Policy)
+}
+
+// ParseStorageQuota parses a quota size (e.g. "500GB"), empty string means no quota
+func ParseStorageQuota(size string, policy string) (*StorageQuota, error) {
+	size = strings.TrimSpace(size)
+	if size == "" {
+		return nil, nil
+	}
+
+	limit, err := humanize.ParseBytes(size)
+	if err != nil {
+		return nil, fmt.Errorf("\"%s\" is not a valid storage quota", size)
+	}
+
+	quota := &StorageQuota{Limit: int64(limit), Policy: QuotaPolicy(policy)}
+	quota.Normalize()
+	if !quota.Policy.IsValid() {
+		return nil, fmt.Errorf("\"%s\" is not a valid quota policy", policy)
+	}
+
+	return quota, nil
+}
+
+// ParseQuotaThresholds parses a comma-separated list of warning thresholds in percents (e.g. "80,95")
+func ParseQuotaThresholds(str string) ([]int, error) {
+	thresholds := make([]int, 0)
+	for _, s := range strings.Split(str, ",") {
+		s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+		if s == "" {
+			continue
+		}
+
+		t, err := strconv.Atoi(s)
+		if err != nil || t <= 0 || t > 100 {
+			return nil, fmt.Errorf("\"%s\" is not a valid quota warning threshold", s)
+		}
+
+		thresholds = append(thresholds, t)
+	}
+
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// StorageUsage is a total length of backups compared to their quota
+type StorageUsage struct {
+	ProjectID string `json:"projectId,omitempty"`
+	// Total length of backups in bytes
+	Used  int64         `json:"used"`
+	Quota *StorageQuota `json:"quota"`
+	// Percent of quota which is used (zero if there's no quota)
+	Percent float64 `json:"percent"`
+}
+
+// String converts an object to string
+func (p *StorageUsage) String() string {
+	return toJSON(&p)
+}
+
+// NewStorageUsage evaluates usage of a quota
+func NewStorageUsage(projectID string, used int64, quota *StorageQuota) *StorageUsage {
+	usage := &StorageUsage{ProjectID: projectID, Used: used, Quota: quota}
+	if !quota.IsEmpty() {
+		usage.Percent = float64(used) * 100 / float64(quota.Limit)
+	}
+
+	return usage
+}
+
+// Exceeds returns true if usage would exceed the quota after an upload of specified length
+func (p *StorageUsage) Exceeds(length int64) bool {
+	return !p.Quota.IsEmpty() && p.Used+length > p.Quota.Limit
+}
+
+// IsFull returns true if no more bytes fit into the quota
+func (p *StorageUsage) IsFull() bool {
+	return !p.Quota.IsEmpty() && p.Used >= p.Quota.Limit
+}
+
+// Description returns a human-readable description of a usage
+func (p *StorageUsage) Description() string {
+	if p.Quota.IsEmpty() {
+		return humanize.Bytes(uint64(p.Used))
+	}
+
+	return fmt.Sprintf("%s of %s (%.0f%%)", humanize.Bytes(uint64(p.Used)), humanize.Bytes(uint64(p.Quota.Limit)), p.Percent)
+}
+
+// CrossedThreshold returns the highest warning threshold (in percents) crossed when usage grows
+// from this usage to "after" usage, or zero if no threshold has been crossed
+func (p *StorageUsage) CrossedThreshold(thresholds []int, after *StorageUsage) int {
+	crossed := 0
+	if p.Quota.IsEmpty() {
+		return crossed
+	}
+
+	for _, t := range thresholds {
+		if p.Percent < float64(t) && after.Percent >= float64(t) {
+			crossed = t
+		}
+	}
+
+	return crossed
+}
+
+// DiskSpace is a free space of storage's disk
+type DiskSpace struct {
+	Free  uint64 `json:"free"`
+	Total uint64 `json:"total"`
+	// Percent of disk space which is free
+	Percent float64 `json:"percent"`
+}
+
+// String converts an object to string
+func (p *DiskSpace) String() string {
+	return toJSON(&p)
+}
+
+// NewDiskSpace creates a disk space report
+func NewDiskSpace(free, total uint64) *DiskSpace {
+	space := &DiskSpace{Free: free, Total: total}
+	if total > 0 {
+		space.Percent = float64(free) * 100 / float64(total)
+	}
+
+	return space
+}
+
+// DiskSpaceAlert is a level of free disk space to alert at
+type DiskSpaceAlert struct {
+	// Alert if free space is less than this number of bytes
+	Bytes uint64
+	// Alert if free space is less than this percent of disk
+	Percent float64
+}
+
+// ParseDiskSpaceAlert parses an alert level, either a size (e.g. "10GB") or a percent of disk (e.g. "5%").
+// Empty string means no alerts
+func ParseDiskSpaceAlert(str string) (*DiskSpaceAlert, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return nil, nil
+	}
+
+	if strings.HasSuffix(str, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+		if err != nil || percent <= 0 || percent >= 100 {
+			return nil, fmt.Errorf("\"%s\" is not a valid free disk space alert level", str)
+		}
+
+		return &DiskSpaceAlert{Percent: percent}, nil
+	}
+
+	bytes, err := humanize.ParseBytes(str)
+	if err != nil || bytes == 0 {
+		return nil, fmt.Errorf("\"%s\" is not a valid free disk space alert level", str)
+	}
+
+	return &DiskSpaceAlert{Bytes: bytes}, nil
+}
+
+// IsLow returns true if free disk space is below alert level
+func (p *DiskSpaceAlert) IsLow(space *DiskSpace) bool {
+	if p.Percent > 0 {
+		return space.Percent < p.Percent
+	}
+
+	return space.Free < p.Bytes
+}
+
+// String returns a human-readable alert level
+func (p *DiskSpaceAlert) String() string {
+	if p.Percent > 0 {
+		return fmt.Sprintf("%g%%", p.Percent)
+	}
+
+	return humanize.Bytes(p.Bytes)
+}
+
+// StorageBackend is a named storage backend which holds backup files
+type StorageBackend struct {
+	Name string `json:"name"`
+	// Either "fs" or "s3"
+	Type      string `json:"type"`
+	IsDefault bool   `json:"isDefault"`
+	LockMode  string `json:"lockMode,omitempty"`
+	// Free disk space (only for file system storage)
+	Disk *DiskSpace `json:"disk,omitempty"`
+}
+
+// String converts an object to string
+func (p *StorageBackend) String() string {
+	return toJSON(&p)
+}
+
+// StorageStatus contains global storage usage
+type StorageStatus struct {
+	Usage    *StorageUsage     `json:"usage"`
+	Backends []*StorageBackend `json:"backends"`
+}
+
+// String converts an object to string
+func (p *StorageStatus) String() string {
+	return toJSON(&p)
+}
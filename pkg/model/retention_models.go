@@ -0,0 +1,417 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// RetentionRules is project's retention policy.
+// GFS (grandfather-father-son) rules keep the newest backup of each of N most recent periods
+// (days, weeks, months or years) that have any backups. If project has no GFS rules, newest BackupRetention backups are kept instead.
+// Backups younger than KeepWithin are kept as well.
+// Then MaxSize drops oldest kept backups until their total length fits the quota,
+// but newest MinCount backups always survive
+type RetentionRules struct {
+	Daily   int `json:"daily"`
+	Weekly  int `json:"weekly"`
+	Monthly int `json:"monthly"`
+	Yearly  int `json:"yearly"`
+	// Keep all backups younger than this age (e.g. "30d" or "12h")
+	KeepWithin string `json:"keepWithin,omitempty"`
+	// Max total length of project's backups in bytes
+	MaxSize int64 `json:"maxSize,omitempty"`
+	// Number of newest backups which are never deleted by retention policy
+	MinCount int `json:"minCount,omitempty"`
+}
+
+// String converts an object to string
+func (p *RetentionRules) String() string {
+	return toJSON(&p)
+}
+
+// IsEmpty returns true if rule set doesn't define any rule (so plain backup count is used instead)
+func (p *RetentionRules) IsEmpty() bool {
+	return p == nil || !p.hasBuckets() && p.keepWithin() <= 0 && p.MaxSize <= 0 && p.MinCount <= 0
+}
+
+func (p *RetentionRules) hasBuckets() bool {
+	return p != nil && (p.Daily > 0 || p.Weekly > 0 || p.Monthly > 0 || p.Yearly > 0)
+}
+
+func (p *RetentionRules) keepWithin() time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	age, _ := ParseRetentionAge(p.KeepWithin)
+	return age
+}
+
+// Normalize normalizes rule set's fields
+func (p *RetentionRules) Normalize() {
+	if p != nil {
+		p.KeepWithin = strings.TrimSpace(p.KeepWithin)
+	}
+}
+
+// Validate validates rule set's fields
+func (p *RetentionRules) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.Daily < 0 || p.Weekly < 0 || p.Monthly < 0 || p.Yearly < 0 || p.MinCount < 0 {
+		return NewError(EBadRequest, "retention rule counts can't be negative")
+	}
+
+	if p.MaxSize < 0 {
+		return NewError(EBadRequest, "\"%d\" is not a valid size quota", p.MaxSize)
+	}
+
+	_, err := ParseRetentionAge(p.KeepWithin)
+	return err
+}
+
+// ParseRetentionAge parses backup age.
+// Accepts a number of days (e.g. "30d") or a duration string (e.g. "12h"), empty string means no limit
+func ParseRetentionAge(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(str, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(str, "d"))
+		if err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	} else {
+		age, err := time.ParseDuration(str)
+		if err == nil && age > 0 {
+			return age, nil
+		}
+	}
+
+	return 0, NewError(EBadRequest, "\"%s\" is not a valid backup age", str)
+}
+
+// Description returns a human-readable description of a rule set
+func (p *RetentionRules) Description() string {
+	parts := make([]string, 0)
+	for _, bucket := range p.buckets() {
+		if bucket.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", bucket.count, bucket.name))
+		}
+	}
+
+	if p.keepWithin() > 0 {
+		parts = append(parts, fmt.Sprintf("everything within %s", p.KeepWithin))
+	}
+
+	if p.MaxSize > 0 {
+		parts = append(parts, fmt.Sprintf("at most %s", humanize.Bytes(uint64(p.MaxSize))))
+	}
+
+	if p.MinCount > 0 {
+		parts = append(parts, fmt.Sprintf("at least %d backup(s)", p.MinCount))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// RetentionDescription returns a human-readable description of project's retention policy
+func (p *Project) RetentionDescription() string {
+	if p.Retention.hasBuckets() {
+		return p.Retention.Description()
+	}
+
+	if p.Retention.IsEmpty() {
+		return fmt.Sprintf("%d backup(s)", p.BackupRetention)
+	}
+
+	return fmt.Sprintf("%d backup(s), %s", p.BackupRetention, p.Retention.Description())
+}
+
+// RetentionPeriod estimates how long a new backup is kept by project's retention policy.
+// It's the longest of GFS rules' periods (or of BackupRetention check periods) and KeepWithin age
+func (p *Project) RetentionPeriod() time.Duration {
+	const day = 24 * time.Hour
+
+	var period time.Duration
+	if p.Retention.hasBuckets() {
+		periods := []time.Duration{
+			time.Duration(p.Retention.Daily) * day,
+			time.Duration(p.Retention.Weekly) * 7 * day,
+			time.Duration(p.Retention.Monthly) * 31 * day,
+			time.Duration(p.Retention.Yearly) * 366 * day,
+		}
+		for _, d := range periods {
+			if d > period {
+				period = d
+			}
+		}
+	} else {
+		period = time.Duration(p.BackupRetention) * time.Duration(p.BackupFrequency) * time.Second
+	}
+
+	if within := p.Retention.keepWithin(); within > period {
+		period = within
+	}
+
+	return period
+}
+
+// retentionBucket is a single rule of GFS rule set
+type retentionBucket struct {
+	name  string
+	count int
+	key   func(t time.Time) string
+}
+
+func (p *RetentionRules) buckets() []*retentionBucket {
+	return []*retentionBucket{
+		{"daily", p.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", p.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%04d-W%02d", year, week)
+		}},
+		{"monthly", p.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{"yearly", p.Yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+}
+
+// RetentionDecision tells whether a backup is kept by project's retention policy and why
+type RetentionDecision struct {
+	Backup *Backup `json:"backup"`
+	Keep   bool    `json:"keep"`
+	Reason string  `json:"reason"`
+}
+
+// RetentionPlan contains a decision for each of project's backups (newest first)
+type RetentionPlan []*RetentionDecision
+
+// Kept returns backups which are kept by retention policy
+func (p RetentionPlan) Kept() Backups {
+	backups := make(Backups, 0)
+	for _, d := range p {
+		if d.Keep {
+			backups = append(backups, d.Backup)
+		}
+	}
+
+	return backups
+}
+
+// Dropped returns decisions for backups which should be deleted by retention policy
+func (p RetentionPlan) Dropped() RetentionPlan {
+	plan := make(RetentionPlan, 0)
+	for _, d := range p {
+		if !d.Keep {
+			plan = append(plan, d)
+		}
+	}
+
+	return plan
+}
+
+// PlanRetention evaluates which of project's backups are kept by project's retention policy and why
+func (p *Project) PlanRetention(backups []*Backup, now time.Time) RetentionPlan {
+	sorted := append([]*Backup{}, backups...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.After(sorted[j].Time)
+	})
+
+	rules := p.Retention
+	if rules == nil {
+		rules = &RetentionRules{}
+	}
+
+	// Backups which are kept by GFS rules (or by backup count), or are young enough
+	var dropReason string
+	reasons := make(map[string][]string)
+	if rules.hasBuckets() {
+		dropReason = "not retained by GFS rules"
+		for _, bucket := range rules.buckets() {
+			seen := make(map[string]bool)
+			for _, backup := range sorted {
+				if len(seen) >= bucket.count {
+					break
+				}
+
+				key := bucket.key(backup.Time.UTC())
+				if seen[key] {
+					continue
+				}
+
+				seen[key] = true
+				reasons[backup.ID] = append(reasons[backup.ID], fmt.Sprintf("%s %s", bucket.name, key))
+			}
+		}
+	} else {
+		dropReason = fmt.Sprintf("exceeded backup count (%d)", p.BackupRetention)
+		for i, backup := range sorted {
+			if i >= p.BackupRetention {
+				break
+			}
+
+			reasons[backup.ID] = []string{fmt.Sprintf("last %d", p.BackupRetention)}
+		}
+	}
+
+	if within := rules.keepWithin(); within > 0 {
+		dropReason = fmt.Sprintf("%s and older than %s", dropReason, rules.KeepWithin)
+		for _, backup := range sorted {
+			if now.Sub(backup.Time) < within {
+				reasons[backup.ID] = append(reasons[backup.ID], fmt.Sprintf("within %s", rules.KeepWithin))
+			}
+		}
+	}
+
+	plan := make(RetentionPlan, len(sorted))
+	for i, backup := range sorted {
+		plan[i] = &RetentionDecision{Backup: backup}
+		if r, ok := reasons[backup.ID]; ok {
+			plan[i].Keep = true
+			plan[i].Reason = strings.Join(r, ", ")
+		} else {
+			plan[i].Reason = dropReason
+		}
+	}
+
+	// Drop oldest kept backups which don't fit size quota.
+	// Unknown lengths (zero or negative) are not counted, just like in storage quotas
+	if rules.MaxSize > 0 {
+		var total int64
+		for i, d := range plan {
+			if !d.Keep {
+				continue
+			}
+
+			if d.Backup.Length > 0 {
+				total += d.Backup.Length
+			}
+			if total > rules.MaxSize && i >= rules.MinCount {
+				d.Keep = false
+				d.Reason = fmt.Sprintf("exceeded size quota (%s)", humanize.Bytes(uint64(rules.MaxSize)))
+			}
+		}
+	}
+
+	// Newest backups always survive
+	for i := 0; i < rules.MinCount && i < len(plan); i++ {
+		if !plan[i].Keep {
+			plan[i].Keep = true
+			plan[i].Reason = fmt.Sprintf("minimum %d", rules.MinCount)
+		}
+	}
+
+	// Pinned and locked backups and backups of projects on legal hold are never deleted
+	for _, d := range plan {
+		if d.Keep {
+			continue
+		}
+
+		if p.LegalHold != nil {
+			d.Keep = true
+			d.Reason = "legal hold"
+		} else if d.Backup.Pin.IsActive(now) {
+			d.Keep = true
+			d.Reason = "pinned"
+		} else if d.Backup.Lock.IsActive(now) {
+			d.Keep = true
+			d.Reason = fmt.Sprintf("locked until %s", d.Backup.Lock.Until.Format("2006-01-02"))
+		}
+	}
+
+	return plan
+}
+
+// RetentionParams contains a proposed retention policy of a project
+type RetentionParams struct {
+	BackupRetention *int            `json:"backupRetention"`
+	Retention       *RetentionRules `json:"retention"`
+}
+
+// String converts an object to string
+func (p *RetentionParams) String() string {
+	return toJSON(&p)
+}
+
+// IsSet returns true if any of policy's fields is set
+func (p *RetentionParams) IsSet() bool {
+	return p.BackupRetention != nil || p.Retention != nil
+}
+
+// Normalize normalizes request's fields
+func (p *RetentionParams) Normalize() {
+	p.Retention.Normalize()
+}
+
+// Validate validates request's fields
+func (p *RetentionParams) Validate() error {
+	if p.BackupRetention != nil && *p.BackupRetention < 0 {
+		return NewError(EBadRequest, fmt.Sprintf("\"%d\" is not a valid backup retention", *p.BackupRetention))
+	}
+
+	return p.Retention.Validate()
+}
+
+// ApplyTo applies request values to a Project
+func (p *RetentionParams) ApplyTo(proj *Project) {
+	if p.BackupRetention != nil {
+		proj.BackupRetention = *p.BackupRetention
+	}
+
+	if p.Retention != nil {
+		// An empty rule set switches a project back to plain backup count
+		if p.Retention.IsEmpty() {
+			proj.Retention = nil
+		} else {
+			rules := *p.Retention
+			proj.Retention = &rules
+		}
+	}
+}
+
+// RetentionPreview describes what a retention policy would do to project's backups
+type RetentionPreview struct {
+	// Backups which would be deleted (newest first)
+	Deleted RetentionPlan `json:"deleted"`
+	// Number of backups which would be kept
+	Kept int `json:"kept"`
+	// Total length of backups which would be deleted
+	FreedBytes int64 `json:"freedBytes"`
+	// Total length of backups which would be kept
+	KeptBytes int64 `json:"keptBytes"`
+}
+
+// String converts an object to string
+func (p *RetentionPreview) String() string {
+	return toJSON(&p)
+}
+
+// NewRetentionPreview summarizes a retention plan
+func NewRetentionPreview(plan RetentionPlan) *RetentionPreview {
+	preview := &RetentionPreview{Deleted: make(RetentionPlan, 0)}
+	for _, d := range plan {
+		var length int64
+		if d.Backup.Length > 0 {
+			length = d.Backup.Length
+		}
+
+		if d.Keep {
+			preview.Kept++
+			preview.KeptBytes += length
+		} else {
+			preview.Deleted = append(preview.Deleted, d)
+			preview.FreedBytes += length
+		}
+	}
+
+	return preview
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// Retention tests are evaluated at this time (Tuesday of ISO week 24)
+var retentionTestNow = time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+
+// retentionTestBackup creates a backup of given age and length, which ID is its age (e.g. "25h0m0s")
+func retentionTestBackup(age time.Duration, length int64) *Backup {
+	return &Backup{ID: age.String(), Time: retentionTestNow.Add(-age), Length: length}
+}
+
+func TestPlanRetention(t *testing.T) {
+	const day = 24 * time.Hour
+
+	pinned := retentionTestBackup(3*day, 100)
+	pinned.Pin = &BackupPin{Reason: "audit", PinnedBy: "admin"}
+
+	expiredUntil := retentionTestNow.Add(-time.Hour)
+	expired := retentionTestBackup(3*day, 100)
+	expired.Pin = &BackupPin{Reason: "audit", PinnedBy: "admin", Until: &expiredUntil}
+
+	locked := retentionTestBackup(3*day, 100)
+	locked.Lock = &BackupLock{Mode: "governance", Until: retentionTestNow.Add(day)}
+
+	pinnedNewest := retentionTestBackup(time.Hour, 100)
+	pinnedNewest.Pin = &BackupPin{Reason: "audit", PinnedBy: "admin"}
+
+	cases := []struct {
+		name    string
+		project *Project
+		backups []*Backup
+		// Reasons of kept backups by ID, other backups are dropped with dropped reason
+		kept    map[string]string
+		dropped string
+	}{
+		{
+			name:    "backup count",
+			project: &Project{BackupRetention: 2},
+			backups: []*Backup{
+				retentionTestBackup(3*day, 100),
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(2*day, 100),
+				retentionTestBackup(day, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "last 2", "24h0m0s": "last 2"},
+			dropped: "exceeded backup count (2)",
+		},
+		{
+			name:    "daily keeps newest backup of each day",
+			project: &Project{BackupRetention: 10, Retention: &RetentionRules{Daily: 2}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(2*time.Hour, 100),
+				retentionTestBackup(25*time.Hour, 100),
+				retentionTestBackup(49*time.Hour, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "daily 2021-06-15", "25h0m0s": "daily 2021-06-14"},
+			dropped: "not retained by GFS rules",
+		},
+		{
+			name:    "daily, weekly and monthly",
+			project: &Project{Retention: &RetentionRules{Daily: 1, Weekly: 2, Monthly: 2}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(7*day, 100),
+				retentionTestBackup(30*day, 100),
+				retentionTestBackup(31*day, 100),
+			},
+			kept: map[string]string{
+				"1h0m0s":   "daily 2021-06-15, weekly 2021-W24, monthly 2021-06",
+				"168h0m0s": "weekly 2021-W23",
+				"720h0m0s": "monthly 2021-05",
+			},
+			dropped: "not retained by GFS rules",
+		},
+		{
+			name:    "keep within",
+			project: &Project{BackupRetention: 1, Retention: &RetentionRules{KeepWithin: "2d"}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(30*time.Hour, 100),
+				retentionTestBackup(50*time.Hour, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "last 1, within 2d", "30h0m0s": "within 2d"},
+			dropped: "exceeded backup count (1) and older than 2d",
+		},
+		{
+			name:    "max size drops oldest kept backups",
+			project: &Project{BackupRetention: 10, Retention: &RetentionRules{MaxSize: 250}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(day, 100),
+				retentionTestBackup(2*day, 100),
+				retentionTestBackup(3*day, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "last 10", "24h0m0s": "last 10"},
+			dropped: "exceeded size quota (250 B)",
+		},
+		{
+			name:    "max size doesn't count unknown lengths",
+			project: &Project{BackupRetention: 10, Retention: &RetentionRules{MaxSize: 150}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(day, 0),
+				retentionTestBackup(2*day, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "last 10", "24h0m0s": "last 10"},
+			dropped: "exceeded size quota (150 B)",
+		},
+		{
+			name:    "min count overrides max size",
+			project: &Project{BackupRetention: 10, Retention: &RetentionRules{MaxSize: 150, MinCount: 3}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(day, 100),
+				retentionTestBackup(2*day, 100),
+				retentionTestBackup(3*day, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "last 10", "24h0m0s": "last 10", "48h0m0s": "last 10"},
+			dropped: "exceeded size quota (150 B)",
+		},
+		{
+			name:    "min count overrides backup count",
+			project: &Project{BackupRetention: 1, Retention: &RetentionRules{MinCount: 2}},
+			backups: []*Backup{
+				retentionTestBackup(time.Hour, 100),
+				retentionTestBackup(day, 100),
+				retentionTestBackup(2*day, 100),
+			},
+			kept:    map[string]string{"1h0m0s": "last 1", "24h0m0s": "minimum 2"},
+			dropped: "exceeded backup count (1)",
+		},
+		{
+			name:    "pinned backup is kept",
+			project: &Project{BackupRetention: 1},
+			backups: []*Backup{retentionTestBackup(time.Hour, 100), retentionTestBackup(day, 100), pinned},
+			kept:    map[string]string{"1h0m0s": "last 1", "72h0m0s": "pinned"},
+			dropped: "exceeded backup count (1)",
+		},
+		{
+			name:    "expired pin doesn't keep backup",
+			project: &Project{BackupRetention: 1},
+			backups: []*Backup{retentionTestBackup(time.Hour, 100), expired},
+			kept:    map[string]string{"1h0m0s": "last 1"},
+			dropped: "exceeded backup count (1)",
+		},
+		{
+			name:    "locked backup is kept",
+			project: &Project{BackupRetention: 1},
+			backups: []*Backup{retentionTestBackup(time.Hour, 100), locked},
+			kept:    map[string]string{"1h0m0s": "last 1", "72h0m0s": "locked until 2021-06-16"},
+			dropped: "exceeded backup count (1)",
+		},
+		{
+			name:    "retention reason takes precedence over pin",
+			project: &Project{BackupRetention: 1},
+			backups: []*Backup{pinnedNewest, retentionTestBackup(day, 100)},
+			kept:    map[string]string{"1h0m0s": "last 1"},
+			dropped: "exceeded backup count (1)",
+		},
+		{
+			name:    "legal hold takes precedence over pin and lock",
+			project: &Project{BackupRetention: 1, LegalHold: &LegalHold{Reason: "lawsuit"}},
+			backups: []*Backup{retentionTestBackup(time.Hour, 100), retentionTestBackup(day, 100), pinned},
+			kept:    map[string]string{"1h0m0s": "last 1", "24h0m0s": "legal hold", "72h0m0s": "legal hold"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plan := c.project.PlanRetention(c.backups, retentionTestNow)
+			if len(plan) != len(c.backups) {
+				t.Fatalf("expected %d decisions, got %d", len(c.backups), len(plan))
+			}
+
+			for i, d := range plan {
+				if i > 0 && d.Backup.Time.After(plan[i-1].Backup.Time) {
+					t.Errorf("expected plan to be sorted from newest to oldest, got %s before %s", plan[i-1].Backup.ID, d.Backup.ID)
+				}
+
+				reason, keep := c.kept[d.Backup.ID]
+				if !keep {
+					reason = c.dropped
+				}
+
+				if d.Keep != keep || d.Reason != reason {
+					t.Errorf("backup %s: expected keep=%v (%q), got keep=%v (%q)", d.Backup.ID, keep, reason, d.Keep, d.Reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package model
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Severity is a severity of notification event
+type Severity string
+
+const (
+	// SeverityInfo means an informational event
+	SeverityInfo Severity = "info"
+
+	// SeverityWarning means an event that requires attention
+	SeverityWarning Severity = "warning"
+
+	// SeverityCritical means an event that requires immediate attention
+	SeverityCritical Severity = "critical"
+)
+
+// Rank returns a comparable rank of severity (or -1 if severity is not valid)
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 0
+	case SeverityWarning:
+		return 1
+	case SeverityCritical:
+		return 2
+	}
+
+	return -1
+}
+
+// Severity returns default severity of an event
+func (e EventType) Severity() Severity {
+	switch e {
+	case EventBackupOutdated, EventLockout, EventQuotaWarning:
+		return SeverityWarning
+	case EventDiskSpaceLow:
+		return SeverityCritical
+	}
+
+	return SeverityInfo
+}
+
+// IsValid returns true if event type is known
+func (e EventType) IsValid() bool {
+	switch e {
+	case EventBackupOutdated, EventDigest, EventLockout, EventQuotaWarning, EventDiskSpaceLow:
+		return true
+	}
+
+	return false
+}
+
+// IsValid returns true if notification channel is known
+func (c NotificationChannel) IsValid() bool {
+	switch c {
+	case NotificationChannelSlack, NotificationChannelTelegram, NotificationChannelWebhook:
+		return true
+	}
+
+	return false
+}
+
+var targetNameRegexp = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// NotificationTarget is a named, reusable notification target
+type NotificationTarget struct {
+	Name        string              `json:"name"`
+	Channel     NotificationChannel `json:"channel"`
+	Address     string              `json:"address"`
+	Description string              `json:"description"`
+}
+
+// String converts an object to string
+func (p *NotificationTarget) String() string {
+	return toJSON(&p)
+}
+
+// NotificationTargets is a list of NotificationTarget
+type NotificationTargets []*NotificationTarget
+
+// NotificationTargetCreateParams contains parameters for notification target creation
+type NotificationTargetCreateParams struct {
+	Name        string              `json:"name" binding:"required"`
+	Channel     NotificationChannel `json:"channel" binding:"required"`
+	Address     string              `json:"address" binding:"required"`
+	Description string              `json:"description"`
+}
+
+// String converts an object to string
+func (p *NotificationTargetCreateParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *NotificationTargetCreateParams) Normalize() {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	p.Address = strings.TrimSpace(p.Address)
+	p.Description = strings.TrimSpace(p.Description)
+}
+
+// Validate validates request's fields
+func (p *NotificationTargetCreateParams) Validate() error {
+	if !targetNameRegexp.MatchString(p.Name) {
+		return NewError(EBadRequest, "\"%s\" is not a valid target name", p.Name)
+	}
+
+	if !p.Channel.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid notification channel", p.Channel)
+	}
+
+	if p.Address == "" {
+		return NewError(EBadRequest, "target address is required")
+	}
+
+	return nil
+}
+
+// ApplyTo applies request values to a NotificationTarget
+func (p *NotificationTargetCreateParams) ApplyTo(target *NotificationTarget) {
+	target.Name = p.Name
+	target.Channel = p.Channel
+	target.Address = p.Address
+	target.Description = p.Description
+}
+
+// NotificationTargetUpdateParams contains parameters for notification target modification
+type NotificationTargetUpdateParams struct {
+	Channel     *NotificationChannel `json:"channel"`
+	Address     *string              `json:"address"`
+	Description *string              `json:"description"`
+}
+
+// String converts an object to string
+func (p *NotificationTargetUpdateParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *NotificationTargetUpdateParams) Normalize() {
+	if p.Address != nil {
+		*p.Address = strings.TrimSpace(*p.Address)
+	}
+
+	if p.Description != nil {
+		*p.Description = strings.TrimSpace(*p.Description)
+	}
+}
+
+// Validate validates request's fields
+func (p *NotificationTargetUpdateParams) Validate() error {
+	if p.Channel != nil && !p.Channel.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid notification channel", *p.Channel)
+	}
+
+	if p.Address != nil && *p.Address == "" {
+		return NewError(EBadRequest, "target address is required")
+	}
+
+	return nil
+}
+
+// ApplyTo applies request values to a NotificationTarget
+func (p *NotificationTargetUpdateParams) ApplyTo(target *NotificationTarget) {
+	if p.Channel != nil {
+		target.Channel = *p.Channel
+	}
+
+	if p.Address != nil {
+		target.Address = *p.Address
+	}
+
+	if p.Description != nil {
+		target.Description = *p.Description
+	}
+}
+
+// RoutingRule routes matching notification events to named targets.
+// Empty criteria match anything
+type RoutingRule struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Position    int               `json:"position"`
+	IsActive    bool              `json:"isActive"`
+	Projects    []string          `json:"projects"`
+	Tags        []string          `json:"tags"`
+	Labels      map[string]string `json:"labels"`
+	Events      []EventType       `json:"events"`
+	MinSeverity Severity          `json:"minSeverity"`
+	Targets     []string          `json:"targets"`
+	Stop        bool              `json:"stop"`
+}
+
+// String converts an object to string
+func (p *RoutingRule) String() string {
+	return toJSON(&p)
+}
+
+// Match returns true if an event matches the rule
+func (p *RoutingRule) Match(project *Project, event EventType, severity Severity) bool {
+	if !p.IsActive {
+		return false
+	}
+
+	if len(p.Events) > 0 {
+		matched := false
+		for _, e := range p.Events {
+			if e == event {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if p.MinSeverity != "" && severity.Rank() < p.MinSeverity.Rank() {
+		return false
+	}
+
+	if len(p.Projects) == 0 && len(p.Tags) == 0 && len(p.Labels) == 0 {
+		return true
+	}
+
+	// Project criteria never match project-less events
+	if project == nil {
+		return false
+	}
+
+	if len(p.Projects) > 0 {
+		matched := false
+		for _, pattern := range p.Projects {
+			ok, _ := path.Match(pattern, project.ID)
+			if ok {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.Tags) > 0 && !project.HasAnyTag(p.Tags) {
+		return false
+	}
+
+	for key, value := range p.Labels {
+		actual, exists := project.Labels[key]
+		if !exists || (value != "*" && value != actual) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RoutingRules is a list of RoutingRule
+type RoutingRules []*RoutingRule
+
+// RoutingRuleParams contains parameters for routing rule creation or modification
+type RoutingRuleParams struct {
+	Name        string            `json:"name" binding:"required"`
+	Position    int               `json:"position"`
+	IsActive    *bool             `json:"isActive"`
+	Projects    []string          `json:"projects"`
+	Tags        []string          `json:"tags"`
+	Labels      map[string]string `json:"labels"`
+	Events      []EventType       `json:"events"`
+	MinSeverity Severity          `json:"minSeverity"`
+	Targets     []string          `json:"targets" binding:"required"`
+	Stop        bool              `json:"stop"`
+}
+
+// String converts an object to string
+func (p *RoutingRuleParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *RoutingRuleParams) Normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Projects = normalizeList(p.Projects, false)
+	p.Tags = normalizeList(p.Tags, true)
+	p.Labels = normalizeLabels(p.Labels)
+	p.Targets = normalizeList(p.Targets, true)
+	p.MinSeverity = Severity(strings.ToLower(strings.TrimSpace(string(p.MinSeverity))))
+}
+
+// Validate validates request's fields
+func (p *RoutingRuleParams) Validate() error {
+	if p.Name == "" {
+		return NewError(EBadRequest, "rule name is required")
+	}
+
+	for _, pattern := range p.Projects {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return NewError(EBadRequest, "\"%s\" is not a valid project pattern", pattern)
+		}
+	}
+
+	for _, event := range p.Events {
+		if !event.IsValid() {
+			return NewError(EBadRequest, "\"%s\" is not a valid event type", event)
+		}
+	}
+
+	if p.MinSeverity != "" && p.MinSeverity.Rank() < 0 {
+		return NewError(EBadRequest, "\"%s\" is not a valid severity", p.MinSeverity)
+	}
+
+	if len(p.Targets) == 0 {
+		return NewError(EBadRequest, "at least one target is required")
+	}
+
+	return nil
+}
+
+// ApplyTo applies request values to a RoutingRule
+func (p *RoutingRuleParams) ApplyTo(rule *RoutingRule) {
+	rule.Name = p.Name
+	rule.Position = p.Position
+	rule.IsActive = p.IsActive == nil || *p.IsActive
+	rule.Projects = append([]string{}, p.Projects...)
+	rule.Tags = append([]string{}, p.Tags...)
+	rule.Events = append([]EventType{}, p.Events...)
+	rule.MinSeverity = p.MinSeverity
+	rule.Targets = append([]string{}, p.Targets...)
+	rule.Stop = p.Stop
+
+	rule.Labels = make(map[string]string)
+	for key, value := range p.Labels {
+		rule.Labels[key] = value
+	}
+}
+
+// RouteTarget is a resolved notification target
+type RouteTarget struct {
+	Channel NotificationChannel `json:"channel"`
+	Address string              `json:"address"`
+	Target  string              `json:"target,omitempty"`
+	Source  string              `json:"source"`
+}
+
+// Route is a list of targets a notification event is sent to
+type Route struct {
+	ProjectID string         `json:"projectId,omitempty"`
+	Event     EventType      `json:"event"`
+	Severity  Severity       `json:"severity"`
+	Override  bool           `json:"override"`
+	Muted     bool           `json:"muted"`
+	Targets   []*RouteTarget `json:"targets"`
+}
+
+// String converts an object to string
+func (p *Route) String() string {
+	return toJSON(&p)
+}
+
+// RouteTestParams contains parameters to test notification routing
+type RouteTestParams struct {
+	ProjectID string    `json:"projectId"`
+	Event     EventType `json:"event" binding:"required"`
+	Severity  Severity  `json:"severity"`
+}
+
+// String converts an object to string
+func (p *RouteTestParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *RouteTestParams) Normalize() {
+	p.ProjectID = strings.TrimSpace(p.ProjectID)
+	if p.Severity == "" {
+		p.Severity = p.Event.Severity()
+	}
+}
+
+// Validate validates request's fields
+func (p *RouteTestParams) Validate() error {
+	if !p.Event.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid event type", p.Event)
+	}
+
+	if p.Severity.Rank() < 0 {
+		return NewError(EBadRequest, "\"%s\" is not a valid severity", p.Severity)
+	}
+
+	return nil
+}
+
+func normalizeList(list []string, lower bool) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if lower {
+			item = strings.ToLower(item)
+		}
+
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+func normalizeLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range labels {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			result[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Session is a user's login session.
+// Session lives as long as its refresh token is being used and is not revoked
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	IsCurrent  bool      `json:"isCurrent"`
+}
+
+// String converts an object to string
+func (p *Session) String() string {
+	return toJSON(&p)
+}
+
+// Sessions is a list of Session
+type Sessions []*Session
+
+// RefreshRequest contains parameters to refresh an access token
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// String converts an object to string
+func (p RefreshRequest) String() string {
+	return "{}"
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// TrashedProject is a deleted project which hasn't been purged yet
+type TrashedProject struct {
+	*Project
+	PurgeAt time.Time `json:"purgeAt"`
+}
+
+// TrashedBackup is a deleted backup which hasn't been purged yet
+type TrashedBackup struct {
+	*Backup
+	ProjectID string    `json:"projectId"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+// Trash contains deleted projects and backups which can still be restored
+type Trash struct {
+	Projects []*TrashedProject `json:"projects"`
+	Backups  []*TrashedBackup  `json:"backups"`
+}
+
+// String converts an object to string
+func (p *Trash) String() string {
+	return toJSON(&p)
+}
+
+// NewTrash creates a list of trashed projects and backups, purge time is evaluated from deletion time
+func NewTrash(projects []*Project, backups []*Backup, purgeDelay time.Duration) *Trash {
+	trash := &Trash{
+		Projects: make([]*TrashedProject, 0, len(projects)),
+		Backups:  make([]*TrashedBackup, 0, len(backups)),
+	}
+
+	for _, project := range projects {
+		if project.DeletedAt != nil {
+			trash.Projects = append(trash.Projects, &TrashedProject{project, project.DeletedAt.Add(purgeDelay)})
+		}
+	}
+
+	for _, backup := range backups {
+		if backup.DeletedAt != nil {
+			trash.Backups = append(trash.Backups, &TrashedBackup{backup, backup.ProjectID, backup.DeletedAt.Add(purgeDelay)})
+		}
+	}
+
+	return trash
+}
@@ -1,14 +1,48 @@
 package model
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // User contains information about application user
 type User struct {
-	ID           int    `json:"id"`
-	UserName     string `json:"username"`
-	PasswordHash string `json:"-"`
+	ID           int        `json:"id"`
+	UserName     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	DisplayName  string     `json:"displayName"`
+	Email        string     `json:"email"`
+	Role         Role       `json:"role"`
+	Source       UserSource `json:"source"`
+	MFAEnabled   bool       `json:"mfaEnabled"`
+	IsDisabled   bool       `json:"isDisabled"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	// Set if user has their role on all projects, otherwise only project grants give access to projects
+	AllProjects bool `json:"allProjects"`
+	// Identity of user at external provider (e.g. issuer and subject of OpenID Connect user), which account is bound to
+	ExternalID string `json:"-"`
+	// Set if sign in is temporarily locked after too many failed attempts
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
 }
 
+// UserSource defines where a user account comes from
+type UserSource string
+
+const (
+	// UserSourceLocal is a user account managed by application itself
+	UserSourceLocal UserSource = "local"
+
+	// UserSourceOIDC is a user account provisioned by OpenID Connect provider
+	UserSourceOIDC UserSource = "oidc"
+
+	// UserSourceLDAP is a user account provisioned by LDAP server
+	UserSourceLDAP UserSource = "ldap"
+)
+
 // String converts an object to string
 func (p *User) String() string {
 	return toJSON(&p)
@@ -34,6 +68,18 @@ func (p *User) SetPassword(password string) error {
 // Users is a list of User
 type Users []*User
 
+// MinPasswordLength is a min length of user's password
+const MinPasswordLength = 8
+
+// ValidatePassword checks that a password is strong enough
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return NewError(EBadRequest, "password should be at least %d characters long", MinPasswordLength)
+	}
+
+	return nil
+}
+
 // UserChangePasswordRequest contains parameters to change user's password
 type UserChangePasswordRequest struct {
 	OldPassword string `json:"oldPassword"`
@@ -44,3 +90,215 @@ type UserChangePasswordRequest struct {
 func (p *UserChangePasswordRequest) String() string {
 	return toJSON(&p)
 }
+
+var userNameRegexp = regexp.MustCompile(`^[a-z0-9_.@-]+$`)
+
+// UserCreateParams contains parameters for user creation
+type UserCreateParams struct {
+	UserName    string `json:"username" binding:"required"`
+	Password    string `json:"password"`
+	DisplayName string `json:"displayName"`
+	Email       string `json:"email"`
+	Role        Role   `json:"role"`
+	// Give user their role on all projects (default), otherwise only project grants give access to projects
+	AllProjects *bool `json:"allProjects"`
+}
+
+// String converts an object to string
+func (p *UserCreateParams) String() string {
+	return toJSON(&UserCreateParams{
+		UserName:    p.UserName,
+		DisplayName: p.DisplayName,
+		Email:       p.Email,
+		Role:        p.Role,
+		AllProjects: p.AllProjects,
+	})
+}
+
+// Normalize normalizes request's fields
+func (p *UserCreateParams) Normalize() {
+	p.UserName = strings.ToLower(strings.TrimSpace(p.UserName))
+	p.DisplayName = strings.TrimSpace(p.DisplayName)
+	p.Email = strings.TrimSpace(p.Email)
+	p.Role = Role(strings.ToLower(strings.TrimSpace(string(p.Role))))
+	if p.Role == "" {
+		p.Role = RoleViewer
+	}
+}
+
+// Validate validates request's fields
+func (p *UserCreateParams) Validate() error {
+	if !p.Role.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid role", p.Role)
+	}
+
+	if !userNameRegexp.MatchString(p.UserName) {
+		return NewError(EBadRequest, "\"%s\" is not a valid username", p.UserName)
+	}
+
+	if p.Password != "" {
+		err := ValidatePassword(p.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateEmail(p.Email)
+}
+
+// ApplyTo applies request values to a User
+func (p *UserCreateParams) ApplyTo(user *User) {
+	user.UserName = p.UserName
+	user.DisplayName = p.DisplayName
+	user.Email = p.Email
+	user.Role = p.Role
+	user.AllProjects = p.AllProjects == nil || *p.AllProjects
+}
+
+// UserUpdateParams contains parameters for user modification
+type UserUpdateParams struct {
+	DisplayName *string `json:"displayName"`
+	Email       *string `json:"email"`
+	Role        *Role   `json:"role"`
+	IsDisabled  *bool   `json:"isDisabled"`
+	AllProjects *bool   `json:"allProjects"`
+}
+
+// String converts an object to string
+func (p *UserUpdateParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *UserUpdateParams) Normalize() {
+	if p.DisplayName != nil {
+		*p.DisplayName = strings.TrimSpace(*p.DisplayName)
+	}
+
+	if p.Email != nil {
+		*p.Email = strings.TrimSpace(*p.Email)
+	}
+
+	if p.Role != nil {
+		*p.Role = Role(strings.ToLower(strings.TrimSpace(string(*p.Role))))
+	}
+}
+
+// Validate validates request's fields
+func (p *UserUpdateParams) Validate() error {
+	if p.Role != nil && !p.Role.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid role", *p.Role)
+	}
+
+	if p.Email != nil {
+		return validateEmail(*p.Email)
+	}
+
+	return nil
+}
+
+// ApplyTo applies request values to a User
+func (p *UserUpdateParams) ApplyTo(user *User) {
+	if p.DisplayName != nil {
+		user.DisplayName = *p.DisplayName
+	}
+
+	if p.Email != nil {
+		user.Email = *p.Email
+	}
+
+	if p.Role != nil {
+		user.Role = *p.Role
+	}
+
+	if p.IsDisabled != nil {
+		user.IsDisabled = *p.IsDisabled
+	}
+
+	if p.AllProjects != nil {
+		user.AllProjects = *p.AllProjects
+	}
+}
+
+// UserProvisionParams contains parameters to provision an externally authenticated user
+type UserProvisionParams struct {
+	UserName    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	// Should be verified by provider, since it's used to bind accounts provisioned before external IDs were stored
+	Email string `json:"email"`
+	// Role to assign (nil to keep existing user's role or to use default one for new user)
+	Role *Role `json:"role"`
+	// Stable identity of user at provider (empty if provider doesn't have one).
+	// Once a user is bound to it, username and email reported by provider don't matter anymore
+	ExternalID string `json:"externalId"`
+}
+
+// String converts an object to string
+func (p *UserProvisionParams) String() string {
+	return toJSON(&p)
+}
+
+// Normalize normalizes request's fields
+func (p *UserProvisionParams) Normalize() {
+	p.UserName = strings.ToLower(strings.TrimSpace(p.UserName))
+	p.DisplayName = strings.TrimSpace(p.DisplayName)
+	p.Email = strings.TrimSpace(p.Email)
+
+	// Providers might return anything as email, so it's dropped instead of failing a login
+	if validateEmail(p.Email) != nil {
+		p.Email = ""
+	}
+}
+
+// Validate validates request's fields
+func (p *UserProvisionParams) Validate() error {
+	if !userNameRegexp.MatchString(p.UserName) {
+		return NewError(EBadRequest, "\"%s\" is not a valid username", p.UserName)
+	}
+
+	if p.Role != nil && !p.Role.IsValid() {
+		return NewError(EBadRequest, "\"%s\" is not a valid role", *p.Role)
+	}
+
+	return nil
+}
+
+// ApplyTo applies request values to a User
+func (p *UserProvisionParams) ApplyTo(user *User) {
+	user.UserName = p.UserName
+	if p.DisplayName != "" {
+		user.DisplayName = p.DisplayName
+	}
+
+	if p.Email != "" {
+		user.Email = p.Email
+	}
+
+	if p.Role != nil {
+		user.Role = *p.Role
+	}
+
+	if p.ExternalID != "" {
+		user.ExternalID = p.ExternalID
+	}
+}
+
+// UserPasswordResponse contains a generated user's password.
+// The password is returned only once and is never shown again
+type UserPasswordResponse struct {
+	User     *User  `json:"user"`
+	Password string `json:"password"`
+}
+
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return NewError(EBadRequest, "\"%s\" is not a valid email", email)
+	}
+
+	return nil
+}
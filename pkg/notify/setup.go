@@ -1,6 +1,7 @@
 package notify
 
 import (
+	"errors"
 	"log"
 	"sync"
 
@@ -10,6 +11,9 @@ import (
 
 const serviceKey = "NotificationService"
 
+// ErrDisabled is returned when a notification is sent via an integration that is not configured
+var ErrDisabled = errors.New("integration is disabled")
+
 // Setup configures package services
 func Setup(builder component.Builder) {
 	builder.AddService(di.Def{
@@ -37,10 +41,27 @@ func Setup(builder component.Builder) {
 
 // SlackMessage is a content for Slack notification
 type SlackMessage struct {
-	To    []string
+	To      []string
+	Title   string
+	Text    string
+	Emoji   string
+	Fields  []*SlackField
+	Actions []*SlackAction
+}
+
+// SlackField is a titled value of Slack notification
+type SlackField struct {
 	Title string
+	Value string
+}
+
+// SlackAction is a button of Slack notification
+type SlackAction struct {
+	ID    string
 	Text  string
-	Emoji string
+	Value string
+	URL   string
+	Style string
 }
 
 // TelegramMessage is a content for Telegram notification
@@ -1,8 +1,10 @@
 package notify
 
 import (
+	"fmt"
 	"log"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/slack-go/slack"
 	"github.com/spf13/viper"
@@ -41,7 +43,7 @@ func (s *disabledSlackNotifier) Notify(msg *SlackMessage) error {
 		msg.Emoji,
 		strings.Join(msg.To, ", "))
 
-	return nil
+	return fmt.Errorf("slack %w", ErrDisabled)
 }
 
 type enabledSlackNotifier struct {
@@ -53,13 +55,7 @@ type enabledSlackNotifier struct {
 func (s *enabledSlackNotifier) Notify(msg *SlackMessage) error {
 	options := make([]slack.MsgOption, 0)
 	options = append(options, slack.MsgOptionText(msg.Title, true))
-
-	if msg.Text != "" {
-		a := slack.Attachment{
-			Text: msg.Text,
-		}
-		options = append(options, slack.MsgOptionAttachments(a))
-	}
+	options = append(options, slack.MsgOptionBlocks(createSlackBlocks(msg)...))
 
 	if msg.Emoji != "" {
 		options = append(options, slack.MsgOptionIconEmoji(msg.Emoji))
@@ -69,15 +65,110 @@ func (s *enabledSlackNotifier) Notify(msg *SlackMessage) error {
 		options = append(options, slack.MsgOptionUsername(s.username))
 	}
 
+	var lastErr error
 	for _, to := range msg.To {
 		_, ts, _, err := s.slack.SendMessage(to, options...)
 		if err != nil {
 			s.logger.Printf("unable to send slack message to \"%s\": %v", to, err)
-			return err
+			lastErr = err
+			continue
 		}
 
 		s.logger.Printf("slack message \"%s\" has been sent to \"%s\"", ts, to)
 	}
 
-	return nil
+	return lastErr
+}
+
+const (
+	slackMaxHeaderLength  = 150
+	slackMaxSectionLength = 3000
+	slackMaxFields        = 10
+	slackMaxBlocks        = 50
+)
+
+// Converts a message into a set of Block Kit blocks
+func createSlackBlocks(msg *SlackMessage) []slack.Block {
+	blocks := make([]slack.Block, 0)
+
+	title := msg.Title
+	if utf8.RuneCountInString(title) > slackMaxHeaderLength {
+		title = title[0:slackRuneOffset(title, slackMaxHeaderLength-3)] + "..."
+	}
+	blocks = append(blocks, slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, title, true, false)))
+
+	// Long texts (e.g. digest reports) are split into several sections
+	for _, chunk := range splitSlackText(msg.Text, slackMaxSectionLength) {
+		text := slack.NewTextBlockObject(slack.MarkdownType, chunk, false, false)
+		blocks = append(blocks, slack.NewSectionBlock(text, nil, nil))
+	}
+
+	if len(msg.Fields) > 0 {
+		fields := make([]*slack.TextBlockObject, 0)
+		for _, field := range msg.Fields {
+			value := fmt.Sprintf("*%s*\n%s", field.Title, field.Value)
+			fields = append(fields, slack.NewTextBlockObject(slack.MarkdownType, value, false, false))
+			if len(fields) == slackMaxFields {
+				break
+			}
+		}
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+
+	if len(msg.Actions) > 0 {
+		elements := make([]slack.BlockElement, 0)
+		for _, action := range msg.Actions {
+			text := slack.NewTextBlockObject(slack.PlainTextType, action.Text, true, false)
+			button := slack.NewButtonBlockElement(action.ID, action.Value, text)
+			button.URL = action.URL
+			if action.Style != "" {
+				button = button.WithStyle(slack.Style(action.Style))
+			}
+			elements = append(elements, button)
+		}
+		blocks = append(blocks, slack.NewActionBlock("", elements...))
+	}
+
+	if len(blocks) > slackMaxBlocks {
+		blocks = blocks[0:slackMaxBlocks]
+	}
+
+	return blocks
+}
+
+// Splits a text into chunks not longer than maxLength characters, preferably by lines.
+// Chunks are never cut in the middle of a multi-byte character
+func splitSlackText(text string, maxLength int) []string {
+	chunks := make([]string, 0)
+	text = strings.TrimSpace(text)
+
+	for text != "" {
+		if utf8.RuneCountInString(text) <= maxLength {
+			chunks = append(chunks, text)
+			break
+		}
+
+		end := slackRuneOffset(text, maxLength)
+		i := strings.LastIndex(text[0:end], "\n")
+		if i <= 0 {
+			i = end
+		}
+
+		chunks = append(chunks, strings.TrimSpace(text[0:i]))
+		text = strings.TrimSpace(text[i:])
+	}
+
+	return chunks
+}
+
+// Returns byte offset of n-th character of a text (or text length if text is shorter)
+func slackRuneOffset(text string, n int) int {
+	for i := range text {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+
+	return len(text)
 }
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSplitSlackText(t *testing.T) {
+	cases := []struct {
+		name      string
+		text      string
+		maxLength int
+		expected  []string
+	}{
+		{"short text", "hello", 10, []string{"hello"}},
+		{"split by lines", "first line\nsecond line", 15, []string{"first line", "second line"}},
+		{"split long line", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"length is counted in characters", "привет", 6, []string{"привет"}},
+		{"multi-byte characters are not cut", "привет мир", 4, []string{"прив", "ет м", "ир"}},
+		{"emoji are not cut", "🔥🔥🔥", 2, []string{"🔥🔥", "🔥"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chunks := splitSlackText(c.text, c.maxLength)
+			if strings.Join(chunks, "|") != strings.Join(c.expected, "|") {
+				t.Fatalf("expected %q, got %q", c.expected, chunks)
+			}
+
+			for _, chunk := range chunks {
+				if !utf8.ValidString(chunk) {
+					t.Fatalf("chunk %q is not a valid UTF-8 string", chunk)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSlackBlocksTruncatesTitle(t *testing.T) {
+	title := strings.Repeat("я", slackMaxHeaderLength+1)
+	blocks := createSlackBlocks(&SlackMessage{Title: title})
+
+	header := blocks[0].(*slack.HeaderBlock).Text.Text
+	if !utf8.ValidString(header) {
+		t.Fatalf("header %q is not a valid UTF-8 string", header)
+	}
+
+	if utf8.RuneCountInString(header) != slackMaxHeaderLength {
+		t.Fatalf("expected header to be %d characters long, got %d", slackMaxHeaderLength, utf8.RuneCountInString(header))
+	}
+}
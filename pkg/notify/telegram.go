@@ -3,6 +3,7 @@ package notify
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	telegram "github.com/go-telegram-bot-api/telegram-bot-api"
@@ -10,6 +11,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Max length of a Telegram message, longer messages are split into parts
+const telegramMaxMessageLength = 4096
+
 type telegramNotifier interface {
 	Notify(msg *TelegramMessage) error
 }
@@ -48,7 +52,7 @@ func (s *disabledTelegramNotifier) Notify(msg *TelegramMessage) error {
 		msg.Emoji,
 		strings.Join(msg.To, ", "))
 
-	return nil
+	return fmt.Errorf("telegram %w", ErrDisabled)
 }
 
 type enabledTelegramNotifier struct {
@@ -61,7 +65,7 @@ func (s *enabledTelegramNotifier) Notify(msg *TelegramMessage) error {
 
 	text = msg.Title
 	if msg.Text != "" {
-		text = fmt.Sprintf("%s\n\n%s", msg.Title, text)
+		text = fmt.Sprintf("%s\n\n%s", msg.Title, msg.Text)
 	}
 
 	if msg.Emoji != "" {
@@ -71,24 +75,90 @@ func (s *enabledTelegramNotifier) Notify(msg *TelegramMessage) error {
 		}
 	}
 
+	parts := splitTelegramText(text, telegramMaxMessageLength)
+
+	var lastErr error
 	for _, to := range msg.To {
-		chat, err := s.telegram.GetChat(telegram.ChatConfig{
-			SuperGroupUsername: to,
-		})
+		chatID, err := s.resolveChatID(to)
 		if err != nil {
 			s.logger.Printf("unable to send telegram message to \"%s\": %v", to, err)
-			return err
+			lastErr = err
+			continue
 		}
 
-		m := telegram.NewMessage(chat.ID, text)
-		r, err := s.telegram.Send(m)
-		if err != nil {
-			s.logger.Printf("unable to send telegram message to \"%s\": %v", to, err)
-			return err
+		for _, part := range parts {
+			m := telegram.NewMessage(chatID, part)
+			r, err := s.telegram.Send(m)
+			if err != nil {
+				s.logger.Printf("unable to send telegram message to \"%s\": %v", to, err)
+				lastErr = err
+				break
+			}
+
+			s.logger.Printf("telegram message %d has been sent to %d", r.MessageID, r.Chat.ID)
+		}
+	}
+
+	return lastErr
+}
+
+// Splits a text into parts that fit into Telegram message length limit.
+// Text is split at line breaks if possible.
+// Length is measured in UTF-16 code units, just like Telegram does
+func splitTelegramText(text string, limit int) []string {
+	parts := make([]string, 0)
+	for {
+		end, length := len(text), 0
+		for i, r := range text {
+			n := 1
+			if r >= 0x10000 {
+				n = 2
+			}
+			if length+n > limit {
+				end = i
+				break
+			}
+			length += n
 		}
 
-		s.logger.Printf("telegram message %d has been sent to %d", r.MessageID, r.Chat.ID)
+		if end >= len(text) {
+			break
+		}
+
+		cut := end
+		if i := strings.LastIndex(text[:end], "\n"); i > 0 {
+			cut = i
+		}
+
+		if part := strings.TrimRight(text[:cut], "\n"); part != "" {
+			parts = append(parts, part)
+		}
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+
+	return append(parts, text)
+}
+
+// Resolves a target into a chat ID.
+// Numeric targets are used as chat IDs directly, others are treated as public chat usernames
+func (s *enabledTelegramNotifier) resolveChatID(to string) (int64, error) {
+	to = strings.TrimSpace(to)
+
+	chatID, err := strconv.ParseInt(to, 10, 64)
+	if err == nil {
+		return chatID, nil
+	}
+
+	if !strings.HasPrefix(to, "@") {
+		to = "@" + to
+	}
+
+	chat, err := s.telegram.GetChat(telegram.ChatConfig{
+		SuperGroupUsername: to,
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return chat.ID, nil
 }
@@ -3,8 +3,12 @@ package notify
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 )
 
 type webhookNotifier interface {
@@ -14,11 +18,13 @@ type webhookNotifier interface {
 func createWebhookNotifier(logger *log.Logger) webhookNotifier {
 	return &webhookNotifierImpl{
 		logger: logger,
+		client: &http.Client{Timeout: 30 * time.Second},
 	}
 }
 
 type webhookNotifierImpl struct {
 	logger *log.Logger
+	client *http.Client
 }
 
 func (s *webhookNotifierImpl) Notify(msg *WebhookMessage) error {
@@ -30,17 +36,29 @@ func (s *webhookNotifierImpl) Notify(msg *WebhookMessage) error {
 
 	contentType := "application/json"
 
+	var lastErr error
 	for _, to := range msg.To {
 		body := bytes.NewBuffer(json)
 
-		r, err := http.Post(to, contentType, body)
+		r, err := s.client.Post(to, contentType, body)
 		if err != nil {
 			s.logger.Printf("unable to trigger webhook \"%s\": %v", to, err)
-			return err
+			lastErr = err
+			continue
+		}
+
+		io.Copy(ioutil.Discard, r.Body)
+		r.Body.Close()
+
+		if r.StatusCode < 200 || r.StatusCode > 299 {
+			err = fmt.Errorf("webhook \"%s\" responded with %s", to, r.Status)
+			s.logger.Printf("unable to trigger webhook: POST %s -> %d", to, r.StatusCode)
+			lastErr = err
+			continue
 		}
 
 		s.logger.Printf("triggered webhook: POST %s -> %d", to, r.StatusCode)
 	}
 
-	return nil
+	return lastErr
 }
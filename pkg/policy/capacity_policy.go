@@ -0,0 +1,50 @@
+package policy
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/component"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/sarulabs/di"
+)
+
+// capacityPolicy periodically checks free disk space of storage
+type capacityPolicy struct {
+	logger       *log.Logger
+	quotaService service.QuotaService
+}
+
+func createCapacityPolicy(c di.Container) (component.T, error) {
+	logger := log.New(log.Writer(), "[quota] ", log.Flags())
+
+	s := &capacityPolicy{
+		logger:       logger,
+		quotaService: service.GetQuotaService(c),
+	}
+	return s, nil
+}
+
+func (s *capacityPolicy) Start(group *sync.WaitGroup, stop chan interface{}) {
+	period := time.Minute
+	t := time.NewTicker(period)
+
+	group.Add(1)
+	go func() {
+		for range t.C {
+			err := s.quotaService.CheckDiskSpace()
+			if err != nil {
+				s.logger.Printf("unable to check free disk space: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		for range stop {
+		}
+
+		t.Stop()
+		group.Done()
+	}()
+}
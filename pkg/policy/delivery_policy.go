@@ -0,0 +1,146 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/component"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/notify"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/itglobal/backupmonitor/pkg/util"
+	"github.com/sarulabs/di"
+	"github.com/spf13/viper"
+)
+
+const (
+	deliveryBatchSize  = 100
+	deliveryMinBackoff = 30 * time.Second
+	deliveryMaxBackoff = 2 * time.Hour
+	// A claimed delivery is sent again if its attempt isn't recorded within this time
+	deliveryClaimTimeout = 5 * time.Minute
+)
+
+type deliveryPolicy struct {
+	logger              *log.Logger
+	deliveryRepository  service.DeliveryRepository
+	notificationService notify.Service
+	maxAttempts         int
+}
+
+func createDeliveryPolicy(c di.Container) (component.T, error) {
+	logger := log.New(log.Writer(), "[outbox] ", log.Flags())
+
+	s := &deliveryPolicy{
+		logger:              logger,
+		deliveryRepository:  service.GetDeliveryRepository(c),
+		notificationService: notify.GetService(c),
+		maxAttempts:         viper.GetInt("NOTIFY_MAX_ATTEMPTS"),
+	}
+	return s, nil
+}
+
+func (s *deliveryPolicy) Start(group *sync.WaitGroup, stop chan interface{}) {
+	period := 10 * time.Second
+	t := time.NewTicker(period)
+
+	group.Add(1)
+	go func() {
+		for range t.C {
+			err := s.Execute()
+			if err != nil {
+				s.logger.Printf("unable to execute background task: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		for range stop {
+		}
+
+		t.Stop()
+		group.Done()
+	}()
+}
+
+// Execute sends due deliveries.
+// A failure to record one delivery's attempt doesn't stop the others, all failures are returned together
+func (s *deliveryPolicy) Execute() error {
+	now := time.Now().UTC()
+	deliveries, err := s.deliveryRepository.ListDue(now, deliveryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs util.Errors
+	for _, delivery := range deliveries {
+		err = s.Send(delivery)
+		if err != nil {
+			s.logger.Printf("unable to process delivery #%d: %v", delivery.ID, err)
+			errs.Add(fmt.Errorf("delivery #%d: %v", delivery.ID, err))
+		}
+	}
+
+	return errs.Err()
+}
+
+// Send claims a delivery, sends it and records the attempt.
+// Deliveries claimed by another worker (e.g. by an overlapping run) are skipped
+func (s *deliveryPolicy) Send(delivery *model.Delivery) error {
+	now := time.Now().UTC()
+	claimed, err := s.deliveryRepository.Claim(delivery.ID, now, now.Add(deliveryClaimTimeout))
+	if err != nil || !claimed {
+		return err
+	}
+
+	err = s.Deliver(delivery)
+	if errors.Is(err, notify.ErrDisabled) {
+		return s.deliveryRepository.MarkSkipped(delivery.ID, err)
+	} else if err != nil {
+		nextAttemptAt := s.NextAttemptTime(delivery, time.Now().UTC())
+		return s.deliveryRepository.MarkFailed(delivery.ID, err, nextAttemptAt)
+	}
+
+	return s.deliveryRepository.MarkSent(delivery.ID, time.Now().UTC())
+}
+
+func (s *deliveryPolicy) Deliver(delivery *model.Delivery) error {
+	msg := delivery.Message
+	if msg == nil {
+		msg = &model.NotificationMessage{}
+	}
+
+	switch delivery.Channel {
+	case model.NotificationChannelSlack:
+		return s.notificationService.NotifySlack(msg.ToSlackMessage(delivery.Target))
+	case model.NotificationChannelTelegram:
+		return s.notificationService.NotifyTelegram(msg.ToTelegramMessage(delivery.Target))
+	case model.NotificationChannelWebhook:
+		return s.notificationService.NotifyWebhook(msg.ToWebhookMessage(delivery.Target))
+	}
+
+	return fmt.Errorf("unknown notification channel \"%s\"", delivery.Channel)
+}
+
+// NextAttemptTime evaluates time of next delivery attempt using exponential backoff.
+// Returns nil if no more attempts should be made
+func (s *deliveryPolicy) NextAttemptTime(delivery *model.Delivery, now time.Time) *time.Time {
+	attempts := delivery.Attempts + 1
+	if attempts >= s.maxAttempts {
+		return nil
+	}
+
+	backoff := deliveryMinBackoff
+	for i := 1; i < attempts && backoff < deliveryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > deliveryMaxBackoff {
+		backoff = deliveryMaxBackoff
+	}
+
+	t := now.Add(backoff)
+	return &t
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/component"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/robfig/cron/v3"
+	"github.com/sarulabs/di"
+	"github.com/spf13/viper"
+)
+
+type digestPolicy struct {
+	logger             *log.Logger
+	digestService      service.DigestService
+	deliveryRepository service.DeliveryRepository
+	router             service.NotificationRouter
+	schedule           string
+	period             time.Duration
+	targets            map[model.NotificationChannel][]string
+}
+
+func createDigestPolicy(c di.Container) (component.T, error) {
+	logger := log.New(log.Writer(), "[digest] ", log.Flags())
+
+	period, err := model.ParseDigestPeriod(viper.GetString("DIGEST_PERIOD"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &digestPolicy{
+		logger:             logger,
+		digestService:      service.GetDigestService(c),
+		deliveryRepository: service.GetDeliveryRepository(c),
+		router:             service.GetNotificationRouter(c),
+		schedule:           strings.TrimSpace(viper.GetString("DIGEST_SCHEDULE")),
+		period:             period,
+		targets: map[model.NotificationChannel][]string{
+			model.NotificationChannelSlack:    splitTargets(viper.GetString("DIGEST_SLACK")),
+			model.NotificationChannelTelegram: splitTargets(viper.GetString("DIGEST_TELEGRAM")),
+			model.NotificationChannelWebhook:  splitTargets(viper.GetString("DIGEST_WEBHOOK")),
+		},
+	}
+	return s, nil
+}
+
+func (s *digestPolicy) Start(group *sync.WaitGroup, stop chan interface{}) {
+	if s.schedule == "" {
+		s.logger.Printf("digest reports are disabled")
+		return
+	}
+
+	scheduler := cron.New(cron.WithLocation(time.UTC))
+	_, err := scheduler.AddFunc(s.schedule, func() {
+		err := s.Execute(time.Now().UTC())
+		if err != nil {
+			s.logger.Printf("unable to execute background task: %v", err)
+		}
+	})
+	if err != nil {
+		s.logger.Printf("digest reports are disabled: \"%s\" is not a valid schedule: %v", s.schedule, err)
+		return
+	}
+
+	s.logger.Printf("digest reports are scheduled at \"%s\" (period %s)", s.schedule, s.period)
+	scheduler.Start()
+
+	group.Add(1)
+	go func() {
+		for range stop {
+		}
+
+		<-scheduler.Stop().Done()
+		group.Done()
+	}()
+}
+
+func (s *digestPolicy) Execute(now time.Time) error {
+	digest, err := s.digestService.Build(now.Add(-s.period), now)
+	if err != nil {
+		return err
+	}
+
+	text, err := s.digestService.Render(digest, model.DigestFormatMarkdown)
+	if err != nil {
+		return err
+	}
+
+	slackText, err := s.digestService.Render(digest, model.DigestFormatSlack)
+	if err != nil {
+		return err
+	}
+
+	payload, err := s.digestService.Render(digest, model.DigestFormatJSON)
+	if err != nil {
+		return err
+	}
+
+	msg := &model.NotificationMessage{
+		Title:   "Backup digest",
+		Text:    string(text),
+		Emoji:   "bar_chart",
+		Payload: payload,
+	}
+
+	// Slack doesn't render Markdown, so it receives a mrkdwn variant of report
+	slackMsg := *msg
+	slackMsg.Text = string(slackText)
+
+	route, err := s.router.Route(nil, model.EventDigest, model.EventDigest.Severity())
+	if err != nil {
+		return err
+	}
+
+	// Targets from environment are combined with ones from routing rules
+	seen := make(map[string]bool)
+	args := make([]*model.DeliveryCreateParams, 0)
+	appendTarget := func(channel model.NotificationChannel, target string) {
+		key := string(channel) + ":" + target
+		if seen[key] {
+			return
+		}
+
+		seen[key] = true
+		message := msg
+		if channel == model.NotificationChannelSlack {
+			message = &slackMsg
+		}
+
+		args = append(args, &model.DeliveryCreateParams{
+			Event:   model.EventDigest,
+			Channel: channel,
+			Target:  target,
+			Message: message,
+		})
+	}
+
+	for channel, targets := range s.targets {
+		for _, target := range targets {
+			appendTarget(channel, target)
+		}
+	}
+
+	for _, target := range route.Targets {
+		appendTarget(target.Channel, target.Address)
+	}
+
+	if len(args) == 0 {
+		s.logger.Printf("digest report has been generated but no targets are configured")
+		return nil
+	}
+
+	_, err = s.deliveryRepository.Enqueue(args...)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("digest report has been sent to %d target(s)", len(args))
+	return nil
+}
+
+func splitTargets(str string) []string {
+	targets := make([]string, 0)
+	for _, target := range strings.Split(str, ";") {
+		target = strings.TrimSpace(target)
+		if target != "" {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
@@ -1,16 +1,20 @@
 package policy
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/itglobal/backupmonitor/pkg/component"
 	"github.com/itglobal/backupmonitor/pkg/model"
-	"github.com/itglobal/backupmonitor/pkg/notify"
 	"github.com/itglobal/backupmonitor/pkg/service"
 	"github.com/sarulabs/di"
+	"github.com/spf13/viper"
 )
 
 const (
@@ -19,18 +23,22 @@ const (
 )
 
 type notificationPolicy struct {
-	logger              *log.Logger
-	projectRepository   service.ProjectRepository
-	notificationService notify.Service
+	logger             *log.Logger
+	projectRepository  service.ProjectRepository
+	deliveryRepository service.DeliveryRepository
+	router             service.NotificationRouter
+	publicURL          string
 }
 
 func createNotificationPolicy(c di.Container) (component.T, error) {
 	logger := log.New(log.Writer(), "[policy] ", log.Flags())
 
 	s := &notificationPolicy{
-		logger:              logger,
-		projectRepository:   service.GetProjectRepository(c),
-		notificationService: notify.GetService(c),
+		logger:             logger,
+		projectRepository:  service.GetProjectRepository(c),
+		deliveryRepository: service.GetDeliveryRepository(c),
+		router:             service.GetNotificationRouter(c),
+		publicURL:          strings.TrimRight(viper.GetString("PUBLIC_URL"), "/"),
 	}
 	return s, nil
 }
@@ -59,7 +67,7 @@ func (s *notificationPolicy) Start(group *sync.WaitGroup, stop chan interface{})
 }
 
 func (s *notificationPolicy) Execute() error {
-	projects, err := s.projectRepository.List()
+	projects, err := s.projectRepository.List(nil)
 	if err != nil {
 		return err
 	}
@@ -69,12 +77,13 @@ func (s *notificationPolicy) Execute() error {
 		if s.ShouldSendNotification(project, now) {
 			err = s.SendNotification(project)
 			if err != nil {
-				return err
+				s.logger.Printf("unable to send notification for project \"%s\": %v", project.ID, err)
+				continue
 			}
 
 			err = s.MarkNotificationAsSent(project, now)
 			if err != nil {
-				return err
+				s.logger.Printf("unable to update project \"%s\": %v", project.ID, err)
 			}
 		}
 	}
@@ -91,6 +100,10 @@ func (s *notificationPolicy) ShouldSendNotification(project *model.Project, now
 		return false
 	}
 
+	if project.IsSilenced(now) || project.IsAcknowledged() {
+		return false
+	}
+
 	if project.LastNotification == nil {
 		return true
 	}
@@ -113,41 +126,52 @@ func (s *notificationPolicy) SendNotification(project *model.Project) error {
 
 	emoji := "warning"
 
-	// Send to slack
-	err := s.notificationService.NotifySlack(&notify.SlackMessage{
-		To:    project.Notifications.SlackUsers,
-		Title: title,
-		Text:  text,
-		Emoji: emoji,
-	})
+	payloadJSON := map[string]interface{}{
+		"project": project.ID,
+	}
+
+	if project.LastBackup != nil {
+		payloadJSON["lastBackupTime"] = project.LastBackup.Time
+	}
+
+	payload, err := json.Marshal(payloadJSON)
 	if err != nil {
 		return err
 	}
 
-	// Send to telegram
-	err = s.notificationService.NotifyTelegram(&notify.TelegramMessage{
-		To:    project.Notifications.TelegramUsers,
-		Title: title,
-		Text:  text,
-		Emoji: emoji,
-	})
+	msg := &model.NotificationMessage{
+		Title:   title,
+		Text:    text,
+		Emoji:   emoji,
+		Fields:  s.CreateMessageFields(project),
+		Actions: s.CreateMessageActions(project),
+		Payload: payload,
+	}
+
+	event := model.EventBackupOutdated
+	route, err := s.router.Route(project, event, event.Severity())
 	if err != nil {
 		return err
 	}
 
-	// Send to webhook
-	payloadJSON := map[string]interface{}{
-		"project": project.ID,
+	// Put a delivery for each target into the outbox
+	args := make([]*model.DeliveryCreateParams, len(route.Targets))
+	for i, target := range route.Targets {
+		args[i] = &model.DeliveryCreateParams{
+			ProjectID: project.ID,
+			Event:     event,
+			Channel:   target.Channel,
+			Target:    target.Address,
+			Message:   msg,
+		}
 	}
 
-	if project.LastBackup != nil {
-		payloadJSON["lastBackupTime"] = project.LastBackup.Time
+	if len(args) == 0 {
+		s.logger.Printf("no notification targets are configured for project \"%s\"", project.ID)
+		return nil
 	}
 
-	err = s.notificationService.NotifyWebhook(&notify.WebhookMessage{
-		To:          project.Notifications.Webhooks,
-		PayloadJSON: payloadJSON,
-	})
+	_, err = s.deliveryRepository.Enqueue(args...)
 	if err != nil {
 		return err
 	}
@@ -155,6 +179,48 @@ func (s *notificationPolicy) SendNotification(project *model.Project) error {
 	return nil
 }
 
+func (s *notificationPolicy) CreateMessageFields(project *model.Project) []*model.NotificationField {
+	lastBackupAge := "never"
+	lastBackupSize := "n/a"
+	if project.LastBackup != nil {
+		lastBackupAge = humanize.Time(project.LastBackup.Time)
+		if project.LastBackup.Length >= 0 {
+			lastBackupSize = humanize.Bytes(uint64(project.LastBackup.Length))
+		}
+	}
+
+	return []*model.NotificationField{
+		{Title: "Project", Value: fmt.Sprintf("%s (%s)", project.ID, project.Name)},
+		{Title: "Status", Value: string(project.BackupStatus)},
+		{Title: "Last backup", Value: lastBackupAge},
+		{Title: "Size", Value: lastBackupSize},
+	}
+}
+
+func (s *notificationPolicy) CreateMessageActions(project *model.Project) []*model.NotificationAction {
+	actions := []*model.NotificationAction{
+		{ID: model.ActionSilence24h, Text: "Silence 24h", Value: project.ID},
+	}
+
+	if s.publicURL != "" {
+		actions = append(actions, &model.NotificationAction{
+			ID:    model.ActionOpenProject,
+			Text:  "Open project",
+			Value: project.ID,
+			URL:   fmt.Sprintf("%s/projects/%s", s.publicURL, url.PathEscape(project.ID)),
+		})
+	}
+
+	actions = append(actions, &model.NotificationAction{
+		ID:    model.ActionAcknowledge,
+		Text:  "Acknowledge",
+		Value: project.ID,
+		Style: "primary",
+	})
+
+	return actions
+}
+
 func (s *notificationPolicy) MarkNotificationAsSent(project *model.Project, now time.Time) error {
 	args := &model.ProjectUpdateParams{
 		LastNotification: &now,
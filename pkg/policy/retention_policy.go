@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/itglobal/backupmonitor/pkg/database"
 	"github.com/itglobal/backupmonitor/pkg/model"
 	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/itglobal/backupmonitor/pkg/util"
 	"github.com/sarulabs/di"
 )
 
@@ -17,6 +19,7 @@ type retentionPolicy struct {
 	dbProvider        database.Provider
 	projectRepository service.ProjectRepository
 	backupRepository  service.BackupRepository
+	auditLog          service.AuditLog
 }
 
 func createRetentionPolicy(c di.Container) (component.T, error) {
@@ -27,6 +30,7 @@ func createRetentionPolicy(c di.Container) (component.T, error) {
 		dbProvider:        database.GetProvider(c),
 		projectRepository: service.GetProjectRepository(c),
 		backupRepository:  service.GetBackupRepository(c),
+		auditLog:          service.GetAuditLog(c),
 	}
 	return s, nil
 }
@@ -54,34 +58,34 @@ func (s *retentionPolicy) Start(group *sync.WaitGroup, stop chan interface{}) {
 	}()
 }
 
+// Execute applies retention policy to all projects.
+// A failure with one project (or one backup) doesn't stop the others, all failures are returned together
 func (s *retentionPolicy) Execute() error {
-	projects, err := s.projectRepository.List()
+	projects, err := s.projectRepository.List(nil)
 	if err != nil {
 		return err
 	}
 
+	var errs util.Errors
 	for _, project := range projects {
 		err = s.Apply(project)
 		if err != nil {
-			return err
+			s.logger.Printf("unable to apply retention policy to project \"%s\": %v", project.ID, err)
+			errs.Add(fmt.Errorf("project \"%s\": %v", project.ID, err))
 		}
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func (s *retentionPolicy) Apply(project *model.Project) error {
-	err := s.DropOldBackups(project)
-	if err != nil {
-		return err
-	}
+	var errs util.Errors
 
-	err = s.UpdateBackupStatus(project)
-	if err != nil {
-		return err
-	}
+	// Backup status is updated even if some backups couldn't be dropped
+	errs.Add(s.DropOldBackups(project))
+	errs.Add(s.UpdateBackupStatus(project))
 
-	return nil
+	return errs.Err()
 }
 
 func (s *retentionPolicy) DropOldBackups(project *model.Project) error {
@@ -90,22 +94,44 @@ func (s *retentionPolicy) DropOldBackups(project *model.Project) error {
 		return err
 	}
 
-	// backups are ordered by time desc
-	// remove all but first N backups
+	plan := project.PlanRetention(backups, time.Now().UTC())
 
-	if len(backups) <= project.BackupRetention {
-		return nil
+	// Mark kept backups with reasons (only changed ones are written)
+	reasons := make(map[string]string)
+	for _, d := range plan {
+		if d.Keep && d.Backup.KeepReason != d.Reason {
+			reasons[d.Backup.ID] = d.Reason
+		}
 	}
 
-	backups = backups[project.BackupRetention:]
-	for _, backup := range backups {
-		err = s.backupRepository.Delete(backup.ID, "by retention policy")
+	var errs util.Errors
+	err = s.backupRepository.SetKeepReasons(reasons)
+	if err != nil {
+		s.logger.Printf("unable to update keep reasons of project \"%s\": %v", project.ID, err)
+		errs.Add(err)
+	}
+
+	for _, d := range plan.Dropped() {
+		backup := d.Backup
+		err = s.backupRepository.Delete(backup.ID, d.Reason)
 		if err != nil {
-			return err
+			s.logger.Printf("unable to drop backup \"%s\" (project \"%s\"): %v", backup.ID, project.ID, err)
+			errs.Add(fmt.Errorf("backup \"%s\": %v", backup.ID, err))
+			continue
 		}
+
+		s.auditLog.Record(&model.AuditEntry{
+			Actor:      model.AuditActor{Type: model.AuditActorPolicy, ID: "retention", Name: "retention policy"},
+			Action:     model.AuditActionBackupDelete,
+			TargetType: model.AuditTargetBackup,
+			TargetID:   backup.ID,
+			ProjectID:  backup.ProjectID,
+			Changes:    model.NewAuditChanges(backup, nil),
+			Details:    d.Reason,
+		})
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func (s *retentionPolicy) UpdateBackupStatus(project *model.Project) error {
@@ -123,6 +149,5 @@ func (s *retentionPolicy) UpdateBackupStatus(project *model.Project) error {
 		return err
 	}
 
-	tx.Commit()
-	return nil
+	return tx.Commit().Error
 }
@@ -8,4 +8,8 @@ import (
 func Setup(builder component.Builder) {
 	builder.AddComponent(createRetentionPolicy)
 	builder.AddComponent(createNotificationPolicy)
+	builder.AddComponent(createDeliveryPolicy)
+	builder.AddComponent(createDigestPolicy)
+	builder.AddComponent(createTrashPolicy)
+	builder.AddComponent(createCapacityPolicy)
 }
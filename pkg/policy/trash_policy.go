@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/component"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/service"
+	"github.com/sarulabs/di"
+	"github.com/spf13/viper"
+)
+
+// trashPolicy purges projects and backups which have been in trash longer than purge delay
+type trashPolicy struct {
+	logger            *log.Logger
+	projectRepository service.ProjectRepository
+	backupRepository  service.BackupRepository
+	auditLog          service.AuditLog
+	delay             time.Duration
+}
+
+var trashActor = model.AuditActor{Type: model.AuditActorPolicy, ID: "trash", Name: "trash purge"}
+
+func createTrashPolicy(c di.Container) (component.T, error) {
+	logger := log.New(log.Writer(), "[trash] ", log.Flags())
+
+	s := &trashPolicy{
+		logger:            logger,
+		projectRepository: service.GetProjectRepository(c),
+		backupRepository:  service.GetBackupRepository(c),
+		auditLog:          service.GetAuditLog(c),
+		delay:             viper.GetDuration("TRASH_PURGE_DELAY"),
+	}
+	return s, nil
+}
+
+func (s *trashPolicy) Start(group *sync.WaitGroup, stop chan interface{}) {
+	period := time.Minute
+	t := time.NewTicker(period)
+
+	group.Add(1)
+	go func() {
+		for range t.C {
+			err := s.Execute(time.Now().UTC())
+			if err != nil {
+				s.logger.Printf("unable to execute background task: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		for range stop {
+		}
+
+		t.Stop()
+		group.Done()
+	}()
+}
+
+func (s *trashPolicy) Execute(now time.Time) error {
+	err := s.PurgeBackups(now)
+	if err != nil {
+		return err
+	}
+
+	return s.PurgeProjects(now)
+}
+
+// PurgeBackups purges backups of live projects which have been in trash longer than purge delay
+func (s *trashPolicy) PurgeBackups(now time.Time) error {
+	cutoff := now.Add(-s.delay)
+
+	backups, err := s.backupRepository.ListTrash("")
+	if err != nil {
+		return err
+	}
+
+	projects := make(map[string]*model.Project)
+	for _, backup := range backups {
+		// Locked backups are purged once their locks expire
+		if backup.DeletedAt.After(cutoff) || backup.Lock.IsActive(now) {
+			continue
+		}
+
+		project, ok := projects[backup.ProjectID]
+		if !ok {
+			project, err = s.projectRepository.Get(backup.ProjectID)
+			if err != nil && !model.IsNotFound(err) {
+				return err
+			}
+			projects[backup.ProjectID] = project
+		}
+
+		// Backups of trashed projects are purged along with their projects,
+		// backups of projects on legal hold are kept until hold is released
+		if project == nil || project.LegalHold != nil {
+			continue
+		}
+
+		err = s.purgeBackup(backup)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeProjects purges projects which have been in trash longer than purge delay, along with all their backups
+func (s *trashPolicy) PurgeProjects(now time.Time) error {
+	cutoff := now.Add(-s.delay)
+
+	projects, err := s.projectRepository.ListTrash()
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		if project.DeletedAt.After(cutoff) || project.LegalHold != nil {
+			continue
+		}
+
+		backups, err := s.backupRepository.ListAll(project.ID)
+		if err != nil {
+			return err
+		}
+
+		// Projects with locked backups are purged once all locks expire
+		if isAnyLocked(backups, now) {
+			continue
+		}
+
+		for _, backup := range backups {
+			err = s.purgeBackup(backup)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.projectRepository.Purge(project.ID)
+		if err != nil {
+			return err
+		}
+
+		s.auditLog.Record(&model.AuditEntry{
+			Actor:      trashActor,
+			Action:     model.AuditActionProjectPurge,
+			TargetType: model.AuditTargetProject,
+			TargetID:   project.ID,
+			ProjectID:  project.ID,
+			Changes:    model.NewAuditChanges(project, nil),
+		})
+	}
+
+	return nil
+}
+
+func (s *trashPolicy) purgeBackup(backup *model.Backup) error {
+	err := s.backupRepository.Purge(backup.ID)
+	if err != nil {
+		return err
+	}
+
+	s.auditLog.Record(&model.AuditEntry{
+		Actor:      trashActor,
+		Action:     model.AuditActionBackupPurge,
+		TargetType: model.AuditTargetBackup,
+		TargetID:   backup.ID,
+		ProjectID:  backup.ProjectID,
+		Changes:    model.NewAuditChanges(backup, nil),
+	})
+
+	return nil
+}
+
+func isAnyLocked(backups []*model.Backup, now time.Time) bool {
+	for _, backup := range backups {
+		if backup.Lock.IsActive(now) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,8 +1,10 @@
 package service
 
 import (
-	"github.com/itglobal/backupmonitor/pkg/model"
 	"log"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
 
 	"github.com/itglobal/backupmonitor/pkg/database"
 	"github.com/itglobal/backupmonitor/pkg/util"
@@ -15,7 +17,7 @@ type AccessKeyRepository interface {
 	// List project's access keys
 	List(projectID string) ([]*model.AccessKey, error)
 
-	// Get an access key by its value
+	// Get an access key by its value. Expired keys are rejected
 	Get(key string) (*model.AccessKey, error)
 
 	// Get an access key by project ID and access key ID
@@ -24,6 +26,12 @@ type AccessKeyRepository interface {
 	// Create new access key
 	Create(projectID string, args *model.AccessKeyCreateParams) (*model.AccessKey, error)
 
+	// Rotate an access key: issue a replacement and let the old key expire after an overlap period
+	Rotate(projectID string, accessKeyID int, args *model.AccessKeyRotateParams) (*model.AccessKey, error)
+
+	// Record that an access key has been used
+	MarkUsed(accessKeyID int, ip string) error
+
 	// Delete an access key
 	Delete(projectID string, accessKeyID int) error
 }
@@ -40,6 +48,74 @@ type accessKeyRepository struct {
 	provider database.Provider
 }
 
+func (s *accessKeyRepository) Initialize() error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Keys used to be stored in plaintext, so they are hashed once and wiped.
+	// Dialect's HasColumn can't be used here: it matches "primary key" of any sqlite table
+	var columns []struct{ Name string }
+	err = db.Raw(`PRAGMA table_info("access_keys")`).Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+
+	hasLegacyColumn := false
+	for _, column := range columns {
+		if column.Name == "key" {
+			hasLegacyColumn = true
+		}
+	}
+
+	if !hasLegacyColumn {
+		return nil
+	}
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	type legacyAccessKey struct {
+		ID  int
+		Key string
+	}
+
+	var legacyKeys []*legacyAccessKey
+	err = tx.Raw(`SELECT id, "key" FROM access_keys WHERE "key" IS NOT NULL AND "key" <> ''`).Scan(&legacyKeys).Error
+	if err != nil {
+		return err
+	}
+
+	for _, legacyKey := range legacyKeys {
+		err = tx.Model(&database.AccessKey{}).Where("id = ?", legacyKey.ID).Updates(map[string]interface{}{
+			"key_hash":   hashSecret(legacyKey.Key),
+			"key_prefix": accessKeyPrefix(legacyKey.Key),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// Column can't be dropped in sqlite, and old unique index allows multiple NULLs only
+	err = tx.Exec(`UPDATE access_keys SET "key" = NULL`).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	if len(legacyKeys) > 0 {
+		s.logger.Printf("%d access key(s) have been migrated to hashed storage", len(legacyKeys))
+	}
+
+	return nil
+}
+
 // List project's access keys
 func (s *accessKeyRepository) List(projectID string) ([]*model.AccessKey, error) {
 	db, err := s.provider.Open()
@@ -76,10 +152,20 @@ func (s *accessKeyRepository) Get(key string) (*model.AccessKey, error) {
 
 	// Fetches access key
 	eAccessKey := &database.AccessKey{}
-	err = db.Where("key = ?", key).First(&eAccessKey).Error
+	err = db.Where("key_hash = ?", hashSecret(key)).First(eAccessKey).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, model.NewError(model.ENotFound, "access key \"%s\" doesn't exist", key)
+			return nil, model.NewError(model.ENotFound, "access key \"%s\" doesn't exist", accessKeyPrefix(key))
+		}
+
+		return nil, err
+	}
+
+	// Access keys of trashed projects can't be used
+	err = db.Where("id = ?", eAccessKey.ProjectID).First(&database.Project{}).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.EAccessDenied, "project \"%s\" has been deleted", eAccessKey.ProjectID)
 		}
 
 		return nil, err
@@ -89,6 +175,10 @@ func (s *accessKeyRepository) Get(key string) (*model.AccessKey, error) {
 	mAccessKey := &model.AccessKey{}
 	eAccessKey.CopyToModel(mAccessKey)
 
+	if mAccessKey.IsExpired(time.Now()) {
+		return nil, model.NewError(model.EAccessDenied, "access key \"%s\" has expired", mAccessKey.Prefix)
+	}
+
 	return mAccessKey, nil
 }
 
@@ -121,6 +211,10 @@ func (s *accessKeyRepository) GetByID(projectID string, accessKeyID int) (*model
 // Create new access key
 func (s *accessKeyRepository) Create(projectID string, args *model.AccessKeyCreateParams) (*model.AccessKey, error) {
 	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
 
 	db, err := s.provider.Open()
 	if err != nil {
@@ -144,26 +238,148 @@ func (s *accessKeyRepository) Create(projectID string, args *model.AccessKeyCrea
 	}
 
 	// Create access key
-	eAccessKey := &database.AccessKey{
-		ProjectID: eProject.ID,
-		Key:       util.GenerateToken(),
-		Label:     args.Label,
+	mAccessKey, err := s.create(tx, &model.AccessKey{
+		ProjectID:       eProject.ID,
+		Label:           args.Label,
+		Scopes:          args.Scopes,
+		ExpiresAt:       args.ExpiresAt,
+		AllowedNetworks: args.AllowedNetworks,
+	})
+	if err != nil {
+		return nil, err
 	}
-	err = tx.Create(eAccessKey).Error
+
+	tx.Commit()
+	s.logger.Printf("new access key #%d \"%s\" (project \"%s\") has been created", mAccessKey.ID, mAccessKey.Label, mAccessKey.ProjectID)
+
+	return mAccessKey, nil
+}
+
+// Rotate an access key: issue a replacement and let the old key expire after an overlap period
+func (s *accessKeyRepository) Rotate(projectID string, accessKeyID int, args *model.AccessKeyRotateParams) (*model.AccessKey, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	overlap, _ := args.OverlapPeriod()
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eAccessKey := &database.AccessKey{}
+	err = tx.Where("project_id = ? and id = ?", projectID, accessKeyID).First(eAccessKey).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "access key #%d (project \"%s\") doesn't exist", accessKeyID, projectID)
+		}
+
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	mOldKey := eAccessKey.ToModel()
+	if mOldKey.IsExpired(now) {
+		return nil, model.NewError(model.EConflict, "access key #%d has expired and can't be rotated", accessKeyID)
+	}
+	if mOldKey.ReplacedBy != nil {
+		return nil, model.NewError(model.EConflict, "access key #%d has already been rotated", accessKeyID)
+	}
+
+	// Replacement keeps the lifetime of the old key unless another expiration date is requested
+	expiresAt := args.ExpiresAt
+	if expiresAt == nil && mOldKey.ExpiresAt != nil && mOldKey.CreatedAt != nil {
+		t := now.Add(mOldKey.ExpiresAt.Sub(*mOldKey.CreatedAt))
+		expiresAt = &t
+	}
+
+	mAccessKey, err := s.create(tx, &model.AccessKey{
+		ProjectID:       projectID,
+		Label:           mOldKey.Label,
+		Scopes:          mOldKey.Scopes,
+		ExpiresAt:       expiresAt,
+		AllowedNetworks: mOldKey.AllowedNetworks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Old key keeps working during overlap period, so clients can be switched to the new one
+	oldExpiresAt := now.Add(overlap)
+	if mOldKey.ExpiresAt == nil || oldExpiresAt.Before(*mOldKey.ExpiresAt) {
+		eAccessKey.ExpiresAt = &oldExpiresAt
+	}
+	eAccessKey.ReplacedByID = &mAccessKey.ID
+
+	err = tx.Save(eAccessKey).Error
 	if err != nil {
 		return nil, err
 	}
 
 	tx.Commit()
-	s.logger.Printf("new access key #%d \"%s\" (project \"%s\") has been created", eAccessKey.ID, eAccessKey.Label, eAccessKey.ProjectID)
+	s.logger.Printf(
+		"access key #%d \"%s\" (project \"%s\") has been rotated, replaced by #%d",
+		eAccessKey.ID,
+		eAccessKey.Label,
+		eAccessKey.ProjectID,
+		mAccessKey.ID,
+	)
+
+	return mAccessKey, nil
+}
+
+// Record that an access key has been used
+func (s *accessKeyRepository) MarkUsed(accessKeyID int, ip string) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Model(&database.AccessKey{}).Where("id = ?", accessKeyID).Updates(map[string]interface{}{
+		"last_used_at": time.Now().UTC(),
+		"last_used_ip": ip,
+	}).Error
+}
+
+// create generates a new key and stores its hash. Returned model is the only place where full key is available
+func (s *accessKeyRepository) create(tx *gorm.DB, mAccessKey *model.AccessKey) (*model.AccessKey, error) {
+	key := util.GenerateToken()
+	now := time.Now().UTC()
+
+	mAccessKey.Prefix = accessKeyPrefix(key)
+	mAccessKey.CreatedAt = &now
+
+	eAccessKey := &database.AccessKey{}
+	eAccessKey.CopyFromModel(mAccessKey)
+	eAccessKey.KeyHash = hashSecret(key)
+	err := tx.Create(eAccessKey).Error
+	if err != nil {
+		return nil, err
+	}
 
 	// Emit result
-	mAccessKey := &model.AccessKey{}
 	eAccessKey.CopyToModel(mAccessKey)
+	mAccessKey.Key = key
 
 	return mAccessKey, nil
 }
 
+func accessKeyPrefix(key string) string {
+	if len(key) > model.AccessKeyPrefixLength {
+		return key[:model.AccessKeyPrefixLength]
+	}
+
+	return key
+}
+
 // Delete an access key
 func (s *accessKeyRepository) Delete(projectID string, accessKeyID int) error {
 	db, err := s.provider.Open()
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// APITokenRepository contains methods to manage users' personal API tokens
+type APITokenRepository interface {
+	// List API tokens of a user
+	List(userID int) ([]*model.APIToken, error)
+
+	// Create new API token for a user. Returned model is the only place where full token is available
+	Create(user *model.User, args *model.APITokenCreateParams) (*model.APIToken, error)
+
+	// Authenticate an API token and record its use.
+	// Returns an access denied error if token is not valid or has expired
+	Authenticate(token, ip string) (*model.APIToken, error)
+
+	// Revoke an API token of a user
+	Revoke(userID int, id int) error
+}
+
+const apiTokenRepositoryKey = "APITokenRepository"
+
+// GetAPITokenRepository returns an implementation of APITokenRepository from DI container
+func GetAPITokenRepository(c di.Container) APITokenRepository {
+	return c.Get(apiTokenRepositoryKey).(APITokenRepository)
+}
+
+// An implementation of APITokenRepository
+type apiTokenRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// List API tokens of a user
+func (s *apiTokenRepository) List(userID int) ([]*model.APIToken, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var eTokens []*database.APIToken
+	err = db.Where("user_id = ?", userID).Order("id asc").Find(&eTokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mTokens := make([]*model.APIToken, len(eTokens))
+	for i, eToken := range eTokens {
+		mTokens[i] = eToken.ToModel()
+	}
+
+	return mTokens, nil
+}
+
+// Create new API token for a user
+func (s *apiTokenRepository) Create(user *model.User, args *model.APITokenCreateParams) (*model.APIToken, error) {
+	args.Normalize()
+	err := args.Validate(user)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	token := model.APITokenPrefix + util.GenerateToken()
+	mToken := &model.APIToken{
+		UserID:    user.ID,
+		Name:      args.Name,
+		Prefix:    token[:model.APITokenPrefixLength],
+		Role:      args.Role,
+		Projects:  args.Projects,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: args.ExpiresAt,
+	}
+	eToken := &database.APIToken{}
+	eToken.CopyFromModel(mToken)
+	eToken.TokenHash = hashSecret(token)
+	err = db.Create(eToken).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("new api token #%d \"%s\" has been created by user #%d", eToken.ID, eToken.Name, user.ID)
+
+	eToken.CopyToModel(mToken)
+	mToken.Token = token
+	return mToken, nil
+}
+
+// Authenticate an API token and record its use
+func (s *apiTokenRepository) Authenticate(token, ip string) (*model.APIToken, error) {
+	invalidToken := model.NewError(model.EAccessDenied, "invalid api token")
+
+	if !strings.HasPrefix(token, model.APITokenPrefix) {
+		return nil, invalidToken
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eToken := &database.APIToken{}
+	err = db.Where("token_hash = ?", hashSecret(token)).First(eToken).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, invalidToken
+		}
+
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	mToken := eToken.ToModel()
+	if mToken.IsExpired(now) {
+		return nil, model.NewError(model.EAccessDenied, "api token has expired")
+	}
+
+	// Failures to record last use shouldn't break requests
+	err = db.Model(eToken).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	}).Error
+	if err != nil {
+		s.logger.Printf("unable to record use of api token #%d: %v", eToken.ID, err)
+	}
+
+	return mToken, nil
+}
+
+// Revoke an API token of a user
+func (s *apiTokenRepository) Revoke(userID int, id int) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&database.APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return model.NewError(model.ENotFound, "api token #%d doesn't exist", id)
+	}
+
+	s.logger.Printf("api token #%d of user #%d has been revoked", id, userID)
+	return nil
+}
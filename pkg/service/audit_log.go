@@ -0,0 +1,200 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// AuditLog contains methods to record and query an append-only audit log
+type AuditLog interface {
+	// Record an audited action. Entry time is set automatically.
+	// Failures are logged, since they shouldn't break an audited action
+	Record(entry *model.AuditEntry)
+
+	// List a page of audit log entries matching a filter, newest first
+	List(args *model.AuditListParams) (*model.AuditPage, error)
+
+	// Iterate over all audit log entries matching a filter, oldest first (offset and limit are ignored)
+	Export(args *model.AuditListParams, fn func(entry *model.AuditEntry) error) error
+}
+
+const auditLogKey = "AuditLog"
+
+// GetAuditLog returns an implementation of AuditLog from DI container
+func GetAuditLog(c di.Container) AuditLog {
+	return c.Get(auditLogKey).(AuditLog)
+}
+
+// An implementation of AuditLog
+type auditLog struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// auditExportBatchSize is a number of entries loaded at once while exporting audit log
+const auditExportBatchSize = 500
+
+// Initialize protects audit log table from modification
+func (s *auditLog) Initialize() error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statements := []string{
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`,
+	}
+
+	for _, statement := range statements {
+		err = db.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Record an audited action
+func (s *auditLog) Record(entry *model.AuditEntry) {
+	entry.Time = time.Now().UTC()
+
+	err := s.record(entry)
+	if err != nil {
+		s.logger.Printf("unable to record audit entry %s: %v", entry, err)
+	}
+}
+
+func (s *auditLog) record(entry *model.AuditEntry) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	eEntry := &database.AuditEntry{}
+	eEntry.CopyFromModel(entry)
+	eEntry.ID = 0
+
+	err = db.Create(eEntry).Error
+	if err != nil {
+		return err
+	}
+
+	entry.ID = eEntry.ID
+	return nil
+}
+
+// List a page of audit log entries matching a filter
+func (s *auditLog) List(args *model.AuditListParams) (*model.AuditPage, error) {
+	args.Normalize()
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := s.filter(db.Model(&database.AuditEntry{}), args)
+
+	page := &model.AuditPage{Offset: args.Offset, Limit: args.Limit}
+	err = query.Count(&page.Total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var eEntries []*database.AuditEntry
+	err = query.Order("id desc").Offset(args.Offset).Limit(args.Limit).Find(&eEntries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page.Entries = make([]*model.AuditEntry, len(eEntries))
+	for i, eEntry := range eEntries {
+		page.Entries[i] = eEntry.ToModel()
+	}
+
+	return page, nil
+}
+
+// Iterate over all audit log entries matching a filter
+func (s *auditLog) Export(args *model.AuditListParams, fn func(entry *model.AuditEntry) error) error {
+	args.Normalize()
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Entries are loaded in batches by ID, so new entries don't shift pages while exporting
+	lastID := 0
+	for {
+		var eEntries []*database.AuditEntry
+		err = s.filter(db, args).
+			Where("id > ?", lastID).
+			Order("id asc").
+			Limit(auditExportBatchSize).
+			Find(&eEntries).Error
+		if err != nil {
+			return err
+		}
+
+		for _, eEntry := range eEntries {
+			err = fn(eEntry.ToModel())
+			if err != nil {
+				return err
+			}
+
+			lastID = eEntry.ID
+		}
+
+		if len(eEntries) < auditExportBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *auditLog) filter(query *gorm.DB, args *model.AuditListParams) *gorm.DB {
+	if args.Actor != "" {
+		query = query.Where("actor_name = ? or actor_id = ?", args.Actor, args.Actor)
+	}
+	if args.ActorType != "" {
+		query = query.Where("actor_type = ?", args.ActorType)
+	}
+	if strings.HasSuffix(args.Action, ".*") {
+		query = query.Where("action like ?", strings.TrimSuffix(args.Action, "*")+"%")
+	} else if args.Action != "" {
+		query = query.Where("action = ?", args.Action)
+	}
+	if args.TargetType != "" {
+		query = query.Where("target_type = ?", args.TargetType)
+	}
+	if args.TargetID != "" {
+		query = query.Where("target_id = ?", args.TargetID)
+	}
+	if args.ProjectID != "" {
+		query = query.Where("project_id = ?", args.ProjectID)
+	}
+	if args.IP != "" {
+		query = query.Where("ip = ?", args.IP)
+	}
+	if !args.From.IsZero() {
+		query = query.Where("time >= ?", args.From.UTC())
+	}
+	if !args.To.IsZero() {
+		query = query.Where("time < ?", args.To.UTC())
+	}
+
+	return query
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"log"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/sarulabs/di"
+)
+
+// Authenticator checks user's credentials
+type Authenticator interface {
+	// Authenticate a user by username and password.
+	// Returns an "invalid credentials" error if credentials are not valid
+	Authenticate(username, password string) (*model.User, error)
+}
+
+const authenticatorKey = "Authenticator"
+
+// GetAuthenticator returns an implementation of Authenticator from DI container
+func GetAuthenticator(c di.Container) Authenticator {
+	return c.Get(authenticatorKey).(Authenticator)
+}
+
+func newInvalidCredentialsError() error {
+	return model.NewError(model.EBadRequest, "invalid credentials")
+}
+
+// An implementation of Authenticator that tries a list of backends one by one
+type chainAuthenticator struct {
+	logger   *log.Logger
+	backends []Authenticator
+}
+
+// Authenticate a user by username and password
+func (s *chainAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	for _, backend := range s.backends {
+		user, err := backend.Authenticate(username, password)
+		if err == nil {
+			return user, nil
+		}
+
+		// Backends that are unavailable shouldn't prevent other ones from authenticating a user
+		if e, ok := err.(*model.Error); !ok || e.Code == model.EInternalError {
+			s.logger.Printf("unable to authenticate user \"%s\": %v", username, err)
+		} else if e.Code == model.EAccessDenied {
+			return nil, err
+		}
+	}
+
+	return nil, newInvalidCredentialsError()
+}
+
+// An implementation of Authenticator that checks passwords of local users
+type localAuthenticator struct {
+	userRepository UserRepository
+}
+
+// Authenticate a user by username and password
+func (s *localAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	user, err := s.userRepository.Get(username)
+	if err != nil {
+		return nil, newInvalidCredentialsError()
+	}
+
+	// External users have no usable password, so they have to sign in with their own backend
+	if user.IsDisabled || user.Source != model.UserSourceLocal || !user.CheckPassword(password) {
+		return nil, newInvalidCredentialsError()
+	}
+
+	return user, nil
+}
+
+// resolveProvisionedRole returns a role to assign to an externally authenticated user.
+// Roles are synchronized on every login if groups are mapped to roles.
+// Otherwise new users get default role, and existing ones keep the role that was set by admin
+func resolveProvisionedRole(
+	userRepository UserRepository,
+	source model.UserSource,
+	username string,
+	groups []string,
+	mapping model.RoleMapping,
+	defaultRole model.Role) (*model.Role, error) {
+	if len(mapping) == 0 {
+		existing, err := userRepository.Get(username)
+		if err == nil && existing.Source == source {
+			return nil, nil
+		}
+	}
+
+	role := mapping.Resolve(groups, defaultRole)
+	if !role.IsValid() {
+		return nil, model.NewError(model.EAccessDenied, "user \"%s\" has no role assigned", username)
+	}
+
+	return &role, nil
+}
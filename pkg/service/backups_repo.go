@@ -31,11 +31,44 @@ type BackupRepository interface {
 	// List project's backups
 	List(projectID string) ([]*model.Backup, error)
 
+	// Get a backup by its ID
+	Get(id string) (*model.Backup, error)
+
 	// Download project's backup content
 	Download(id string) (*BackupFile, error)
 
-	// Delete a backup
+	// Move a backup to trash. Pinned and locked backups and backups of projects on legal hold can't be deleted
 	Delete(id, reason string) error
+
+	// List backups in trash (of all projects if projectID is empty)
+	ListTrash(projectID string) ([]*model.Backup, error)
+
+	// Get a backup in trash
+	GetTrashed(id string) (*model.Backup, error)
+
+	// List all backups of a project, including trashed ones (and even if project itself is in trash)
+	ListAll(projectID string) ([]*model.Backup, error)
+
+	// Restore a backup from trash
+	Restore(id string) (*model.Backup, error)
+
+	// Delete a backup (either trashed or not) with its file permanently
+	Purge(id string) error
+
+	// Pin a backup (or unpin it if pin is nil)
+	Pin(id string, pin *model.BackupPin) (*model.Backup, error)
+
+	// List backups of all projects received within a time range
+	ListReceived(from, to time.Time) ([]*model.Backup, error)
+
+	// List backups of all projects deleted within a time range
+	ListDeletions(from, to time.Time) ([]*model.BackupDeletion, error)
+
+	// Get total length of stored backups for each project (including backups in trash)
+	GetStorageUsage() (map[string]int64, error)
+
+	// Mark backups with reasons why retention policy keeps them (map of backup ID to reason)
+	SetKeepReasons(reasons map[string]string) error
 }
 
 const backupRepositoryKey = "BackupRepository"
@@ -48,8 +81,39 @@ func GetBackupRepository(c di.Container) BackupRepository {
 type backupRepository struct {
 	logger            *log.Logger
 	provider          database.Provider
-	store             storage.Service
+	storage           storage.Registry
 	projectRepository ProjectRepository
+	quotas            QuotaService
+	auditLog          AuditLog
+}
+
+// Initialize assigns backups uploaded before storage backends were named to a backend,
+// so they don't move to another backend when STORAGE_DEFAULT changes.
+// Old storage is the only backend (named "default") unless named backends are already configured
+func (s *backupRepository) Initialize() error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	name := s.storage.Default()
+	if s.storage.Has(storage.DefaultBackendName) {
+		name = storage.DefaultBackendName
+	}
+
+	result := db.Unscoped().Model(&database.Backup{}).
+		Where("storage = ? OR storage IS NULL", "").
+		UpdateColumn("storage", name)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		s.logger.Printf("%d backup(s) have been assigned to storage backend \"%s\"", result.RowsAffected, name)
+	}
+
+	return nil
 }
 
 // Create new backup
@@ -73,6 +137,18 @@ func (s *backupRepository) Upload(projectID, filename string, source io.Reader)
 		return nil, model.NewError(model.EAccessDenied, "access denied")
 	}
 
+	// Reject upload early if a quota is already full
+	usages, err := s.getUsages(project)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, usage := range usages {
+		if usage.IsFull() && usage.Quota.Policy == model.QuotaPolicyReject {
+			return nil, quotaExceededError(usage, 0)
+		}
+	}
+
 	// Create backup
 	mBackup := &model.Backup{}
 	mBackup.ID = util.GenerateToken()
@@ -81,16 +157,44 @@ func (s *backupRepository) Upload(projectID, filename string, source io.Reader)
 	mBackup.Type = model.BackupTypeLast
 	mBackup.Time = time.Now().UTC()
 
-	// Upload backup file
+	// Upload backup file into project's storage backend
+	mBackup.Storage = project.Storage
+	if mBackup.Storage == "" {
+		mBackup.Storage = s.storage.Default()
+	}
+
+	store, err := s.storage.Get(mBackup.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	sourceWrapper := &readWrapper{reader: source}
 	fileRef := s.GenerateBackupFileName(project, filename)
-	fileRef, err = s.store.Upload(fileRef, sourceWrapper)
+	fileRef, err = store.Upload(fileRef, sourceWrapper)
 	if err != nil {
 		return nil, err
 	}
 	mBackup.StorageFilePath = string(fileRef)
 	mBackup.Length = sourceWrapper.length
 
+	// Backup file is deleted unless backup is saved, nothing would ever delete it otherwise
+	saved := false
+	defer func() {
+		if !saved {
+			_ = store.Delete(fileRef)
+		}
+	}()
+
+	// Pick backups to make room for backup file (or reject it) if it exceeds any quota
+	pruned, pruneReason, err := s.checkQuotas(project, mBackup.Length, usages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Backup file is locked for project's retention period (WORM mode) only after backup is saved,
+	// since a locked file couldn't be deleted if saving fails
+	mBackup.Lock = s.fileLock(project, store, mBackup.Time)
+
 	// Save backup to DB
 	eBackup := &database.Backup{}
 	eBackup.CopyFromModel(mBackup)
@@ -106,13 +210,32 @@ func (s *backupRepository) Upload(projectID, filename string, source io.Reader)
 		return nil, err
 	}
 
-	tx.Commit()
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.lockFile(store, fileRef, mBackup.Lock)
+	if err != nil {
+		// An unlocked file is of no use in WORM mode
+		if e := s.discard(eBackup); e != nil {
+			s.logger.Printf("unable to discard backup \"%s\": %v", eBackup.ID, e)
+		}
+		return nil, err
+	}
+	saved = true
+
+	// Backups are pruned only after new one is saved, so a failed upload doesn't cost any of them
+	s.prune(pruned, pruneReason)
+
+	s.warnUsages(project, usages)
 
 	s.logger.Printf(
-		"new backup \"%s\" (project \"%s\") has been uploaded (%s, see \"%s\")",
+		"new backup \"%s\" (project \"%s\") has been uploaded (%s, see \"%s:%s\")",
 		eBackup.ID,
 		eBackup.ProjectID,
 		humanize.Bytes(uint64(eBackup.Length)),
+		eBackup.Storage,
 		eBackup.StorageFilePath)
 	return mBackup, nil
 }
@@ -146,7 +269,7 @@ func (s *backupRepository) List(projectID string) ([]*model.Backup, error) {
 
 	// Load entities
 	eBackups := make([]*database.Backup, 0)
-	err = db.Where("project_id = ?", projectID).Order("time desc").Find(&eBackups).Error
+	err = db.Scopes(liveBackups).Where("backups.project_id = ?", projectID).Order("backups.time desc").Find(&eBackups).Error
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +283,27 @@ func (s *backupRepository) List(projectID string) ([]*model.Backup, error) {
 	return mBackups, nil
 }
 
+// Get a backup by its ID
+func (s *backupRepository) Get(id string) (*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eBackup := &database.Backup{}
+	err = db.Scopes(liveBackups).Where("backups.id = ?", id).First(eBackup).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "backup \"%s\" doesn't exist", id)
+		}
+
+		return nil, err
+	}
+
+	return eBackup.ToModel(), nil
+}
+
 // Download project's backup content
 func (s *backupRepository) Download(id string) (*BackupFile, error) {
 	db, err := s.provider.Open()
@@ -170,7 +314,7 @@ func (s *backupRepository) Download(id string) (*BackupFile, error) {
 
 	// Load backup
 	eBackup := &database.Backup{}
-	err = db.Where("id = ?", id).First(eBackup).Error
+	err = db.Scopes(liveBackups).Where("backups.id = ?", id).First(eBackup).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, model.NewError(model.ENotFound, "backup \"%s\" doesn't exist", id)
@@ -179,8 +323,13 @@ func (s *backupRepository) Download(id string) (*BackupFile, error) {
 		return nil, err
 	}
 
-	// Open backup file
-	file, err := s.store.Download(storage.FileRef(eBackup.StorageFilePath))
+	// Open backup file in the backend it has been uploaded to
+	store, err := s.storage.Get(eBackup.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := store.Download(storage.FileRef(eBackup.StorageFilePath))
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +356,7 @@ func (s *backupRepository) Delete(id, reason string) error {
 
 	// Load backup
 	eBackup := &database.Backup{}
-	err = db.Where("id = ?", id).First(eBackup).Error
+	err = tx.Where("id = ?", id).First(eBackup).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return model.NewError(model.ENotFound, "backup \"%s\" doesn't exist", id)
@@ -216,30 +365,565 @@ func (s *backupRepository) Delete(id, reason string) error {
 		return err
 	}
 
+	// Pinned backups and backups of projects on legal hold are kept
+	project, err := s.projectRepository.Get(eBackup.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	err = project.CheckBackupDeletion(eBackup.ToModel(), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
 	// Delete backup
 	err = tx.Delete(eBackup).Error
 	if err != nil {
 		return err
 	}
 
+	// Keep a record of deleted backup
+	eDeletion := &database.BackupDeletion{
+		BackupID:     eBackup.ID,
+		ProjectID:    eBackup.ProjectID,
+		FileName:     eBackup.FileName,
+		BackupTime:   eBackup.Time,
+		Length:       eBackup.Length,
+		DeletionTime: time.Now().UTC(),
+		Reason:       reason,
+	}
+	err = tx.Create(eDeletion).Error
+	if err != nil {
+		return err
+	}
+
 	// Update statuses of project's backups
 	err = s.UpdateBackupStatuses(tx, eBackup.ProjectID)
 	if err != nil {
 		return err
 	}
 
-	// Delete backup file
-	err = s.store.Delete(storage.FileRef(eBackup.FileName))
+	tx.Commit()
+
+	s.logger.Printf("backup \"%s\" (project \"%s\") has been moved to trash (%s)", eBackup.ID, eBackup.ProjectID, reason)
+	return nil
+}
+
+// List backups in trash
+func (s *backupRepository) ListTrash(projectID string) ([]*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := db.Unscoped().Where("deleted_at IS NOT NULL")
+	if projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	eBackups := make([]*database.Backup, 0)
+	err = query.Order("deleted_at desc").Find(&eBackups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mBackups := make([]*model.Backup, len(eBackups))
+	for i, eBackup := range eBackups {
+		mBackups[i] = eBackup.ToModel()
+	}
+
+	return mBackups, nil
+}
+
+// Get a backup in trash
+func (s *backupRepository) GetTrashed(id string) (*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eBackup := &database.Backup{}
+	err = db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(eBackup).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "backup \"%s\" isn't in trash", id)
+		}
+
+		return nil, err
+	}
+
+	return eBackup.ToModel(), nil
+}
+
+// List all backups of a project, including trashed ones
+func (s *backupRepository) ListAll(projectID string) ([]*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eBackups := make([]*database.Backup, 0)
+	err = db.Unscoped().Where("project_id = ?", projectID).Order("time desc").Find(&eBackups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mBackups := make([]*model.Backup, len(eBackups))
+	for i, eBackup := range eBackups {
+		mBackups[i] = eBackup.ToModel()
+	}
+
+	return mBackups, nil
+}
+
+// Restore a backup from trash
+func (s *backupRepository) Restore(id string) (*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eBackup := &database.Backup{}
+	err = tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(eBackup).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "backup \"%s\" isn't in trash", id)
+		}
+
+		return nil, err
+	}
+
+	// Backups of deleted projects are restored along with their project.
+	// Trashed backups count against quotas, so restoring one doesn't change quota usage
+	_, err = s.projectRepository.Get(eBackup.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Unscoped().Model(eBackup).Update("deleted_at", nil).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Restored backup is not deleted anymore
+	err = tx.Where("backup_id = ?", id).Delete(&database.BackupDeletion{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.UpdateBackupStatuses(tx, eBackup.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("backup \"%s\" (project \"%s\") has been restored from trash", eBackup.ID, eBackup.ProjectID)
+	return s.Get(id)
+}
+
+// Delete a backup with its file permanently
+func (s *backupRepository) Purge(id string) error {
+	db, err := s.provider.Open()
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
-	tx.Commit()
+	eBackup := &database.Backup{}
+	err = db.Unscoped().Where("id = ?", id).First(eBackup).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return model.NewError(model.ENotFound, "backup \"%s\" doesn't exist", id)
+		}
 
-	s.logger.Printf("backup \"%s\" (project \"%s\") has been deleted %s", eBackup.ID, eBackup.ProjectID, reason)
+		return err
+	}
+
+	// Locked files can't be deleted, neither can their backups
+	if mBackup := eBackup.ToModel(); mBackup.Lock.IsActive(time.Now()) {
+		return model.NewError(model.EConflict, "backup \"%s\" is locked until %s", id, mBackup.Lock.Until.Format(time.RFC3339))
+	}
+
+	// File is deleted first, so backup can be purged again if it fails
+	store, err := s.storage.Get(eBackup.Storage)
+	if err != nil {
+		return err
+	}
+
+	err = store.Delete(storage.FileRef(eBackup.StorageFilePath))
+	if err != nil {
+		return err
+	}
+
+	err = db.Unscoped().Delete(eBackup).Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("backup \"%s\" (project \"%s\") has been purged", eBackup.ID, eBackup.ProjectID)
+	return nil
+}
+
+// Pin a backup (or unpin it if pin is nil)
+func (s *backupRepository) Pin(id string, pin *model.BackupPin) (*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eBackup := &database.Backup{}
+	err = db.Where("id = ?", id).First(eBackup).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "backup \"%s\" doesn't exist", id)
+		}
+
+		return nil, err
+	}
+
+	mBackup := eBackup.ToModel()
+	mBackup.Pin = pin
+	eBackup.CopyFromModel(mBackup)
+
+	err = db.Save(eBackup).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if pin != nil {
+		s.logger.Printf("backup \"%s\" (project \"%s\") has been pinned by \"%s\": %s", id, eBackup.ProjectID, pin.PinnedBy, pin.Reason)
+	} else {
+		s.logger.Printf("backup \"%s\" (project \"%s\") has been unpinned", id, eBackup.ProjectID)
+	}
+
+	return eBackup.ToModel(), nil
+}
+
+// List backups of all projects received within a time range
+func (s *backupRepository) ListReceived(from, to time.Time) ([]*model.Backup, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eBackups := make([]*database.Backup, 0)
+	err = db.Where("time >= ? and time < ?", from, to).Order("time desc").Find(&eBackups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mBackups := make([]*model.Backup, len(eBackups))
+	for i, eBackup := range eBackups {
+		mBackups[i] = eBackup.ToModel()
+	}
+
+	return mBackups, nil
+}
+
+// List backups of all projects deleted within a time range
+func (s *backupRepository) ListDeletions(from, to time.Time) ([]*model.BackupDeletion, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eDeletions := make([]*database.BackupDeletion, 0)
+	err = db.Where("deletion_time >= ? and deletion_time < ?", from, to).Order("deletion_time desc").Find(&eDeletions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mDeletions := make([]*model.BackupDeletion, len(eDeletions))
+	for i, eDeletion := range eDeletions {
+		mDeletions[i] = eDeletion.ToModel()
+	}
+
+	return mDeletions, nil
+}
+
+// Get total length of stored backups for each project
+func (s *backupRepository) GetStorageUsage() (map[string]int64, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	type row struct {
+		ProjectID string
+		Length    int64
+	}
+
+	// Files of trashed backups occupy storage until they are purged
+	var rows []*row
+	err = db.Unscoped().Model(&database.Backup{}).
+		Select("project_id, sum(length) as length").
+		Where("length > 0").
+		Group("project_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]int64)
+	for _, r := range rows {
+		usage[r.ProjectID] = r.Length
+	}
+
+	return usage, nil
+}
+
+// Mark backups with reasons why retention policy keeps them
+func (s *backupRepository) SetKeepReasons(reasons map[string]string) error {
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	for id, reason := range reasons {
+		err = tx.Model(&database.Backup{}).Where("id = ?", id).Update("keep_reason", reason).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.Commit()
 	return nil
 }
 
+// getUsages returns usages of project's and global quotas (if any)
+func (s *backupRepository) getUsages(project *model.Project) ([]*model.StorageUsage, error) {
+	usages := make([]*model.StorageUsage, 0)
+
+	usage, err := s.quotas.ProjectUsage(project)
+	if err != nil {
+		return nil, err
+	}
+	usages = append(usages, usage)
+
+	usage, err = s.quotas.GlobalUsage()
+	if err != nil {
+		return nil, err
+	}
+	usages = append(usages, usage)
+
+	return usages, nil
+}
+
+// checkQuotas checks if a backup file of specified length fits into quotas.
+// If it doesn't and all exceeded quotas allow pruning, project's oldest backups are picked to make room for it
+// (along with a deletion reason), otherwise a "quota exceeded" error is returned
+func (s *backupRepository) checkQuotas(project *model.Project, length int64, usages []*model.StorageUsage) ([]*model.Backup, string, error) {
+	var excess int64
+	var exceeded *model.StorageUsage
+	for _, usage := range usages {
+		if !usage.Exceeds(length) {
+			continue
+		}
+
+		if usage.Quota.Policy != model.QuotaPolicyPrune {
+			return nil, "", quotaExceededError(usage, length)
+		}
+
+		if e := usage.Used + length - usage.Quota.Limit; e > excess {
+			excess = e
+			exceeded = usage
+		}
+	}
+
+	if excess == 0 {
+		return nil, "", nil
+	}
+
+	// Pick backups which can be deleted until they free enough space:
+	// ones in trash first (they count against quotas too), then oldest live ones
+	trash, err := s.ListTrash(project.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	backups, err := s.List(project.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Both lists are sorted from newest to oldest
+	backups = append(backups, trash...)
+
+	now := time.Now().UTC()
+	pruned := make([]*model.Backup, 0)
+	var freed int64
+	for i := len(backups) - 1; i >= 0 && freed < excess; i-- {
+		backup := backups[i]
+		if backup.Length <= 0 || project.CheckBackupDeletion(backup, now) != nil {
+			continue
+		}
+
+		pruned = append(pruned, backup)
+		freed += backup.Length
+	}
+
+	if freed < excess {
+		return nil, "", quotaExceededError(exceeded, length)
+	}
+
+	reason := fmt.Sprintf("exceeded project's storage quota (%s)", humanize.Bytes(uint64(exceeded.Quota.Limit)))
+	if exceeded.ProjectID == "" {
+		reason = fmt.Sprintf("exceeded global storage quota (%s)", humanize.Bytes(uint64(exceeded.Quota.Limit)))
+	}
+
+	return pruned, reason, nil
+}
+
+// prune permanently deletes backups picked to make room for a new one.
+// They are not kept in trash, since trashed backups still occupy storage but don't count against quotas
+func (s *backupRepository) prune(backups []*model.Backup, reason string) {
+	actor := model.AuditActor{Type: model.AuditActorPolicy, ID: "quota", Name: "storage quota"}
+
+	for _, backup := range backups {
+		// Backups in trash are already deleted
+		if backup.DeletedAt == nil {
+			err := s.Delete(backup.ID, reason)
+			if err != nil {
+				s.logger.Printf("unable to prune backup \"%s\" (project \"%s\"): %v", backup.ID, backup.ProjectID, err)
+				continue
+			}
+
+			s.auditLog.Record(&model.AuditEntry{
+				Actor:      actor,
+				Action:     model.AuditActionBackupDelete,
+				TargetType: model.AuditTargetBackup,
+				TargetID:   backup.ID,
+				ProjectID:  backup.ProjectID,
+				Changes:    model.NewAuditChanges(backup, nil),
+				Details:    reason,
+			})
+		}
+
+		// If file can't be deleted right now, backup is left in trash to be purged later
+		err := s.Purge(backup.ID)
+		if err != nil {
+			s.logger.Printf("unable to purge pruned backup \"%s\" (project \"%s\"): %v", backup.ID, backup.ProjectID, err)
+			continue
+		}
+
+		s.auditLog.Record(&model.AuditEntry{
+			Actor:      actor,
+			Action:     model.AuditActionBackupPurge,
+			TargetType: model.AuditTargetBackup,
+			TargetID:   backup.ID,
+			ProjectID:  backup.ProjectID,
+			Changes:    model.NewAuditChanges(backup, nil),
+			Details:    reason,
+		})
+	}
+}
+
+// warnUsages sends quota warnings if an upload has made usage cross a warning threshold
+func (s *backupRepository) warnUsages(project *model.Project, before []*model.StorageUsage) {
+	after, err := s.getUsages(project)
+	if err != nil {
+		s.logger.Printf("unable to check storage quotas: %v", err)
+		return
+	}
+
+	s.quotas.WarnUsage(project, before[0], after[0])
+	s.quotas.WarnUsage(nil, before[1], after[1])
+}
+
+func quotaExceededError(usage *model.StorageUsage, length int64) error {
+	quota := "global storage quota"
+	if usage.ProjectID != "" {
+		quota = fmt.Sprintf("storage quota of project \"%s\"", usage.ProjectID)
+	}
+
+	return model.NewError(
+		model.EQuotaExceeded,
+		"backup (%s) doesn't fit into %s, %s is used",
+		humanize.Bytes(uint64(length)),
+		quota,
+		usage.Description())
+}
+
+// fileLock returns a lock of a new backup file until project's retention period expires,
+// if storage is in WORM mode. Returns nil if file shouldn't be locked
+func (s *backupRepository) fileLock(project *model.Project, store storage.Service, now time.Time) *model.BackupLock {
+	locker, ok := store.(storage.Locker)
+	if !ok || locker.LockMode() == storage.LockModeNone {
+		return nil
+	}
+
+	period := project.RetentionPeriod()
+	if period <= 0 {
+		return nil
+	}
+
+	return &model.BackupLock{
+		Mode:  string(locker.LockMode()),
+		Until: now.Add(period),
+	}
+}
+
+// lockFile applies a lock returned by fileLock to a backup file
+func (s *backupRepository) lockFile(store storage.Service, fileRef storage.FileRef, lock *model.BackupLock) error {
+	if lock == nil {
+		return nil
+	}
+
+	return store.(storage.Locker).Lock(fileRef, lock.Until)
+}
+
+// discard deletes a just saved backup whose file couldn't be locked (file itself is deleted by caller)
+func (s *backupRepository) discard(eBackup *database.Backup) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	err = tx.Unscoped().Delete(eBackup).Error
+	if err != nil {
+		return err
+	}
+
+	err = s.UpdateBackupStatuses(tx, eBackup.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// liveBackups limits a query to backups of projects which are not in trash
+func liveBackups(db *gorm.DB) *gorm.DB {
+	return db.Select("backups.*").Joins("JOIN projects ON projects.id = backups.project_id AND projects.deleted_at IS NULL")
+}
+
 // Update statuses of project's backups
 func (s *backupRepository) UpdateBackupStatuses(tx *gorm.DB, projectID string) error {
 	// Load all backups
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// DeliveryRepository contains methods to manage notification outbox
+type DeliveryRepository interface {
+	// Enqueue new notification deliveries
+	Enqueue(args ...*model.DeliveryCreateParams) ([]*model.Delivery, error)
+
+	// List recent deliveries
+	List(args *model.DeliveryListParams) ([]*model.Delivery, error)
+
+	// Get a delivery by its ID
+	Get(id int) (*model.Delivery, error)
+
+	// List pending deliveries that are due to be sent
+	ListDue(now time.Time, limit int) ([]*model.Delivery, error)
+
+	// Claim a due delivery before sending it by moving its next attempt to a later time.
+	// Returns false if delivery is not due anymore (e.g. it has been claimed by another worker)
+	Claim(id int, now, until time.Time) (bool, error)
+
+	// Record a successful delivery attempt
+	MarkSent(id int, now time.Time) error
+
+	// Record a failed delivery attempt.
+	// If nextAttemptAt is nil, delivery is moved into dead-letter state
+	MarkFailed(id int, reason error, nextAttemptAt *time.Time) error
+
+	// Record a delivery that won't be sent because its channel is not configured
+	MarkSkipped(id int, reason error) error
+
+	// Schedule a delivery to be sent again
+	Redeliver(id int) (*model.Delivery, error)
+}
+
+const deliveryRepositoryKey = "DeliveryRepository"
+
+// GetDeliveryRepository returns an implementation of DeliveryRepository from DI container
+func GetDeliveryRepository(c di.Container) DeliveryRepository {
+	return c.Get(deliveryRepositoryKey).(DeliveryRepository)
+}
+
+// An implementation of DeliveryRepository
+type deliveryRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// Enqueue new notification deliveries
+func (s *deliveryRepository) Enqueue(args ...*model.DeliveryCreateParams) ([]*model.Delivery, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	now := time.Now().UTC()
+	mDeliveries := make([]*model.Delivery, 0, len(args))
+	for _, arg := range args {
+		arg.Normalize()
+		if arg.Target == "" {
+			continue
+		}
+
+		mDelivery := &model.Delivery{
+			ProjectID:     arg.ProjectID,
+			Event:         arg.Event,
+			Channel:       arg.Channel,
+			Target:        arg.Target,
+			Message:       arg.Message,
+			Status:        model.DeliveryStatusPending,
+			CreatedAt:     now,
+			NextAttemptAt: &now,
+		}
+
+		eDelivery := &database.Delivery{}
+		eDelivery.CopyFromModel(mDelivery)
+		err = tx.Create(eDelivery).Error
+		if err != nil {
+			return nil, err
+		}
+
+		eDelivery.CopyToModel(mDelivery)
+		mDeliveries = append(mDeliveries, mDelivery)
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mDelivery := range mDeliveries {
+		s.logger.Printf("delivery #%d (%s to \"%s\") has been enqueued", mDelivery.ID, mDelivery.Channel, mDelivery.Target)
+	}
+
+	return mDeliveries, nil
+}
+
+// List recent deliveries
+func (s *deliveryRepository) List(args *model.DeliveryListParams) ([]*model.Delivery, error) {
+	args.Normalize()
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := db.Order("id desc").Limit(args.Limit)
+	if args.ProjectID != "" {
+		query = query.Where("project_id = ?", args.ProjectID)
+	}
+	if args.Status != "" {
+		query = query.Where("status = ?", args.Status)
+	}
+
+	var eDeliveries []*database.Delivery
+	err = query.Find(&eDeliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mDeliveries := make([]*model.Delivery, len(eDeliveries))
+	for i, eDelivery := range eDeliveries {
+		mDeliveries[i] = eDelivery.ToModel()
+	}
+
+	return mDeliveries, nil
+}
+
+// Get a delivery by its ID
+func (s *deliveryRepository) Get(id int) (*model.Delivery, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eDelivery := &database.Delivery{}
+	err = db.Where("id = ?", id).First(eDelivery).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "delivery #%d doesn't exist", id)
+		}
+
+		return nil, err
+	}
+
+	return eDelivery.ToModel(), nil
+}
+
+// List pending deliveries that are due to be sent
+func (s *deliveryRepository) ListDue(now time.Time, limit int) ([]*model.Delivery, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var eDeliveries []*database.Delivery
+	err = db.
+		Where("status = ? and next_attempt_at <= ?", model.DeliveryStatusPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&eDeliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mDeliveries := make([]*model.Delivery, len(eDeliveries))
+	for i, eDelivery := range eDeliveries {
+		mDeliveries[i] = eDelivery.ToModel()
+	}
+
+	return mDeliveries, nil
+}
+
+// Claim a due delivery before sending it
+func (s *deliveryRepository) Claim(id int, now, until time.Time) (bool, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	// Claim is a single conditional update, so only one of concurrent workers succeeds.
+	// If worker stops before recording the attempt, delivery becomes due again at "until"
+	result := db.Model(&database.Delivery{}).
+		Where("id = ? and status = ? and next_attempt_at <= ?", id, model.DeliveryStatusPending, now).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// Record a successful delivery attempt
+func (s *deliveryRepository) MarkSent(id int, now time.Time) error {
+	return s.update(id, func(eDelivery *database.Delivery) {
+		eDelivery.Status = model.DeliveryStatusSent
+		eDelivery.Attempts++
+		eDelivery.LastError = ""
+		eDelivery.NextAttemptAt = nil
+		eDelivery.DeliveredAt = &now
+	})
+}
+
+// Record a failed delivery attempt
+func (s *deliveryRepository) MarkFailed(id int, reason error, nextAttemptAt *time.Time) error {
+	return s.update(id, func(eDelivery *database.Delivery) {
+		eDelivery.Attempts++
+		eDelivery.LastError = truncateString(reason.Error(), 1024)
+		eDelivery.NextAttemptAt = nextAttemptAt
+
+		if nextAttemptAt == nil {
+			eDelivery.Status = model.DeliveryStatusDead
+			s.logger.Printf("delivery #%d has been moved to dead-letter state after %d attempt(s)", eDelivery.ID, eDelivery.Attempts)
+		}
+	})
+}
+
+// Record a delivery that won't be sent because its channel is not configured
+func (s *deliveryRepository) MarkSkipped(id int, reason error) error {
+	return s.update(id, func(eDelivery *database.Delivery) {
+		eDelivery.Status = model.DeliveryStatusSkipped
+		eDelivery.Attempts++
+		eDelivery.LastError = truncateString(reason.Error(), 1024)
+		eDelivery.NextAttemptAt = nil
+	})
+}
+
+// Schedule a delivery to be sent again
+func (s *deliveryRepository) Redeliver(id int) (*model.Delivery, error) {
+	now := time.Now().UTC()
+	err := s.update(id, func(eDelivery *database.Delivery) {
+		eDelivery.Status = model.DeliveryStatusPending
+		eDelivery.Attempts = 0
+		eDelivery.NextAttemptAt = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("delivery #%d has been scheduled for redelivery", id)
+	return s.Get(id)
+}
+
+func (s *deliveryRepository) update(id int, fn func(eDelivery *database.Delivery)) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eDelivery := &database.Delivery{}
+	err = tx.Where("id = ?", id).First(eDelivery).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return model.NewError(model.ENotFound, "delivery #%d doesn't exist", id)
+		}
+
+		return err
+	}
+
+	fn(eDelivery)
+
+	err = tx.Save(eDelivery).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func truncateString(str string, length int) string {
+	if len(str) <= length {
+		return str
+	}
+
+	return str[0:length]
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
+)
+
+func TestDeliveryClaim(t *testing.T) {
+	deliveries := &deliveryRepository{log.New(ioutil.Discard, "", 0), newTestProvider(t)}
+
+	created, err := deliveries.Enqueue(&model.DeliveryCreateParams{
+		Event:   model.EventDigest,
+		Channel: model.NotificationChannelWebhook,
+		Target:  "http://localhost/hook",
+		Message: &model.NotificationMessage{Title: "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created[0].ID
+
+	due := func(now time.Time) int {
+		t.Helper()
+
+		list, err := deliveries.ListDue(now, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(list)
+	}
+
+	claim := func(now time.Time, expected bool) {
+		t.Helper()
+
+		claimed, err := deliveries.Claim(id, now, now.Add(5*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claimed != expected {
+			t.Fatalf("expected claim result to be %v", expected)
+		}
+	}
+
+	now := time.Now().UTC()
+	if due(now) != 1 {
+		t.Fatal("expected new delivery to be due")
+	}
+
+	claim(now, true)
+
+	// Claimed delivery isn't picked up by overlapping runs
+	claim(now.Add(10*time.Second), false)
+	if due(now.Add(10*time.Second)) != 0 {
+		t.Fatal("expected claimed delivery not to be due")
+	}
+
+	// Claim expires if attempt hasn't been recorded
+	if due(now.Add(6*time.Minute)) != 1 {
+		t.Fatal("expected delivery to be due after claim has expired")
+	}
+
+	// Sent delivery can't be claimed anymore
+	err = deliveries.MarkSent(id, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claim(now.Add(time.Hour), false)
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	htmlTemplate "html/template"
+	"log"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/sarulabs/di"
+)
+
+// DigestService contains methods to build summary reports across all projects
+type DigestService interface {
+	// Build a digest report for a time range
+	Build(from, to time.Time) (*model.Digest, error)
+
+	// Render a digest report in specified format
+	Render(digest *model.Digest, format model.DigestFormat) ([]byte, error)
+}
+
+const digestServiceKey = "DigestService"
+
+// GetDigestService returns an implementation of DigestService from DI container
+func GetDigestService(c di.Container) DigestService {
+	return c.Get(digestServiceKey).(DigestService)
+}
+
+// An implementation of DigestService
+type digestService struct {
+	logger            *log.Logger
+	projectRepository ProjectRepository
+	backupRepository  BackupRepository
+}
+
+// Build a digest report for a time range
+func (s *digestService) Build(from, to time.Time) (*model.Digest, error) {
+	projects, err := s.projectRepository.List(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.backupRepository.GetStorageUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := s.backupRepository.ListReceived(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	deletions, err := s.backupRepository.ListDeletions(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &model.Digest{
+		From:     from,
+		To:       to,
+		Projects: make([]*model.DigestProject, len(projects)),
+		Totals:   &model.DigestTotals{},
+	}
+
+	projectsByID := make(map[string]*model.DigestProject)
+	for i, project := range projects {
+		p := &model.DigestProject{
+			ID:           project.ID,
+			Name:         project.Name,
+			IsActive:     project.IsActive,
+			BackupStatus: project.BackupStatus,
+			StorageUsed:  usage[project.ID],
+		}
+
+		if project.LastBackup != nil {
+			t := project.LastBackup.Time
+			p.LastBackupTime = &t
+			p.LastBackupLength = project.LastBackup.Length
+		}
+
+		digest.Projects[i] = p
+		projectsByID[p.ID] = p
+
+		digest.Totals.Projects++
+		digest.Totals.StorageUsed += p.StorageUsed
+
+		switch p.BackupStatus {
+		case model.BackupStatusOutdated:
+			digest.Totals.Outdated++
+		case model.BackupStatusNone:
+			digest.Totals.WithoutBackups++
+		}
+	}
+
+	for _, backup := range received {
+		digest.Totals.BackupsReceived++
+		digest.Totals.BytesReceived += nonNegative(backup.Length)
+
+		p, exists := projectsByID[backup.ProjectID]
+		if exists {
+			p.BackupsReceived++
+			p.BytesReceived += nonNegative(backup.Length)
+		}
+	}
+
+	for _, deletion := range deletions {
+		// Backups that were both received and pruned within a period
+		// are counted as received too
+		isReceived := !deletion.Time.Before(from) && deletion.Time.Before(to)
+
+		digest.Totals.BackupsPruned++
+		digest.Totals.BytesPruned += nonNegative(deletion.Length)
+		if isReceived {
+			digest.Totals.BackupsReceived++
+			digest.Totals.BytesReceived += nonNegative(deletion.Length)
+		}
+
+		p, exists := projectsByID[deletion.ProjectID]
+		if exists {
+			p.BackupsPruned++
+			p.BytesPruned += nonNegative(deletion.Length)
+			if isReceived {
+				p.BackupsReceived++
+				p.BytesReceived += nonNegative(deletion.Length)
+			}
+		}
+	}
+
+	return digest, nil
+}
+
+// Render a digest report in specified format
+func (s *digestService) Render(digest *model.Digest, format model.DigestFormat) ([]byte, error) {
+	switch format {
+	case model.DigestFormatJSON:
+		return json.MarshalIndent(digest, "", "  ")
+
+	case model.DigestFormatHTML:
+		buffer := &bytes.Buffer{}
+		err := digestHTMLTemplate.Execute(buffer, digest)
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+
+	case model.DigestFormatMarkdown:
+		buffer := &bytes.Buffer{}
+		err := digestMarkdownTemplate.Execute(buffer, digest)
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+
+	case model.DigestFormatSlack:
+		buffer := &bytes.Buffer{}
+		err := digestSlackTemplate.Execute(buffer, digest)
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+
+	return nil, model.NewError(model.EBadRequest, "\"%s\" is not a valid digest format", format)
+}
+
+func nonNegative(length int64) int64 {
+	if length < 0 {
+		return 0
+	}
+
+	return length
+}
+
+var digestTemplateFuncs = map[string]interface{}{
+	"bytes": func(length int64) string {
+		return humanize.Bytes(uint64(nonNegative(length)))
+	},
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"ago": func(t *time.Time) string {
+		if t == nil {
+			return "never"
+		}
+		return humanize.Time(*t)
+	},
+	// Escapes control characters of Slack's mrkdwn markup
+	"mrkdwn": func(str string) string {
+		return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(str)
+	},
+}
+
+var digestMarkdownTemplate = textTemplate.Must(textTemplate.New("digest.md").Funcs(digestTemplateFuncs).Parse(
+	`# Backup digest
+
+{{ time .From }} - {{ time .To }}
+
+* Projects: {{ .Totals.Projects }} ({{ .Totals.Outdated }} outdated, {{ .Totals.WithoutBackups }} without backups)
+* Storage used: {{ bytes .Totals.StorageUsed }}
+* Backups received: {{ .Totals.BackupsReceived }} ({{ bytes .Totals.BytesReceived }})
+* Backups pruned: {{ .Totals.BackupsPruned }} ({{ bytes .Totals.BytesPruned }})
+
+## Projects
+{{ range .Projects }}
+* **{{ .ID }}** ({{ .Name }}): {{ .BackupStatus }}{{ if not .IsActive }}, disabled{{ end }}; last backup {{ ago .LastBackupTime }}{{ if .LastBackupTime }} ({{ bytes .LastBackupLength }}){{ end }}; storage {{ bytes .StorageUsed }}; received {{ .BackupsReceived }} ({{ bytes .BytesReceived }}); pruned {{ .BackupsPruned }} ({{ bytes .BytesPruned }})
+{{- end }}
+`))
+
+// Slack doesn't support CommonMark, so its variant uses mrkdwn markup (*bold*, no headings).
+// Report title is shown by message's header block
+var digestSlackTemplate = textTemplate.Must(textTemplate.New("digest.slack").Funcs(digestTemplateFuncs).Parse(
+	`{{ time .From }} - {{ time .To }}
+
+• Projects: {{ .Totals.Projects }} ({{ .Totals.Outdated }} outdated, {{ .Totals.WithoutBackups }} without backups)
+• Storage used: {{ bytes .Totals.StorageUsed }}
+• Backups received: {{ .Totals.BackupsReceived }} ({{ bytes .Totals.BytesReceived }})
+• Backups pruned: {{ .Totals.BackupsPruned }} ({{ bytes .Totals.BytesPruned }})
+
+*Projects*
+{{ range .Projects }}
+• *{{ mrkdwn .ID }}* ({{ mrkdwn .Name }}): {{ .BackupStatus }}{{ if not .IsActive }}, disabled{{ end }}; last backup {{ ago .LastBackupTime }}{{ if .LastBackupTime }} ({{ bytes .LastBackupLength }}){{ end }}; storage {{ bytes .StorageUsed }}; received {{ .BackupsReceived }} ({{ bytes .BytesReceived }}); pruned {{ .BackupsPruned }} ({{ bytes .BytesPruned }})
+{{- end }}
+`))
+
+var digestHTMLTemplate = htmlTemplate.Must(htmlTemplate.New("digest.html").Funcs(digestTemplateFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Backup digest</title>
+</head>
+<body>
+<h1>Backup digest</h1>
+<p>{{ time .From }} - {{ time .To }}</p>
+<ul>
+<li>Projects: {{ .Totals.Projects }} ({{ .Totals.Outdated }} outdated, {{ .Totals.WithoutBackups }} without backups)</li>
+<li>Storage used: {{ bytes .Totals.StorageUsed }}</li>
+<li>Backups received: {{ .Totals.BackupsReceived }} ({{ bytes .Totals.BytesReceived }})</li>
+<li>Backups pruned: {{ .Totals.BackupsPruned }} ({{ bytes .Totals.BytesPruned }})</li>
+</ul>
+<table border="1" cellpadding="4" cellspacing="0">
+<thead>
+<tr>
+<th>Project</th>
+<th>Status</th>
+<th>Last backup</th>
+<th>Last backup size</th>
+<th>Storage used</th>
+<th>Received</th>
+<th>Pruned</th>
+</tr>
+</thead>
+<tbody>
+{{- range .Projects }}
+<tr>
+<td><b>{{ .ID }}</b> ({{ .Name }})</td>
+<td>{{ .BackupStatus }}{{ if not .IsActive }}, disabled{{ end }}</td>
+<td>{{ ago .LastBackupTime }}</td>
+<td>{{ if .LastBackupTime }}{{ bytes .LastBackupLength }}{{ end }}</td>
+<td>{{ bytes .StorageUsed }}</td>
+<td>{{ .BackupsReceived }} ({{ bytes .BytesReceived }})</td>
+<td>{{ .BackupsPruned }} ({{ bytes .BytesPruned }})</td>
+</tr>
+{{- end }}
+</tbody>
+</table>
+</body>
+</html>
+`))
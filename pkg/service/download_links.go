@@ -0,0 +1,125 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/storage"
+	"github.com/sarulabs/di"
+)
+
+// DownloadLinkService issues and verifies short-lived backup download links
+type DownloadLinkService interface {
+	// Create a short-lived download link for a backup
+	Create(backup *model.Backup, ttl time.Duration) (*model.DownloadLink, error)
+
+	// Verify a download link signature
+	Verify(backupID, expires, signature string) error
+
+	// Max lifetime of a download link
+	MaxTTL() time.Duration
+}
+
+const downloadLinkServiceKey = "DownloadLinkService"
+
+// GetDownloadLinkService returns an implementation of DownloadLinkService from DI container
+func GetDownloadLinkService(c di.Container) DownloadLinkService {
+	return c.Get(downloadLinkServiceKey).(DownloadLinkService)
+}
+
+// An implementation of DownloadLinkService.
+// Links point either to the app (and are signed with HMAC-SHA256)
+// or directly to the storage (if it supports presigned URLs and they are enabled)
+type downloadLinkService struct {
+	logger    *log.Logger
+	key       []byte
+	maxTTL    time.Duration
+	publicURL string
+	storage   storage.Registry
+	presign   bool
+	now       func() time.Time
+}
+
+// Create a short-lived download link for a backup
+func (s *downloadLinkService) Create(backup *model.Backup, ttl time.Duration) (*model.DownloadLink, error) {
+	expiresAt := s.now().Add(ttl).UTC().Truncate(time.Second)
+
+	if s.presign {
+		store, err := s.storage.Get(backup.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		presigner, ok := store.(storage.Presigner)
+		if ok {
+			u, err := presigner.PresignDownload(storage.FileRef(backup.StorageFilePath), backup.FileName, ttl)
+			if err != nil {
+				return nil, err
+			}
+
+			s.logger.Printf("presigned download link for backup \"%s\" has been issued, expires at %s", backup.ID, expiresAt)
+			return &model.DownloadLink{URL: u, ExpiresAt: expiresAt}, nil
+		}
+	}
+
+	if len(s.key) == 0 {
+		return nil, model.NewError(model.ENotFound, "signed download links are disabled")
+	}
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := make(url.Values)
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(backup.ID, expires))
+
+	link := &model.DownloadLink{
+		URL:       fmt.Sprintf("%s/api/backup/%s?%s", s.publicURL, url.PathEscape(backup.ID), query.Encode()),
+		ExpiresAt: expiresAt,
+	}
+
+	s.logger.Printf("signed download link for backup \"%s\" has been issued, expires at %s", backup.ID, expiresAt)
+	return link, nil
+}
+
+// Verify a download link signature
+func (s *downloadLinkService) Verify(backupID, expires, signature string) error {
+	if len(s.key) == 0 {
+		return model.NewError(model.EAccessDenied, "signed download links are disabled")
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return model.NewError(model.EAccessDenied, "malformed download link")
+	}
+
+	expected := s.sign(backupID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return model.NewError(model.EAccessDenied, "invalid download link signature")
+	}
+
+	if s.now().Unix() > unix {
+		return model.NewError(model.EAccessDenied, "download link has expired")
+	}
+
+	return nil
+}
+
+// Max lifetime of a download link
+func (s *downloadLinkService) MaxTTL() time.Duration {
+	return s.maxTTL
+}
+
+func (s *downloadLinkService) sign(backupID, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(backupID))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"log"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// ProjectGrantRepository contains methods to manage users' project grants
+type ProjectGrantRepository interface {
+	// List all grants of a user
+	List(userID int) ([]*model.ProjectGrant, error)
+
+	// Create new grant for a user
+	Create(userID int, args *model.ProjectGrantCreateParams) (*model.ProjectGrant, error)
+
+	// Delete an existing grant of a user
+	Delete(userID int, id int) error
+
+	// GetAccess returns user's access rights
+	GetAccess(user *model.User) (*model.Access, error)
+}
+
+const projectGrantRepositoryKey = "ProjectGrantRepository"
+
+// GetProjectGrantRepository returns an implementation of ProjectGrantRepository from DI container
+func GetProjectGrantRepository(c di.Container) ProjectGrantRepository {
+	return c.Get(projectGrantRepositoryKey).(ProjectGrantRepository)
+}
+
+// An implementation of ProjectGrantRepository
+type projectGrantRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// List all grants of a user
+func (s *projectGrantRepository) List(userID int) ([]*model.ProjectGrant, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return s.list(db, userID)
+}
+
+// Create new grant for a user
+func (s *projectGrantRepository) Create(userID int, args *model.ProjectGrantCreateParams) (*model.ProjectGrant, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eUser := &database.User{}
+	err = tx.Where("id = ?", userID).First(eUser).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "user #%d doesn't exist", userID)
+		}
+
+		return nil, err
+	}
+
+	if model.Role(eUser.Role) == model.RoleAdmin {
+		return nil, model.NewError(model.EBadRequest, "admins have access to all projects and can't be granted access to a subset of them")
+	}
+
+	mGrant := &model.ProjectGrant{
+		UserID:  userID,
+		Tag:     args.Tag,
+		Project: args.Project,
+		Role:    args.Role,
+	}
+
+	eGrant := &database.ProjectGrant{}
+	eGrant.CopyFromModel(mGrant)
+	err = tx.Create(eGrant).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// A grant limits user to granted projects, even after it's revoked
+	err = tx.Model(eUser).Update("all_projects", false).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	eGrant.CopyToModel(mGrant)
+	s.logger.Printf("user #%d has been granted access: %v", userID, mGrant)
+
+	return mGrant, nil
+}
+
+// Delete an existing grant of a user
+func (s *projectGrantRepository) Delete(userID int, id int) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	eGrant := &database.ProjectGrant{}
+	err = db.Where("id = ? AND user_id = ?", id, userID).First(eGrant).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return model.NewError(model.ENotFound, "grant #%d of user #%d doesn't exist", id, userID)
+		}
+
+		return err
+	}
+
+	err = db.Delete(eGrant).Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("grant #%d of user #%d has been revoked", id, userID)
+	return nil
+}
+
+// GetAccess returns user's access rights
+func (s *projectGrantRepository) GetAccess(user *model.User) (*model.Access, error) {
+	access := &model.Access{User: user}
+	if user.Role == model.RoleAdmin {
+		return access, nil
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	access.Grants, err = s.list(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return access, nil
+}
+
+func (s *projectGrantRepository) list(db *gorm.DB, userID int) ([]*model.ProjectGrant, error) {
+	var eGrants []*database.ProjectGrant
+	err := db.Where("user_id = ?", userID).Order("id").Find(&eGrants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mGrants := make([]*model.ProjectGrant, len(eGrants))
+	for i, eGrant := range eGrants {
+		mGrants[i] = eGrant.ToModel()
+	}
+
+	return mGrants, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"path"
+	"testing"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+)
+
+// newTestProvider creates a provider for an empty database in a temporary directory
+func newTestProvider(t *testing.T) database.Provider {
+	t.Helper()
+
+	provider, err := database.NewProvider(path.Join(t.TempDir(), "sqlite.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return provider
+}
+
+func TestProjectGrantRevokeLastGrant(t *testing.T) {
+	provider := newTestProvider(t)
+	logger := log.New(ioutil.Discard, "", 0)
+	users := &userRepository{logger, provider}
+	grants := &projectGrantRepository{logger, provider}
+
+	err := users.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, _, err := users.Create(&model.UserCreateParams{UserName: "alice", Role: model.RoleOperator})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	granted := &model.Project{ID: "team-a-db", Tags: []string{"team-a"}}
+	other := &model.Project{ID: "team-b-db", Tags: []string{"team-b"}}
+
+	visible := func(expected ...bool) {
+		t.Helper()
+
+		user, err := users.GetByID(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		access, err := grants.GetAccess(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, project := range []*model.Project{granted, other} {
+			if access.CanView(project) != expected[i] {
+				t.Fatalf("expected visibility of \"%s\" to be %v, got %v", project.ID, expected[i], !expected[i])
+			}
+		}
+	}
+
+	// New users have their role on all projects
+	visible(true, true)
+
+	grant, err := grants.Create(user.ID, &model.ProjectGrantCreateParams{Tag: "team-a", Role: model.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visible(true, false)
+
+	// Revoking the last grant mustn't widen access to all projects
+	err = grants.Delete(user.ID, grant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visible(false, false)
+
+	// Access to all projects is given back explicitly only
+	allProjects := true
+	_, err = users.Update(user.ID, &model.UserUpdateParams{AllProjects: &allProjects})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visible(true, true)
+}
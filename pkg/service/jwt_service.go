@@ -1,10 +1,13 @@
 package service
 
 import (
+	"fmt"
 	"log"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/util"
 	"github.com/sarulabs/di"
 )
 
@@ -26,67 +29,98 @@ func NewJwtError(statusCode int, message string) *JwtError {
 
 // Jwt provides methods to create and to parse JWT tokens
 type Jwt interface {
-	// Validate a JWT token
-	ValidateToken(token string) (*model.User, *JwtError)
+	// Validate a JWT token and return its user and session
+	ValidateToken(token string) (*model.User, *model.Session, *JwtError)
 
-	// Generate new JWT token
-	GenerateToken(user *model.User) (string, error)
+	// Generate new short-lived JWT token within a session
+	GenerateToken(user *model.User, session *model.Session) (string, time.Time, error)
 }
 
 const jwtKey = "JwtService"
 
+// DefaultJWTKey is a default value of JWT_KEY, it must be changed in production
+const DefaultJWTKey = "test"
+
 // GetJwt returns an implementation of Jwt from DI container
 func GetJwt(c di.Container) Jwt {
 	return c.Get(jwtKey).(Jwt)
 }
 
 type jwtService struct {
-	logger         *log.Logger
-	tokenPassword  string
-	signingMethod  jwt.SigningMethod
-	userRepository UserRepository
+	logger            *log.Logger
+	tokenPassword     string
+	signingMethod     jwt.SigningMethod
+	ttl               time.Duration
+	userRepository    UserRepository
+	sessionRepository SessionRepository
 }
 
 // Validate a JWT token
-func (s *jwtService) ValidateToken(tokenStr string) (*model.User, *JwtError) {
+func (s *jwtService) ValidateToken(tokenStr string) (*model.User, *model.Session, *JwtError) {
 	if tokenStr == "" {
-		return nil, NewJwtError(401, "missing access token")
+		return nil, nil, NewJwtError(401, "missing access token")
 	}
 
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != s.signingMethod {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
 		return []byte(s.tokenPassword), nil
 	})
 
 	if err != nil || !token.Valid || token.Claims.Valid() != nil {
-		return nil, NewJwtError(401, "bad access token")
+		return nil, nil, NewJwtError(401, "bad access token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok {
-		id, exists := claims["id"]
-		if exists {
-			idStr, ok := id.(string)
-			if ok {
-				user, err := s.userRepository.Get(idStr)
-				if err == nil {
-					return user, nil
-				}
-			}
-		}
+	if !ok {
+		return nil, nil, NewJwtError(401, "bad access token")
+	}
+
+	// Tokens without expiration time or session are not accepted
+	if _, ok := claims["exp"]; !ok {
+		return nil, nil, NewJwtError(401, "bad access token")
+	}
+
+	username, _ := claims["id"].(string)
+	sessionID, _ := claims["sid"].(string)
+	if username == "" || sessionID == "" {
+		return nil, nil, NewJwtError(401, "bad access token")
 	}
 
-	return nil, NewJwtError(401, "bad access token")
+	user, err := s.userRepository.Get(username)
+	if err != nil {
+		return nil, nil, NewJwtError(401, "bad access token")
+	}
+
+	if user.IsDisabled {
+		return nil, nil, NewJwtError(401, "user is disabled")
+	}
+
+	session, err := s.sessionRepository.Get(sessionID)
+	if err != nil || session.UserID != user.ID {
+		return nil, nil, NewJwtError(401, "session has expired or has been revoked")
+	}
+
+	return user, session, nil
 }
 
-// Generate new JWT token
-func (s *jwtService) GenerateToken(user *model.User) (string, error) {
+// Generate new short-lived JWT token within a session
+func (s *jwtService) GenerateToken(user *model.User, session *model.Session) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.ttl)
+
 	token := jwt.New(s.signingMethod)
 	token.Claims = jwt.MapClaims{
-		"id": user.UserName,
+		"id":  user.UserName,
+		"sid": session.ID,
+		"jti": util.GenerateShortToken(),
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
 	}
 	str, err := token.SignedString([]byte(s.tokenPassword))
 	if err != nil {
 		s.logger.Printf("unable to generate jwt token for user #%d: %v", user.ID, err)
 	}
-	return str, err
+	return str, expiresAt, err
 }
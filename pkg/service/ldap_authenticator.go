@@ -0,0 +1,154 @@
+package service
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/itglobal/backupmonitor/pkg/model"
+)
+
+// LDAPConfig contains LDAP (or Active Directory) server settings
+type LDAPConfig struct {
+	URL               string
+	StartTLS          bool
+	BindDN            string
+	BindPassword      string
+	BaseDN            string
+	UserFilter        string
+	UsernameAttribute string
+	GroupAttribute    string
+	RoleMapping       model.RoleMapping
+	DefaultRole       model.Role
+}
+
+// An implementation of Authenticator that checks passwords against LDAP server.
+// User entry is looked up with a service account, then user's DN is bound with user's password
+type ldapAuthenticator struct {
+	logger         *log.Logger
+	config         LDAPConfig
+	userRepository UserRepository
+}
+
+// Authenticate a user by username and password
+func (s *ldapAuthenticator) Authenticate(username, password string) (*model.User, error) {
+	// LDAP servers treat a bind with empty password as anonymous one, and it always succeeds
+	if username == "" || password == "" {
+		return nil, newInvalidCredentialsError()
+	}
+
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if s.config.BindDN != "" {
+		err = conn.Bind(s.config.BindDN, s.config.BindPassword)
+		if err != nil {
+			return nil, fmt.Errorf("unable to bind ldap service account: %v", err)
+		}
+	}
+
+	request := ldap.NewSearchRequest(
+		s.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		0,
+		false,
+		fmt.Sprintf(s.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{s.config.UsernameAttribute, "displayName", "cn", "mail", s.config.GroupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			s.logger.Printf("ldap user filter matches more than one entry for \"%s\"", username)
+			return nil, newInvalidCredentialsError()
+		}
+
+		return nil, fmt.Errorf("unable to search ldap: %v", err)
+	}
+
+	if len(result.Entries) != 1 {
+		return nil, newInvalidCredentialsError()
+	}
+
+	entry := result.Entries[0]
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, newInvalidCredentialsError()
+		}
+
+		return nil, fmt.Errorf("unable to bind ldap user: %v", err)
+	}
+
+	args := &model.UserProvisionParams{
+		UserName:    entry.GetAttributeValue(s.config.UsernameAttribute),
+		DisplayName: entry.GetAttributeValue("displayName"),
+		Email:       entry.GetAttributeValue("mail"),
+	}
+	if args.UserName == "" {
+		args.UserName = username
+	}
+	if args.DisplayName == "" {
+		args.DisplayName = entry.GetAttributeValue("cn")
+	}
+	args.Normalize()
+
+	groups := ldapGroupNames(entry.GetAttributeValues(s.config.GroupAttribute))
+	args.Role, err = resolveProvisionedRole(s.userRepository, model.UserSourceLDAP, args.UserName, groups, s.config.RoleMapping, s.config.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepository.Provision(model.UserSourceLDAP, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *ldapAuthenticator) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(s.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to ldap server: %v", err)
+	}
+
+	if s.config.StartTLS {
+		host := strings.TrimPrefix(s.config.URL, "ldap://")
+		if i := strings.IndexAny(host, ":/"); i >= 0 {
+			host = host[:i]
+		}
+
+		err = conn.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to start tls with ldap server: %v", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// ldapGroupNames returns groups both as full DNs and as their common names,
+// so role mapping can use either of them
+func ldapGroupNames(values []string) []string {
+	groups := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		groups = append(groups, value)
+
+		dn, err := ldap.ParseDN(value)
+		if err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			groups = append(groups, dn.RDNs[0].Attributes[0].Value)
+		}
+	}
+
+	return groups
+}
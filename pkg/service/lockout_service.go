@@ -0,0 +1,287 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/sarulabs/di"
+)
+
+// LockoutService throttles failed sign in attempts and access key guesses
+type LockoutService interface {
+	// Check returns a "too many requests" error if next attempt for any of keys is delayed or locked
+	Check(keys ...model.LockoutKey) error
+
+	// Record a failed attempt from an IP address for each of keys.
+	// Lockouts caused by this attempt are written to audit log and reported
+	Fail(ip string, keys ...model.LockoutKey)
+
+	// Forget failed attempts of keys (e.g. after a successful sign in)
+	Reset(keys ...model.LockoutKey)
+
+	// List counters with recent failed attempts
+	List() []*model.Lockout
+
+	// Get state of a counter (or nil if there were no recent failed attempts)
+	Get(key model.LockoutKey) *model.Lockout
+
+	// Release a lockout and forget failed attempts. Returns false if there was nothing to release
+	Unlock(key model.LockoutKey) bool
+}
+
+const lockoutServiceKey = "LockoutService"
+
+// GetLockoutService returns an implementation of LockoutService from DI container
+func GetLockoutService(c di.Container) LockoutService {
+	return c.Get(lockoutServiceKey).(LockoutService)
+}
+
+// An implementation of LockoutService.
+// Counters are kept in memory, so they are reset when server restarts
+type lockoutService struct {
+	logger             *log.Logger
+	policy             model.LockoutPolicy
+	notify             bool
+	auditLog           AuditLog
+	router             NotificationRouter
+	deliveryRepository DeliveryRepository
+	mutex              sync.Mutex
+	counters           map[model.LockoutKey]*model.Lockout
+}
+
+// Check returns an error if next attempt is delayed or locked
+func (s *lockoutService) Check(keys ...model.LockoutKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().UTC()
+	var wait time.Duration
+	for _, key := range keys {
+		counter := s.counter(key, now)
+		if counter == nil {
+			continue
+		}
+
+		var until time.Time
+		if counter.IsLocked(now) {
+			until = *counter.LockedUntil
+		} else {
+			until = counter.LastFailureAt.Add(s.policy.DelayAfter(key.Scope, counter.Failures))
+		}
+
+		if until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+
+	if wait > 0 {
+		return model.NewError(model.ETooManyRequests, "too many failed attempts, try again in %s", wait.Truncate(time.Second)+time.Second)
+	}
+
+	return nil
+}
+
+// Record a failed attempt
+func (s *lockoutService) Fail(ip string, keys ...model.LockoutKey) {
+	s.mutex.Lock()
+
+	now := time.Now().UTC()
+	s.purge(now)
+
+	locked := make([]*model.Lockout, 0)
+	for _, key := range keys {
+		counter := s.counter(key, now)
+		if counter == nil {
+			counter = &model.Lockout{LockoutKey: key}
+			s.counters[key] = counter
+		}
+
+		counter.Failures++
+		counter.LastFailureAt = now
+
+		max := s.policy.MaxFailures[key.Scope]
+		if max > 0 && counter.Failures >= max && !counter.IsLocked(now) {
+			lockedUntil := now.Add(s.policy.Duration)
+			counter.LockedUntil = &lockedUntil
+
+			copy := *counter
+			locked = append(locked, &copy)
+		}
+	}
+
+	s.mutex.Unlock()
+
+	for _, lockout := range locked {
+		s.report(lockout, ip)
+	}
+}
+
+// Forget failed attempts of keys
+func (s *lockoutService) Reset(keys ...model.LockoutKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(s.counters, key)
+	}
+}
+
+// List counters with recent failed attempts
+func (s *lockoutService) List() []*model.Lockout {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.purge(time.Now().UTC())
+
+	list := make([]*model.Lockout, 0, len(s.counters))
+	for _, counter := range s.counters {
+		copy := *counter
+		list = append(list, &copy)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastFailureAt.After(list[j].LastFailureAt)
+	})
+
+	return list
+}
+
+// Get state of a counter
+func (s *lockoutService) Get(key model.LockoutKey) *model.Lockout {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter := s.counter(key, time.Now().UTC())
+	if counter == nil {
+		return nil
+	}
+
+	copy := *counter
+	return &copy
+}
+
+// Release a lockout
+func (s *lockoutService) Unlock(key model.LockoutKey) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter := s.counter(key, time.Now().UTC())
+	if counter == nil {
+		return false
+	}
+
+	delete(s.counters, key)
+	s.logger.Printf("%s has been unlocked", key)
+	return true
+}
+
+// counter returns an active counter, should be called under mutex
+func (s *lockoutService) counter(key model.LockoutKey, now time.Time) *model.Lockout {
+	counter, exists := s.counters[key]
+	if !exists || s.isStale(counter, now) {
+		return nil
+	}
+
+	return counter
+}
+
+// isStale returns true if a counter can be forgotten
+func (s *lockoutService) isStale(counter *model.Lockout, now time.Time) bool {
+	if counter.LockedUntil != nil {
+		return !counter.IsLocked(now)
+	}
+
+	return now.Sub(counter.LastFailureAt) > s.policy.Window
+}
+
+// purge drops stale counters, should be called under mutex
+func (s *lockoutService) purge(now time.Time) {
+	for key, counter := range s.counters {
+		if s.isStale(counter, now) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// report writes a lockout to log and audit log and sends a notification (if enabled)
+func (s *lockoutService) report(lockout *model.Lockout, ip string) {
+	var text string
+	targetType := model.AuditTargetIP
+	switch lockout.Scope {
+	case model.LockoutScopeUser:
+		targetType = model.AuditTargetUser
+		text = fmt.Sprintf("Sign in as \"%s\" has been locked", lockout.Key)
+	case model.LockoutScopeIP:
+		text = fmt.Sprintf("Sign in from %s has been locked", lockout.Key)
+	case model.LockoutScopeAccessKey:
+		text = fmt.Sprintf("Access keys from %s have been locked", lockout.Key)
+	}
+	text = fmt.Sprintf(
+		"%s until %s after %d failed attempt(s) (last one from %s)",
+		text,
+		lockout.LockedUntil.Format(time.RFC3339),
+		lockout.Failures,
+		ip)
+
+	s.logger.Print(text)
+
+	s.auditLog.Record(&model.AuditEntry{
+		Actor:      model.AuditActor{Type: model.AuditActorAnonymous},
+		Action:     model.AuditActionLockout,
+		TargetType: targetType,
+		TargetID:   lockout.Key,
+		IP:         ip,
+		Details:    text,
+	})
+
+	if s.notify {
+		err := s.sendNotification(lockout, ip, text)
+		if err != nil {
+			s.logger.Printf("unable to send lockout notification: %v", err)
+		}
+	}
+}
+
+func (s *lockoutService) sendNotification(lockout *model.Lockout, ip, text string) error {
+	route, err := s.router.Route(nil, model.EventLockout, model.EventLockout.Severity())
+	if err != nil {
+		return err
+	}
+
+	if len(route.Targets) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(struct {
+		*model.Lockout
+		IP string `json:"ip"`
+	}{lockout, ip})
+	if err != nil {
+		return err
+	}
+
+	msg := &model.NotificationMessage{
+		Title:   "Too many failed attempts",
+		Text:    text,
+		Emoji:   "lock",
+		Payload: payload,
+	}
+
+	args := make([]*model.DeliveryCreateParams, len(route.Targets))
+	for i, target := range route.Targets {
+		args[i] = &model.DeliveryCreateParams{
+			Event:   model.EventLockout,
+			Channel: target.Channel,
+			Target:  target.Address,
+			Message: msg,
+		}
+	}
+
+	_, err = s.deliveryRepository.Enqueue(args...)
+	return err
+}
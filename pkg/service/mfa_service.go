@@ -0,0 +1,585 @@
+package service
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/sarulabs/di"
+)
+
+// MFAService manages TOTP two-factor authentication of users
+type MFAService interface {
+	// Start TOTP enrollment and return a new secret.
+	// The secret is not used to sign in until it's activated with a valid one-time code
+	Enroll(userID int) (*model.MFAEnrollment, error)
+
+	// Activate pending secret with a one-time code and return new recovery codes
+	Activate(userID int, code string) ([]string, error)
+
+	// Disable MFA after checking a one-time (or recovery) code
+	Disable(userID int, code string) error
+
+	// Replace recovery codes with new ones after checking a one-time code
+	RegenerateRecoveryCodes(userID int, code string) ([]string, error)
+
+	// Reset user's MFA (e.g. if user has lost both authenticator and recovery codes)
+	Reset(userID int) error
+
+	// Issue a one-time token that allows user to enroll while signing in (if enrollment tokens are required)
+	IssueEnrollmentToken(userID int) (*model.MFAEnrollmentToken, error)
+
+	// Return an MFA challenge if user has to enter a one-time code to sign in (or nil otherwise)
+	Challenge(user *model.User) (*model.MFAChallenge, error)
+
+	// Check an enrollment token of an MFA challenge and return user's pending enrollment
+	StartEnrollment(token, enrollmentToken string) (*model.MFAEnrollment, error)
+
+	// Complete an MFA challenge with a one-time (or recovery) code.
+	// If user has enrolled while signing in, new recovery codes are returned too
+	Complete(token, code string) (*model.User, []string, error)
+}
+
+const mfaServiceKey = "MFAService"
+
+// GetMFAService returns an implementation of MFAService from DI container
+func GetMFAService(c di.Container) MFAService {
+	return c.Get(mfaServiceKey).(MFAService)
+}
+
+const (
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	mfaRecoveryCodeCount    = 10
+	mfaPeriod               = 30
+	mfaEnrollmentTokenTTL   = 24 * time.Hour
+)
+
+var mfaSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var mfaValidateOpts = totp.ValidateOpts{
+	Period:    mfaPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// A pending MFA challenge
+type mfaChallenge struct {
+	userID        int
+	enroll        bool
+	tokenRequired bool
+	attempts      int
+	expiresAt     time.Time
+}
+
+// An implementation of MFAService.
+// Secrets and hashed recovery codes are stored in users table, challenges are kept in memory
+type mfaService struct {
+	logger             *log.Logger
+	provider           database.Provider
+	settingsRepository SettingsRepository
+	issuer             string
+
+	mutex      sync.Mutex
+	challenges map[string]*mfaChallenge
+}
+
+// Start TOTP enrollment and return a new secret
+func (s *mfaService) Enroll(userID int) (*model.MFAEnrollment, error) {
+	var enrollment *model.MFAEnrollment
+	err := s.update(userID, func(eUser *database.User) error {
+		if eUser.MFAEnabled {
+			return model.NewError(model.EConflict, "two-factor authentication is already enabled")
+		}
+
+		if eUser.Source == string(model.UserSourceOIDC) {
+			return model.NewError(model.EBadRequest, "two-factor authentication of single sign-on users is managed by identity provider")
+		}
+
+		var err error
+		enrollment, err = s.generate(eUser)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// Activate pending secret with a one-time code and return new recovery codes
+func (s *mfaService) Activate(userID int, code string) ([]string, error) {
+	var codes []string
+	err := s.update(userID, func(eUser *database.User) error {
+		if eUser.MFAEnabled {
+			return model.NewError(model.EConflict, "two-factor authentication is already enabled")
+		}
+
+		if eUser.MFASecret == "" {
+			return model.NewError(model.EBadRequest, "two-factor authentication enrollment hasn't been started")
+		}
+
+		if !s.checkTOTP(eUser, code) {
+			return model.NewError(model.EBadRequest, "invalid one-time code")
+		}
+
+		codes = s.activate(eUser)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("two-factor authentication has been enabled for user #%d", userID)
+	return codes, nil
+}
+
+// Disable MFA after checking a one-time (or recovery) code
+func (s *mfaService) Disable(userID int, code string) error {
+	settings, err := s.settingsRepository.GetSecurity()
+	if err != nil {
+		return err
+	}
+
+	if settings.RequireMFA {
+		return model.NewError(model.EBadRequest, "two-factor authentication is required by administrator")
+	}
+
+	err = s.update(userID, func(eUser *database.User) error {
+		if !eUser.MFAEnabled {
+			return model.NewError(model.EBadRequest, "two-factor authentication is not enabled")
+		}
+
+		if !s.checkCode(eUser, code) {
+			return model.NewError(model.EBadRequest, "invalid one-time code")
+		}
+
+		s.clear(eUser)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("two-factor authentication has been disabled for user #%d", userID)
+	return nil
+}
+
+// Replace recovery codes with new ones after checking a one-time code
+func (s *mfaService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	var codes []string
+	err := s.update(userID, func(eUser *database.User) error {
+		if !eUser.MFAEnabled {
+			return model.NewError(model.EBadRequest, "two-factor authentication is not enabled")
+		}
+
+		if !s.checkTOTP(eUser, code) {
+			return model.NewError(model.EBadRequest, "invalid one-time code")
+		}
+
+		codes = s.activate(eUser)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("recovery codes have been regenerated for user #%d", userID)
+	return codes, nil
+}
+
+// Reset user's MFA
+func (s *mfaService) Reset(userID int) error {
+	err := s.update(userID, func(eUser *database.User) error {
+		s.clear(eUser)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("two-factor authentication has been reset for user #%d", userID)
+	return nil
+}
+
+// Issue a one-time token that allows user to enroll while signing in
+func (s *mfaService) IssueEnrollmentToken(userID int) (*model.MFAEnrollmentToken, error) {
+	token := &model.MFAEnrollmentToken{
+		Token:     util.GenerateToken(),
+		ExpiresAt: time.Now().Add(mfaEnrollmentTokenTTL).UTC(),
+	}
+
+	err := s.update(userID, func(eUser *database.User) error {
+		if eUser.MFAEnabled {
+			return model.NewError(model.EConflict, "two-factor authentication is already enabled")
+		}
+
+		if eUser.Source == string(model.UserSourceOIDC) {
+			return model.NewError(model.EBadRequest, "two-factor authentication of single sign-on users is managed by identity provider")
+		}
+
+		eUser.MFAEnrollmentToken = hashSecret(token.Token)
+		eUser.MFAEnrollmentTokenExpiresAt = &token.ExpiresAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("two-factor enrollment token has been issued for user #%d, expires at %s", userID, token.ExpiresAt)
+	return token, nil
+}
+
+// Return an MFA challenge if user has to enter a one-time code to sign in
+func (s *mfaService) Challenge(user *model.User) (*model.MFAChallenge, error) {
+	challenge := &mfaChallenge{
+		userID:    user.ID,
+		expiresAt: time.Now().Add(mfaChallengeTTL).UTC(),
+	}
+
+	result := &model.MFAChallenge{
+		Token:     util.GenerateToken(),
+		ExpiresAt: challenge.expiresAt,
+	}
+
+	if !user.MFAEnabled {
+		settings, err := s.settingsRepository.GetSecurity()
+		if err != nil {
+			return nil, err
+		}
+
+		if !settings.RequireMFA {
+			return nil, nil
+		}
+
+		// User has to enroll before signing in.
+		// Anyone who knows user's password could enroll their own authenticator,
+		// so admin may require an enrollment token to be handed to user out-of-band
+		challenge.enroll = true
+		if settings.RequireMFAEnrollmentToken {
+			challenge.tokenRequired = true
+			result.EnrollmentTokenRequired = true
+		} else {
+			err = s.update(user.ID, func(eUser *database.User) error {
+				var err error
+				result.Enrollment, err = s.pending(eUser)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.purge()
+	s.challenges[result.Token] = challenge
+	return result, nil
+}
+
+// Check an enrollment token of an MFA challenge and return user's pending enrollment
+func (s *mfaService) StartEnrollment(token, enrollmentToken string) (*model.MFAEnrollment, error) {
+	invalidChallenge := model.NewError(model.EAccessDenied, "sign in has expired, please try again")
+
+	s.mutex.Lock()
+	challenge, ok := s.challenges[token]
+	if ok {
+		challenge.attempts++
+		if challenge.attempts >= mfaChallengeMaxAttempts {
+			delete(s.challenges, token)
+		}
+	}
+	s.mutex.Unlock()
+
+	if !ok || !challenge.enroll || time.Now().After(challenge.expiresAt) {
+		return nil, invalidChallenge
+	}
+
+	var enrollment *model.MFAEnrollment
+	err := s.update(challenge.userID, func(eUser *database.User) error {
+		if eUser.IsDisabled {
+			return invalidChallenge
+		}
+
+		expiresAt := eUser.MFAEnrollmentTokenExpiresAt
+		if eUser.MFAEnrollmentToken == "" || expiresAt == nil || time.Now().After(*expiresAt) ||
+			!hashEquals(eUser.MFAEnrollmentToken, hashSecret(strings.TrimSpace(enrollmentToken))) {
+			return model.NewError(model.EBadRequest, "invalid or expired enrollment token")
+		}
+
+		var err error
+		enrollment, err = s.pending(eUser)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	challenge.tokenRequired = false
+	s.mutex.Unlock()
+
+	return enrollment, nil
+}
+
+// Complete an MFA challenge with a one-time (or recovery) code
+func (s *mfaService) Complete(token, code string) (*model.User, []string, error) {
+	invalidChallenge := model.NewError(model.EAccessDenied, "sign in has expired, please try again")
+
+	s.mutex.Lock()
+	challenge, ok := s.challenges[token]
+	tokenRequired := false
+	if ok {
+		tokenRequired = challenge.tokenRequired
+		challenge.attempts++
+		if challenge.attempts >= mfaChallengeMaxAttempts {
+			delete(s.challenges, token)
+		}
+	}
+	s.mutex.Unlock()
+
+	if !ok || time.Now().After(challenge.expiresAt) {
+		return nil, nil, invalidChallenge
+	}
+
+	if tokenRequired {
+		return nil, nil, model.NewError(model.EBadRequest, "enrollment token is required")
+	}
+
+	var user *model.User
+	var codes []string
+	err := s.update(challenge.userID, func(eUser *database.User) error {
+		if eUser.IsDisabled {
+			return invalidChallenge
+		}
+
+		if challenge.enroll {
+			if eUser.MFAEnabled || eUser.MFASecret == "" || !s.checkTOTP(eUser, code) {
+				return model.NewError(model.EBadRequest, "invalid one-time code")
+			}
+
+			codes = s.activate(eUser)
+		} else {
+			if !eUser.MFAEnabled {
+				return invalidChallenge
+			}
+
+			if !s.checkCode(eUser, code) {
+				return model.NewError(model.EBadRequest, "invalid one-time code")
+			}
+		}
+
+		user = eUser.ToModel()
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mutex.Lock()
+	delete(s.challenges, token)
+	s.mutex.Unlock()
+
+	if challenge.enroll {
+		s.logger.Printf("two-factor authentication has been enabled for user #%d", user.ID)
+	}
+
+	return user, codes, nil
+}
+
+// update loads a user, applies a function and saves the user within a transaction
+func (s *mfaService) update(userID int, fn func(eUser *database.User) error) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eUser := &database.User{}
+	err = tx.Where("id = ?", userID).First(eUser).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return model.NewError(model.ENotFound, "user #%d doesn't exist", userID)
+		}
+
+		return err
+	}
+
+	err = fn(eUser)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Save(eUser).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// generate creates a new pending secret
+func (s *mfaService) generate(eUser *database.User) (*model.MFAEnrollment, error) {
+	enrollment, err := s.provision(eUser.UserName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	eUser.MFASecret = enrollment.Secret
+	eUser.MFAEnabled = false
+	eUser.MFARecoveryCodes = ""
+	eUser.MFALastStep = 0
+	return enrollment, nil
+}
+
+// pending returns user's pending enrollment, a new secret is generated only if there's no pending one.
+// Otherwise each sign in attempt would replace a secret user might have already added to authenticator app
+func (s *mfaService) pending(eUser *database.User) (*model.MFAEnrollment, error) {
+	if eUser.MFAEnabled {
+		return nil, model.NewError(model.EConflict, "two-factor authentication is already enabled")
+	}
+
+	if eUser.MFASecret != "" {
+		secret, err := mfaSecretEncoding.DecodeString(eUser.MFASecret)
+		if err == nil {
+			return s.provision(eUser.UserName, secret)
+		}
+	}
+
+	return s.generate(eUser)
+}
+
+// provision creates provisioning URI and QR code for a secret (a random one if secret is nil)
+func (s *mfaService) provision(username string, secret []byte) (*model.MFAEnrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.issuer,
+		AccountName: username,
+		Period:      mfaPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+		Secret:      secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	var buff bytes.Buffer
+	err = png.Encode(&buff, image)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MFAEnrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buff.Bytes()),
+	}, nil
+}
+
+// activate enables MFA and replaces recovery codes with new ones
+func (s *mfaService) activate(eUser *database.User) []string {
+	codes := make([]string, mfaRecoveryCodeCount)
+	hashes := make([]string, mfaRecoveryCodeCount)
+	for i := range codes {
+		token := util.GenerateShortToken()
+		codes[i] = fmt.Sprintf("%s-%s-%s-%s", token[0:4], token[4:8], token[8:12], token[12:16])
+		hashes[i] = hashSecret(token)
+	}
+
+	eUser.MFAEnabled = true
+	eUser.MFARecoveryCodes = strings.Join(hashes, ";")
+	eUser.MFAEnrollmentToken = ""
+	eUser.MFAEnrollmentTokenExpiresAt = nil
+	return codes
+}
+
+func (s *mfaService) clear(eUser *database.User) {
+	eUser.MFAEnabled = false
+	eUser.MFASecret = ""
+	eUser.MFARecoveryCodes = ""
+	eUser.MFALastStep = 0
+	eUser.MFAEnrollmentToken = ""
+	eUser.MFAEnrollmentTokenExpiresAt = nil
+}
+
+// checkCode checks either a one-time code or a recovery code
+func (s *mfaService) checkCode(eUser *database.User, code string) bool {
+	return s.checkTOTP(eUser, code) || s.useRecoveryCode(eUser, code)
+}
+
+// checkTOTP checks a one-time code allowing one period of clock skew.
+// Each code can be used only once
+func (s *mfaService) checkTOTP(eUser *database.User, code string) bool {
+	code = model.NormalizeMFACode(code)
+	if len(code) != int(otp.DigitsSix) {
+		return false
+	}
+
+	step := time.Now().Unix() / mfaPeriod
+	for i := step - 1; i <= step+1; i++ {
+		if i <= eUser.MFALastStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(eUser.MFASecret, time.Unix(i*mfaPeriod, 0), mfaValidateOpts)
+		if err != nil {
+			return false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			eUser.MFALastStep = i
+			return true
+		}
+	}
+
+	return false
+}
+
+// useRecoveryCode checks a recovery code and removes it if it's valid
+func (s *mfaService) useRecoveryCode(eUser *database.User, code string) bool {
+	hash := hashSecret(model.NormalizeMFACode(code))
+
+	hashes := strings.Split(eUser.MFARecoveryCodes, ";")
+	for i, h := range hashes {
+		if h != "" && hashEquals(h, hash) {
+			eUser.MFARecoveryCodes = strings.Join(append(hashes[:i], hashes[i+1:]...), ";")
+			s.logger.Printf("user #%d has used a recovery code, %d left", eUser.ID, len(hashes)-1)
+			return true
+		}
+	}
+
+	return false
+}
+
+// purge drops expired challenges, should be called under mutex
+func (s *mfaService) purge() {
+	now := time.Now()
+	for k, v := range s.challenges {
+		if now.After(v.expiresAt) {
+			delete(s.challenges, k)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/pquerna/otp/totp"
+)
+
+const mfaTestSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func newTestMFAService(provider database.Provider) *mfaService {
+	logger := log.New(ioutil.Discard, "", 0)
+	return &mfaService{
+		logger:             logger,
+		provider:           provider,
+		settingsRepository: &settingsRepository{logger, provider},
+		issuer:             "Test",
+		challenges:         make(map[string]*mfaChallenge),
+	}
+}
+
+// mfaTestStep returns a time step relative to the current one
+func mfaTestStep(offset int64) int64 {
+	return time.Now().Unix()/mfaPeriod + offset
+}
+
+// waitForMFAStep waits for a new time step if the current one is about to end,
+// so that codes generated by a test don't cross a step boundary before they are checked
+func waitForMFAStep() {
+	elapsed := time.Now().UnixNano() % int64(mfaPeriod*time.Second)
+	if left := time.Duration(int64(mfaPeriod*time.Second) - elapsed); left < 2*time.Second {
+		time.Sleep(left)
+	}
+}
+
+// mfaTestCode generates a one-time code for a time step relative to the current one
+func mfaTestCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := totp.GenerateCodeCustom(secret, time.Unix(mfaTestStep(offset)*mfaPeriod, 0), mfaValidateOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
+
+func TestMFACheckTOTP(t *testing.T) {
+	s := newTestMFAService(nil)
+	waitForMFAStep()
+
+	cases := []struct {
+		name string
+		// One-time code is generated for this step offset unless code is set
+		offset int64
+		code   string
+		// Offset of the last used step, if any code has been used
+		used     bool
+		lastUsed int64
+		valid    bool
+	}{
+		{name: "current code", offset: 0, valid: true},
+		{name: "previous code (clock skew)", offset: -1, valid: true},
+		{name: "next code (clock skew)", offset: 1, valid: true},
+		{name: "expired code", offset: -2, valid: false},
+		{name: "future code", offset: 2, valid: false},
+		{name: "wrong code", code: "abcdef", valid: false},
+		{name: "too short code", code: "12345", valid: false},
+		{name: "replayed code", offset: 0, used: true, lastUsed: 0, valid: false},
+		{name: "code older than last used one", offset: -1, used: true, lastUsed: 0, valid: false},
+		{name: "code newer than last used one", offset: 1, used: true, lastUsed: 0, valid: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			eUser := &database.User{MFASecret: mfaTestSecret}
+			if c.used {
+				eUser.MFALastStep = mfaTestStep(c.lastUsed)
+			}
+
+			code := c.code
+			if code == "" {
+				code = mfaTestCode(t, mfaTestSecret, c.offset)
+			}
+
+			if s.checkTOTP(eUser, code) != c.valid {
+				t.Fatalf("expected code validity to be %v", c.valid)
+			}
+
+			// Accepted code can't be used again
+			if c.valid && s.checkTOTP(eUser, code) {
+				t.Fatal("expected code to be rejected when replayed")
+			}
+		})
+	}
+}
+
+func TestMFARecoveryCodes(t *testing.T) {
+	s := newTestMFAService(nil)
+	waitForMFAStep()
+
+	eUser := &database.User{MFASecret: mfaTestSecret}
+	codes := s.activate(eUser)
+
+	if len(codes) != mfaRecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", mfaRecoveryCodeCount, len(codes))
+	}
+
+	cases := []struct {
+		name  string
+		code  string
+		valid bool
+		left  int
+	}{
+		{"recovery code", codes[0], true, mfaRecoveryCodeCount - 1},
+		{"used recovery code", codes[0], false, mfaRecoveryCodeCount - 1},
+		{"recovery code without dashes", strings.ReplaceAll(codes[1], "-", ""), true, mfaRecoveryCodeCount - 2},
+		{"recovery code in upper case", strings.ToUpper(codes[2]), true, mfaRecoveryCodeCount - 3},
+		{"unknown recovery code", "0000-0000-0000-0000", false, mfaRecoveryCodeCount - 3},
+		{"one-time code", mfaTestCode(t, mfaTestSecret, 0), true, mfaRecoveryCodeCount - 3},
+		{"replayed one-time code", mfaTestCode(t, mfaTestSecret, 0), false, mfaRecoveryCodeCount - 3},
+	}
+
+	// Cases depend on each other, since each valid code is used up
+	for _, c := range cases {
+		if s.checkCode(eUser, c.code) != c.valid {
+			t.Fatalf("%s: expected code validity to be %v", c.name, c.valid)
+		}
+
+		left := len(strings.Split(eUser.MFARecoveryCodes, ";"))
+		if left != c.left {
+			t.Fatalf("%s: expected %d recovery codes left, got %d", c.name, c.left, left)
+		}
+	}
+}
+
+func TestMFAReplayIsPersisted(t *testing.T) {
+	provider := newTestProvider(t)
+	users := &userRepository{log.New(ioutil.Discard, "", 0), provider}
+	s := newTestMFAService(provider)
+
+	user, _, err := users.Create(&model.UserCreateParams{UserName: "alice", Role: model.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enrollment, err := s.Enroll(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitForMFAStep()
+	code := mfaTestCode(t, enrollment.Secret, 0)
+	codes, err := s.Activate(user.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Code used to activate MFA can't be used again
+	_, err = s.RegenerateRecoveryCodes(user.ID, code)
+	if err == nil {
+		t.Fatal("expected replayed one-time code to be rejected")
+	}
+
+	// Recovery codes aren't accepted where only a one-time code is allowed
+	_, err = s.RegenerateRecoveryCodes(user.ID, codes[0])
+	if err == nil {
+		t.Fatal("expected recovery code to be rejected")
+	}
+
+	err = s.Disable(user.ID, codes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err = users.GetByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.MFAEnabled {
+		t.Fatal("expected two-factor authentication to be disabled")
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/sarulabs/di"
+)
+
+// NotificationRouter resolves notification targets for events
+type NotificationRouter interface {
+	// Resolve targets of a notification event.
+	// Project is nil for project-less events (e.g. digest reports).
+	// Project's own targets (if enabled) override global routing rules
+	Route(project *model.Project, event model.EventType, severity model.Severity) (*model.Route, error)
+
+	// Show which targets an event would reach
+	Test(args *model.RouteTestParams) (*model.Route, error)
+}
+
+const notificationRouterKey = "NotificationRouter"
+
+// GetNotificationRouter returns an implementation of NotificationRouter from DI container
+func GetNotificationRouter(c di.Container) NotificationRouter {
+	return c.Get(notificationRouterKey).(NotificationRouter)
+}
+
+// An implementation of NotificationRouter
+type notificationRouter struct {
+	logger                       *log.Logger
+	projectRepository            ProjectRepository
+	notificationTargetRepository NotificationTargetRepository
+	routingRuleRepository        RoutingRuleRepository
+}
+
+// Resolve targets of a notification event
+func (s *notificationRouter) Route(project *model.Project, event model.EventType, severity model.Severity) (*model.Route, error) {
+	route := &model.Route{
+		Event:    event,
+		Severity: severity,
+		Targets:  make([]*model.RouteTarget, 0),
+	}
+
+	seen := make(map[string]bool)
+	add := func(target *model.RouteTarget) {
+		key := fmt.Sprintf("%s:%s", target.Channel, target.Address)
+		if target.Address == "" || seen[key] {
+			return
+		}
+
+		seen[key] = true
+		route.Targets = append(route.Targets, target)
+	}
+
+	if project != nil {
+		route.ProjectID = project.ID
+
+		// Projects with disabled notifications are not notified even by global routing rules
+		if project.Notifications == nil || !project.Notifications.Enabled {
+			route.Muted = true
+			return route, nil
+		}
+
+		if hasProjectTargets(project) {
+			route.Override = true
+
+			addAll := func(channel model.NotificationChannel, addresses []string) {
+				for _, address := range addresses {
+					add(&model.RouteTarget{Channel: channel, Address: address, Source: "project"})
+				}
+			}
+
+			addAll(model.NotificationChannelSlack, project.Notifications.SlackUsers)
+			addAll(model.NotificationChannelTelegram, project.Notifications.TelegramUsers)
+			addAll(model.NotificationChannelWebhook, project.Notifications.Webhooks)
+			return route, nil
+		}
+	}
+
+	rules, err := s.routingRuleRepository.List()
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := s.notificationTargetRepository.List()
+	if err != nil {
+		return nil, err
+	}
+
+	targetsByName := make(map[string]*model.NotificationTarget)
+	for _, target := range targets {
+		targetsByName[target.Name] = target
+	}
+
+	for _, rule := range rules {
+		if !rule.Match(project, event, severity) {
+			continue
+		}
+
+		for _, name := range rule.Targets {
+			target, exists := targetsByName[name]
+			if !exists {
+				s.logger.Printf("routing rule #%d refers to a missing target \"%s\"", rule.ID, name)
+				continue
+			}
+
+			add(&model.RouteTarget{
+				Channel: target.Channel,
+				Address: target.Address,
+				Target:  target.Name,
+				Source:  fmt.Sprintf("rule #%d (%s)", rule.ID, rule.Name),
+			})
+		}
+
+		if rule.Stop {
+			break
+		}
+	}
+
+	return route, nil
+}
+
+// Show which targets an event would reach
+func (s *notificationRouter) Test(args *model.RouteTestParams) (*model.Route, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var project *model.Project
+	if args.ProjectID != "" {
+		project, err = s.projectRepository.Get(args.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Route(project, args.Event, args.Severity)
+}
+
+func hasProjectTargets(project *model.Project) bool {
+	n := project.Notifications
+	return len(n.SlackUsers) > 0 || len(n.TelegramUsers) > 0 || len(n.Webhooks) > 0
+}
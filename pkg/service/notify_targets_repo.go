@@ -0,0 +1,223 @@
+package service
+
+import (
+	"log"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// NotificationTargetRepository contains methods to manage named notification targets
+type NotificationTargetRepository interface {
+	// List all notification targets
+	List() ([]*model.NotificationTarget, error)
+
+	// Get a notification target by its name
+	Get(name string) (*model.NotificationTarget, error)
+
+	// Create new notification target
+	Create(args *model.NotificationTargetCreateParams) (*model.NotificationTarget, error)
+
+	// Update an existing notification target
+	Update(name string, args *model.NotificationTargetUpdateParams) (*model.NotificationTarget, error)
+
+	// Delete an existing notification target.
+	// Targets that are used by routing rules can't be deleted
+	Delete(name string) error
+}
+
+const notificationTargetRepositoryKey = "NotificationTargetRepository"
+
+// GetNotificationTargetRepository returns an implementation of NotificationTargetRepository from DI container
+func GetNotificationTargetRepository(c di.Container) NotificationTargetRepository {
+	return c.Get(notificationTargetRepositoryKey).(NotificationTargetRepository)
+}
+
+// An implementation of NotificationTargetRepository
+type notificationTargetRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// List all notification targets
+func (s *notificationTargetRepository) List() ([]*model.NotificationTarget, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var eTargets []*database.NotificationTarget
+	err = db.Order("name").Find(&eTargets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mTargets := make([]*model.NotificationTarget, len(eTargets))
+	for i, eTarget := range eTargets {
+		mTargets[i] = eTarget.ToModel()
+	}
+
+	return mTargets, nil
+}
+
+// Get a notification target by its name
+func (s *notificationTargetRepository) Get(name string) (*model.NotificationTarget, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eTarget, err := s.find(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return eTarget.ToModel(), nil
+}
+
+// Create new notification target
+func (s *notificationTargetRepository) Create(args *model.NotificationTargetCreateParams) (*model.NotificationTarget, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	var count int
+	err = tx.Model(&database.NotificationTarget{}).Where("name = ?", args.Name).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, model.NewError(model.EConflict, "notification target \"%s\" already exists", args.Name)
+	}
+
+	mTarget := &model.NotificationTarget{}
+	args.ApplyTo(mTarget)
+
+	eTarget := &database.NotificationTarget{}
+	eTarget.CopyFromModel(mTarget)
+	err = tx.Create(eTarget).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("notification target \"%s\" has been created: %v", mTarget.Name, mTarget)
+	return mTarget, nil
+}
+
+// Update an existing notification target
+func (s *notificationTargetRepository) Update(name string, args *model.NotificationTargetUpdateParams) (*model.NotificationTarget, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eTarget, err := s.find(tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	mTarget := eTarget.ToModel()
+	args.ApplyTo(mTarget)
+	eTarget.CopyFromModel(mTarget)
+
+	err = tx.Save(eTarget).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("notification target \"%s\" has been updated: %v", mTarget.Name, mTarget)
+	return mTarget, nil
+}
+
+// Delete an existing notification target
+func (s *notificationTargetRepository) Delete(name string) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eTarget, err := s.find(tx, name)
+	if err != nil {
+		return err
+	}
+
+	var eRules []*database.RoutingRule
+	err = tx.Find(&eRules).Error
+	if err != nil {
+		return err
+	}
+
+	for _, eRule := range eRules {
+		for _, target := range eRule.ToModel().Targets {
+			if target == eTarget.Name {
+				return model.NewError(model.EConflict, "notification target \"%s\" is used by routing rule \"%s\"", name, eRule.Name)
+			}
+		}
+	}
+
+	err = tx.Delete(eTarget).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("notification target \"%s\" has been deleted", name)
+	return nil
+}
+
+func (s *notificationTargetRepository) find(db *gorm.DB, name string) (*database.NotificationTarget, error) {
+	eTarget := &database.NotificationTarget{}
+	err := db.Where("name = ?", name).First(eTarget).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "notification target \"%s\" doesn't exist", name)
+		}
+
+		return nil, err
+	}
+
+	return eTarget, nil
+}
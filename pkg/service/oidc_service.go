@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/util"
+	"github.com/sarulabs/di"
+	"golang.org/x/oauth2"
+)
+
+// OIDCService implements OpenID Connect authorization code flow (with PKCE)
+// and provisions users from ID token claims
+type OIDCService interface {
+	// Returns true if OpenID Connect is configured
+	Enabled() bool
+
+	// Start a new login and return provider's URL to redirect user to,
+	// along with a secret which binds the login to user's browser (e.g. via a cookie)
+	Start() (string, string, error)
+
+	// Finish a login: exchange authorization code and return an authenticated (possibly just provisioned) user.
+	// Login can be finished only in the browser it has been started in
+	Finish(ctx context.Context, state, binding, code string) (*model.User, error)
+
+	// Issue a short-lived one-time ticket that can be exchanged to a session by client app
+	IssueTicket(user *model.User) string
+
+	// Redeem a one-time ticket and return its user
+	RedeemTicket(ticket string) (*model.User, error)
+}
+
+const oidcServiceKey = "OIDCService"
+
+// GetOIDCService returns an implementation of OIDCService from DI container
+func GetOIDCService(c di.Container) OIDCService {
+	return c.Get(oidcServiceKey).(OIDCService)
+}
+
+const (
+	oidcLoginTTL  = 10 * time.Minute
+	oidcTicketTTL = time.Minute
+)
+
+// OIDCConfig contains OpenID Connect provider settings
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMapping   model.RoleMapping
+	DefaultRole   model.Role
+}
+
+// A pending login, identified by its state parameter
+type oidcLogin struct {
+	binding   string
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// A one-time ticket issued after a successful login
+type oidcTicket struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// An implementation of OIDCService.
+// Provider metadata is discovered on first use, so application can start while provider is unavailable
+type oidcService struct {
+	logger         *log.Logger
+	config         OIDCConfig
+	userRepository UserRepository
+
+	mutex    sync.Mutex
+	provider *oidc.Provider
+	logins   map[string]*oidcLogin
+	tickets  map[string]*oidcTicket
+}
+
+// Returns true if OpenID Connect is configured
+func (s *oidcService) Enabled() bool {
+	return s.config.Issuer != ""
+}
+
+// Start a new login and return provider's URL to redirect user to along with login's browser binding
+func (s *oidcService) Start() (string, string, error) {
+	oauth, _, err := s.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state := util.GenerateToken()
+	login := &oidcLogin{
+		binding:   util.GenerateToken(),
+		verifier:  util.GenerateToken(),
+		nonce:     util.GenerateToken(),
+		expiresAt: time.Now().Add(oidcLoginTTL),
+	}
+
+	s.mutex.Lock()
+	s.purge()
+	s.logins[state] = login
+	s.mutex.Unlock()
+
+	challenge := sha256.Sum256([]byte(login.verifier))
+	url := oauth.AuthCodeURL(
+		state,
+		oidc.Nonce(login.nonce),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+
+	return url, login.binding, nil
+}
+
+// Finish a login: exchange authorization code and return an authenticated user
+func (s *oidcService) Finish(ctx context.Context, state, binding, code string) (*model.User, error) {
+	s.mutex.Lock()
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	s.mutex.Unlock()
+
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, model.NewError(model.EAccessDenied, "login has expired, please try again")
+	}
+
+	// Otherwise a victim could be signed in as an attacker by a link with attacker's state and code
+	if subtle.ConstantTimeCompare([]byte(login.binding), []byte(binding)) != 1 {
+		return nil, model.NewError(model.EAccessDenied, "login has been started in another browser, please try again")
+	}
+
+	oauth, provider, err := s.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", login.verifier))
+	if err != nil {
+		s.logger.Printf("unable to exchange authorization code: %v", err)
+		return nil, model.NewError(model.EAccessDenied, "unable to exchange authorization code")
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, model.NewError(model.EAccessDenied, "provider returned no id token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		s.logger.Printf("unable to verify id token: %v", err)
+		return nil, model.NewError(model.EAccessDenied, "invalid id token")
+	}
+
+	if idToken.Nonce != login.nonce {
+		return nil, model.NewError(model.EAccessDenied, "invalid id token nonce")
+	}
+
+	claims := make(map[string]interface{})
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some providers put profile and groups into userinfo only, ID token claims take precedence though
+	userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err == nil {
+		extra := make(map[string]interface{})
+		if userInfo.Claims(&extra) == nil {
+			for k, v := range extra {
+				if _, exists := claims[k]; !exists {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	if idToken.Subject == "" {
+		return nil, model.NewError(model.EAccessDenied, "id token has no \"sub\" claim")
+	}
+
+	// Users can change their username and email at many providers, so accounts are bound to issuer and subject.
+	// Username is taken from claims on first login only, and unverified email is never used
+	args := &model.UserProvisionParams{
+		DisplayName: claimString(claims, "name"),
+		ExternalID:  idToken.Issuer + "#" + idToken.Subject,
+	}
+	if claimBool(claims, "email_verified") {
+		args.Email = claimString(claims, "email")
+	}
+
+	existing, err := s.userRepository.GetByExternalID(model.UserSourceOIDC, args.ExternalID)
+	if err == nil {
+		args.UserName = existing.UserName
+	} else if e, ok := err.(*model.Error); ok && e.Code == model.ENotFound {
+		args.UserName = s.username(claims, args.Email)
+	} else {
+		return nil, err
+	}
+
+	args.Normalize()
+
+	groups := claimStrings(claims, s.config.GroupsClaim)
+	args.Role, err = resolveProvisionedRole(s.userRepository, model.UserSourceOIDC, args.UserName, groups, s.config.RoleMapping, s.config.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepository.Provision(model.UserSourceOIDC, args)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("user #%d (\"%s\") has signed in with openid connect", user.ID, user.UserName)
+	return user, nil
+}
+
+// Issue a short-lived one-time ticket that can be exchanged to a session by client app
+func (s *oidcService) IssueTicket(user *model.User) string {
+	ticket := util.GenerateToken()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.purge()
+	s.tickets[ticket] = &oidcTicket{userID: user.ID, expiresAt: time.Now().Add(oidcTicketTTL)}
+	return ticket
+}
+
+// Redeem a one-time ticket and return its user
+func (s *oidcService) RedeemTicket(ticket string) (*model.User, error) {
+	s.mutex.Lock()
+	t, ok := s.tickets[ticket]
+	delete(s.tickets, ticket)
+	s.mutex.Unlock()
+
+	if !ok || time.Now().After(t.expiresAt) {
+		return nil, model.NewError(model.EAccessDenied, "invalid or expired login ticket")
+	}
+
+	user, err := s.userRepository.GetByID(t.userID)
+	if err != nil || user.IsDisabled {
+		return nil, model.NewError(model.EAccessDenied, "invalid or expired login ticket")
+	}
+
+	return user, nil
+}
+
+// discover fetches provider metadata (once) and returns OAuth2 client configuration
+func (s *oidcService) discover() (*oauth2.Config, *oidc.Provider, error) {
+	if !s.Enabled() {
+		return nil, nil, model.NewError(model.ENotFound, "openid connect is not configured")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.provider == nil {
+		// Provider keeps the context to refresh its signing keys later, so it shouldn't be request's one
+		provider, err := oidc.NewProvider(context.Background(), s.config.Issuer)
+		if err != nil {
+			s.logger.Printf("unable to discover openid connect provider \"%s\": %v", s.config.Issuer, err)
+			return nil, nil, fmt.Errorf("openid connect provider is unavailable")
+		}
+
+		s.provider = provider
+	}
+
+	oauth := &oauth2.Config{
+		ClientID:     s.config.ClientID,
+		ClientSecret: s.config.ClientSecret,
+		RedirectURL:  s.config.RedirectURL,
+		Endpoint:     s.provider.Endpoint(),
+		Scopes:       s.config.Scopes,
+	}
+
+	return oauth, s.provider, nil
+}
+
+// purge drops expired logins and tickets, should be called under mutex
+func (s *oidcService) purge() {
+	now := time.Now()
+	for k, v := range s.logins {
+		if now.After(v.expiresAt) {
+			delete(s.logins, k)
+		}
+	}
+
+	for k, v := range s.tickets {
+		if now.After(v.expiresAt) {
+			delete(s.tickets, k)
+		}
+	}
+}
+
+// username returns username from configured claim, falling back to verified email and subject
+func (s *oidcService) username(claims map[string]interface{}, verifiedEmail string) string {
+	for _, claim := range []string{s.config.UsernameClaim, "email", "sub"} {
+		value := claimString(claims, claim)
+		if claim == "email" {
+			value = verifiedEmail
+		}
+
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimBool returns a boolean claim, some providers send them as strings
+func claimBool(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+
+	return false
+}
+
+// claimStrings returns a claim that can be either a string or an array of strings
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			str, ok := item.(string)
+			if ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
@@ -0,0 +1,527 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
+)
+
+const (
+	oidcTestClientID     = "backupmonitor"
+	oidcTestClientSecret = "client-secret"
+	oidcTestRedirectURL  = "https://backup.example.com/api/authorize/oidc/callback"
+)
+
+// mockOIDCIssuer is a minimal OpenID Connect provider with discovery, JWKS and token endpoints.
+// Authorization endpoint is never called, tests grant codes directly instead
+type mockOIDCIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mutex sync.Mutex
+	codes map[string]*mockOIDCGrant
+}
+
+// A granted authorization code
+type mockOIDCGrant struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newMockOIDCIssuer(t *testing.T) *mockOIDCIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &mockOIDCIssuer{t: t, key: key, codes: make(map[string]*mockOIDCGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/keys", issuer.keys)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// Grant an authorization code for a login started with an authorization URL
+func (s *mockOIDCIssuer) grant(authURL string, claims map[string]interface{}) (state, code string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		s.t.Fatalf("authorization url has no pkce challenge: %s", authURL)
+	}
+
+	if query.Get("client_id") != oidcTestClientID || query.Get("redirect_uri") != oidcTestRedirectURL {
+		s.t.Fatalf("authorization url has wrong client parameters: %s", authURL)
+	}
+
+	if _, exists := claims["nonce"]; !exists {
+		claims["nonce"] = query.Get("nonce")
+	}
+
+	code = base64.RawURLEncoding.EncodeToString([]byte(query.Get("state")))
+
+	s.mutex.Lock()
+	s.codes[code] = &mockOIDCGrant{challenge: query.Get("code_challenge"), claims: claims}
+	s.mutex.Unlock()
+
+	return query.Get("state"), code
+}
+
+func (s *mockOIDCIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, 200, map[string]interface{}{
+		"issuer":                                s.server.URL,
+		"authorization_endpoint":                s.server.URL + "/authorize",
+		"token_endpoint":                        s.server.URL + "/token",
+		"jwks_uri":                              s.server.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (s *mockOIDCIssuer) keys(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, 200, map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (s *mockOIDCIssuer) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+
+	if clientID != oidcTestClientID || clientSecret != oidcTestClientSecret {
+		writeMockJSON(w, 401, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+	s.mutex.Lock()
+	grant, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mutex.Unlock()
+
+	if r.PostFormValue("grant_type") != "authorization_code" || !ok {
+		writeMockJSON(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		writeMockJSON(w, 400, map[string]string{"error": "invalid_grant", "error_description": "pkce verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": s.server.URL,
+		"aud": oidcTestClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+
+	writeMockJSON(w, 200, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.sign(claims),
+	})
+}
+
+// sign issues an RS256 JWT
+func (s *mockOIDCIssuer) sign(claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	payload := encode(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "test"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestOIDCService(t *testing.T, issuer *mockOIDCIssuer) (*oidcService, *userRepository) {
+	mapping, err := model.ParseRoleMapping("backup-admins=admin;developers=operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &userRepository{log.New(ioutil.Discard, "", 0), newTestProvider(t)}
+	s := &oidcService{
+		logger: log.New(ioutil.Discard, "", 0),
+		config: OIDCConfig{
+			Issuer:        issuer.server.URL,
+			ClientID:      oidcTestClientID,
+			ClientSecret:  oidcTestClientSecret,
+			RedirectURL:   oidcTestRedirectURL,
+			Scopes:        []string{"openid", "profile", "email", "groups"},
+			UsernameClaim: "preferred_username",
+			GroupsClaim:   "groups",
+			RoleMapping:   mapping,
+		},
+		userRepository: users,
+		logins:         make(map[string]*oidcLogin),
+		tickets:        make(map[string]*oidcTicket),
+	}
+
+	return s, users
+}
+
+// oidcTestLogin signs in with claims granted by issuer
+func oidcTestLogin(t *testing.T, s *oidcService, issuer *mockOIDCIssuer, claims map[string]interface{}) (*model.User, error) {
+	t.Helper()
+
+	authURL, binding, err := s.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, code := issuer.grant(authURL, claims)
+	return s.Finish(context.Background(), state, binding, code)
+}
+
+func countOIDCTestUsers(t *testing.T, users *userRepository) int {
+	t.Helper()
+
+	list, err := users.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for _, user := range list {
+		if user.Source == model.UserSourceOIDC {
+			count++
+		}
+	}
+
+	return count
+}
+
+func expectAccessDenied(t *testing.T, err error) {
+	t.Helper()
+
+	if e, ok := err.(*model.Error); !ok || e.Code != model.EAccessDenied {
+		t.Fatalf("expected access denied error, got %v", err)
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	cases := []struct {
+		name     string
+		groups   []interface{}
+		role     model.Role
+		rejected bool
+	}{
+		{name: "admin group", groups: []interface{}{"everyone", "backup-admins"}, role: model.RoleAdmin},
+		{name: "operator group", groups: []interface{}{"developers"}, role: model.RoleOperator},
+		{name: "unmapped group", groups: []interface{}{"everyone"}, rejected: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issuer := newMockOIDCIssuer(t)
+			s, users := newTestOIDCService(t, issuer)
+
+			user, err := oidcTestLogin(t, s, issuer, map[string]interface{}{
+				"sub":                "248289761001",
+				"preferred_username": "jane.doe",
+				"name":               "Jane Doe",
+				"email":              "jane.doe@example.com",
+				"email_verified":     true,
+				"groups":             c.groups,
+			})
+			if c.rejected {
+				expectAccessDenied(t, err)
+				if count := countOIDCTestUsers(t, users); count != 0 {
+					t.Fatalf("expected no users to be provisioned, got %d", count)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if user.UserName != "jane.doe" || user.DisplayName != "Jane Doe" || user.Email != "jane.doe@example.com" {
+				t.Fatalf("unexpected user profile: %v", user)
+			}
+
+			if user.Source != model.UserSourceOIDC {
+				t.Fatalf("expected user source %s, got %s", model.UserSourceOIDC, user.Source)
+			}
+
+			if user.Role != c.role {
+				t.Fatalf("expected role %s, got %s", c.role, user.Role)
+			}
+
+			if user.ExternalID != issuer.server.URL+"#248289761001" {
+				t.Fatalf("expected user to be bound to issuer and subject, got \"%s\"", user.ExternalID)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginUpdatesProvisionedUser(t *testing.T) {
+	issuer := newMockOIDCIssuer(t)
+	s, users := newTestOIDCService(t, issuer)
+
+	for _, groups := range [][]interface{}{{"backup-admins"}, {"developers"}} {
+		_, err := oidcTestLogin(t, s, issuer, map[string]interface{}{
+			"sub":                "248289761001",
+			"preferred_username": "jane.doe",
+			"groups":             groups,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := countOIDCTestUsers(t, users); count != 1 {
+		t.Fatalf("expected a single user, got %d", count)
+	}
+
+	user, err := users.Get("jane.doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Role != model.RoleOperator {
+		t.Fatalf("expected role to be updated from groups to %s, got %s", model.RoleOperator, user.Role)
+	}
+}
+
+func TestOIDCLoginBindsSubject(t *testing.T) {
+	issuer := newMockOIDCIssuer(t)
+	s, users := newTestOIDCService(t, issuer)
+
+	claims := func(sub, username, email string, verified bool) map[string]interface{} {
+		return map[string]interface{}{
+			"sub":                sub,
+			"preferred_username": username,
+			"email":              email,
+			"email_verified":     verified,
+			"groups":             []interface{}{"developers"},
+		}
+	}
+
+	jane, err := oidcTestLogin(t, s, issuer, claims("1001", "jane.doe", "jane.doe@example.com", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another subject can't take over an account by reporting the same username or email
+	_, err = oidcTestLogin(t, s, issuer, claims("1002", "jane.doe", "jane.doe@example.com", true))
+	expectAccessDenied(t, err)
+
+	// Username and email changes of the same subject don't move it to another account
+	user, err := oidcTestLogin(t, s, issuer, claims("1001", "janet", "janet@example.com", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != jane.ID || user.UserName != "jane.doe" || user.Email != "jane.doe@example.com" {
+		t.Fatalf("expected to sign in as jane.doe without unverified email, got %v", user)
+	}
+
+	// Unverified email is neither stored nor used as username
+	user, err = oidcTestLogin(t, s, issuer, map[string]interface{}{
+		"sub":    "1003",
+		"email":  "john@example.com",
+		"groups": []interface{}{"developers"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.UserName != "1003" || user.Email != "" {
+		t.Fatalf("expected username to fall back to subject without email, got %v", user)
+	}
+
+	// Local users are never taken over
+	_, _, err = users.Create(&model.UserCreateParams{UserName: "admin.local", Email: "admin@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = oidcTestLogin(t, s, issuer, claims("1004", "admin.local", "admin@example.com", true))
+	expectAccessDenied(t, err)
+}
+
+func TestOIDCLoginBindsLegacyUser(t *testing.T) {
+	issuer := newMockOIDCIssuer(t)
+	s, users := newTestOIDCService(t, issuer)
+
+	// Users provisioned before subjects were stored have no external ID
+	_, err := users.Provision(model.UserSourceOIDC, &model.UserProvisionParams{UserName: "jane.doe", Email: "jane.doe@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(sub string, verified bool) map[string]interface{} {
+		return map[string]interface{}{
+			"sub":                sub,
+			"preferred_username": "jane.doe",
+			"email":              "jane.doe@example.com",
+			"email_verified":     verified,
+			"groups":             []interface{}{"developers"},
+		}
+	}
+
+	_, err = oidcTestLogin(t, s, issuer, claims("1001", false))
+	expectAccessDenied(t, err)
+
+	user, err := oidcTestLogin(t, s, issuer, claims("1001", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ExternalID != issuer.server.URL+"#1001" {
+		t.Fatalf("expected user to be bound on login with verified email, got \"%s\"", user.ExternalID)
+	}
+
+	_, err = oidcTestLogin(t, s, issuer, claims("1002", true))
+	expectAccessDenied(t, err)
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":                "248289761001",
+			"preferred_username": "jane.doe",
+			"groups":             []interface{}{"backup-admins"},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		finish func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error
+	}{
+		{
+			name: "unknown state",
+			finish: func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error {
+				_, code := issuer.grant(authURL, claims())
+				_, err := s.Finish(context.Background(), "forged-state", binding, code)
+				return err
+			},
+		},
+		{
+			name: "reused state",
+			finish: func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error {
+				state, code := issuer.grant(authURL, claims())
+				_, err := s.Finish(context.Background(), state, binding, code)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				_, code = issuer.grant(authURL, claims())
+				_, err = s.Finish(context.Background(), state, binding, code)
+				return err
+			},
+		},
+		{
+			name: "another browser",
+			finish: func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error {
+				state, code := issuer.grant(authURL, claims())
+				_, err := s.Finish(context.Background(), state, "", code)
+				return err
+			},
+		},
+		{
+			name: "wrong nonce",
+			finish: func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error {
+				c := claims()
+				c["nonce"] = "forged-nonce"
+				state, code := issuer.grant(authURL, c)
+				_, err := s.Finish(context.Background(), state, binding, code)
+				return err
+			},
+		},
+		{
+			name: "wrong code verifier",
+			finish: func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error {
+				state, code := issuer.grant(authURL, claims())
+				s.mutex.Lock()
+				s.logins[state].verifier = "forged-verifier"
+				s.mutex.Unlock()
+
+				_, err := s.Finish(context.Background(), state, binding, code)
+				return err
+			},
+		},
+		{
+			name: "unknown code",
+			finish: func(s *oidcService, issuer *mockOIDCIssuer, authURL, binding string) error {
+				state, _ := issuer.grant(authURL, claims())
+				_, err := s.Finish(context.Background(), state, binding, "forged-code")
+				return err
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issuer := newMockOIDCIssuer(t)
+			s, _ := newTestOIDCService(t, issuer)
+
+			authURL, binding, err := s.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.finish(s, issuer, authURL, binding)
+			expectAccessDenied(t, err)
+		})
+	}
+}
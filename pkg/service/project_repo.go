@@ -1,8 +1,10 @@
 package service
 
 import (
-	"github.com/itglobal/backupmonitor/pkg/model"
 	"log"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/model"
 
 	"github.com/itglobal/backupmonitor/pkg/database"
 	"github.com/jinzhu/gorm"
@@ -11,8 +13,9 @@ import (
 
 // ProjectRepository contains methods to manage projects registry
 type ProjectRepository interface {
-	// List all registered projects
-	List() ([]*model.Project, error)
+	// List registered projects that are visible with specified access rights.
+	// If access is nil, all projects are returned
+	List(access *model.Access) ([]*model.Project, error)
 
 	// Get a project by its ID
 	Get(id string) (*model.Project, error)
@@ -23,11 +26,34 @@ type ProjectRepository interface {
 	// Update an existing project
 	Update(id string, args *model.ProjectUpdateParams) (*model.Project, error)
 
-	// Delete an existing project
+	// Move an existing project to trash
 	Delete(id string) error
 
+	// List projects in trash
+	ListTrash() ([]*model.Project, error)
+
+	// Restore a project from trash
+	Restore(id string) (*model.Project, error)
+
+	// Delete a project (either trashed or not) with its access keys and grants permanently.
+	// Project's backups should be purged before
+	Purge(id string) error
+
 	// Update status of project's backups
 	UpdateBackupStatus(tx *gorm.DB, projectID string) error
+
+	// Silence project notifications until specified time (or unsilence them if time is nil)
+	Silence(id string, until *time.Time) (*model.Project, error)
+
+	// Acknowledge current backup problem of a project.
+	// Acknowledgement is dropped as soon as project's backup status changes
+	Acknowledge(id, by string) (*model.Project, error)
+
+	// Record a check-in of project's backup host
+	CheckIn(id string) (*model.Project, error)
+
+	// Put a project on legal hold (or release it if hold is nil)
+	SetLegalHold(id string, hold *model.LegalHold) (*model.Project, error)
 }
 
 const projectRepositoryKey = "ProjectRepository"
@@ -43,8 +69,8 @@ type projectRepository struct {
 	provider database.Provider
 }
 
-// List all registered projects
-func (s *projectRepository) List() ([]*model.Project, error) {
+// List registered projects that are visible with specified access rights
+func (s *projectRepository) List(access *model.Access) ([]*model.Project, error) {
 	db, err := s.provider.Open()
 	if err != nil {
 		return nil, err
@@ -66,11 +92,15 @@ func (s *projectRepository) List() ([]*model.Project, error) {
 	}
 
 	// Emit results
-	mProjects := make([]*model.Project, len(eProjects))
+	mProjects := make([]*model.Project, 0, len(eProjects))
 	mProjectsByID := make(map[string]*model.Project)
-	for i, eProject := range eProjects {
+	for _, eProject := range eProjects {
 		mProject := eProject.ToModel()
-		mProjects[i] = mProject
+		if access != nil && !access.CanView(mProject) {
+			continue
+		}
+
+		mProjects = append(mProjects, mProject)
 		mProjectsByID[mProject.ID] = mProject
 	}
 
@@ -149,6 +179,15 @@ func (s *projectRepository) Create(args *model.ProjectCreateParams) (*model.Proj
 		return nil, model.NewError(model.EConflict, "project \"%s\" already exists", args.ID)
 	}
 
+	err = tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", args.ID).First(&database.Project{}).Error
+	if err != gorm.ErrRecordNotFound {
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, model.NewError(model.EConflict, "project \"%s\" is in trash, restore it or wait until it's purged", args.ID)
+	}
+
 	// Create new project
 	args.ApplyTo(mProject)
 	mProject.BackupStatus = model.BackupStatusNone
@@ -244,7 +283,95 @@ func (s *projectRepository) Delete(id string) error {
 
 	tx.Commit()
 
-	s.logger.Printf("project \"%s\" has been deleted", id)
+	s.logger.Printf("project \"%s\" has been moved to trash", id)
+	return nil
+}
+
+// List projects in trash
+func (s *projectRepository) ListTrash() ([]*model.Project, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eProjects := make([]*database.Project, 0)
+	err = db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&eProjects).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mProjects := make([]*model.Project, len(eProjects))
+	for i, eProject := range eProjects {
+		mProjects[i] = eProject.ToModel()
+	}
+
+	return mProjects, nil
+}
+
+// Restore a project from trash
+func (s *projectRepository) Restore(id string) (*model.Project, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eProject := &database.Project{}
+	err = db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(eProject).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "project \"%s\" isn't in trash", id)
+		}
+
+		return nil, err
+	}
+
+	err = db.Unscoped().Model(eProject).Update("deleted_at", nil).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("project \"%s\" has been restored from trash", id)
+	return s.Get(id)
+}
+
+// Delete a project with its access keys and grants permanently
+func (s *projectRepository) Purge(id string) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	err = tx.Where("project_id = ?", id).Delete(&database.AccessKey{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("project = ?", id).Delete(&database.ProjectGrant{}).Error
+	if err != nil {
+		return err
+	}
+
+	result := tx.Unscoped().Where("id = ?", id).Delete(&database.Project{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return model.NewError(model.ENotFound, "project \"%s\" doesn't exist", id)
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("project \"%s\" has been purged", id)
 	return nil
 }
 
@@ -284,6 +411,8 @@ func (s *projectRepository) UpdateBackupStatus(tx *gorm.DB, projectID string) er
 
 	mProject.BackupStatus = status
 	mProject.LastNotification = nil
+	mProject.AcknowledgedAt = nil
+	mProject.AcknowledgedBy = ""
 	eProject.CopyFromModel(mProject)
 
 	// Update project
@@ -295,3 +424,101 @@ func (s *projectRepository) UpdateBackupStatus(tx *gorm.DB, projectID string) er
 	s.logger.Printf("backup status of project \"%s\" is now \"%s\"", projectID, status)
 	return nil
 }
+
+// Silence project notifications until specified time (or unsilence them if time is nil)
+func (s *projectRepository) Silence(id string, until *time.Time) (*model.Project, error) {
+	err := s.modify(id, func(mProject *model.Project) {
+		mProject.SilencedUntil = until
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if until != nil {
+		s.logger.Printf("notifications of project \"%s\" have been silenced until %s", id, until.Format(time.RFC3339))
+	} else {
+		s.logger.Printf("notifications of project \"%s\" have been unsilenced", id)
+	}
+
+	return s.Get(id)
+}
+
+// Acknowledge current backup problem of a project
+func (s *projectRepository) Acknowledge(id, by string) (*model.Project, error) {
+	now := time.Now().UTC()
+	err := s.modify(id, func(mProject *model.Project) {
+		mProject.AcknowledgedAt = &now
+		mProject.AcknowledgedBy = by
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("backup status of project \"%s\" has been acknowledged by \"%s\"", id, by)
+
+	return s.Get(id)
+}
+
+// Record a check-in of project's backup host
+func (s *projectRepository) CheckIn(id string) (*model.Project, error) {
+	now := time.Now().UTC()
+	err := s.modify(id, func(mProject *model.Project) {
+		mProject.LastCheckIn = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(id)
+}
+
+// Put a project on legal hold (or release it if hold is nil)
+func (s *projectRepository) SetLegalHold(id string, hold *model.LegalHold) (*model.Project, error) {
+	err := s.modify(id, func(mProject *model.Project) {
+		mProject.LegalHold = hold
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if hold != nil {
+		s.logger.Printf("project \"%s\" has been put on legal hold by \"%s\": %s", id, hold.PlacedBy, hold.Reason)
+	} else {
+		s.logger.Printf("legal hold of project \"%s\" has been released", id)
+	}
+
+	return s.Get(id)
+}
+
+// Load a project, apply changes to it and save it back
+func (s *projectRepository) modify(id string, fn func(mProject *model.Project)) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eProject := &database.Project{}
+	err = tx.Where("id = ?", id).First(eProject).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return model.NewError(model.ENotFound, "project \"%s\" doesn't exist", id)
+		}
+
+		return err
+	}
+
+	mProject := eProject.ToModel()
+	fn(mProject)
+	eProject.CopyFromModel(mProject)
+
+	err = tx.Save(eProject).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/storage"
+	"github.com/sarulabs/di"
+)
+
+// QuotaService measures storage usage against project and global quotas and reports low capacity
+type QuotaService interface {
+	// Get global quota (nil if there's no global quota)
+	GlobalQuota() *model.StorageQuota
+
+	// Get total length of project's backups compared to project's quota
+	ProjectUsage(project *model.Project) (*model.StorageUsage, error)
+
+	// Get total length of backups of all projects compared to global quota
+	GlobalUsage() (*model.StorageUsage, error)
+
+	// List storage backends
+	Backends() []*model.StorageBackend
+
+	// Get global storage usage and storage backends with their free disk space (if they are able to report it)
+	Status() (*model.StorageStatus, error)
+
+	// Send a warning if usage has crossed any of warning thresholds.
+	// Project is nil for global usage
+	WarnUsage(project *model.Project, before, after *model.StorageUsage)
+
+	// Send an alert if free disk space of any storage backend has dropped below alert level
+	CheckDiskSpace() error
+}
+
+const quotaServiceKey = "QuotaService"
+
+// GetQuotaService returns an implementation of QuotaService from DI container
+func GetQuotaService(c di.Container) QuotaService {
+	return c.Get(quotaServiceKey).(QuotaService)
+}
+
+type quotaService struct {
+	logger             *log.Logger
+	provider           database.Provider
+	storage            storage.Registry
+	router             NotificationRouter
+	deliveryRepository DeliveryRepository
+	globalQuota        *model.StorageQuota
+	thresholds         []int
+	diskAlert          *model.DiskSpaceAlert
+	mutex              sync.Mutex
+	diskSpaceLow       map[string]bool
+}
+
+// Get global quota
+func (s *quotaService) GlobalQuota() *model.StorageQuota {
+	return s.globalQuota
+}
+
+// Get total length of project's backups compared to project's quota
+func (s *quotaService) ProjectUsage(project *model.Project) (*model.StorageUsage, error) {
+	used, err := s.sum(project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewStorageUsage(project.ID, used, project.Quota), nil
+}
+
+// Get total length of backups of all projects compared to global quota
+func (s *quotaService) GlobalUsage() (*model.StorageUsage, error) {
+	used, err := s.sum("")
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewStorageUsage("", used, s.globalQuota), nil
+}
+
+// List storage backends
+func (s *quotaService) Backends() []*model.StorageBackend {
+	list := make([]*model.StorageBackend, 0)
+	for _, b := range s.storage.List() {
+		backend := &model.StorageBackend{
+			Name:      b.Name,
+			Type:      b.Type,
+			IsDefault: b.Name == s.storage.Default(),
+		}
+
+		if locker, ok := b.Service.(storage.Locker); ok {
+			backend.LockMode = string(locker.LockMode())
+		}
+
+		list = append(list, backend)
+	}
+
+	return list
+}
+
+// Get global storage usage and storage backends with their free disk space
+func (s *quotaService) Status() (*model.StorageStatus, error) {
+	usage, err := s.GlobalUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.StorageStatus{Usage: usage, Backends: s.Backends()}
+	for _, backend := range status.Backends {
+		backend.Disk, err = s.diskSpace(backend.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// Send a warning if usage has crossed any of warning thresholds
+func (s *quotaService) WarnUsage(project *model.Project, before, after *model.StorageUsage) {
+	threshold := before.CrossedThreshold(s.thresholds, after)
+	if threshold == 0 {
+		return
+	}
+
+	if project != nil && project.IsSilenced(time.Now().UTC()) {
+		return
+	}
+
+	title := "Storage quota warning"
+	text := fmt.Sprintf("Backups of all projects use %s of global storage quota", after.Description())
+	fields := []*model.NotificationField{
+		{Title: "Used", Value: humanize.Bytes(uint64(after.Used))},
+		{Title: "Quota", Value: after.Quota.Description()},
+	}
+	if project != nil {
+		title = fmt.Sprintf("%s storage quota warning", project.ID)
+		text = fmt.Sprintf("Backups of %s (%s) use %s of project's storage quota", project.ID, project.Name, after.Description())
+		fields = append([]*model.NotificationField{{Title: "Project", Value: fmt.Sprintf("%s (%s)", project.ID, project.Name)}}, fields...)
+	}
+
+	s.logger.Print(text)
+
+	payload, err := json.Marshal(struct {
+		*model.StorageUsage
+		Threshold int `json:"threshold"`
+	}{after, threshold})
+	if err != nil {
+		s.logger.Printf("unable to send quota warning: %v", err)
+		return
+	}
+
+	msg := &model.NotificationMessage{
+		Title:   title,
+		Text:    text,
+		Emoji:   "warning",
+		Fields:  fields,
+		Payload: payload,
+	}
+
+	err = s.send(project, model.EventQuotaWarning, msg)
+	if err != nil {
+		s.logger.Printf("unable to send quota warning: %v", err)
+	}
+}
+
+// Send an alert if free disk space of any storage backend has dropped below alert level.
+// An alert is sent once, then next one is sent only after free space has recovered
+func (s *quotaService) CheckDiskSpace() error {
+	if s.diskAlert == nil {
+		return nil
+	}
+
+	for _, backend := range s.storage.List() {
+		err := s.checkDiskSpace(backend.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkDiskSpace sends an alert if free disk space of a storage backend has dropped below alert level
+func (s *quotaService) checkDiskSpace(name string) error {
+	space, err := s.diskSpace(name)
+	if err != nil || space == nil {
+		return err
+	}
+
+	low := s.diskAlert.IsLow(space)
+
+	s.mutex.Lock()
+	alert := low && !s.diskSpaceLow[name]
+	s.diskSpaceLow[name] = low
+	s.mutex.Unlock()
+
+	if !alert {
+		return nil
+	}
+
+	text := fmt.Sprintf(
+		"Storage backend \"%s\" has %s (%.1f%%) of free disk space left, which is below %s",
+		name,
+		humanize.Bytes(space.Free),
+		space.Percent,
+		s.diskAlert)
+	s.logger.Print(text)
+
+	payload, err := json.Marshal(struct {
+		*model.DiskSpace
+		Storage string `json:"storage"`
+	}{space, name})
+	if err != nil {
+		return err
+	}
+
+	msg := &model.NotificationMessage{
+		Title: "Low disk space",
+		Text:  text,
+		Emoji: "rotating_light",
+		Fields: []*model.NotificationField{
+			{Title: "Storage", Value: name},
+			{Title: "Free", Value: humanize.Bytes(space.Free)},
+			{Title: "Total", Value: humanize.Bytes(space.Total)},
+		},
+		Payload: payload,
+	}
+
+	return s.send(nil, model.EventDiskSpaceLow, msg)
+}
+
+// sum returns total length of backups of a project (or of all projects if projectID is empty).
+// Backups in trash (and backups of projects in trash) are included, since their files occupy storage until they are purged
+func (s *quotaService) sum(projectID string) (int64, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result struct {
+		Length int64
+	}
+
+	query := db.Unscoped().Model(&database.Backup{}).
+		Select("coalesce(sum(backups.length), 0) as length").
+		Where("backups.length > 0")
+	if projectID != "" {
+		query = query.Where("backups.project_id = ?", projectID)
+	}
+
+	err = query.Scan(&result).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Length, nil
+}
+
+// diskSpace returns free disk space of a storage backend, or nil if backend is unable to report it
+func (s *quotaService) diskSpace(name string) (*model.DiskSpace, error) {
+	store, err := s.storage.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	reporter, ok := store.(storage.SpaceReporter)
+	if !ok {
+		return nil, nil
+	}
+
+	free, total, err := reporter.DiskSpace()
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewDiskSpace(free, total), nil
+}
+
+// send puts a delivery for each routed target into the outbox
+func (s *quotaService) send(project *model.Project, event model.EventType, msg *model.NotificationMessage) error {
+	route, err := s.router.Route(project, event, event.Severity())
+	if err != nil {
+		return err
+	}
+
+	if len(route.Targets) == 0 {
+		return nil
+	}
+
+	projectID := ""
+	if project != nil {
+		projectID = project.ID
+	}
+
+	args := make([]*model.DeliveryCreateParams, len(route.Targets))
+	for i, target := range route.Targets {
+		args[i] = &model.DeliveryCreateParams{
+			ProjectID: projectID,
+			Event:     event,
+			Channel:   target.Channel,
+			Target:    target.Address,
+			Message:   msg,
+		}
+	}
+
+	_, err = s.deliveryRepository.Enqueue(args...)
+	return err
+}
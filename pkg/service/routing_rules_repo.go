@@ -0,0 +1,219 @@
+package service
+
+import (
+	"log"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// RoutingRuleRepository contains methods to manage global notification routing rules
+type RoutingRuleRepository interface {
+	// List all routing rules in evaluation order
+	List() ([]*model.RoutingRule, error)
+
+	// Get a routing rule by its ID
+	Get(id int) (*model.RoutingRule, error)
+
+	// Create new routing rule
+	Create(args *model.RoutingRuleParams) (*model.RoutingRule, error)
+
+	// Update an existing routing rule
+	Update(id int, args *model.RoutingRuleParams) (*model.RoutingRule, error)
+
+	// Delete an existing routing rule
+	Delete(id int) error
+}
+
+const routingRuleRepositoryKey = "RoutingRuleRepository"
+
+// GetRoutingRuleRepository returns an implementation of RoutingRuleRepository from DI container
+func GetRoutingRuleRepository(c di.Container) RoutingRuleRepository {
+	return c.Get(routingRuleRepositoryKey).(RoutingRuleRepository)
+}
+
+// An implementation of RoutingRuleRepository
+type routingRuleRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// List all routing rules in evaluation order
+func (s *routingRuleRepository) List() ([]*model.RoutingRule, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var eRules []*database.RoutingRule
+	err = db.Order("position").Order("id").Find(&eRules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mRules := make([]*model.RoutingRule, len(eRules))
+	for i, eRule := range eRules {
+		mRules[i] = eRule.ToModel()
+	}
+
+	return mRules, nil
+}
+
+// Get a routing rule by its ID
+func (s *routingRuleRepository) Get(id int) (*model.RoutingRule, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eRule, err := s.find(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return eRule.ToModel(), nil
+}
+
+// Create new routing rule
+func (s *routingRuleRepository) Create(args *model.RoutingRuleParams) (*model.RoutingRule, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	err = s.validateTargets(tx, args.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	mRule := &model.RoutingRule{}
+	args.ApplyTo(mRule)
+
+	eRule := &database.RoutingRule{}
+	eRule.CopyFromModel(mRule)
+	err = tx.Create(eRule).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	eRule.CopyToModel(mRule)
+	s.logger.Printf("routing rule #%d has been created: %v", mRule.ID, mRule)
+	return mRule, nil
+}
+
+// Update an existing routing rule
+func (s *routingRuleRepository) Update(id int, args *model.RoutingRuleParams) (*model.RoutingRule, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eRule, err := s.find(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.validateTargets(tx, args.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	mRule := eRule.ToModel()
+	args.ApplyTo(mRule)
+	eRule.CopyFromModel(mRule)
+
+	err = tx.Save(eRule).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("routing rule #%d has been updated: %v", mRule.ID, mRule)
+	return mRule, nil
+}
+
+// Delete an existing routing rule
+func (s *routingRuleRepository) Delete(id int) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	eRule, err := s.find(db, id)
+	if err != nil {
+		return err
+	}
+
+	err = db.Delete(eRule).Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("routing rule #%d has been deleted", id)
+	return nil
+}
+
+func (s *routingRuleRepository) find(db *gorm.DB, id int) (*database.RoutingRule, error) {
+	eRule := &database.RoutingRule{}
+	err := db.Where("id = ?", id).First(eRule).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "routing rule #%d doesn't exist", id)
+		}
+
+		return nil, err
+	}
+
+	return eRule, nil
+}
+
+// Checks that all referenced notification targets exist
+func (s *routingRuleRepository) validateTargets(db *gorm.DB, targets []string) error {
+	for _, name := range targets {
+		var count int
+		err := db.Model(&database.NotificationTarget{}).Where("name = ?", name).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return model.NewError(model.EBadRequest, "notification target \"%s\" doesn't exist", name)
+		}
+	}
+
+	return nil
+}
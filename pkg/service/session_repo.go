@@ -0,0 +1,276 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/itglobal/backupmonitor/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// SessionRepository contains methods to manage users' login sessions.
+// Each session has a refresh token which is rotated on every use
+type SessionRepository interface {
+	// Create new session for a user and return its refresh token
+	Create(user *model.User, ip, userAgent string) (*model.Session, string, error)
+
+	// Rotate session's refresh token and return a new one.
+	// Previous refresh token becomes invalid, and its reuse revokes the session
+	Refresh(refreshToken, ip string) (*model.Session, string, error)
+
+	// Get an active session by its ID
+	Get(id string) (*model.Session, error)
+
+	// List active sessions of a user
+	List(userID int) ([]*model.Session, error)
+
+	// Revoke a session of a user
+	Revoke(userID int, id string) error
+
+	// Revoke all sessions of a user
+	RevokeAll(userID int) error
+}
+
+const sessionRepositoryKey = "SessionRepository"
+
+// GetSessionRepository returns an implementation of SessionRepository from DI container
+func GetSessionRepository(c di.Container) SessionRepository {
+	return c.Get(sessionRepositoryKey).(SessionRepository)
+}
+
+// An implementation of SessionRepository
+type sessionRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+	ttl      time.Duration
+}
+
+// Create new session for a user and return its refresh token
+func (s *sessionRepository) Create(user *model.User, ip, userAgent string) (*model.Session, string, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+
+	now := time.Now().UTC()
+
+	// Drop expired sessions
+	err = db.Where("expires_at < ?", now).Delete(&database.Session{}).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := util.GenerateToken()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	eSession := &database.Session{
+		ID:         util.GenerateToken(),
+		UserID:     user.ID,
+		TokenHash:  hashSecret(secret),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+		IP:         ip,
+		UserAgent:  userAgent,
+	}
+	err = db.Create(eSession).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Printf("new session \"%s\" has been started by user #%d from %s", eSession.ID, user.ID, ip)
+	return eSession.ToModel(), formatRefreshToken(eSession.ID, secret), nil
+}
+
+// Rotate session's refresh token and return a new one
+func (s *sessionRepository) Refresh(refreshToken, ip string) (*model.Session, string, error) {
+	invalidToken := model.NewError(model.EAccessDenied, "invalid refresh token")
+
+	id, secret, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, "", invalidToken
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	now := time.Now().UTC()
+
+	eSession := &database.Session{}
+	err = tx.Where("id = ? AND expires_at >= ?", id, now).First(eSession).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", invalidToken
+		}
+
+		return nil, "", err
+	}
+
+	hash := hashSecret(secret)
+	if !hashEquals(hash, eSession.TokenHash) {
+		// A rotated refresh token is being reused, so it might have been stolen
+		if hashEquals(hash, eSession.PreviousTokenHash) {
+			err = tx.Delete(eSession).Error
+			if err != nil {
+				return nil, "", err
+			}
+
+			err = tx.Commit().Error
+			if err != nil {
+				return nil, "", err
+			}
+
+			s.logger.Printf("session \"%s\" of user #%d has been revoked: refresh token reuse from %s", eSession.ID, eSession.UserID, ip)
+		}
+
+		return nil, "", invalidToken
+	}
+
+	secret = util.GenerateToken()
+	eSession.PreviousTokenHash = eSession.TokenHash
+	eSession.TokenHash = hashSecret(secret)
+	eSession.LastUsedAt = now
+	eSession.ExpiresAt = now.Add(s.ttl)
+	eSession.IP = ip
+
+	err = tx.Save(eSession).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	return eSession.ToModel(), formatRefreshToken(eSession.ID, secret), nil
+}
+
+// Get an active session by its ID
+func (s *sessionRepository) Get(id string) (*model.Session, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eSession := &database.Session{}
+	err = db.Where("id = ? AND expires_at >= ?", id, time.Now().UTC()).First(eSession).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "session \"%s\" doesn't exist", id)
+		}
+
+		return nil, err
+	}
+
+	return eSession.ToModel(), nil
+}
+
+// List active sessions of a user
+func (s *sessionRepository) List(userID int) ([]*model.Session, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var eSessions []*database.Session
+	err = db.Where("user_id = ? AND expires_at >= ?", userID, time.Now().UTC()).
+		Order("last_used_at desc").
+		Find(&eSessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mSessions := make([]*model.Session, len(eSessions))
+	for i, eSession := range eSessions {
+		mSessions[i] = eSession.ToModel()
+	}
+
+	return mSessions, nil
+}
+
+// Revoke a session of a user
+func (s *sessionRepository) Revoke(userID int, id string) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&database.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return model.NewError(model.ENotFound, "session \"%s\" doesn't exist", id)
+	}
+
+	s.logger.Printf("session \"%s\" of user #%d has been revoked", id, userID)
+	return nil
+}
+
+// Revoke all sessions of a user
+func (s *sessionRepository) RevokeAll(userID int) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return revokeUserSessions(db, s.logger, userID)
+}
+
+// revokeUserSessions revokes all sessions of a user within a transaction
+func revokeUserSessions(tx *gorm.DB, logger *log.Logger, userID int) error {
+	result := tx.Where("user_id = ?", userID).Delete(&database.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		logger.Printf("%d session(s) of user #%d have been revoked", result.RowsAffected, userID)
+	}
+
+	return nil
+}
+
+func formatRefreshToken(id, secret string) string {
+	return fmt.Sprintf("%s.%s", id, secret)
+}
+
+func parseRefreshToken(token string) (string, string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return fmt.Sprintf("%x", hash)
+}
+
+func hashEquals(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
+	"github.com/jinzhu/gorm"
+	"github.com/sarulabs/di"
+)
+
+// SettingsRepository contains methods to manage global settings
+type SettingsRepository interface {
+	// Get security settings
+	GetSecurity() (*model.SecuritySettings, error)
+
+	// Update security settings
+	UpdateSecurity(args *model.SecuritySettingsUpdateParams) (*model.SecuritySettings, error)
+}
+
+const settingsRepositoryKey = "SettingsRepository"
+
+// GetSettingsRepository returns an implementation of SettingsRepository from DI container
+func GetSettingsRepository(c di.Container) SettingsRepository {
+	return c.Get(settingsRepositoryKey).(SettingsRepository)
+}
+
+const securitySettingsName = "security"
+
+// An implementation of SettingsRepository
+type settingsRepository struct {
+	logger   *log.Logger
+	provider database.Provider
+}
+
+// Get security settings
+func (s *settingsRepository) GetSecurity() (*model.SecuritySettings, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	settings := &model.SecuritySettings{}
+	err = s.load(db, securitySettingsName, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// Update security settings
+func (s *settingsRepository) UpdateSecurity(args *model.SecuritySettingsUpdateParams) (*model.SecuritySettings, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	settings := &model.SecuritySettings{}
+	err = s.load(tx, securitySettingsName, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	args.ApplyTo(settings)
+
+	err = s.save(tx, securitySettingsName, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("security settings have been updated: %v", settings)
+	return settings, nil
+}
+
+// load reads a group of settings, missing settings are left with their default values
+func (s *settingsRepository) load(db *gorm.DB, name string, value interface{}) error {
+	eSetting := &database.Setting{}
+	err := db.Where("name = ?", name).First(eSetting).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	return json.Unmarshal([]byte(eSetting.Value), value)
+}
+
+func (s *settingsRepository) save(db *gorm.DB, name string, value interface{}) error {
+	buff, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return db.Save(&database.Setting{Name: name, Value: string(buff)}).Error
+}
@@ -1,11 +1,15 @@
 package service
 
 import (
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/itglobal/backupmonitor/pkg/component"
 	"github.com/itglobal/backupmonitor/pkg/database"
+	"github.com/itglobal/backupmonitor/pkg/model"
 	"github.com/itglobal/backupmonitor/pkg/storage"
 	"github.com/sarulabs/di"
 	"github.com/spf13/viper"
@@ -19,8 +23,31 @@ func Setup(builder component.Builder) {
 		Build: func(c di.Container) (interface{}, error) {
 			password := viper.GetString("JWT_KEY")
 			logger := log.New(log.Writer(), "[jwt] ", log.Flags())
+			ttl := viper.GetDuration("ACCESS_TOKEN_TTL")
 			userRepository := GetUserRepository(c)
-			return &jwtService{logger, password, jwt.SigningMethodHS256, userRepository}, nil
+			sessionRepository := GetSessionRepository(c)
+			return &jwtService{logger, password, jwt.SigningMethodHS256, ttl, userRepository, sessionRepository}, nil
+		},
+	})
+
+	// Session repository
+	builder.AddService(di.Def{
+		Name: sessionRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[jwt] ", log.Flags())
+			provider := database.GetProvider(c)
+			ttl := viper.GetDuration("REFRESH_TOKEN_TTL")
+			return &sessionRepository{logger, provider, ttl}, nil
+		},
+	})
+
+	// API token repository
+	builder.AddService(di.Def{
+		Name: apiTokenRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[tokens] ", log.Flags())
+			provider := database.GetProvider(c)
+			return &apiTokenRepository{logger, provider}, nil
 		},
 	})
 
@@ -39,6 +66,166 @@ func Setup(builder component.Builder) {
 		},
 	})
 
+	// Authenticator
+	builder.AddService(di.Def{
+		Name: authenticatorKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[auth] ", log.Flags())
+			userRepository := GetUserRepository(c)
+
+			var backends []Authenticator
+			for _, name := range strings.Split(viper.GetString("AUTH_BACKENDS"), ",") {
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "local":
+					backends = append(backends, &localAuthenticator{userRepository})
+				case "ldap":
+					roleMapping, err := model.ParseRoleMapping(viper.GetString("LDAP_ROLE_MAPPING"))
+					if err != nil {
+						return nil, err
+					}
+
+					config := LDAPConfig{
+						URL:               viper.GetString("LDAP_URL"),
+						StartTLS:          viper.GetBool("LDAP_START_TLS"),
+						BindDN:            viper.GetString("LDAP_BIND_DN"),
+						BindPassword:      viper.GetString("LDAP_BIND_PASSWORD"),
+						BaseDN:            viper.GetString("LDAP_BASE_DN"),
+						UserFilter:        viper.GetString("LDAP_USER_FILTER"),
+						UsernameAttribute: viper.GetString("LDAP_USERNAME_ATTRIBUTE"),
+						GroupAttribute:    viper.GetString("LDAP_GROUP_ATTRIBUTE"),
+						RoleMapping:       roleMapping,
+						DefaultRole:       model.Role(strings.ToLower(viper.GetString("LDAP_DEFAULT_ROLE"))),
+					}
+					if config.URL == "" {
+						return nil, fmt.Errorf("LDAP_URL is required for \"ldap\" auth backend")
+					}
+
+					backends = append(backends, &ldapAuthenticator{logger, config, userRepository})
+				case "":
+				default:
+					return nil, fmt.Errorf("unknown auth backend \"%s\"", name)
+				}
+			}
+
+			return &chainAuthenticator{logger, backends}, nil
+		},
+	})
+
+	// Settings repository
+	builder.AddService(di.Def{
+		Name: settingsRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[settings] ", log.Flags())
+			provider := database.GetProvider(c)
+			return &settingsRepository{logger, provider}, nil
+		},
+	})
+
+	// Audit log
+	builder.AddService(di.Def{
+		Name: auditLogKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[audit] ", log.Flags())
+			provider := database.GetProvider(c)
+			auditLog := &auditLog{logger, provider}
+			err := auditLog.Initialize()
+			if err != nil {
+				return nil, err
+			}
+			return auditLog, nil
+		},
+	})
+
+	// Lockout service
+	builder.AddService(di.Def{
+		Name: lockoutServiceKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[lockout] ", log.Flags())
+			policy := model.LockoutPolicy{
+				MaxFailures: map[model.LockoutScope]int{
+					model.LockoutScopeUser:      viper.GetInt("LOCKOUT_MAX_FAILURES"),
+					model.LockoutScopeIP:        viper.GetInt("LOCKOUT_MAX_IP_FAILURES"),
+					model.LockoutScopeAccessKey: viper.GetInt("LOCKOUT_MAX_KEY_FAILURES"),
+				},
+				Window:   viper.GetDuration("LOCKOUT_WINDOW"),
+				Duration: viper.GetDuration("LOCKOUT_DURATION"),
+				Delay:    viper.GetDuration("LOCKOUT_DELAY"),
+				MaxDelay: 30 * time.Second,
+			}
+			return &lockoutService{
+				logger:             logger,
+				policy:             policy,
+				notify:             viper.GetBool("LOCKOUT_NOTIFY"),
+				auditLog:           GetAuditLog(c),
+				router:             GetNotificationRouter(c),
+				deliveryRepository: GetDeliveryRepository(c),
+				counters:           make(map[model.LockoutKey]*model.Lockout),
+			}, nil
+		},
+	})
+
+	// MFA service
+	builder.AddService(di.Def{
+		Name: mfaServiceKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[mfa] ", log.Flags())
+			return &mfaService{
+				logger:             logger,
+				provider:           database.GetProvider(c),
+				settingsRepository: GetSettingsRepository(c),
+				issuer:             viper.GetString("MFA_ISSUER"),
+				challenges:         make(map[string]*mfaChallenge),
+			}, nil
+		},
+	})
+
+	// OpenID Connect service
+	builder.AddService(di.Def{
+		Name: oidcServiceKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[oidc] ", log.Flags())
+			roleMapping, err := model.ParseRoleMapping(viper.GetString("OIDC_ROLE_MAPPING"))
+			if err != nil {
+				return nil, err
+			}
+
+			redirectURL := viper.GetString("OIDC_REDIRECT_URL")
+			if redirectURL == "" {
+				redirectURL = strings.TrimRight(viper.GetString("PUBLIC_URL"), "/") + "/api/authorize/oidc/callback"
+			}
+
+			config := OIDCConfig{
+				Issuer:        viper.GetString("OIDC_ISSUER"),
+				ClientID:      viper.GetString("OIDC_CLIENT_ID"),
+				ClientSecret:  viper.GetString("OIDC_CLIENT_SECRET"),
+				RedirectURL:   redirectURL,
+				Scopes:        strings.Fields(viper.GetString("OIDC_SCOPES")),
+				UsernameClaim: viper.GetString("OIDC_USERNAME_CLAIM"),
+				GroupsClaim:   viper.GetString("OIDC_GROUPS_CLAIM"),
+				RoleMapping:   roleMapping,
+				DefaultRole:   model.Role(strings.ToLower(viper.GetString("OIDC_DEFAULT_ROLE"))),
+			}
+
+			return &oidcService{
+				logger:         logger,
+				config:         config,
+				userRepository: GetUserRepository(c),
+				logins:         make(map[string]*oidcLogin),
+				tickets:        make(map[string]*oidcTicket),
+			}, nil
+		},
+	})
+
+	// Project grant repository
+	builder.AddService(di.Def{
+		Name: projectGrantRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[users] ", log.Flags())
+			provider := database.GetProvider(c)
+			return &projectGrantRepository{logger, provider}, nil
+		},
+	})
+
 	// Project repository
 	builder.AddService(di.Def{
 		Name: projectRepositoryKey,
@@ -55,7 +242,12 @@ func Setup(builder component.Builder) {
 		Build: func(c di.Container) (interface{}, error) {
 			logger := log.New(log.Writer(), "[access] ", log.Flags())
 			provider := database.GetProvider(c)
-			return &accessKeyRepository{logger, provider}, nil
+			repository := &accessKeyRepository{logger, provider}
+			err := repository.Initialize()
+			if err != nil {
+				return nil, err
+			}
+			return repository, nil
 		},
 	})
 
@@ -65,9 +257,131 @@ func Setup(builder component.Builder) {
 		Build: func(c di.Container) (interface{}, error) {
 			logger := log.New(log.Writer(), "[backup] ", log.Flags())
 			provider := database.GetProvider(c)
-			store := storage.GetService(c)
+			registry := storage.GetRegistry(c)
+			projectRepository := GetProjectRepository(c)
+			quotas := GetQuotaService(c)
+			auditLog := GetAuditLog(c)
+			repository := &backupRepository{logger, provider, registry, projectRepository, quotas, auditLog}
+			err := repository.Initialize()
+			if err != nil {
+				return nil, err
+			}
+			return repository, nil
+		},
+	})
+
+	// Quota service
+	builder.AddService(di.Def{
+		Name: quotaServiceKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[quota] ", log.Flags())
+			globalQuota, err := model.ParseStorageQuota(viper.GetString("STORAGE_QUOTA"), viper.GetString("STORAGE_QUOTA_POLICY"))
+			if err != nil {
+				return nil, err
+			}
+
+			thresholds, err := model.ParseQuotaThresholds(viper.GetString("QUOTA_WARNING_THRESHOLDS"))
+			if err != nil {
+				return nil, err
+			}
+
+			diskAlert, err := model.ParseDiskSpaceAlert(viper.GetString("DISK_FREE_ALERT"))
+			if err != nil {
+				return nil, err
+			}
+
+			return &quotaService{
+				logger:             logger,
+				provider:           database.GetProvider(c),
+				storage:            storage.GetRegistry(c),
+				router:             GetNotificationRouter(c),
+				deliveryRepository: GetDeliveryRepository(c),
+				globalQuota:        globalQuota,
+				thresholds:         thresholds,
+				diskAlert:          diskAlert,
+				diskSpaceLow:       make(map[string]bool),
+			}, nil
+		},
+	})
+
+	// Notification delivery repository
+	builder.AddService(di.Def{
+		Name: deliveryRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[outbox] ", log.Flags())
+			provider := database.GetProvider(c)
+			return &deliveryRepository{logger, provider}, nil
+		},
+	})
+
+	// Notification target repository
+	builder.AddService(di.Def{
+		Name: notificationTargetRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[routing] ", log.Flags())
+			provider := database.GetProvider(c)
+			return &notificationTargetRepository{logger, provider}, nil
+		},
+	})
+
+	// Routing rule repository
+	builder.AddService(di.Def{
+		Name: routingRuleRepositoryKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[routing] ", log.Flags())
+			provider := database.GetProvider(c)
+			return &routingRuleRepository{logger, provider}, nil
+		},
+	})
+
+	// Notification router
+	builder.AddService(di.Def{
+		Name: notificationRouterKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[routing] ", log.Flags())
+			projectRepository := GetProjectRepository(c)
+			notificationTargetRepository := GetNotificationTargetRepository(c)
+			routingRuleRepository := GetRoutingRuleRepository(c)
+			return &notificationRouter{logger, projectRepository, notificationTargetRepository, routingRuleRepository}, nil
+		},
+	})
+
+	// Download link service
+	builder.AddService(di.Def{
+		Name: downloadLinkServiceKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[backup] ", log.Flags())
+			// A default JWT key is publicly known, so links signed with it could be forged
+			key := viper.GetString("DOWNLOAD_LINK_KEY")
+			if key == "" {
+				key = viper.GetString("JWT_KEY")
+				if key == DefaultJWTKey {
+					key = ""
+				}
+			}
+			if key == "" {
+				logger.Printf("signed download links are disabled: neither DOWNLOAD_LINK_KEY nor a non-default JWT_KEY is set")
+			}
+			return &downloadLinkService{
+				logger:    logger,
+				key:       []byte(key),
+				maxTTL:    viper.GetDuration("DOWNLOAD_LINK_MAX_TTL"),
+				publicURL: strings.TrimRight(viper.GetString("PUBLIC_URL"), "/"),
+				storage:   storage.GetRegistry(c),
+				presign:   viper.GetBool("S3_PRESIGNED_DOWNLOADS"),
+				now:       time.Now,
+			}, nil
+		},
+	})
+
+	// Digest service
+	builder.AddService(di.Def{
+		Name: digestServiceKey,
+		Build: func(c di.Container) (interface{}, error) {
+			logger := log.New(log.Writer(), "[digest] ", log.Flags())
 			projectRepository := GetProjectRepository(c)
-			return &backupRepository{logger, provider, store, projectRepository}, nil
+			backupRepository := GetBackupRepository(c)
+			return &digestService{logger, projectRepository, backupRepository}, nil
 		},
 	})
 }
@@ -2,6 +2,8 @@ package service
 
 import (
 	"log"
+	"strings"
+	"time"
 
 	"github.com/itglobal/backupmonitor/pkg/database"
 	"github.com/itglobal/backupmonitor/pkg/model"
@@ -12,11 +14,37 @@ import (
 
 // UserRepository contains methods to manage user accounts
 type UserRepository interface {
+	// List all users
+	List() ([]*model.User, error)
+
 	// Get a user by its username
 	Get(username string) (*model.User, error)
 
-	// Set user's password
+	// Get a user by its ID
+	GetByID(id int) (*model.User, error)
+
+	// Get an externally authenticated user by its identity at provider
+	GetByExternalID(source model.UserSource, externalID string) (*model.User, error)
+
+	// Create new user.
+	// If password is not specified, a random one is generated and returned
+	Create(args *model.UserCreateParams) (*model.User, string, error)
+
+	// Create or update an externally authenticated user.
+	// Users that already exist but are managed by another source (or bound to another external ID) are rejected
+	Provision(source model.UserSource, args *model.UserProvisionParams) (*model.User, error)
+
+	// Update an existing user
+	Update(id int, args *model.UserUpdateParams) (*model.User, error)
+
+	// Delete an existing user
+	Delete(id int) error
+
+	// Set user's password and revoke all user's sessions
 	SetPassword(id int, password string) error
+
+	// Reset user's password to a random one and return it
+	ResetPassword(id int) (string, error)
 }
 
 const userRepositoryKey = "UserRepository"
@@ -42,39 +70,47 @@ func (s *userRepository) Initialize() error {
 	tx := db.Begin()
 	defer tx.RollbackUnlessCommitted()
 
-	count := 0
-	err = tx.Model(&database.User{}).Count(&count).Error
+	// Users created before roles were introduced had full access, so they become admins
+	err = tx.Model(&database.User{}).
+		Where("role IS NULL OR role = ''").
+		Update("role", string(model.RoleAdmin)).Error
 	if err != nil {
 		return err
 	}
 
-	if count > 0 {
-		return nil
+	// Users without project grants used to have their role on all projects, so they keep it
+	err = tx.Exec(`UPDATE users SET all_projects = (id NOT IN (SELECT user_id FROM project_grants)) WHERE all_projects IS NULL`).Error
+	if err != nil {
+		return err
 	}
 
-	// Generate new admin user
-	g, err := generator.New(&generator.Config{
-		Length:                     16,
-		IncludeSymbols:             false,
-		IncludeNumbers:             true,
-		IncludeLowercaseLetters:    true,
-		IncludeUppercaseLetters:    true,
-		ExcludeSimilarCharacters:   false,
-		ExcludeAmbiguousCharacters: false,
-	})
+	// Admin user is generated only on first boot, so its password is written into log only once.
+	// Users can't delete themselves, so there is always at least one user left
+	count := 0
+	err = tx.Model(&database.User{}).Count(&count).Error
 	if err != nil {
 		return err
 	}
 
-	password, err := g.Generate()
+	if count > 0 {
+		return tx.Commit().Error
+	}
+
+	// Generate new admin user
+	password, err := generatePassword()
 	if err != nil {
 		return err
 	}
 
 	mUser := &model.User{
-		UserName: "admin",
+		UserName:    "admin",
+		DisplayName: "Administrator",
+		Role:        model.RoleAdmin,
+		Source:      model.UserSourceLocal,
+		AllProjects: true,
+		CreatedAt:   time.Now().UTC(),
 	}
-	mUser.SetPassword(*password)
+	mUser.SetPassword(password)
 
 	eUser := &database.User{}
 	eUser.CopyFromModel(mUser)
@@ -85,11 +121,34 @@ func (s *userRepository) Initialize() error {
 	s.logger.Printf("new admin user #%d has been generated", eUser.ID)
 	s.logger.Printf("use the following credentials to log in:")
 	s.logger.Printf("  username: \"%s\"", mUser.UserName)
-	s.logger.Printf("  password: \"%s\"", *password)
+	s.logger.Printf("  password: \"%s\"", password)
+	s.logger.Printf("this password won't be shown again, please change it after logging in")
 
 	return nil
 }
 
+// List all users
+func (s *userRepository) List() ([]*model.User, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var eUsers []*database.User
+	err = db.Order("username").Find(&eUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mUsers := make([]*model.User, len(eUsers))
+	for i, eUser := range eUsers {
+		mUsers[i] = eUser.ToModel()
+	}
+
+	return mUsers, nil
+}
+
 // Get a user by its username
 func (s *userRepository) Get(username string) (*model.User, error) {
 	db, err := s.provider.Open()
@@ -114,6 +173,294 @@ func (s *userRepository) Get(username string) (*model.User, error) {
 	return mUser, nil
 }
 
+// Get an externally authenticated user by its identity at provider
+func (s *userRepository) GetByExternalID(source model.UserSource, externalID string) (*model.User, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eUser := &database.User{}
+	err = db.Where("source = ? AND external_id = ?", string(source), externalID).First(eUser).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "%s user \"%s\" doesn't exist", source, externalID)
+		}
+
+		return nil, err
+	}
+
+	return eUser.ToModel(), nil
+}
+
+// Get a user by its ID
+func (s *userRepository) GetByID(id int) (*model.User, error) {
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	eUser, err := s.find(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return eUser.ToModel(), nil
+}
+
+// Create new user
+func (s *userRepository) Create(args *model.UserCreateParams) (*model.User, string, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	password := args.Password
+	if password == "" {
+		password, err = generatePassword()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	count := 0
+	err = tx.Model(&database.User{}).Where("username = ?", args.UserName).Count(&count).Error
+	if err != nil {
+		return nil, "", err
+	}
+	if count > 0 {
+		return nil, "", model.NewError(model.EConflict, "user \"%s\" already exists", args.UserName)
+	}
+
+	mUser := &model.User{Source: model.UserSourceLocal, CreatedAt: time.Now().UTC()}
+	args.ApplyTo(mUser)
+	err = mUser.SetPassword(password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	eUser := &database.User{}
+	eUser.CopyFromModel(mUser)
+	err = tx.Create(eUser).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	eUser.CopyToModel(mUser)
+	s.logger.Printf("new user #%d has been created: %v", mUser.ID, mUser)
+
+	// Don't return a password that has been chosen by caller
+	if args.Password != "" {
+		password = ""
+	}
+
+	return mUser, password, nil
+}
+
+// Create or update an externally authenticated user
+func (s *userRepository) Provision(source model.UserSource, args *model.UserProvisionParams) (*model.User, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	// Users bound to an external ID are found by it, so username changes at provider don't affect them
+	eUser := &database.User{}
+	err = gorm.ErrRecordNotFound
+	if args.ExternalID != "" {
+		err = tx.Where("source = ? AND external_id = ?", string(source), args.ExternalID).First(eUser).Error
+		if err == nil {
+			args.UserName = eUser.UserName
+		}
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		err = tx.Where("username = ?", args.UserName).First(eUser).Error
+	}
+
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	created := err == gorm.ErrRecordNotFound
+	mUser := &model.User{}
+	if created {
+		// External users can't sign in with a password, so it's just a random unknown one
+		password, err := generatePassword()
+		if err != nil {
+			return nil, err
+		}
+
+		mUser.Role = model.RoleViewer
+		mUser.Source = source
+		mUser.AllProjects = true
+		mUser.CreatedAt = time.Now().UTC()
+		err = mUser.SetPassword(password)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		eUser.CopyToModel(mUser)
+
+		// A local user can't be taken over by signing in with a provider that reports the same username
+		if mUser.Source != source {
+			return nil, model.NewError(model.EAccessDenied, "user \"%s\" already exists and isn't managed by %s", mUser.UserName, source)
+		}
+
+		// Neither can a user bound to another external ID. Users provisioned before external IDs were stored
+		// are bound on their next login, but only if provider has verified the same email
+		if args.ExternalID != "" && mUser.ExternalID != args.ExternalID {
+			if mUser.ExternalID != "" || args.Email == "" || !strings.EqualFold(mUser.Email, args.Email) {
+				return nil, model.NewError(model.EAccessDenied, "user \"%s\" already exists and can't be bound to this %s account", mUser.UserName, source)
+			}
+		}
+
+		if mUser.IsDisabled {
+			return nil, model.NewError(model.EAccessDenied, "user \"%s\" is disabled", mUser.UserName)
+		}
+	}
+
+	args.ApplyTo(mUser)
+	eUser.CopyFromModel(mUser)
+
+	err = tx.Save(eUser).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	eUser.CopyToModel(mUser)
+	if created {
+		s.logger.Printf("new user #%d has been provisioned by %s: %v", mUser.ID, source, mUser)
+	}
+
+	return mUser, nil
+}
+
+// Update an existing user
+func (s *userRepository) Update(id int, args *model.UserUpdateParams) (*model.User, error) {
+	args.Normalize()
+	err := args.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eUser, err := s.find(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mUser := eUser.ToModel()
+	args.ApplyTo(mUser)
+	eUser.CopyFromModel(mUser)
+
+	err = tx.Save(eUser).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Disabled users are signed out immediately
+	if mUser.IsDisabled {
+		err = revokeUserSessions(tx, s.logger, mUser.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("user #%d has been updated: %v", mUser.ID, mUser)
+	return mUser, nil
+}
+
+// Delete an existing user
+func (s *userRepository) Delete(id int) error {
+	db, err := s.provider.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	eUser, err := s.find(tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("user_id = ?", id).Delete(&database.ProjectGrant{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = revokeUserSessions(tx, s.logger, id)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("user_id = ?", id).Delete(&database.APIToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Delete(eUser).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("user #%d (\"%s\") has been deleted", eUser.ID, eUser.UserName)
+	return nil
+}
+
 // Set user's password
 func (s *userRepository) SetPassword(id int, password string) error {
 	db, err := s.provider.Open()
@@ -145,6 +492,12 @@ func (s *userRepository) SetPassword(id int, password string) error {
 		return err
 	}
 
+	// Password change signs user out everywhere
+	err = revokeUserSessions(tx, s.logger, eUser.ID)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit().Error
 	if err != nil {
 		return err
@@ -154,3 +507,63 @@ func (s *userRepository) SetPassword(id int, password string) error {
 
 	return nil
 }
+
+// Reset user's password to a random one and return it
+func (s *userRepository) ResetPassword(id int) (string, error) {
+	user, err := s.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	if user.Source != model.UserSourceLocal {
+		return "", model.NewError(model.EBadRequest, "user \"%s\" is managed by %s and has no password", user.UserName, user.Source)
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.SetPassword(id, password)
+	if err != nil {
+		return "", err
+	}
+
+	return password, nil
+}
+
+func (s *userRepository) find(db *gorm.DB, id int) (*database.User, error) {
+	eUser := &database.User{}
+	err := db.Where("id = ?", id).First(eUser).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, model.NewError(model.ENotFound, "user #%d doesn't exist", id)
+		}
+
+		return nil, err
+	}
+
+	return eUser, nil
+}
+
+func generatePassword() (string, error) {
+	g, err := generator.New(&generator.Config{
+		Length:                     16,
+		IncludeSymbols:             false,
+		IncludeNumbers:             true,
+		IncludeLowercaseLetters:    true,
+		IncludeUppercaseLetters:    true,
+		ExcludeSimilarCharacters:   false,
+		ExcludeAmbiguousCharacters: false,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	password, err := g.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	return *password, nil
+}
@@ -1,11 +1,17 @@
 package storage
 
 import (
+	"fmt"
 	"io"
 	"log"
+	"path"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/itglobal/backupmonitor/pkg/component"
 	"github.com/sarulabs/di"
+	"github.com/spf13/viper"
 )
 
 // FileRef is a reference to a storage file
@@ -28,6 +34,54 @@ type Service interface {
 	Delete(file FileRef) error
 }
 
+// Presigner is implemented by storages that are able to issue
+// short-lived download URLs which bypass the app
+type Presigner interface {
+	// Create a presigned download URL for existing file
+	PresignDownload(file FileRef, downloadName string, ttl time.Duration) (string, error)
+}
+
+// LockMode is a WORM (write once, read many) protection mode of storage files
+type LockMode string
+
+const (
+	// LockModeNone means files aren't locked
+	LockModeNone LockMode = ""
+
+	// LockModeGovernance locks files, but users with special permissions are able to remove locks
+	LockModeGovernance LockMode = "governance"
+
+	// LockModeCompliance locks files, nobody is able to remove locks before they expire
+	LockModeCompliance LockMode = "compliance"
+)
+
+// ParseLockMode parses a lock mode string (empty string means no locks)
+func ParseLockMode(str string) (LockMode, error) {
+	mode := LockMode(strings.ToLower(strings.TrimSpace(str)))
+	switch mode {
+	case LockModeNone, LockModeGovernance, LockModeCompliance:
+		return mode, nil
+	default:
+		return LockModeNone, fmt.Errorf("\"%s\" is not a valid storage lock mode", str)
+	}
+}
+
+// Locker is implemented by storages that are able to protect files
+// from being deleted or overwritten until a retention date (WORM mode)
+type Locker interface {
+	// Lock mode of new files (LockModeNone if WORM mode is disabled)
+	LockMode() LockMode
+
+	// Lock existing file until specified time
+	Lock(file FileRef, until time.Time) error
+}
+
+// SpaceReporter is implemented by storages that are able to report free disk space
+type SpaceReporter interface {
+	// Free and total disk space in bytes
+	DiskSpace() (free uint64, total uint64, err error)
+}
+
 type serviceInternal interface {
 	Service
 
@@ -35,32 +89,191 @@ type serviceInternal interface {
 	Initialize() error
 }
 
-const serviceKey = "StorageService"
+// Backend type names
+const (
+	// BackendTypeFileSystem is a file system storage
+	BackendTypeFileSystem = "fs"
+
+	// BackendTypeS3 is an S3 (or S3-compatible) storage
+	BackendTypeS3 = "s3"
+)
+
+// DefaultBackendName is a name of the only backend if named backends are not configured
+const DefaultBackendName = "default"
+
+// Backend is a named storage
+type Backend struct {
+	Name    string
+	Type    string
+	Service Service
+}
+
+// Registry contains configured storage backends
+type Registry interface {
+	// Get a backend's storage by backend name (default backend if name is empty)
+	Get(name string) (Service, error)
+
+	// Check if a backend exists
+	Has(name string) bool
+
+	// Name of default backend
+	Default() string
+
+	// List all backends
+	List() []*Backend
+}
+
+const registryKey = "StorageRegistry"
+
+// GetRegistry returns an implementation of Registry from DI container
+func GetRegistry(c di.Container) Registry {
+	return c.Get(registryKey).(Registry)
+}
+
+type registry struct {
+	backends    []*Backend
+	defaultName string
+}
+
+// Get a backend's storage by backend name
+func (r *registry) Get(name string) (Service, error) {
+	if name == "" {
+		name = r.defaultName
+	}
 
-// GetService returns an implementation Service from DI container
-func GetService(c di.Container) Service {
-	return c.Get(serviceKey).(Service)
+	for _, b := range r.backends {
+		if b.Name == name {
+			return b.Service, nil
+		}
+	}
+
+	return nil, fmt.Errorf("storage backend \"%s\" is not configured", name)
+}
+
+// Check if a backend exists
+func (r *registry) Has(name string) bool {
+	_, err := r.Get(name)
+	return err == nil
+}
+
+// Name of default backend
+func (r *registry) Default() string {
+	return r.defaultName
+}
+
+// List all backends
+func (r *registry) List() []*Backend {
+	return append([]*Backend{}, r.backends...)
 }
 
+var backendNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 // Setup configures package services
 func Setup(builder component.Builder) {
 	builder.AddService(di.Def{
-		Name: serviceKey,
+		Name: registryKey,
 		Build: func(c di.Container) (interface{}, error) {
-			logger := log.New(log.Writer(), "[storage] ", log.Flags())
+			lockMode, err := ParseLockMode(viper.GetString("STORAGE_LOCK_MODE"))
+			if err != nil {
+				return nil, err
+			}
 
-			s := createS3Service(c, logger)
+			names := make([]string, 0)
+			for _, name := range strings.Split(viper.GetString("STORAGE_BACKENDS"), ",") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name != "" {
+					names = append(names, name)
+				}
+			}
 
-			if s == nil {
-				s = createFileSystemService(c, logger)
+			// Without named backends there's a single backend configured with S3_* variables (or file system)
+			if len(names) == 0 {
+				logger := log.New(log.Writer(), "[storage] ", log.Flags())
+				backend := &Backend{Name: DefaultBackendName, Type: BackendTypeS3}
+				s := createS3Service(logger, "S3_", lockMode)
+				if s == nil {
+					backend.Type = BackendTypeFileSystem
+					s = createFileSystemService(logger, path.Join(viper.GetString("VAR"), "blob"), lockMode)
+				}
+
+				err = s.Initialize()
+				if err != nil {
+					return nil, err
+				}
+
+				backend.Service = s
+				return &registry{[]*Backend{backend}, DefaultBackendName}, nil
 			}
 
-			err := s.Initialize()
-			if err != nil {
-				return nil, err
+			r := &registry{defaultName: strings.ToLower(strings.TrimSpace(viper.GetString("STORAGE_DEFAULT")))}
+			if r.defaultName == "" {
+				r.defaultName = names[0]
+			}
+
+			for _, name := range names {
+				if !backendNameRegex.MatchString(name) {
+					return nil, fmt.Errorf("\"%s\" is not a valid storage backend name", name)
+				}
+
+				if r.Has(name) {
+					return nil, fmt.Errorf("storage backend \"%s\" is configured twice", name)
+				}
+
+				backend, err := createBackend(name, lockMode)
+				if err != nil {
+					return nil, err
+				}
+
+				r.backends = append(r.backends, backend)
+			}
+
+			if !r.Has(r.defaultName) {
+				return nil, fmt.Errorf("default storage backend \"%s\" is not configured", r.defaultName)
 			}
 
-			return s, nil
+			return r, nil
 		},
 	})
 }
+
+// createBackend creates a named backend configured with STORAGE_<NAME>_* variables
+func createBackend(name string, defaultLockMode LockMode) (*Backend, error) {
+	prefix := "STORAGE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	logger := log.New(log.Writer(), fmt.Sprintf("[storage:%s] ", name), log.Flags())
+
+	lockMode := defaultLockMode
+	if str := viper.GetString(prefix + "LOCK_MODE"); str != "" {
+		var err error
+		lockMode, err = ParseLockMode(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backend := &Backend{Name: name, Type: strings.ToLower(viper.GetString(prefix + "TYPE"))}
+
+	var s serviceInternal
+	switch backend.Type {
+	case BackendTypeFileSystem:
+		directory := viper.GetString(prefix + "PATH")
+		if directory == "" {
+			directory = path.Join(viper.GetString("VAR"), "blob", name)
+		}
+		s = createFileSystemService(logger, directory, lockMode)
+	case BackendTypeS3:
+		s = createS3Service(logger, prefix, lockMode)
+		if s == nil {
+			return nil, fmt.Errorf("%sBUCKET, %sACCESS_KEY and %sSECRET_KEY are required for storage backend \"%s\"", prefix, prefix, prefix, name)
+		}
+	default:
+		return nil, fmt.Errorf("%sTYPE must be either \"%s\" or \"%s\"", prefix, BackendTypeFileSystem, BackendTypeS3)
+	}
+
+	err := s.Initialize()
+	if err != nil {
+		return nil, err
+	}
+
+	backend.Service = s
+	return backend, nil
+}
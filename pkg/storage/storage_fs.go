@@ -1,26 +1,29 @@
 package storage
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
-
-	"github.com/sarulabs/di"
-	"github.com/spf13/viper"
+	"strings"
+	"time"
 )
 
+// Lock dates of files are kept in this subdirectory
+const lockDirectory = ".locks"
+
 type filesystemServiceImpl struct {
 	logger    *log.Logger
 	directory string
+	lockMode  LockMode
 }
 
-func createFileSystemService(c di.Container, logger *log.Logger) serviceInternal {
-	directory := path.Join(viper.GetString("VAR"), "blob")
+func createFileSystemService(logger *log.Logger, directory string, lockMode LockMode) serviceInternal {
 	directory = path.Clean(directory)
 
-	s := &filesystemServiceImpl{logger, directory}
+	s := &filesystemServiceImpl{logger, directory, lockMode}
 	return s
 }
 
@@ -32,6 +35,11 @@ func (s *filesystemServiceImpl) Initialize() error {
 		return err
 	}
 
+	if s.lockMode != LockModeNone {
+		s.logger.Printf("using file system as append-only storage (see \"%s\")", s.directory)
+		return nil
+	}
+
 	s.logger.Printf("using file system as storage (see \"%s\")", s.directory)
 	return nil
 }
@@ -48,9 +56,15 @@ func (s *filesystemServiceImpl) Upload(filename FileRef, source io.Reader) (File
 		return emptyFileRef, err
 	}
 
+	// Append-only storage never overwrites existing files
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if s.lockMode != LockModeNone {
+		flags = os.O_RDWR | os.O_CREATE | os.O_EXCL
+	}
+
 	var n int64
 	{
-		file, err := os.Create(fullFileName)
+		file, err := os.OpenFile(fullFileName, flags, 0666)
 		if err != nil {
 			s.logger.Printf("unable to create file \"%s\": %v", fullFileName, err)
 			return emptyFileRef, err
@@ -97,7 +111,7 @@ func (s *filesystemServiceImpl) List() ([]FileRef, error) {
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 			items, err = s.listDirectory(entry.Name(), items)
 			if err != nil {
 				return nil, err
@@ -135,7 +149,18 @@ func (s *filesystemServiceImpl) listDirectory(directory string, items []FileRef)
 // Delete existing file
 func (s *filesystemServiceImpl) Delete(file FileRef) error {
 	fullFileName := path.Join(s.directory, string(file))
-	err := os.Remove(fullFileName)
+
+	until, err := s.lockedUntil(file)
+	if err != nil {
+		return err
+	}
+
+	if until != nil && time.Now().Before(*until) {
+		s.logger.Printf("won't remove file \"%s\" since it's locked until %s", fullFileName, until.Format(time.RFC3339))
+		return fmt.Errorf("file \"%s\" is locked until %s", file, until.Format(time.RFC3339))
+	}
+
+	err = os.Remove(fullFileName)
 	if err != nil {
 		if os.IsNotExist(err) {
 			s.logger.Printf("won't remove file \"%s\" since it doesn't exist", fullFileName)
@@ -145,6 +170,84 @@ func (s *filesystemServiceImpl) Delete(file FileRef) error {
 		return err
 	}
 
+	if until != nil {
+		err = os.Remove(s.lockFileName(file))
+		if err != nil && !os.IsNotExist(err) {
+			s.logger.Printf("unable to remove lock of file \"%s\": %v", fullFileName, err)
+		}
+	}
+
 	s.logger.Printf("file \"%s\" has been removed", fullFileName)
 	return nil
 }
+
+// Lock mode of new files
+func (s *filesystemServiceImpl) LockMode() LockMode {
+	return s.lockMode
+}
+
+// Lock existing file until specified time.
+// File system has no governance, so locks are enforced by service itself in both modes
+func (s *filesystemServiceImpl) Lock(file FileRef, until time.Time) error {
+	fullFileName := path.Join(s.directory, string(file))
+
+	// Existing lock can be extended, but not shortened
+	current, err := s.lockedUntil(file)
+	if err != nil {
+		return err
+	}
+
+	if current != nil && current.After(until) {
+		return fmt.Errorf("file \"%s\" is already locked until %s", file, current.Format(time.RFC3339))
+	}
+
+	lockFileName := s.lockFileName(file)
+	directory, _ := path.Split(lockFileName)
+	err = os.MkdirAll(directory, 0700)
+	if err != nil {
+		s.logger.Printf("unable to create directory \"%s\": %v", directory, err)
+		return err
+	}
+
+	err = ioutil.WriteFile(lockFileName, []byte(until.UTC().Format(time.RFC3339)), 0600)
+	if err != nil {
+		s.logger.Printf("unable to lock file \"%s\": %v", fullFileName, err)
+		return err
+	}
+
+	// Locked file is read-only, as a safeguard against other processes
+	err = os.Chmod(fullFileName, 0444)
+	if err != nil {
+		s.logger.Printf("unable to make file \"%s\" read-only: %v", fullFileName, err)
+		return err
+	}
+
+	s.logger.Printf("file \"%s\" has been locked until %s", fullFileName, until.UTC().Format(time.RFC3339))
+	return nil
+}
+
+func (s *filesystemServiceImpl) lockFileName(file FileRef) string {
+	return path.Join(s.directory, lockDirectory, string(file))
+}
+
+// lockedUntil returns lock date of a file (or nil if file isn't locked)
+func (s *filesystemServiceImpl) lockedUntil(file FileRef) (*time.Time, error) {
+	lockFileName := s.lockFileName(file)
+	content, err := ioutil.ReadFile(lockFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		s.logger.Printf("unable to read lock file \"%s\": %v", lockFileName, err)
+		return nil, err
+	}
+
+	until, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+	if err != nil {
+		s.logger.Printf("unable to parse lock file \"%s\": %v", lockFileName, err)
+		return nil, err
+	}
+
+	return &until, nil
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package storage
+
+import "syscall"
+
+// Free and total disk space in bytes
+func (s *filesystemServiceImpl) DiskSpace() (uint64, uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(s.directory, &stat)
+	if err != nil {
+		s.logger.Printf("unable to get disk space of \"%s\": %v", s.directory, err)
+		return 0, 0, err
+	}
+
+	free := stat.Bavail * uint64(stat.Bsize)
+	total := stat.Blocks * uint64(stat.Bsize)
+	return free, total, nil
+}
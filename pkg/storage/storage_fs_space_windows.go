@@ -0,0 +1,8 @@
+package storage
+
+import "errors"
+
+// Free and total disk space in bytes
+func (s *filesystemServiceImpl) DiskSpace() (uint64, uint64, error) {
+	return 0, 0, errors.New("disk space reports are not supported on windows")
+}
@@ -1,12 +1,23 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/minio/minio-go"
-	"github.com/sarulabs/di"
+	"github.com/minio/minio-go/pkg/s3signer"
 	"github.com/spf13/viper"
 )
 
@@ -18,13 +29,16 @@ type s3ServiceImpl struct {
 	accessKey string
 	secretKey string
 	useHTTPS  bool
+	lockMode  LockMode
 }
 
-func createS3Service(c di.Container, logger *log.Logger) serviceInternal {
-	bucket := viper.GetString("S3_BUCKET")
-	accessKey := viper.GetString("S3_ACCESS_KEY")
-	secretKey := viper.GetString("S3_SECRET_KEY")
-	domain := viper.GetString("S3_DOMAIN")
+// createS3Service creates an S3 storage configured with <prefix>BUCKET, <prefix>ACCESS_KEY,
+// <prefix>SECRET_KEY and <prefix>DOMAIN variables, returns nil if S3 is not configured
+func createS3Service(logger *log.Logger, prefix string, lockMode LockMode) serviceInternal {
+	bucket := viper.GetString(prefix + "BUCKET")
+	accessKey := viper.GetString(prefix + "ACCESS_KEY")
+	secretKey := viper.GetString(prefix + "SECRET_KEY")
+	domain := viper.GetString(prefix + "DOMAIN")
 
 	if bucket == "" || accessKey == "" || secretKey == "" {
 		return nil
@@ -54,6 +68,7 @@ func createS3Service(c di.Container, logger *log.Logger) serviceInternal {
 		accessKey: accessKey,
 		secretKey: secretKey,
 		useHTTPS:  useHTTPS,
+		lockMode:  lockMode,
 	}
 
 	return impl
@@ -79,6 +94,17 @@ func (s *s3ServiceImpl) Initialize() error {
 		break
 	}
 
+	if s.lockMode != LockModeNone {
+		err = s.checkObjectLock()
+		if err != nil {
+			s.logger.Printf("unable to use s3 bucket \"%s\" in %s lock mode: %v", s.bucket, s.lockMode, err)
+			return err
+		}
+
+		s.logger.Printf("using s3 service %s as storage (bucket \"%s\", %s lock mode)", s.endpoint, s.bucket, s.lockMode)
+		return nil
+	}
+
 	s.logger.Printf("using s3 service %s as storage (bucket \"%s\")", s.endpoint, s.bucket)
 	return nil
 }
@@ -144,3 +170,131 @@ func (s *s3ServiceImpl) Delete(filename FileRef) error {
 	s.logger.Printf("s3 file \"%s:%s\" has been removed", s.bucket, filename)
 	return nil
 }
+
+// Create a presigned download URL for existing file
+func (s *s3ServiceImpl) PresignDownload(filename FileRef, downloadName string, ttl time.Duration) (string, error) {
+	params := make(url.Values)
+	params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=%s", downloadName))
+
+	u, err := s.client.PresignedGetObject(s.bucket, string(filename), ttl, params)
+	if err != nil {
+		s.logger.Printf("unable to presign s3 file \"%s:%s\": %v", s.bucket, filename, err)
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// Lock mode of new files
+func (s *s3ServiceImpl) LockMode() LockMode {
+	return s.lockMode
+}
+
+// s3Retention is a body of PutObjectRetention request
+type s3Retention struct {
+	XMLName         xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ Retention"`
+	Mode            string   `xml:"Mode"`
+	RetainUntilDate string   `xml:"RetainUntilDate"`
+}
+
+// Lock existing file until specified time using S3 Object Lock
+func (s *s3ServiceImpl) Lock(filename FileRef, until time.Time) error {
+	body, err := xml.Marshal(&s3Retention{
+		Mode:            strings.ToUpper(string(s.lockMode)),
+		RetainUntilDate: until.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.execute("PUT", string(filename), "retention", body)
+	if err != nil {
+		s.logger.Printf("unable to lock s3 file \"%s:%s\": %v", s.bucket, filename, err)
+		return err
+	}
+
+	s.logger.Printf("s3 file \"%s:%s\" has been locked until %s", s.bucket, filename, until.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// checkObjectLock makes sure Object Lock is enabled for bucket
+func (s *s3ServiceImpl) checkObjectLock() error {
+	body, err := s.execute("GET", "", "object-lock", nil)
+	if err != nil {
+		return err
+	}
+
+	var config struct {
+		ObjectLockEnabled string `xml:"ObjectLockEnabled"`
+	}
+	err = xml.Unmarshal(body, &config)
+	if err != nil {
+		return err
+	}
+
+	if config.ObjectLockEnabled != "Enabled" {
+		return fmt.Errorf("object lock is not enabled for bucket \"%s\"", s.bucket)
+	}
+
+	return nil
+}
+
+// execute sends a signed request to a bucket (or bucket's object) subresource.
+// Used for Object Lock APIs which aren't supported by minio client
+func (s *s3ServiceImpl) execute(method, object, subresource string, body []byte) ([]byte, error) {
+	location, err := s.client.GetBucketLocation(s.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if s.useHTTPS {
+		scheme = "https"
+	}
+
+	u := &url.URL{
+		Scheme:   scheme,
+		Host:     s.endpoint,
+		Path:     "/" + s.bucket,
+		RawQuery: subresource,
+	}
+	if object != "" {
+		u.Path += "/" + object
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	sha := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sha[:]))
+	if body != nil {
+		sum := md5.Sum(body)
+		req.Header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
+	req = s3signer.SignV4(*req, s.accessKey, s.secretKey, "", location)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e minio.ErrorResponse
+		if xml.Unmarshal(respBody, &e) == nil && e.Code != "" {
+			return nil, e
+		}
+
+		return nil, fmt.Errorf("s3 service responded with %s", resp.Status)
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Errors collects errors of a batch operation which continues past failed items
+type Errors []error
+
+// Add appends an error to the list, nil errors are ignored
+func (e *Errors) Add(err error) {
+	if err != nil {
+		*e = append(*e, err)
+	}
+}
+
+// Err returns nil if there are no errors, otherwise the list itself (or its only error)
+func (e Errors) Err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+// Error returns messages of all errors in the list
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d errors occurred: %s", len(e), strings.Join(messages, "; "))
+}